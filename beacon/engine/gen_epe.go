@@ -7,7 +7,9 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 var _ = (*executionPayloadEnvelopeMarshaling)(nil)
@@ -15,20 +17,35 @@ var _ = (*executionPayloadEnvelopeMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (e ExecutionPayloadEnvelope) MarshalJSON() ([]byte, error) {
 	type ExecutionPayloadEnvelope struct {
-		ExecutionPayload *ExecutableData `json:"executionPayload"  gencodec:"required"`
-		BlockValue       *hexutil.Big    `json:"blockValue"  gencodec:"required"`
+		ExecutionPayload      *ExecutableData       `json:"executionPayload"  gencodec:"required"`
+		BlockValue            *hexutil.Big          `json:"blockValue"  gencodec:"required"`
+		BundleValue           *hexutil.Big          `json:"bundleValue,omitempty"`
+		PublicValue           *hexutil.Big          `json:"publicValue,omitempty"`
+		BundleResults         []*types.BundleResult `json:"bundleResults,omitempty"`
+		BlobsBundle           *BlobsBundleV1        `json:"blobsBundle,omitempty"`
+		ParentBeaconBlockRoot *common.Hash          `json:"parentBeaconBlockRoot,omitempty"`
 	}
 	var enc ExecutionPayloadEnvelope
 	enc.ExecutionPayload = e.ExecutionPayload
 	enc.BlockValue = (*hexutil.Big)(e.BlockValue)
+	enc.BundleValue = (*hexutil.Big)(e.BundleValue)
+	enc.PublicValue = (*hexutil.Big)(e.PublicValue)
+	enc.BundleResults = e.BundleResults
+	enc.BlobsBundle = e.BlobsBundle
+	enc.ParentBeaconBlockRoot = e.ParentBeaconBlockRoot
 	return json.Marshal(&enc)
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (e *ExecutionPayloadEnvelope) UnmarshalJSON(input []byte) error {
 	type ExecutionPayloadEnvelope struct {
-		ExecutionPayload *ExecutableData `json:"executionPayload"  gencodec:"required"`
-		BlockValue       *hexutil.Big    `json:"blockValue"  gencodec:"required"`
+		ExecutionPayload      *ExecutableData       `json:"executionPayload"  gencodec:"required"`
+		BlockValue            *hexutil.Big          `json:"blockValue"  gencodec:"required"`
+		BundleValue           *hexutil.Big          `json:"bundleValue,omitempty"`
+		PublicValue           *hexutil.Big          `json:"publicValue,omitempty"`
+		BundleResults         []*types.BundleResult `json:"bundleResults,omitempty"`
+		BlobsBundle           *BlobsBundleV1        `json:"blobsBundle,omitempty"`
+		ParentBeaconBlockRoot *common.Hash          `json:"parentBeaconBlockRoot,omitempty"`
 	}
 	var dec ExecutionPayloadEnvelope
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -42,5 +59,20 @@ func (e *ExecutionPayloadEnvelope) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'blockValue' for ExecutionPayloadEnvelope")
 	}
 	e.BlockValue = (*big.Int)(dec.BlockValue)
+	if dec.BundleValue != nil {
+		e.BundleValue = (*big.Int)(dec.BundleValue)
+	}
+	if dec.PublicValue != nil {
+		e.PublicValue = (*big.Int)(dec.PublicValue)
+	}
+	if dec.BundleResults != nil {
+		e.BundleResults = dec.BundleResults
+	}
+	if dec.BlobsBundle != nil {
+		e.BlobsBundle = dec.BlobsBundle
+	}
+	if dec.ParentBeaconBlockRoot != nil {
+		e.ParentBeaconBlockRoot = dec.ParentBeaconBlockRoot
+	}
 	return nil
 }
@@ -61,6 +61,8 @@ type ExecutableData struct {
 	BlockHash     common.Hash         `json:"blockHash"     gencodec:"required"`
 	Transactions  [][]byte            `json:"transactions"  gencodec:"required"`
 	Withdrawals   []*types.Withdrawal `json:"withdrawals"`
+	BlobGasUsed   *uint64             `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas *uint64             `json:"excessBlobGas,omitempty"`
 }
 
 // JSON type overrides for executableData.
@@ -73,18 +75,38 @@ type executableDataMarshaling struct {
 	ExtraData     hexutil.Bytes
 	LogsBloom     hexutil.Bytes
 	Transactions  []hexutil.Bytes
+	BlobGasUsed   *hexutil.Uint64
+	ExcessBlobGas *hexutil.Uint64
 }
 
 //go:generate go run github.com/fjl/gencodec -type ExecutionPayloadEnvelope -field-override executionPayloadEnvelopeMarshaling -out gen_epe.go
 
 type ExecutionPayloadEnvelope struct {
-	ExecutionPayload *ExecutableData `json:"executionPayload"  gencodec:"required"`
-	BlockValue       *big.Int        `json:"blockValue"  gencodec:"required"`
+	ExecutionPayload *ExecutableData       `json:"executionPayload"  gencodec:"required"`
+	BlockValue       *big.Int              `json:"blockValue"  gencodec:"required"`
+	BundleValue      *big.Int              `json:"bundleValue,omitempty"` // Paid by the transactions or bundles the block is built from
+	PublicValue      *big.Int              `json:"publicValue,omitempty"` // Paid by the txpool transactions filling the block
+	BundleResults    []*types.BundleResult `json:"bundleResults,omitempty"`
+	BlobsBundle      *BlobsBundleV1        `json:"blobsBundle,omitempty"` // Of the blob transactions of post-Cancun blocks
+
+	// ParentBeaconBlockRoot is committed to by the header of post-Cancun blocks,
+	// but is not part of the execution payload.
+	ParentBeaconBlockRoot *common.Hash `json:"parentBeaconBlockRoot,omitempty"`
+}
+
+// BlobsBundleV1 holds the blobs of the blob transactions of a block, with
+// their commitments and proofs.
+type BlobsBundleV1 struct {
+	Commitments []hexutil.Bytes `json:"commitments"`
+	Proofs      []hexutil.Bytes `json:"proofs"`
+	Blobs       []hexutil.Bytes `json:"blobs"`
 }
 
 // JSON type overrides for ExecutionPayloadEnvelope.
 type executionPayloadEnvelopeMarshaling struct {
-	BlockValue *hexutil.Big
+	BlockValue  *hexutil.Big
+	BundleValue *hexutil.Big
+	PublicValue *hexutil.Big
 }
 
 type PayloadStatusV1 struct {
@@ -159,7 +181,10 @@ func decodeTransactions(enc [][]byte) ([]*types.Transaction, error) {
 // and that the blockhash of the constructed block matches the parameters. Nil
 // Withdrawals value will propagate through the returned block. Empty
 // Withdrawals value must be passed via non-nil, length 0 value in params.
-func ExecutableDataToBlock(params ExecutableData) (*types.Block, error) {
+//
+// The parent beacon block root is not part of the payload, post-Cancun payloads
+// need it passed alongside to rebuild the header they commit to.
+func ExecutableDataToBlock(params ExecutableData, beaconRoot *common.Hash) (*types.Block, error) {
 	txs, err := decodeTransactions(params.Transactions)
 	if err != nil {
 		return nil, err
@@ -200,6 +225,11 @@ func ExecutableDataToBlock(params ExecutableData) (*types.Block, error) {
 		MixDigest:       params.Random,
 		WithdrawalsHash: withdrawalsRoot,
 	}
+	if params.ExcessBlobGas != nil {
+		header.ExcessDataGas = new(big.Int).SetUint64(*params.ExcessBlobGas)
+	}
+	header.DataGasUsed = params.BlobGasUsed
+	header.ParentBeaconRoot = beaconRoot
 	block := types.NewBlockWithHeader(header).WithBody(txs, nil /* uncles */).WithWithdrawals(params.Withdrawals)
 	if block.Hash() != params.BlockHash {
 		return nil, fmt.Errorf("blockhash mismatch, want %x, got %x", params.BlockHash, block.Hash())
@@ -227,7 +257,32 @@ func BlockToExecutableData(block *types.Block, fees *big.Int) *ExecutionPayloadE
 		ExtraData:     block.Extra(),
 		Withdrawals:   block.Withdrawals(),
 	}
-	return &ExecutionPayloadEnvelope{ExecutionPayload: data, BlockValue: fees}
+	envelope := &ExecutionPayloadEnvelope{ExecutionPayload: data, BlockValue: fees}
+
+	// Blocks past Cancun carry the blob gas fields and the parent beacon block
+	// root, the blobs of their transactions are not known to the block and left
+	// to the caller
+	header := block.Header()
+	if header.ExcessDataGas != nil {
+		var blobGasUsed uint64
+		if header.DataGasUsed != nil {
+			blobGasUsed = *header.DataGasUsed
+		} else {
+			for _, tx := range block.Transactions() {
+				blobGasUsed += tx.BlobGas()
+			}
+		}
+		excessBlobGas := header.ExcessDataGas.Uint64()
+		data.BlobGasUsed = &blobGasUsed
+		data.ExcessBlobGas = &excessBlobGas
+		envelope.ParentBeaconBlockRoot = header.ParentBeaconRoot
+		envelope.BlobsBundle = &BlobsBundleV1{
+			Commitments: []hexutil.Bytes{},
+			Proofs:      []hexutil.Bytes{},
+			Blobs:       []hexutil.Bytes{},
+		}
+	}
+	return envelope
 }
 
 // ExecutionPayloadBodyV1 is used in the response to GetPayloadBodiesByHashV1 and GetPayloadBodiesByRangeV1
@@ -191,10 +191,26 @@ var (
 	suaveFlags = []cli.Flag{
 		utils.SuaveEthRemoteBackendEndpointFlag,
 		utils.SuaveConfidentialTransportRedisEndpointFlag,
+		utils.SuaveConfidentialTransportP2PFlag,
 		utils.SuaveConfidentialStoreRedisEndpointFlag,
 		utils.SuaveConfidentialStorePebbleDbPathFlag,
+		utils.SuaveConfidentialStoreRetentionFlag,
+		utils.SuaveConfidentialStoreEncryptionKeyFileFlag,
+		utils.SuaveConfidentialStoreEncryptionAccountFlag,
+		utils.SuaveConfidentialStorePreviousEncryptionKeyFilesFlag,
 		utils.SuaveEthBundleSigningKeyFlag,
 		utils.SuaveEthBlockSigningKeyFlag,
+		utils.SuaveHTTPAllowedDomainsFlag,
+		utils.SuaveHTTPDeniedDomainsFlag,
+		utils.SuaveHTTPAllowPrivateIPsFlag,
+		utils.SuaveHTTPRateLimitFlag,
+		utils.SuaveHTTPRateBurstFlag,
+		utils.SuaveHTTPMaxRequestSizeFlag,
+		utils.SuaveHTTPMaxResponseSizeFlag,
+		utils.SuaveExecutionWorkersFlag,
+		utils.SuaveExecutionQueueSizeFlag,
+		utils.SuaveExecutionSenderQueueSizeFlag,
+		utils.SuaveExecutionTimeoutFlag,
 		utils.SuaveDevModeFlag,
 	}
 )
@@ -238,6 +254,7 @@ func init() {
 		verkleCommand,
 		// Suave commands
 		forgeCommand,
+		suaveCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
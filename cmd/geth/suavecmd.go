@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/suave/cstore"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	suaveCommand = &cli.Command{
+		Name:  "suave",
+		Usage: "Suave confidential store operations",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "encrypt-store",
+				Usage:  "Encrypt the values of an existing pebble confidential store",
+				Action: encryptStore,
+				Flags: flags.Merge([]cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+				}, suaveFlags),
+				Description: `
+geth suave encrypt-store --suave.confidential.pebble-store-db-path <path> --suave.confidential.encryption-key-file <file>
+
+Encrypts every plaintext value of the pebble confidential store with the given key.
+Values encrypted with one of --suave.confidential.previous-encryption-key-files are
+re-encrypted with the given key. The node must not be running.`,
+			},
+		},
+	}
+)
+
+func encryptStore(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	if cfg.Eth.Suave.PebbleDbPath == "" {
+		return errors.New("no pebble confidential store configured")
+	}
+
+	key, previousKeys, err := cstore.LoadEncryptionKeys(cfg.Eth.Suave)
+	if err != nil {
+		return err
+	}
+
+	pebbleBackend, err := cstore.NewPebbleStoreBackend(cfg.Eth.Suave.PebbleDbPath)
+	if err != nil {
+		return err
+	}
+
+	encryptedBackend, err := cstore.NewEncryptedStoreBackend(pebbleBackend, key, previousKeys...)
+	if err != nil {
+		pebbleBackend.Stop()
+		return err
+	}
+	defer encryptedBackend.Stop()
+
+	encrypted, err := encryptedBackend.Reencrypt()
+	if err != nil {
+		return fmt.Errorf("encrypted %d values before failing: %w", encrypted, err)
+	}
+
+	log.Info("Encrypted confidential store", "path", cfg.Eth.Suave.PebbleDbPath, "values", encrypted)
+	return nil
+}
@@ -77,6 +77,7 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/ethereum/go-ethereum/suave/cstore"
 	"github.com/ethereum/go-ethereum/suave/genesis"
 	"github.com/ethereum/go-ethereum/trie"
 	pcsclite "github.com/gballet/go-libpcsclite"
@@ -531,6 +532,12 @@ var (
 		Category: flags.SuaveCategory,
 	}
 
+	SuaveConfidentialTransportP2PFlag = &cli.BoolFlag{
+		Name:     "suave.confidential.p2p-transport",
+		Usage:    "Gossip confidential store messages to devp2p peers, alternative to redis transport",
+		Category: flags.SuaveCategory,
+	}
+
 	SuaveConfidentialStoreRedisEndpointFlag = &cli.StringFlag{
 		Name:     "suave.confidential.redis-store-endpoint",
 		Usage:    "Redis endpoint to use as confidential storage backend (default: local store)",
@@ -543,6 +550,30 @@ var (
 		Category: flags.SuaveCategory,
 	}
 
+	SuaveConfidentialStoreRetentionFlag = &cli.Uint64Flag{
+		Name:     "suave.confidential.retention",
+		Usage:    "Number of blocks of the target chain past a bid's decryption condition after which the bid and its data are pruned (default: 0, keep forever)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveConfidentialStoreEncryptionKeyFileFlag = &cli.StringFlag{
+		Name:     "suave.confidential.encryption-key-file",
+		Usage:    "File holding the hex encoded 32 byte key to encrypt confidential store values with (default: no encryption)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveConfidentialStoreEncryptionAccountFlag = &cli.StringFlag{
+		Name:     "suave.confidential.encryption-account",
+		Usage:    "Unlocked keystore account to derive the confidential store encryption key from (default: no encryption)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveConfidentialStorePreviousEncryptionKeyFilesFlag = &cli.StringSliceFlag{
+		Name:     "suave.confidential.previous-encryption-key-files",
+		Usage:    "Files holding previous confidential store encryption keys, values encrypted with them are re-encrypted with the current key",
+		Category: flags.SuaveCategory,
+	}
+
 	SuaveEthBundleSigningKeyFlag = &cli.StringFlag{
 		Name:     "suave.eth.bundle-signing-key",
 		EnvVars:  []string{"SUAVE_ETH_BUNDLE_SIGNING_KEY"},
@@ -557,6 +588,73 @@ var (
 		Category: flags.SuaveCategory,
 	}
 
+	SuaveHTTPAllowedDomainsFlag = &cli.StringSliceFlag{
+		Name:     "suave.http.allowed-domains",
+		Usage:    "Domains confidential contracts may send HTTP requests to, \"*.example.com\" matches subdomains and \"*\" any domain (default: none for doHTTPRequest, any for relay submissions)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveHTTPDeniedDomainsFlag = &cli.StringSliceFlag{
+		Name:     "suave.http.denied-domains",
+		Usage:    "Domains confidential contracts may never send HTTP requests to, takes precedence over the allowed domains",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveHTTPAllowPrivateIPsFlag = &cli.BoolFlag{
+		Name:     "suave.http.allow-private-ips",
+		Usage:    "Allow confidential contracts to send HTTP requests to loopback, private and link-local addresses",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveHTTPRateLimitFlag = &cli.Float64Flag{
+		Name:     "suave.http.rate-limit",
+		Usage:    "HTTP requests per second each confidential contract may send (default: 0, no limit)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveHTTPRateBurstFlag = &cli.IntFlag{
+		Name:     "suave.http.rate-burst",
+		Usage:    "HTTP requests each confidential contract may send at once above the rate limit",
+		Value:    1,
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveHTTPMaxRequestSizeFlag = &cli.Uint64Flag{
+		Name:     "suave.http.max-request-size",
+		Usage:    "Maximum size in bytes of the body of HTTP requests sent by confidential contracts (default: 1 MiB)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveHTTPMaxResponseSizeFlag = &cli.Uint64Flag{
+		Name:     "suave.http.max-response-size",
+		Usage:    "Maximum size in bytes of the body of HTTP responses returned to confidential contracts (default: 4 MiB)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveExecutionWorkersFlag = &cli.IntFlag{
+		Name:     "suave.execution.workers",
+		Usage:    "Confidential compute requests received over RPC executed at once (default: 4)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveExecutionQueueSizeFlag = &cli.IntFlag{
+		Name:     "suave.execution.queue-size",
+		Usage:    "Confidential compute requests waiting to be executed, beyond which requests are rejected (default: 1024)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveExecutionSenderQueueSizeFlag = &cli.IntFlag{
+		Name:     "suave.execution.sender-queue-size",
+		Usage:    "Confidential compute requests of a single sender waiting to be executed (default: 64)",
+		Category: flags.SuaveCategory,
+	}
+
+	SuaveExecutionTimeoutFlag = &cli.DurationFlag{
+		Name:     "suave.execution.timeout",
+		Usage:    "Execution time of a confidential compute request received over RPC, beyond which it fails and its nonce is released (default: 30s)",
+		Category: flags.SuaveCategory,
+	}
+
 	SuaveDevModeFlag = &cli.BoolFlag{
 		Name:     "suave.dev",
 		Usage:    "Dev mode for suave",
@@ -1707,6 +1805,8 @@ func CheckExclusive(ctx *cli.Context, args ...interface{}) {
 
 func SetSuaveConfig(ctx *cli.Context, stack *node.Node, cfg *suave.Config) {
 	CheckExclusive(ctx, SuaveConfidentialStoreRedisEndpointFlag, SuaveConfidentialStorePebbleDbPathFlag)
+	CheckExclusive(ctx, SuaveConfidentialStoreEncryptionKeyFileFlag, SuaveConfidentialStoreEncryptionAccountFlag)
+	CheckExclusive(ctx, SuaveConfidentialTransportRedisEndpointFlag, SuaveConfidentialTransportP2PFlag)
 	if ctx.IsSet(SuaveEthRemoteBackendEndpointFlag.Name) {
 		cfg.SuaveEthRemoteBackendEndpoint = ctx.String(SuaveEthRemoteBackendEndpointFlag.Name)
 	}
@@ -1715,6 +1815,10 @@ func SetSuaveConfig(ctx *cli.Context, stack *node.Node, cfg *suave.Config) {
 		cfg.RedisStorePubsubUri = ctx.String(SuaveConfidentialTransportRedisEndpointFlag.Name)
 	}
 
+	if ctx.IsSet(SuaveConfidentialTransportP2PFlag.Name) {
+		cfg.P2PStoreTransport = ctx.Bool(SuaveConfidentialTransportP2PFlag.Name)
+	}
+
 	if ctx.IsSet(SuaveConfidentialStoreRedisEndpointFlag.Name) {
 		cfg.RedisStoreUri = ctx.String(SuaveConfidentialStoreRedisEndpointFlag.Name)
 	}
@@ -1723,6 +1827,22 @@ func SetSuaveConfig(ctx *cli.Context, stack *node.Node, cfg *suave.Config) {
 		cfg.PebbleDbPath = ctx.String(SuaveConfidentialStorePebbleDbPathFlag.Name)
 	}
 
+	if ctx.IsSet(SuaveConfidentialStoreRetentionFlag.Name) {
+		cfg.ConfidentialStoreRetention = ctx.Uint64(SuaveConfidentialStoreRetentionFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveConfidentialStoreEncryptionKeyFileFlag.Name) {
+		cfg.EncryptionKeyFile = ctx.String(SuaveConfidentialStoreEncryptionKeyFileFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveConfidentialStoreEncryptionAccountFlag.Name) {
+		cfg.EncryptionKey = deriveSuaveEncryptionKey(ctx, stack)
+	}
+
+	if ctx.IsSet(SuaveConfidentialStorePreviousEncryptionKeyFilesFlag.Name) {
+		cfg.PreviousEncryptionKeyFiles = ctx.StringSlice(SuaveConfidentialStorePreviousEncryptionKeyFilesFlag.Name)
+	}
+
 	if ctx.IsSet(SuaveEthBundleSigningKeyFlag.Name) {
 		cfg.EthBundleSigningKeyHex = ctx.String(SuaveEthBundleSigningKeyFlag.Name)
 	}
@@ -1730,6 +1850,75 @@ func SetSuaveConfig(ctx *cli.Context, stack *node.Node, cfg *suave.Config) {
 	if ctx.IsSet(SuaveEthBlockSigningKeyFlag.Name) {
 		cfg.EthBlockSigningKeyHex = ctx.String(SuaveEthBlockSigningKeyFlag.Name)
 	}
+
+	if ctx.IsSet(SuaveHTTPAllowedDomainsFlag.Name) {
+		cfg.HTTP.AllowedDomains = ctx.StringSlice(SuaveHTTPAllowedDomainsFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveHTTPDeniedDomainsFlag.Name) {
+		cfg.HTTP.DeniedDomains = ctx.StringSlice(SuaveHTTPDeniedDomainsFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveHTTPAllowPrivateIPsFlag.Name) {
+		cfg.HTTP.AllowPrivateIPs = ctx.Bool(SuaveHTTPAllowPrivateIPsFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveHTTPRateLimitFlag.Name) {
+		cfg.HTTP.RequestsPerSecond = ctx.Float64(SuaveHTTPRateLimitFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveHTTPRateBurstFlag.Name) {
+		cfg.HTTP.RequestBurst = ctx.Int(SuaveHTTPRateBurstFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveHTTPMaxRequestSizeFlag.Name) {
+		cfg.HTTP.MaxRequestSize = ctx.Uint64(SuaveHTTPMaxRequestSizeFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveHTTPMaxResponseSizeFlag.Name) {
+		cfg.HTTP.MaxResponseSize = ctx.Uint64(SuaveHTTPMaxResponseSizeFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveExecutionWorkersFlag.Name) {
+		cfg.Execution.Workers = ctx.Int(SuaveExecutionWorkersFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveExecutionQueueSizeFlag.Name) {
+		cfg.Execution.QueueSize = ctx.Int(SuaveExecutionQueueSizeFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveExecutionSenderQueueSizeFlag.Name) {
+		cfg.Execution.SenderQueueSize = ctx.Int(SuaveExecutionSenderQueueSizeFlag.Name)
+	}
+
+	if ctx.IsSet(SuaveExecutionTimeoutFlag.Name) {
+		cfg.Execution.Timeout = ctx.Duration(SuaveExecutionTimeoutFlag.Name)
+	}
+}
+
+// deriveSuaveEncryptionKey derives the confidential store encryption key from the
+// configured keystore account, unlocked with the first password of --password.
+func deriveSuaveEncryptionKey(ctx *cli.Context, stack *node.Node) []byte {
+	addr := ctx.String(SuaveConfidentialStoreEncryptionAccountFlag.Name)
+	if !common.IsHexAddress(addr) {
+		Fatalf("-%s: invalid account address %q", SuaveConfidentialStoreEncryptionAccountFlag.Name, addr)
+	}
+
+	keystores := stack.AccountManager().Backends(keystore.KeyStoreType)
+	if len(keystores) == 0 {
+		Fatalf("-%s: keystore is not available", SuaveConfidentialStoreEncryptionAccountFlag.Name)
+	}
+
+	passphrase := ""
+	if passwords := MakePasswordList(ctx); len(passwords) > 0 {
+		passphrase = passwords[0]
+	}
+
+	key, err := cstore.DeriveEncryptionKey(keystores[0].(*keystore.KeyStore), common.HexToAddress(addr), passphrase)
+	if err != nil {
+		Fatalf("Failed to derive confidential store encryption key: %v", err)
+	}
+	return key
 }
 
 // SetEthConfig applies eth-related command line flags to the config.
@@ -101,3 +101,27 @@ var (
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	ErrSenderNoEOA = errors.New("sender not an eoa")
 )
+
+// List of SuaveTransaction validation errors, returned if the result of a
+// confidential compute request is not acceptable.
+var (
+	// ErrSuaveExecutionNodeMismatch is returned if a SuaveTransaction is submitted
+	// by another execution node than the one its confidential compute request names.
+	ErrSuaveExecutionNodeMismatch = errors.New("execution node differs from the requested one")
+
+	// ErrSuaveUnregisteredExecutionNode is returned if a SuaveTransaction is
+	// submitted by an execution node missing from the execution node registry.
+	ErrSuaveUnregisteredExecutionNode = errors.New("unregistered execution node")
+
+	// ErrSuaveUntrustedExecutionNode is returned if a SuaveTransaction is
+	// submitted by an execution node the chain config does not trust.
+	ErrSuaveUntrustedExecutionNode = errors.New("untrusted execution node")
+
+	// ErrSuaveNoCallback is returned if the confidential compute request of a
+	// SuaveTransaction has no recipient to call back with the result.
+	ErrSuaveNoCallback = errors.New("confidential compute request has no callback recipient")
+
+	// ErrSuaveMalformedCallback is returned if the result of a confidential
+	// compute request is not a call of a method of the callback recipient.
+	ErrSuaveMalformedCallback = errors.New("malformed confidential compute callback")
+)
@@ -31,11 +31,13 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -599,6 +601,15 @@ func DeveloperGenesisBlock(period uint64, gasLimit uint64, faucet common.Address
 	// Override the default period to the user requested one
 	config := *params.DeveloperSuaveChainConfig
 
+	// Trust and register the developer account as the execution node, and only
+	// accept its results of confidential compute requests from the genesis on
+	config.SuaveValidationBlock = big.NewInt(0)
+	config.Suave = &params.SuaveConfig{ExecutionNodes: []common.Address{faucet}}
+	registryStorage, err := vm.ExecutionNodeRegistryStorage([]suave.ExecutionNode{{Address: faucet, DAKeys: []common.Address{faucet}}})
+	if err != nil {
+		panic(err)
+	}
+
 	// Assemble and return the genesis with the precompiles and faucet pre-funded
 	return &Genesis{
 		Config:     &config,
@@ -618,6 +629,8 @@ func DeveloperGenesisBlock(period uint64, gasLimit uint64, faucet common.Address
 			common.BytesToAddress([]byte{9}): {Balance: big.NewInt(1)}, // BLAKE2b
 			common.HexToAddress("0x4201000"): {Balance: big.NewInt(1)}, // isConfidential
 
+			suave.ExecutionNodeRegistryAddress: {Balance: big.NewInt(0), Code: vm.ExecutionNodeRegistryCode, Storage: registryStorage},
+
 			common.HexToAddress("0x4f91862699aF93251B3e3518E4Ca627803689252"): {Balance: new(big.Int).Mul(big.NewInt(1000000000000000000), big.NewInt(10000000000))}, // initial signer
 			common.HexToAddress("0x71B21E9b8029d1E384B71B2A1708005A7d4D0428"): {Balance: new(big.Int).Mul(big.NewInt(1000000000000000000), big.NewInt(10000000000))},
 			common.HexToAddress("0xfB8CcAb59b2d3Ef32B966F26891842db2b35d787"): {Balance: new(big.Int).Mul(big.NewInt(1000000000000000000), big.NewInt(10000000000))},
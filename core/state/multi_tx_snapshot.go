@@ -0,0 +1,140 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	errMultiTxSnapshotTaken   = errors.New("multi-transaction snapshot already taken")
+	errNoMultiTxSnapshotTaken = errors.New("no multi-transaction snapshot taken")
+)
+
+// multiTxSnapshot holds the journal entries of the transactions finalised since
+// the snapshot was taken, which the journal otherwise forgets.
+type multiTxSnapshot struct {
+	entries []journalEntry
+}
+
+// objectDeletedChange undoes the deletion of an object when finalising a
+// transaction. It is only journaled while a multi-transaction snapshot is taken.
+type objectDeletedChange struct {
+	account      *common.Address
+	prev         bool // whether the object was already deleted
+	prevdestruct bool
+
+	// Data of the account in the snapshot maps, cleared on deletion
+	prevSnapAccount []byte
+	prevSnapStorage map[common.Hash][]byte
+}
+
+func (ch objectDeletedChange) revert(s *StateDB) {
+	obj := s.stateObjects[*ch.account]
+	obj.deleted = ch.prev
+	if !ch.prevdestruct {
+		delete(s.stateObjectsDestruct, *ch.account)
+	}
+	if s.snap != nil {
+		if ch.prevSnapAccount != nil {
+			s.snapAccounts[obj.addrHash] = ch.prevSnapAccount
+		}
+		if ch.prevSnapStorage != nil {
+			s.snapStorage[obj.addrHash] = ch.prevSnapStorage
+		}
+	}
+}
+
+func (ch objectDeletedChange) dirtied() *common.Address {
+	return nil
+}
+
+// MultiTxSnapshot takes a snapshot of the state which, unlike Snapshot, spans
+// the transactions finalised after it, so that RevertToMultiTxSnapshot reverts
+// them all at once without copying the state. The snapshot must be taken in
+// between transactions, and the state must not be hashed nor committed until
+// the snapshot is reverted or discarded. Only one snapshot is taken at a time.
+func (s *StateDB) MultiTxSnapshot() error {
+	if s.multiTxSnapshot != nil {
+		return errMultiTxSnapshotTaken
+	}
+	s.multiTxSnapshot = new(multiTxSnapshot)
+	return nil
+}
+
+// RevertToMultiTxSnapshot reverts all state changes made since the
+// multi-transaction snapshot was taken, and discards the snapshot.
+func (s *StateDB) RevertToMultiTxSnapshot() error {
+	if s.multiTxSnapshot == nil {
+		return errNoMultiTxSnapshotTaken
+	}
+	entries := s.multiTxSnapshot.entries
+	s.multiTxSnapshot = nil
+
+	// Revert the transaction in progress, then the finalised ones
+	dirtied := make(map[common.Address]struct{})
+	for addr := range s.journal.dirties {
+		dirtied[addr] = struct{}{}
+	}
+	s.journal.revert(s, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if addr := entries[i].dirtied(); addr != nil {
+			dirtied[*addr] = struct{}{}
+		}
+		entries[i].revert(s)
+	}
+	s.clearJournalAndRefund()
+
+	// The reverted values are finalised like the ones they replace, objects
+	// created since the snapshot are dropped altogether
+	for addr := range dirtied {
+		if obj, exist := s.stateObjects[addr]; exist {
+			obj.finalise(false)
+			continue
+		}
+		delete(s.stateObjectsPending, addr)
+		delete(s.stateObjectsDirty, addr)
+	}
+	return nil
+}
+
+// DiscardMultiTxSnapshot keeps the state changes made since the
+// multi-transaction snapshot was taken, and discards the snapshot.
+func (s *StateDB) DiscardMultiTxSnapshot() {
+	s.multiTxSnapshot = nil
+}
+
+// keepFinalisedEntries moves the journal entries of the finalised transaction
+// to the multi-transaction snapshot, if one is taken. The changes to the access
+// list and the transient storage do not outlive the transaction and are not kept.
+func (s *StateDB) keepFinalisedEntries() {
+	if s.multiTxSnapshot == nil {
+		return
+	}
+	for _, entry := range s.journal.entries {
+		switch entry.(type) {
+		case accessListAddAccountChange, accessListAddSlotChange, transientStorageChange, refundChange:
+			continue
+		}
+		s.multiTxSnapshot.entries = append(s.multiTxSnapshot.entries, entry)
+	}
+}
+
+// journalObjectDeleted journals the deletion of the object when finalising a
+// transaction, if a multi-transaction snapshot is taken.
+func (s *StateDB) journalObjectDeleted(obj *stateObject) {
+	if s.multiTxSnapshot == nil {
+		return
+	}
+	_, prevdestruct := s.stateObjectsDestruct[obj.address]
+	ch := objectDeletedChange{
+		account:      &obj.address,
+		prev:         obj.deleted,
+		prevdestruct: prevdestruct,
+	}
+	if s.snap != nil {
+		ch.prevSnapAccount = s.snapAccounts[obj.addrHash]
+		ch.prevSnapStorage = s.snapStorage[obj.addrHash]
+	}
+	s.journal.append(ch)
+}
@@ -110,6 +110,9 @@ type StateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Journal of the transactions finalised since MultiTxSnapshot
+	multiTxSnapshot *multiTxSnapshot
+
 	// Measurements gathered during execution for debugging purposes
 	AccountReads         time.Duration
 	AccountHashes        time.Duration
@@ -846,6 +849,7 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			continue
 		}
 		if obj.suicided || (deleteEmptyObjects && obj.empty()) {
+			s.journalObjectDeleted(obj)
 			obj.deleted = true
 
 			// We need to maintain account deletions explicitly (will remain
@@ -874,7 +878,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	if s.prefetcher != nil && len(addressesToPrefetch) > 0 {
 		s.prefetcher.prefetch(common.Hash{}, s.originalRoot, common.Address{}, addressesToPrefetch)
 	}
-	// Invalidate journal because reverting across transactions is not allowed.
+	// Invalidate journal because reverting across transactions is not allowed,
+	// unless through the multi-transaction snapshot
+	s.keepFinalisedEntries()
 	s.clearJournalAndRefund()
 }
 
@@ -998,3 +998,79 @@ func TestStateDBTransientStorage(t *testing.T) {
 		t.Fatalf("transient storage mismatch: have %x, want %x", got, value)
 	}
 }
+
+func TestMultiTxSnapshot(t *testing.T) {
+	state, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		addrA = common.BytesToAddress([]byte("a"))
+		addrB = common.BytesToAddress([]byte("b"))
+		addrC = common.BytesToAddress([]byte("c"))
+		key   = common.HexToHash("0x01")
+	)
+	state.SetBalance(addrA, big.NewInt(1))
+	state.SetState(addrA, key, common.HexToHash("0x01"))
+	state.SetBalance(addrB, big.NewInt(5))
+	root, _ := state.Commit(false)
+	state, _ = New(root, state.db, state.snaps)
+
+	if err := state.MultiTxSnapshot(); err != nil {
+		t.Fatalf("could not take snapshot: %v", err)
+	}
+	if err := state.MultiTxSnapshot(); err == nil {
+		t.Fatalf("took a second snapshot")
+	}
+
+	// Two finalised transactions and one in progress
+	state.SetTxContext(common.Hash{0x01}, 0)
+	state.SetBalance(addrA, big.NewInt(10))
+	state.SetState(addrA, key, common.HexToHash("0x02"))
+	state.SetBalance(addrC, big.NewInt(3))
+	state.AddLog(&types.Log{Address: addrA})
+	state.Finalise(true)
+
+	state.SetTxContext(common.Hash{0x02}, 1)
+	state.Suicide(addrB)
+	state.SetState(addrA, key, common.HexToHash("0x03"))
+	state.Finalise(true)
+
+	state.SetTxContext(common.Hash{0x03}, 2)
+	state.SetBalance(addrA, big.NewInt(20))
+
+	if err := state.RevertToMultiTxSnapshot(); err != nil {
+		t.Fatalf("could not revert snapshot: %v", err)
+	}
+	if balance := state.GetBalance(addrA); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("balance of a: have %v, want 1", balance)
+	}
+	if !state.Exist(addrB) || state.GetBalance(addrB).Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("self-destructed account b not restored")
+	}
+	if state.Exist(addrC) {
+		t.Errorf("created account c not removed")
+	}
+	if value := state.GetState(addrA, key); value != common.HexToHash("0x01") {
+		t.Errorf("storage of a: have %x, want 0x01", value)
+	}
+	if value := state.GetCommittedState(addrA, key); value != common.HexToHash("0x01") {
+		t.Errorf("committed storage of a: have %x, want 0x01", value)
+	}
+	if logs := state.Logs(); len(logs) != 0 {
+		t.Errorf("have %d logs, want none", len(logs))
+	}
+	if have := state.IntermediateRoot(true); have != root {
+		t.Errorf("root: have %x, want %x", have, root)
+	}
+	if err := state.RevertToMultiTxSnapshot(); err == nil {
+		t.Fatalf("reverted a discarded snapshot")
+	}
+
+	// Changes made since a discarded snapshot are kept
+	state.MultiTxSnapshot()
+	state.SetBalance(addrA, big.NewInt(10))
+	state.Finalise(true)
+	state.DiscardMultiTxSnapshot()
+	if balance := state.GetBalance(addrA); balance.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("balance of a: have %v, want 10", balance)
+	}
+}
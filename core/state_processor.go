@@ -101,6 +101,16 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 }
 
 func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
+	// Results of confidential compute requests must come from a registered execution node
+	if config.IsSuaveValidation(blockNumber) {
+		if err := ValidateSuaveTransaction(config, tx); err != nil {
+			return nil, err
+		}
+		if err := ValidateSuaveExecutionNode(config, statedb, tx); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
 	evm.Reset(txContext, statedb)
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/ethereum/go-ethereum/trie"
 	"golang.org/x/crypto/sha3"
 )
@@ -309,6 +310,115 @@ func TestStateProcessorErrors(t *testing.T) {
 		}
 	}
 
+	// SUAVE errors, for these we need an execution node registry
+	{
+		var (
+			db          = rawdb.NewMemoryDatabase()
+			suaveConfig = *config
+			key3, _     = crypto.HexToECDSA("0303030303030303030303030303030303030303030303030303030303030303")
+			key4, _     = crypto.HexToECDSA("0404040404040404040404040404040404040404040404040404040404040404")
+			node2       = crypto.PubkeyToAddress(key2.PublicKey)
+			node3       = crypto.PubkeyToAddress(key3.PublicKey)
+			node4       = crypto.PubkeyToAddress(key4.PublicKey)
+		)
+		suaveConfig.SuaveBlock = big.NewInt(0)
+		suaveConfig.SuaveValidationBlock = big.NewInt(0)
+		// node2 is trusted and registered, node3 registered without being
+		// trusted and node4 trusted without being registered
+		suaveConfig.Suave = &params.SuaveConfig{ExecutionNodes: []common.Address{node2, node4}}
+		registryStorage, _ := vm.ExecutionNodeRegistryStorage([]suave.ExecutionNode{{Address: node2}, {Address: node3}})
+
+		var (
+			gspec = &Genesis{
+				Config: &suaveConfig,
+				Alloc: GenesisAlloc{
+					common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7"): GenesisAccount{
+						Balance: big.NewInt(1000000000000000000), // 1 ether
+						Nonce:   0,
+					},
+					suave.ExecutionNodeRegistryAddress: GenesisAccount{
+						Balance: big.NewInt(0),
+						Code:    vm.ExecutionNodeRegistryCode,
+						Storage: registryStorage,
+					},
+				},
+			}
+			blockchain, _ = NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+			suaveSigner   = types.NewSuaveSigner(suaveConfig.ChainID)
+		)
+		defer blockchain.Stop()
+
+		var mkSuaveTx = func(nodeKey *ecdsa.PrivateKey, executionNode, requestNode common.Address, to *common.Address, result []byte) *types.Transaction {
+			request, _ := types.SignTx(types.NewTx(&types.ConfidentialComputeRecord{
+				GasPrice:      big.NewInt(875000000),
+				Gas:           params.TxGas,
+				To:            to,
+				Value:         big.NewInt(0),
+				ExecutionNode: requestNode,
+				ChainID:       suaveConfig.ChainID,
+			}), suaveSigner, key1)
+			record, _ := types.CastTxInner[*types.ConfidentialComputeRecord](request)
+
+			tx, _ := types.SignTx(types.NewTx(&types.SuaveTransaction{
+				ExecutionNode:              executionNode,
+				ConfidentialComputeRequest: *record,
+				ConfidentialComputeResult:  result,
+				ChainID:                    suaveConfig.ChainID,
+			}), suaveSigner, nodeKey)
+			return tx
+		}
+		for i, tt := range []struct {
+			txs  []*types.Transaction
+			want string
+		}{
+			{ // ErrSuaveUntrustedExecutionNode
+				txs: []*types.Transaction{
+					mkSuaveTx(key3, node3, node3, &common.Address{}, []byte{0x1, 0x2, 0x3, 0x4}),
+				},
+				want: "could not apply tx 0 [0x15a9a9b7c70426eb98e61d7c2ab57d3553f1d3fc581a0f6d996187e62401723b]: untrusted execution node: 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70",
+			},
+			{ // ErrSuaveUnregisteredExecutionNode
+				txs: []*types.Transaction{
+					mkSuaveTx(key4, node4, node4, &common.Address{}, []byte{0x1, 0x2, 0x3, 0x4}),
+				},
+				want: "could not apply tx 0 [0x2ed48e994b0866dd1aec413b3bac2e0782c668eb678e35aba2b543ef6cfb0b93]: unregistered execution node: 0xc48B812bB43401392c037381AcA934F4069C0517 not in the execution node registry",
+			},
+			{ // ErrSuaveExecutionNodeMismatch
+				txs: []*types.Transaction{
+					mkSuaveTx(key2, node2, node3, &common.Address{}, nil),
+				},
+				want: "could not apply tx 0 [0xac575166ff8a344a953cdd4c73a8cfe0cb8d68703d8cf7fcb9c39d4bb308241d]: execution node differs from the requested one: have 0xfd0810DD14796680f72adf1a371963d0745BCc64, want 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70",
+			},
+			{ // ErrSuaveNoCallback
+				txs: []*types.Transaction{
+					mkSuaveTx(key2, node2, node2, nil, nil),
+				},
+				want: "could not apply tx 0 [0x18a8c1800c5a3ecdd9692d4a593faa72f1b15163e5230b1ee3c415549c909917]: confidential compute request has no callback recipient",
+			},
+			{ // Result not signed by the execution node
+				txs: []*types.Transaction{
+					mkSuaveTx(key3, node2, node2, &common.Address{}, nil),
+				},
+				want: "could not apply tx 0 [0x60bbc7474279a2b2b685d2f1cb4013de0d17f3e9fc447e41dc25f2b60d68749d]: compute request 0x60bbc7474279a2b2b685d2f1cb4013de0d17f3e9fc447e41dc25f2b60d68749d signed by incorrect execution node 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70, expected 0xfd0810DD14796680f72adf1a371963d0745BCc64",
+			},
+			{ // ErrSuaveMalformedCallback
+				txs: []*types.Transaction{
+					mkSuaveTx(key2, node2, node2, &common.Address{}, []byte{0x1, 0x2, 0x3}),
+				},
+				want: "could not apply tx 0 [0xf6ca4dfa549c4567c387c3876c2b8bdde390820631f1782e7851fec9565073ad]: malformed confidential compute callback: 3 bytes",
+			},
+		} {
+			block := GenerateBadBlock(gspec.ToBlock(), ethash.NewFaker(), tt.txs, gspec.Config)
+			_, err := blockchain.InsertChain(types.Blocks{block})
+			if err == nil {
+				t.Fatal("block imported without errors")
+			}
+			if have, want := err.Error(), tt.want; have != want {
+				t.Errorf("test %d:\nhave \"%v\"\nwant \"%v\"\n", i, have, want)
+			}
+		}
+	}
+
 	// ErrMaxInitCodeSizeExceeded, for this we need extra Shanghai (EIP-3860) enabled.
 	{
 		var (
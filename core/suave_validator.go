@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ValidateSuaveTransaction checks the result of a confidential compute request
+// carried by a SuaveTransaction against the consensus rules, enforced from the
+// SUAVE validation fork on. The transaction must be signed by the execution node
+// the embedded request names, the request must be signed by its sender and name
+// a recipient to call back, and the result must be the ABI encoded call of a
+// method of the recipient. Other transactions are left alone. The execution
+// node is checked against the registry by ValidateSuaveExecutionNode.
+func ValidateSuaveTransaction(config *params.ChainConfig, tx *types.Transaction) error {
+	suaveTx, ok := types.CastTxInner[*types.SuaveTransaction](tx)
+	if !ok {
+		return nil
+	}
+
+	// Recovering the sender checks both the signature of the execution node and
+	// the signature of the request
+	if _, err := types.Sender(types.NewSuaveSigner(config.ChainID), tx); err != nil {
+		return err
+	}
+
+	request := suaveTx.ConfidentialComputeRequest
+	if request.ExecutionNode != suaveTx.ExecutionNode {
+		return fmt.Errorf("%w: have %s, want %s", ErrSuaveExecutionNodeMismatch, suaveTx.ExecutionNode.Hex(), request.ExecutionNode.Hex())
+	}
+	if request.To == nil {
+		return ErrSuaveNoCallback
+	}
+	// The callback is a method selector followed by its ABI encoded arguments,
+	// the recipient is the contract the request called
+	if result := suaveTx.ConfidentialComputeResult; len(result) < 4 || (len(result)-4)%32 != 0 {
+		return fmt.Errorf("%w: %d bytes", ErrSuaveMalformedCallback, len(result))
+	}
+	return nil
+}
+
+// ValidateSuaveExecutionNode checks that the execution node which signed a
+// SuaveTransaction is trusted by the chain config and registered in the
+// execution node registry, if the chain deployed one in its genesis. It is
+// enforced from the SUAVE validation fork on, like ValidateSuaveTransaction.
+// Other transactions are left alone.
+func ValidateSuaveExecutionNode(config *params.ChainConfig, statedb vm.StateDB, tx *types.Transaction) error {
+	suaveTx, ok := types.CastTxInner[*types.SuaveTransaction](tx)
+	if !ok {
+		return nil
+	}
+	if !config.Suave.IsTrustedExecutionNode(suaveTx.ExecutionNode) {
+		return fmt.Errorf("%w: %s", ErrSuaveUntrustedExecutionNode, suaveTx.ExecutionNode.Hex())
+	}
+	if vm.IsExecutionNodeRegistryDeployed(statedb) && vm.ReadExecutionNode(statedb, suaveTx.ExecutionNode) == nil {
+		return fmt.Errorf("%w: %s not in the execution node registry", ErrSuaveUnregisteredExecutionNode, suaveTx.ExecutionNode.Hex())
+	}
+	return nil
+}
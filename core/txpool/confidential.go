@@ -0,0 +1,106 @@
+package txpool
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var errNotConfidentialRequest = errors.New("not a confidential compute request")
+
+// ReserveConfidentialRequest reserves the nonce of a confidential compute
+// request before it is executed, so that only one request is executed for each
+// nonce of its sender. The request is rejected if its nonce is already used on
+// chain, by a pooled transaction or by another request being executed, and if
+// the request is already being executed or the result of an execution of it is
+// pooled. The reservation is held until it is released, once the execution
+// returned: its result is then pooled, or it never will be.
+func (pool *TxPool) ReserveConfidentialRequest(tx *types.Transaction) error {
+	if tx.Type() != types.ConfidentialComputeRequestTxType {
+		return errNotConfidentialRequest
+	}
+	requestHash, _ := confidentialRequestHash(tx)
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return ErrInvalidSender
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	nonce := tx.Nonce()
+	if pool.currentState.GetNonce(from) > nonce {
+		return core.ErrNonceTooLow
+	}
+	if reserved, ok := pool.reservations[from][nonce]; ok {
+		if reserved == requestHash {
+			return ErrConfidentialRequestKnown
+		}
+		return ErrNonceReserved
+	}
+	if pooled := pool.pooledTx(from, nonce); pooled != nil {
+		if pooledHash, ok := confidentialRequestHash(pooled); ok && pooledHash == requestHash {
+			return ErrConfidentialRequestKnown
+		}
+		return ErrNonceInUse
+	}
+
+	if pool.reservations[from] == nil {
+		pool.reservations[from] = make(map[uint64]common.Hash)
+	}
+	pool.reservations[from][nonce] = requestHash
+	return nil
+}
+
+// ReleaseConfidentialRequest releases the nonce reserved by a confidential
+// compute request. Requests without a reservation are ignored.
+func (pool *TxPool) ReleaseConfidentialRequest(tx *types.Transaction) {
+	requestHash, ok := confidentialRequestHash(tx)
+	if !ok {
+		return
+	}
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if reserved, ok := pool.reservations[from][tx.Nonce()]; !ok || reserved != requestHash {
+		return
+	}
+	delete(pool.reservations[from], tx.Nonce())
+	if len(pool.reservations[from]) == 0 {
+		delete(pool.reservations, from)
+	}
+}
+
+// pooledTx returns the pending or queued transaction of the account with the
+// given nonce, if there is one. It must be called with the lock held.
+func (pool *TxPool) pooledTx(addr common.Address, nonce uint64) *types.Transaction {
+	if list := pool.pending[addr]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[addr]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
+// confidentialRequestHash returns the hash identifying the confidential compute
+// request of a ConfidentialComputeRequest, or of the SuaveTransaction holding
+// the result of its execution. Other transactions have none.
+func confidentialRequestHash(tx *types.Transaction) (common.Hash, bool) {
+	if inner, ok := types.CastTxInner[*types.ConfidentialComputeRequest](tx); ok {
+		return types.NewTx(&inner.ConfidentialComputeRecord).Hash(), true
+	}
+	if inner, ok := types.CastTxInner[*types.SuaveTransaction](tx); ok {
+		return types.NewTx(&inner.ConfidentialComputeRequest).Hash(), true
+	}
+	return common.Hash{}, false
+}
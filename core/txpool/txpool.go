@@ -95,6 +95,19 @@ var (
 	// ErrOverdraft is returned if a transaction would cause the senders balance to go negative
 	// thus invalidating a potential large number of transactions.
 	ErrOverdraft = errors.New("transaction would cause overdraft")
+
+	// ErrConfidentialRequestKnown is returned if a confidential compute request is
+	// already being executed, or the result of another execution of it is already
+	// contained within the pool.
+	ErrConfidentialRequestKnown = errors.New("confidential compute request already known")
+
+	// ErrNonceReserved is returned if the nonce of a transaction or confidential
+	// compute request is reserved by another request being executed.
+	ErrNonceReserved = errors.New("nonce reserved by another confidential compute request")
+
+	// ErrNonceInUse is returned if the nonce of a confidential compute request is
+	// used by a transaction contained within the pool.
+	ErrNonceInUse = errors.New("nonce used by a pooled transaction")
 )
 
 var (
@@ -256,6 +269,8 @@ type TxPool struct {
 	eip1559  atomic.Bool // Fork indicator whether we are using EIP-1559 type transactions.
 	shanghai atomic.Bool // Fork indicator whether we are in the Shanghai stage.
 
+	suaveValidation atomic.Bool // Fork indicator whether the results of confidential compute requests are validated.
+
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *noncer        // Pending state tracking virtual nonces
 	currentMaxGas atomic.Uint64  // Current gas limit for transaction caps
@@ -269,6 +284,8 @@ type TxPool struct {
 	all     *lookup                      // All transactions to allow lookups
 	priced  *pricedList                  // All transactions sorted by price
 
+	reservations map[common.Address]map[uint64]common.Hash // Nonces reserved by confidential compute requests being executed
+
 	chainHeadCh     chan core.ChainHeadEvent
 	chainHeadSub    event.Subscription
 	reqResetCh      chan *txpoolResetRequest
@@ -302,6 +319,7 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 		queue:           make(map[common.Address]*list),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		reservations:    make(map[common.Address]map[uint64]common.Hash),
 		chainHeadCh:     make(chan core.ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -473,12 +491,19 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 }
 
 // Nonce returns the next nonce of an account, with all transactions executable
-// by the pool already applied on top.
+// by the pool already applied on top. Nonces following them which are reserved
+// by confidential compute requests being executed are skipped as well.
 func (pool *TxPool) Nonce(addr common.Address) uint64 {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
-	return pool.pendingNonces.get(addr)
+	nonce := pool.pendingNonces.get(addr)
+	for {
+		if _, reserved := pool.reservations[addr][nonce]; !reserved {
+			return nonce
+		}
+		nonce++
+	}
 }
 
 // Stats retrieves the current pool stats, namely the number of pending and the
@@ -642,6 +667,12 @@ func (pool *TxPool) validateTxBasics(tx *types.Transaction, local bool) error {
 	if _, err := types.Sender(pool.signer, tx); err != nil {
 		return ErrInvalidSender
 	}
+	// Make sure the results of confidential compute requests are acceptable.
+	if pool.suaveValidation.Load() {
+		if err := core.ValidateSuaveTransaction(pool.chainconfig, tx); err != nil {
+			return err
+		}
+	}
 	// Drop non-local transactions under our own minimal accepted gas price or tip
 	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
@@ -686,6 +717,27 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 			return ErrOverdraft
 		}
 	}
+	// Ensure confidential compute results come from trusted and registered execution nodes
+	if pool.suaveValidation.Load() {
+		if err := core.ValidateSuaveExecutionNode(pool.chainconfig, pool.currentState, tx); err != nil {
+			return err
+		}
+	}
+	// Nonces reserved by confidential compute requests being executed are kept
+	// for their results
+	if reserved, ok := pool.reservations[from][tx.Nonce()]; ok {
+		if requestHash, ok := confidentialRequestHash(tx); !ok || requestHash != reserved {
+			return ErrNonceReserved
+		}
+	}
+	// Only keep the result of a single execution of each confidential compute request
+	if requestHash, ok := confidentialRequestHash(tx); ok {
+		if pooled := pool.pooledTx(from, tx.Nonce()); pooled != nil && pooled.Hash() != tx.Hash() {
+			if pooledHash, ok := confidentialRequestHash(pooled); ok && pooledHash == requestHash {
+				return ErrConfidentialRequestKnown
+			}
+		}
+	}
 	return nil
 }
 
@@ -1398,6 +1450,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.eip2718.Store(pool.chainconfig.IsBerlin(next))
 	pool.eip1559.Store(pool.chainconfig.IsLondon(next))
 	pool.shanghai.Store(pool.chainconfig.IsShanghai(next, uint64(time.Now().Unix())))
+	pool.suaveValidation.Store(pool.chainconfig.IsSuaveValidation(next))
 }
 
 // promoteExecutables moves transactions that have become processable from the
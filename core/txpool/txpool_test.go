@@ -33,9 +33,11 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -278,6 +280,23 @@ func testAddBalance(pool *TxPool, addr common.Address, amount *big.Int) {
 	pool.mu.Unlock()
 }
 
+func testDeployExecutionNodeRegistry(pool *TxPool, nodes ...common.Address) {
+	registered := make([]suave.ExecutionNode, len(nodes))
+	for i, node := range nodes {
+		registered[i].Address = node
+	}
+	storage, err := vm.ExecutionNodeRegistryStorage(registered)
+	if err != nil {
+		panic(err)
+	}
+	pool.mu.Lock()
+	pool.currentState.SetCode(suave.ExecutionNodeRegistryAddress, vm.ExecutionNodeRegistryCode)
+	for slot, value := range storage {
+		pool.currentState.SetState(suave.ExecutionNodeRegistryAddress, slot, value)
+	}
+	pool.mu.Unlock()
+}
+
 func testSetNonce(pool *TxPool, addr common.Address, nonce uint64) {
 	pool.mu.Lock()
 	pool.currentState.SetNonce(addr, nonce)
@@ -322,6 +341,237 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+func TestInvalidSuaveTransactions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		registeredKey, _   = crypto.GenerateKey()
+		unregisteredKey, _ = crypto.GenerateKey()
+		untrustedKey, _    = crypto.GenerateKey()
+		registered         = crypto.PubkeyToAddress(registeredKey.PublicKey)
+		unregistered       = crypto.PubkeyToAddress(unregisteredKey.PublicKey)
+		untrusted          = crypto.PubkeyToAddress(untrustedKey.PublicKey)
+		recipient          = common.Address{0x1}
+	)
+	config := *params.TestChainConfig
+	config.SuaveBlock = big.NewInt(0)
+	config.SuaveValidationBlock = big.NewInt(0)
+	config.Suave = &params.SuaveConfig{ExecutionNodes: []common.Address{registered, unregistered}}
+
+	pool, key := setupPoolWithConfig(&config)
+	defer pool.Stop()
+	testDeployExecutionNodeRegistry(pool, registered, untrusted)
+
+	signer := types.NewSuaveSigner(config.ChainID)
+	callback := append([]byte{0x1, 0x2, 0x3, 0x4}, common.LeftPadBytes([]byte{0x5}, 32)...)
+	suaveTransaction := func(nodeKey *ecdsa.PrivateKey, requestNode common.Address, to *common.Address) *types.Transaction {
+		request, _ := types.SignTx(types.NewTx(&types.ConfidentialComputeRecord{
+			GasPrice:      big.NewInt(1),
+			Gas:           100000,
+			To:            to,
+			Value:         big.NewInt(0),
+			ExecutionNode: requestNode,
+			ChainID:       config.ChainID,
+		}), signer, key)
+		record, _ := types.CastTxInner[*types.ConfidentialComputeRecord](request)
+
+		tx, _ := types.SignTx(types.NewTx(&types.SuaveTransaction{
+			ExecutionNode:              crypto.PubkeyToAddress(nodeKey.PublicKey),
+			ConfidentialComputeRequest: *record,
+			ConfidentialComputeResult:  callback,
+			ChainID:                    config.ChainID,
+		}), signer, nodeKey)
+		return tx
+	}
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(0xffffffffffffff))
+
+	// Trusted but unregistered execution node
+	tx := suaveTransaction(unregisteredKey, unregistered, &recipient)
+	if err, want := pool.AddRemote(tx), core.ErrSuaveUnregisteredExecutionNode; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Registered but untrusted execution node
+	tx = suaveTransaction(untrustedKey, untrusted, &recipient)
+	if err, want := pool.AddRemote(tx), core.ErrSuaveUntrustedExecutionNode; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Result from a different execution node than the requested one
+	tx = suaveTransaction(registeredKey, unregistered, &recipient)
+	if err, want := pool.AddRemote(tx), core.ErrSuaveExecutionNodeMismatch; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Request without a callback recipient
+	tx = suaveTransaction(registeredKey, registered, nil)
+	if err, want := pool.AddRemote(tx), core.ErrSuaveNoCallback; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Result not signed by the execution node
+	tx = suaveTransaction(unregisteredKey, registered, &recipient)
+	inner, _ := types.CastTxInner[*types.SuaveTransaction](tx)
+	inner.ExecutionNode = registered
+	tx = types.NewTx(inner)
+	if err, want := pool.AddRemote(tx), ErrInvalidSender; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Result which is not an ABI encoded call
+	callback = callback[:len(callback)-1]
+	tx = suaveTransaction(registeredKey, registered, &recipient)
+	if err, want := pool.AddRemote(tx), core.ErrSuaveMalformedCallback; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	callback = callback[:4]
+
+	// Well formed result from a registered execution node
+	tx = suaveTransaction(registeredKey, registered, &recipient)
+	if err := pool.AddRemote(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
+func TestSuaveTransactionsBeforeValidationFork(t *testing.T) {
+	t.Parallel()
+
+	var (
+		nodeKey, _ = crypto.GenerateKey()
+		node       = crypto.PubkeyToAddress(nodeKey.PublicKey)
+		recipient  = common.Address{0x1}
+	)
+	config := *params.TestChainConfig
+	config.SuaveBlock = big.NewInt(0)
+	config.SuaveValidationBlock = big.NewInt(100)
+
+	pool, key := setupPoolWithConfig(&config)
+	defer pool.Stop()
+	testDeployExecutionNodeRegistry(pool, common.Address{0x2})
+
+	signer := types.NewSuaveSigner(config.ChainID)
+	request, _ := types.SignTx(types.NewTx(&types.ConfidentialComputeRecord{
+		GasPrice:      big.NewInt(1),
+		Gas:           100000,
+		To:            &recipient,
+		Value:         big.NewInt(0),
+		ExecutionNode: node,
+		ChainID:       config.ChainID,
+	}), signer, key)
+	record, _ := types.CastTxInner[*types.ConfidentialComputeRecord](request)
+	tx, _ := types.SignTx(types.NewTx(&types.SuaveTransaction{
+		ExecutionNode:              node,
+		ConfidentialComputeRequest: *record,
+		ConfidentialComputeResult:  []byte{0x1},
+		ChainID:                    config.ChainID,
+	}), signer, nodeKey)
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(0xffffffffffffff))
+
+	// Unregistered execution node with a malformed callback, accepted until the fork
+	if err := pool.AddRemote(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
+func TestConfidentialRequestReservations(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.SuaveBlock = big.NewInt(0)
+
+	pool, key := setupPoolWithConfig(&config)
+	defer pool.Stop()
+
+	nodeKey, _ := crypto.GenerateKey()
+	signer := types.NewSuaveSigner(config.ChainID)
+	request := func(nonce uint64, inputs []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				Nonce:                  nonce,
+				GasPrice:               big.NewInt(1),
+				Gas:                    100000,
+				To:                     &common.Address{0x1},
+				Value:                  big.NewInt(0),
+				ExecutionNode:          crypto.PubkeyToAddress(nodeKey.PublicKey),
+				ConfidentialInputsHash: crypto.Keccak256Hash(inputs),
+				ChainID:                config.ChainID,
+			},
+			ConfidentialInputs: inputs,
+		}), signer, key)
+		return tx
+	}
+	result := func(request *types.Transaction, computeResult []byte) *types.Transaction {
+		inner, _ := types.CastTxInner[*types.ConfidentialComputeRequest](request)
+		tx, _ := types.SignTx(types.NewTx(&types.SuaveTransaction{
+			ExecutionNode:              inner.ExecutionNode,
+			ConfidentialComputeRequest: inner.ConfidentialComputeRecord,
+			ConfidentialComputeResult:  computeResult,
+			ChainID:                    config.ChainID,
+		}), signer, nodeKey)
+		return tx
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(0xffffffffffffff))
+
+	// Reserve the nonce of a request
+	req0 := request(0, []byte{0x1})
+	if err := pool.ReserveConfidentialRequest(req0); err != nil {
+		t.Fatalf("failed to reserve request: %v", err)
+	}
+	if nonce := pool.Nonce(from); nonce != 1 {
+		t.Errorf("pool nonce mismatch: have %d, want %d", nonce, 1)
+	}
+	if err, want := pool.ReserveConfidentialRequest(req0), ErrConfidentialRequestKnown; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err, want := pool.ReserveConfidentialRequest(request(0, []byte{0x2})), ErrNonceReserved; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err, want := pool.ReserveConfidentialRequest(transaction(1, 100000, key)), errNotConfidentialRequest; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Reserved nonces are kept for the result of the request
+	if err, want := pool.AddLocal(transaction(0, 100000, key)), ErrNonceReserved; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err, want := pool.AddLocal(result(request(0, []byte{0x2}), []byte{0x1})), ErrNonceReserved; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Pool the result of the request and release its nonce
+	if err := pool.AddLocal(result(req0, []byte{0x1})); err != nil {
+		t.Fatalf("failed to add result: %v", err)
+	}
+	pool.ReleaseConfidentialRequest(req0)
+	if len(pool.reservations) != 0 {
+		t.Errorf("reservations mismatch: have %d, want %d", len(pool.reservations), 0)
+	}
+	if nonce := pool.Nonce(from); nonce != 1 {
+		t.Errorf("pool nonce mismatch: have %d, want %d", nonce, 1)
+	}
+
+	// The request is known and its nonce used by the pooled result
+	if err, want := pool.ReserveConfidentialRequest(req0), ErrConfidentialRequestKnown; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err, want := pool.ReserveConfidentialRequest(request(0, []byte{0x2})), ErrNonceInUse; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err, want := pool.AddLocal(result(req0, []byte{0x2})), ErrConfidentialRequestKnown; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+
+	// Nonces already used on chain can not be reserved
+	testSetNonce(pool, from, 2)
+	if err, want := pool.ReserveConfidentialRequest(request(1, []byte{0x1})), core.ErrNonceTooLow; !errors.Is(err, want) {
+		t.Errorf("want %v have %v", want, err)
+	}
+	if err := pool.ReserveConfidentialRequest(request(2, []byte{0x1})); err != nil {
+		t.Errorf("failed to reserve request: %v", err)
+	}
+}
+
 func TestQueue(t *testing.T) {
 	t.Parallel()
 
@@ -194,6 +194,43 @@ func TestEIP2718BlockEncoding(t *testing.T) {
 	}
 }
 
+func TestCancunHeaderEncoding(t *testing.T) {
+	dataGasUsed, beaconRoot := uint64(131072), common.Hash{0x1}
+	header := &Header{
+		Difficulty:       common.Big0,
+		Number:           big.NewInt(1),
+		BaseFee:          big.NewInt(7),
+		WithdrawalsHash:  &EmptyWithdrawalsHash,
+		DataGasUsed:      &dataGasUsed,
+		ExcessDataGas:    big.NewInt(262144),
+		ParentBeaconRoot: &beaconRoot,
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	var decoded Header
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if decoded.DataGasUsed == nil || *decoded.DataGasUsed != dataGasUsed {
+		t.Errorf("data gas used mismatch: got %v, want %d", decoded.DataGasUsed, dataGasUsed)
+	}
+	if decoded.ParentBeaconRoot == nil || *decoded.ParentBeaconRoot != beaconRoot {
+		t.Errorf("parent beacon root mismatch: got %v, want %x", decoded.ParentBeaconRoot, beaconRoot)
+	}
+	if decoded.Hash() != header.Hash() {
+		t.Errorf("header hash mismatch: got %x, want %x", decoded.Hash(), header.Hash())
+	}
+
+	// The block hash commits to the parent beacon root
+	rootless := CopyHeader(header)
+	rootless.ParentBeaconRoot = nil
+	if rootless.Hash() == header.Hash() {
+		t.Error("header hash does not commit to the parent beacon root")
+	}
+}
+
 func TestUncleHash(t *testing.T) {
 	uncles := make([]*Header, 0)
 	h := CalcUncleHash(uncles)
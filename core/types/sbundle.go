@@ -2,19 +2,80 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/exp/slices"
 )
 
-// Simplified Share Bundle Type for PoC
+// SBundleVersion is the version of the MEV-Share bundle format.
+const SBundleVersion = "v0.1"
 
+// MaxSBundleDepth is how deeply bundles may be nested in the body of a bundle,
+// directly or by hash.
+const MaxSBundleDepth = 5
+
+// SBundle is a bundle either in the mev_sendBundle v0.1 format of MEV-Share,
+// or in the simplified format listing its transactions.
+//
+// Bundles in the MEV-Share format are described by their Body, Validity and
+// Privacy. Their Txs and RevertingHashes hold the transactions of the body and
+// of the nested bundles, in order, for the code that only needs those. The
+// transactions of the bundles referenced by hash are only part of them once
+// resolved with ResolveReferences.
 type SBundle struct {
 	BlockNumber     *big.Int      `json:"blockNumber"` // if BlockNumber is set it must match DecryptionCondition!
 	Txs             Transactions  `json:"txs"`
 	RevertingHashes []common.Hash `json:"revertingHashes,omitempty"`
-	RefundPercent   *int          `json:"percent,omitempty"`
+	RefundPercent   *int          `json:"percent,omitempty"` // Refund to the first transaction, simplified format only
+
+	Version  string          // SBundleVersion for bundles in the MEV-Share format
+	MaxBlock *big.Int        // Last block the bundle can be included in, BlockNumber if not set
+	Body     []SBundleBody   // Transactions and bundles, in the order they are applied in
+	Validity *BundleValidity // Refunds required for the bundle to be included
+	Privacy  *BundlePrivacy  `json:"privacy,omitempty"`
+
+	Required bool `json:"required,omitempty"` // The block is not built without the bundle
+}
+
+// SBundleBody is an element of the body of a bundle, either a transaction, the
+// hash of another bundle or a nested bundle.
+type SBundleBody struct {
+	Tx        *Transaction
+	CanRevert bool
+	Hash      *common.Hash
+	Bundle    *SBundle
+}
+
+// BundleValidity holds the refunds a bundle requires to be included.
+type BundleValidity struct {
+	Refund       []RefundConstraint `json:"refund,omitempty"`
+	RefundConfig []RefundConfig     `json:"refundConfig,omitempty"`
+}
+
+// RefundConstraint requires Percent of the profit of the bundle to be refunded
+// to the body at BodyIdx.
+type RefundConstraint struct {
+	BodyIdx int `json:"bodyIdx"`
+	Percent int `json:"percent"`
+}
+
+// RefundConfig splits the refunds paid to a bundle, Address gets Percent of
+// them. Without a refund config the sender of the first transaction of the
+// bundle gets all of them.
+type RefundConfig struct {
+	Address common.Address `json:"address"`
+	Percent int            `json:"percent"`
+}
+
+// BundlePrivacy holds what the sender of a bundle allows to be revealed about it.
+type BundlePrivacy struct {
+	Hints    []string `json:"hints,omitempty"`    // MEV-Share hints to share, the default hints if empty
+	Builders []string `json:"builders,omitempty"` // Builders the bundle may be shared with, besides the one it is sent to
 }
 
 type RpcSBundle struct {
@@ -22,9 +83,140 @@ type RpcSBundle struct {
 	Txs             []hexutil.Bytes `json:"txs"`
 	RevertingHashes []common.Hash   `json:"revertingHashes,omitempty"`
 	RefundPercent   *int            `json:"percent,omitempty"`
+	Privacy         *BundlePrivacy  `json:"privacy,omitempty"`
+	Required        bool            `json:"required,omitempty"`
+}
+
+// RPCMevShareBundle is a bundle as sent with mev_sendBundle.
+type RPCMevShareBundle struct {
+	Version   string                  `json:"version"`
+	Inclusion RPCMevShareInclusion    `json:"inclusion"`
+	Body      []RPCMevShareBundleBody `json:"body"`
+	Validity  *BundleValidity         `json:"validity,omitempty"`
+	Privacy   *BundlePrivacy          `json:"privacy,omitempty"`
+	Required  bool                    `json:"required,omitempty"`
+}
+
+type RPCMevShareInclusion struct {
+	Block    hexutil.Uint64  `json:"block"`
+	MaxBlock *hexutil.Uint64 `json:"maxBlock,omitempty"`
+}
+
+type RPCMevShareBundleBody struct {
+	Tx        *hexutil.Bytes     `json:"tx,omitempty"`
+	CanRevert *bool              `json:"canRevert,omitempty"`
+	Hash      *common.Hash       `json:"hash,omitempty"`
+	Bundle    *RPCMevShareBundle `json:"bundle,omitempty"`
+}
+
+// IsMevShare returns whether the bundle is in the MEV-Share format.
+func (s *SBundle) IsMevShare() bool {
+	return s.Version != "" || len(s.Body) > 0
+}
+
+// Hash returns the hash of the bundle, the hash of the hashes of its
+// transactions, referenced bundles and nested bundles.
+func (s *SBundle) Hash() common.Hash {
+	var hashes []byte
+	if !s.IsMevShare() {
+		for _, tx := range s.Txs {
+			hashes = append(hashes, tx.Hash().Bytes()...)
+		}
+		return crypto.Keccak256Hash(hashes)
+	}
+
+	for _, body := range s.Body {
+		switch {
+		case body.Tx != nil:
+			hashes = append(hashes, body.Tx.Hash().Bytes()...)
+		case body.Hash != nil:
+			hashes = append(hashes, body.Hash.Bytes()...)
+		case body.Bundle != nil:
+			hashes = append(hashes, body.Bundle.Hash().Bytes()...)
+		}
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// HasReferences returns whether the body of the bundle, or of its nested
+// bundles, references other bundles by hash.
+func (s *SBundle) HasReferences() bool {
+	for _, body := range s.Body {
+		if body.Hash != nil || (body.Bundle != nil && body.Bundle.HasReferences()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveReferences sets the Txs and RevertingHashes of the bundle, and of its
+// nested bundles, to the transactions of their body including those of the
+// bundles they reference by hash, looked up in known. It fails if a referenced
+// bundle is not known.
+func (s *SBundle) ResolveReferences(known map[common.Hash]*SBundle) error {
+	return s.resolveReferences(known, 0)
+}
+
+func (s *SBundle) resolveReferences(known map[common.Hash]*SBundle, depth int) error {
+	if !s.IsMevShare() {
+		return nil
+	}
+	if depth > MaxSBundleDepth {
+		return fmt.Errorf("bundle nested more than %d levels deep", MaxSBundleDepth)
+	}
+
+	var (
+		txs       Transactions
+		reverting []common.Hash
+	)
+	for i, body := range s.Body {
+		switch {
+		case body.Tx != nil:
+			txs = append(txs, body.Tx)
+			if body.CanRevert {
+				reverting = append(reverting, body.Tx.Hash())
+			}
+		case body.Hash != nil, body.Bundle != nil:
+			nested := body.Bundle
+			if body.Hash != nil {
+				if nested = known[*body.Hash]; nested == nil {
+					return fmt.Errorf("body %d of the bundle references unknown bundle %s", i, body.Hash)
+				}
+			}
+			if err := nested.resolveReferences(known, depth+1); err != nil {
+				return fmt.Errorf("body %d of the bundle: %w", i, err)
+			}
+			txs = append(txs, nested.Txs...)
+			reverting = append(reverting, nested.RevertingHashes...)
+		}
+	}
+	s.Txs, s.RevertingHashes = txs, reverting
+	return nil
+}
+
+// IsValidAt returns whether the bundle can be included in the block with the
+// given number. Bundles in the simplified format have no inclusion range.
+func (s *SBundle) IsValidAt(number *big.Int) bool {
+	if !s.IsMevShare() || s.BlockNumber == nil {
+		return true
+	}
+
+	maxBlock := s.MaxBlock
+	if maxBlock == nil {
+		maxBlock = s.BlockNumber
+	}
+	return number.Cmp(s.BlockNumber) >= 0 && number.Cmp(maxBlock) <= 0
 }
 
 func (s *SBundle) MarshalJSON() ([]byte, error) {
+	if s.IsMevShare() {
+		rpcBundle, err := s.toRPC(0)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(rpcBundle)
+	}
+
 	txs := []hexutil.Bytes{}
 	for _, tx := range s.Txs {
 		txBytes, err := tx.MarshalBinary()
@@ -44,10 +236,26 @@ func (s *SBundle) MarshalJSON() ([]byte, error) {
 		Txs:             txs,
 		RevertingHashes: s.RevertingHashes,
 		RefundPercent:   s.RefundPercent,
+		Privacy:         s.Privacy,
+		Required:        s.Required,
 	})
 }
 
 func (s *SBundle) UnmarshalJSON(data []byte) error {
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return err
+	}
+	if version.Version != "" {
+		var rpcBundle RPCMevShareBundle
+		if err := json.Unmarshal(data, &rpcBundle); err != nil {
+			return err
+		}
+		return s.fromRPC(&rpcBundle, 0)
+	}
+
 	var rpcSBundle RpcSBundle
 	if err := json.Unmarshal(data, &rpcSBundle); err != nil {
 		return err
@@ -68,23 +276,207 @@ func (s *SBundle) UnmarshalJSON(data []byte) error {
 	s.Txs = txs
 	s.RevertingHashes = rpcSBundle.RevertingHashes
 	s.RefundPercent = rpcSBundle.RefundPercent
+	s.Privacy = rpcSBundle.Privacy
+	s.Required = rpcSBundle.Required
 
 	return nil
 }
 
-type RPCMevShareBundle struct {
-	Version   string `json:"version"`
-	Inclusion struct {
-		Block string `json:"block"`
-	} `json:"inclusion"`
-	Body []struct {
-		Tx        string `json:"tx"`
-		CanRevert bool   `json:"canRevert"`
-	} `json:"body"`
-	Validity struct {
-		Refund []struct {
-			BodyIdx int `json:"bodyIdx"`
-			Percent int `json:"percent"`
-		} `json:"refund"`
-	} `json:"validity"`
+// toRPC converts the bundle to the MEV-Share format. The transactions of
+// bundles in the simplified format make up their body.
+func (s *SBundle) toRPC(depth int) (*RPCMevShareBundle, error) {
+	if depth > MaxSBundleDepth {
+		return nil, fmt.Errorf("bundle nested more than %d levels deep", MaxSBundleDepth)
+	}
+
+	rpcBundle := &RPCMevShareBundle{
+		Version:  SBundleVersion,
+		Body:     []RPCMevShareBundleBody{},
+		Validity: s.Validity,
+		Privacy:  s.Privacy,
+		Required: s.Required,
+	}
+	if s.BlockNumber != nil {
+		rpcBundle.Inclusion.Block = hexutil.Uint64(s.BlockNumber.Uint64())
+	}
+	if s.MaxBlock != nil {
+		maxBlock := hexutil.Uint64(s.MaxBlock.Uint64())
+		rpcBundle.Inclusion.MaxBlock = &maxBlock
+	}
+
+	body := s.Body
+	if !s.IsMevShare() {
+		for _, tx := range s.Txs {
+			body = append(body, SBundleBody{Tx: tx, CanRevert: slices.Contains(s.RevertingHashes, tx.Hash())})
+		}
+	}
+
+	for i, b := range body {
+		var rpcBody RPCMevShareBundleBody
+		switch {
+		case b.Tx != nil:
+			txBytes, err := b.Tx.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			canRevert := b.CanRevert
+			rpcBody.Tx, rpcBody.CanRevert = (*hexutil.Bytes)(&txBytes), &canRevert
+		case b.Hash != nil:
+			rpcBody.Hash = b.Hash
+		case b.Bundle != nil:
+			nested, err := b.Bundle.toRPC(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			rpcBody.Bundle = nested
+		default:
+			return nil, fmt.Errorf("body %d of the bundle is empty", i)
+		}
+		rpcBundle.Body = append(rpcBundle.Body, rpcBody)
+	}
+
+	return rpcBundle, nil
+}
+
+// fromRPC sets the bundle from the MEV-Share format, checking it is well formed.
+func (s *SBundle) fromRPC(rpcBundle *RPCMevShareBundle, depth int) error {
+	if depth > MaxSBundleDepth {
+		return fmt.Errorf("bundle nested more than %d levels deep", MaxSBundleDepth)
+	}
+	if rpcBundle.Version != SBundleVersion {
+		return fmt.Errorf("unsupported bundle version %q", rpcBundle.Version)
+	}
+	if len(rpcBundle.Body) == 0 {
+		return errors.New("bundle has no body")
+	}
+
+	*s = SBundle{
+		BlockNumber: new(big.Int).SetUint64(uint64(rpcBundle.Inclusion.Block)),
+		Version:     rpcBundle.Version,
+		Validity:    rpcBundle.Validity,
+		Privacy:     rpcBundle.Privacy,
+		Required:    rpcBundle.Required,
+	}
+	if rpcBundle.Inclusion.MaxBlock != nil {
+		if *rpcBundle.Inclusion.MaxBlock < rpcBundle.Inclusion.Block {
+			return fmt.Errorf("invalid inclusion range %d-%d", rpcBundle.Inclusion.Block, *rpcBundle.Inclusion.MaxBlock)
+		}
+		s.MaxBlock = new(big.Int).SetUint64(uint64(*rpcBundle.Inclusion.MaxBlock))
+	}
+
+	for i, rpcBody := range rpcBundle.Body {
+		var body SBundleBody
+		switch {
+		case rpcBody.Tx != nil:
+			tx := new(Transaction)
+			if err := tx.UnmarshalBinary(*rpcBody.Tx); err != nil {
+				return fmt.Errorf("body %d of the bundle: %w", i, err)
+			}
+			body.Tx = tx
+			body.CanRevert = rpcBody.CanRevert != nil && *rpcBody.CanRevert
+
+			s.Txs = append(s.Txs, tx)
+			if body.CanRevert {
+				s.RevertingHashes = append(s.RevertingHashes, tx.Hash())
+			}
+		case rpcBody.Hash != nil:
+			body.Hash = rpcBody.Hash
+		case rpcBody.Bundle != nil:
+			body.Bundle = new(SBundle)
+			if err := body.Bundle.fromRPC(rpcBody.Bundle, depth+1); err != nil {
+				return fmt.Errorf("body %d of the bundle: %w", i, err)
+			}
+
+			s.Txs = append(s.Txs, body.Bundle.Txs...)
+			s.RevertingHashes = append(s.RevertingHashes, body.Bundle.RevertingHashes...)
+		default:
+			return fmt.Errorf("body %d of the bundle is empty", i)
+		}
+		s.Body = append(s.Body, body)
+	}
+
+	if s.Validity != nil {
+		refunded := 0
+		for _, refund := range s.Validity.Refund {
+			if refund.BodyIdx < 0 || refund.BodyIdx >= len(s.Body) {
+				return fmt.Errorf("refund to body %d out of range", refund.BodyIdx)
+			}
+			if refund.Percent < 0 || refund.Percent > 100 {
+				return fmt.Errorf("invalid refund percent %d", refund.Percent)
+			}
+			refunded += refund.Percent
+		}
+		if refunded > 100 {
+			return fmt.Errorf("refund percents add up to %d", refunded)
+		}
+
+		total := 0
+		for _, config := range s.Validity.RefundConfig {
+			if config.Percent < 0 {
+				return fmt.Errorf("invalid refund config percent %d", config.Percent)
+			}
+			total += config.Percent
+		}
+		if total > 100 {
+			return fmt.Errorf("refund config percents add up to %d", total)
+		}
+	}
+
+	return nil
+}
+
+// MEV-Share hints, selecting what is revealed about the transactions of a bundle.
+const (
+	HintCalldata         = "calldata"          // The calldata of the transactions
+	HintContractAddress  = "contract_address"  // The address the transactions are sent to
+	HintFunctionSelector = "function_selector" // The first four bytes of the calldata
+	HintLogs             = "logs"              // The logs emitted when simulating the bundle
+	HintHash             = "hash"              // The hash of the bundle, always revealed
+	HintTxHash           = "tx_hash"           // The hashes of the transactions
+	HintDefault          = "default"           // The hash, contract address, function selector and logs
+)
+
+// BlockValue is the value of a block built from transactions or bundles.
+type BlockValue struct {
+	Profit  *big.Int // Paid to the fee recipient of the block
+	Bundles *big.Int // Paid to the builder by the transactions or bundles given
+	Public  *big.Int // Paid to the builder by the txpool transactions filling the block
+}
+
+// BundleResult is the outcome of a bundle when building a block from bundles.
+type BundleResult struct {
+	Hash     common.Hash     `json:"hash"`
+	Included bool            `json:"included"`
+	Error    string          `json:"error,omitempty"` // Why the bundle was skipped
+	GasUsed  hexutil.Uint64  `json:"gasUsed"`
+	Profit   *hexutil.Big    `json:"profit"` // Paid to the block by the bundle, net of its refunds
+	Refunds  []*BundleRefund `json:"refunds,omitempty"`
+}
+
+// BundleRefund is a refund paid to a recipient of a bundle. The gas cost of the
+// refund transfer is deducted from the value transferred.
+type BundleRefund struct {
+	Recipient common.Address `json:"recipient"`
+	Value     *hexutil.Big   `json:"value"`
+	GasCost   *hexutil.Big   `json:"gasCost"`
+}
+
+// MevShareHint is the hint of a bundle, encoded as the events of the MEV-Share event stream.
+type MevShareHint struct {
+	Hash common.Hash        `json:"hash"`
+	Logs []*MevShareLogHint `json:"logs"`
+	Txs  []*MevShareTxHint  `json:"txs"`
+}
+
+type MevShareLogHint struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+type MevShareTxHint struct {
+	Hash             *common.Hash    `json:"hash,omitempty"`
+	To               *common.Address `json:"to,omitempty"`
+	FunctionSelector *hexutil.Bytes  `json:"functionSelector,omitempty"`
+	CallData         *hexutil.Bytes  `json:"callData,omitempty"`
 }
@@ -0,0 +1,215 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSBundleMevShareJSON(t *testing.T) {
+	txBytes, err := rightvrsTx.MarshalBinary()
+	require.NoError(t, err)
+	tx := hexutil.Encode(txBytes)
+
+	// Sample bundles from the mev_sendBundle specification
+	cases := []struct {
+		name    string
+		bundle  string
+		txs     int
+		reverts int
+	}{
+		{
+			name: "transactions",
+			bundle: `{
+				"version": "v0.1",
+				"inclusion": {"block": "0x1"},
+				"body": [{"tx": "TX", "canRevert": false}, {"tx": "TX", "canRevert": true}]
+			}`,
+			txs:     2,
+			reverts: 1,
+		},
+		{
+			name: "backrun",
+			bundle: `{
+				"version": "v0.1",
+				"inclusion": {"block": "0x8b8da8", "maxBlock": "0x8b8dab"},
+				"body": [
+					{"hash": "0x1f5a41d0abbc2ad20d4a2d8de2a8a0d00a2d7a5b02a1e4bc6d0c7e37f0a6a7bc"},
+					{"tx": "TX", "canRevert": false}
+				],
+				"validity": {"refund": [{"bodyIdx": 0, "percent": 90}]},
+				"privacy": {"hints": ["calldata", "logs"], "builders": ["flashbots", "rsync"]},
+				"required": true
+			}`,
+			txs: 1,
+		},
+		{
+			name: "nested bundles",
+			bundle: `{
+				"version": "v0.1",
+				"inclusion": {"block": "0x1", "maxBlock": "0x5"},
+				"body": [
+					{"bundle": {
+						"version": "v0.1",
+						"inclusion": {"block": "0x1"},
+						"body": [{"tx": "TX", "canRevert": true}],
+						"validity": {"refundConfig": [
+							{"address": "0x0100000000000000000000000000000000000000", "percent": 60},
+							{"address": "0x0200000000000000000000000000000000000000", "percent": 40}
+						]}
+					}},
+					{"tx": "TX", "canRevert": false}
+				],
+				"validity": {"refund": [{"bodyIdx": 0, "percent": 50}]}
+			}`,
+			txs:     2,
+			reverts: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sample := strings.ReplaceAll(c.bundle, "TX", tx)
+
+			var bundle SBundle
+			require.NoError(t, json.Unmarshal([]byte(sample), &bundle))
+			require.True(t, bundle.IsMevShare())
+			require.Len(t, bundle.Txs, c.txs)
+			require.Len(t, bundle.RevertingHashes, c.reverts)
+
+			encoded, err := json.Marshal(&bundle)
+			require.NoError(t, err)
+			require.JSONEq(t, sample, string(encoded))
+
+			// Bundles are passed between nodes by value
+			var decoded SBundle
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			require.Equal(t, bundle.Hash(), decoded.Hash())
+		})
+	}
+}
+
+func TestSBundleMevShareFields(t *testing.T) {
+	txBytes, err := rightvrsTx.MarshalBinary()
+	require.NoError(t, err)
+
+	sample := `{
+		"version": "v0.1",
+		"inclusion": {"block": "0xa", "maxBlock": "0xc"},
+		"body": [
+			{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"},
+			{"tx": "` + hexutil.Encode(txBytes) + `", "canRevert": true}
+		],
+		"validity": {
+			"refund": [{"bodyIdx": 0, "percent": 90}],
+			"refundConfig": [{"address": "0x0100000000000000000000000000000000000000", "percent": 100}]
+		},
+		"privacy": {"builders": ["flashbots"]}
+	}`
+
+	var bundle SBundle
+	require.NoError(t, json.Unmarshal([]byte(sample), &bundle))
+
+	require.Equal(t, big.NewInt(10), bundle.BlockNumber)
+	require.Equal(t, big.NewInt(12), bundle.MaxBlock)
+	require.Equal(t, &common.Hash{0x1}, bundle.Body[0].Hash)
+	require.Equal(t, rightvrsTx.Hash(), bundle.Body[1].Tx.Hash())
+	require.True(t, bundle.Body[1].CanRevert)
+	require.Equal(t, []common.Hash{rightvrsTx.Hash()}, bundle.RevertingHashes)
+	require.Equal(t, []RefundConstraint{{BodyIdx: 0, Percent: 90}}, bundle.Validity.Refund)
+	require.Equal(t, []RefundConfig{{Address: common.Address{0x1}, Percent: 100}}, bundle.Validity.RefundConfig)
+	require.Equal(t, []string{"flashbots"}, bundle.Privacy.Builders)
+
+	for number, valid := range map[int64]bool{9: false, 10: true, 12: true, 13: false} {
+		require.Equal(t, valid, bundle.IsValidAt(big.NewInt(number)), number)
+	}
+}
+
+func TestSBundleResolveReferences(t *testing.T) {
+	userBundle := &SBundle{Txs: Transactions{emptyTx}, RevertingHashes: []common.Hash{emptyTx.Hash()}}
+	userHash := userBundle.Hash()
+
+	backrunBundle := &SBundle{
+		Version: SBundleVersion,
+		Body: []SBundleBody{
+			{Bundle: &SBundle{Version: SBundleVersion, Body: []SBundleBody{{Hash: &userHash}}}},
+			{Tx: rightvrsTx},
+		},
+	}
+	require.True(t, backrunBundle.HasReferences())
+
+	// The transactions of the referenced bundles are part of the bundles referencing them
+	require.NoError(t, backrunBundle.ResolveReferences(map[common.Hash]*SBundle{userHash: userBundle}))
+	require.Equal(t, Transactions{emptyTx, rightvrsTx}, backrunBundle.Txs)
+	require.Equal(t, []common.Hash{emptyTx.Hash()}, backrunBundle.RevertingHashes)
+	require.Equal(t, Transactions{emptyTx}, backrunBundle.Body[0].Bundle.Txs)
+
+	err := backrunBundle.ResolveReferences(nil)
+	require.ErrorContains(t, err, "references unknown bundle "+userHash.Hex())
+	require.False(t, userBundle.HasReferences())
+}
+
+func TestSBundleSimplifiedJSON(t *testing.T) {
+	percent := 10
+	bundle := &SBundle{
+		BlockNumber:     big.NewInt(5),
+		Txs:             Transactions{rightvrsTx},
+		RevertingHashes: []common.Hash{rightvrsTx.Hash()},
+		RefundPercent:   &percent,
+		Required:        true,
+	}
+
+	encoded, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	require.NotContains(t, string(encoded), "version")
+
+	var decoded SBundle
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.False(t, decoded.IsMevShare())
+	require.Equal(t, bundle.BlockNumber, decoded.BlockNumber)
+	require.Equal(t, bundle.RevertingHashes, decoded.RevertingHashes)
+	require.Equal(t, bundle.RefundPercent, decoded.RefundPercent)
+	require.True(t, decoded.Required)
+	require.Equal(t, bundle.Hash(), decoded.Hash())
+
+	// Simplified bundles have no inclusion range
+	require.True(t, decoded.IsValidAt(big.NewInt(100)))
+
+	// The hash is the same in both formats
+	mevShare := &SBundle{Version: SBundleVersion, Body: []SBundleBody{{Tx: rightvrsTx}}}
+	require.Equal(t, bundle.Hash(), mevShare.Hash())
+
+	// Simplified bundles nested in a bundle are encoded in the MEV-Share format
+	encoded, err = json.Marshal(&SBundle{Body: []SBundleBody{{Bundle: bundle}}})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, []common.Hash{rightvrsTx.Hash()}, decoded.Body[0].Bundle.RevertingHashes)
+}
+
+func TestSBundleMevShareInvalid(t *testing.T) {
+	nested := `{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}]}`
+	for i := 0; i <= MaxSBundleDepth; i++ {
+		nested = `{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{"bundle": ` + nested + `}]}`
+	}
+
+	cases := map[string]string{
+		`{"version": "v0.2", "inclusion": {"block": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}]}`:                                                           "unsupported bundle version",
+		`{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": []}`:                                                                                                                                         "bundle has no body",
+		`{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{}]}`:                                                                                                                                       "body 0 of the bundle is empty",
+		`{"version": "v0.1", "inclusion": {"block": "0x2", "maxBlock": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}]}`:                                        "invalid inclusion range",
+		`{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}], "validity": {"refund": [{"bodyIdx": 1, "percent": 10}]}}`:  "refund to body 1 out of range",
+		`{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}], "validity": {"refund": [{"bodyIdx": 0, "percent": 101}]}}`: "invalid refund percent",
+		`{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}, {"hash": "0x0200000000000000000000000000000000000000000000000000000000000000"}], "validity": {"refund": [{"bodyIdx": 0, "percent": 60}, {"bodyIdx": 1, "percent": 50}]}}`:             "refund percents add up to 110",
+		`{"version": "v0.1", "inclusion": {"block": "0x1"}, "body": [{"hash": "0x0100000000000000000000000000000000000000000000000000000000000000"}], "validity": {"refundConfig": [{"address": "0x0100000000000000000000000000000000000000", "percent": 60}, {"address": "0x0200000000000000000000000000000000000000", "percent": 60}]}}`: "refund config percents add up to 120",
+		nested: "nested more than",
+	}
+	for sample, expected := range cases {
+		var bundle SBundle
+		require.ErrorContains(t, json.Unmarshal([]byte(sample), &bundle), expected)
+	}
+}
@@ -1,8 +1,12 @@
 // Code generated by suave/gen. DO NOT EDIT.
-// Hash: 23a6dd8b9b224b11b8baea19a80c55c7787c50c0eb2fc69727e66d615c913483
+// Hash: 5341be8db22c7380c1d580248d5fe1bdbbdbcb7d20816a70c774dae8a626db21
 package types
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
 
 type BidId [16]byte
 
@@ -18,14 +22,55 @@ type Bid struct {
 }
 
 type BuildBlockArgs struct {
-	Slot           uint64
-	ProposerPubkey []byte
-	Parent         common.Hash
-	Timestamp      uint64
-	FeeRecipient   common.Address
-	GasLimit       uint64
-	Random         common.Hash
-	Withdrawals    []*Withdrawal
+	Slot                  uint64
+	ProposerPubkey        []byte
+	Parent                common.Hash
+	Timestamp             uint64
+	FeeRecipient          common.Address
+	GasLimit              uint64
+	Random                common.Hash
+	Withdrawals           []*Withdrawal
+	Algorithm             string
+	FillPending           bool
+	ParentBeaconBlockRoot common.Hash
+	ExcessBlobGas         uint64
+}
+
+type HttpRequest struct {
+	Url     string
+	Method  string
+	Headers []string
+	Body    []byte
+	Timeout uint64
+}
+
+type HttpResponse struct {
+	Status  uint64
+	Headers []string
+	Body    []byte
+}
+
+type SimulatedBundle struct {
+	Success       bool
+	Error         string
+	GasUsed       uint64
+	CoinbaseDelta *big.Int
+	Txs           []*SimulatedTransaction
+}
+
+type SimulatedLog struct {
+	Addr   common.Address
+	Topics []common.Hash
+	Data   []byte
+}
+
+type SimulatedTransaction struct {
+	TxHash        common.Hash
+	Success       bool
+	GasUsed       uint64
+	Error         string
+	CoinbaseDelta *big.Int
+	Logs          []*SimulatedLog
 }
 
 type Withdrawal struct {
@@ -94,9 +94,11 @@ var PrecompiledContractsSuave = map[common.Address]SuavePrecompiledContract{
 	confStoreStoreAddress:    newConfStoreStore(),
 	confStoreRetrieveAddress: newConfStoreRetrieve(),
 
-	newBidAddress:      newNewBid(),
-	fetchBidsAddress:   newFetchBids(),
-	extractHintAddress: &extractHint{},
+	newBidAddress:           newNewBid(),
+	fetchBidsAddress:        newFetchBids(),
+	queryBidsAddress:        &queryBids{},
+	setKeyAccessRuleAddress: &setKeyAccessRule{},
+	extractHintAddress:      &extractHint{},
 
 	signEthTransactionAddress:       &signEthTransaction{},
 	simulateBundleAddress:           &simulateBundle{},
@@ -104,6 +106,8 @@ var PrecompiledContractsSuave = map[common.Address]SuavePrecompiledContract{
 	submitEthBlockBidToRelayAddress: &submitEthBlockBidToRelay{},
 	submitBundleJsonRPCAddress:      &submitBundleJsonRPC{},
 	fillMevShareBundleAddress:       &fillMevShareBundle{},
+	doHTTPRequestAddress:            &doHTTPRequest{},
+	simulateEthBundleAddress:        &simulateEthBundle{},
 
 	ethcallAddr: &ethCallPrecompile{},
 }
@@ -190,12 +194,15 @@ func ActivePrecompiles(rules params.Rules) []common.Address {
 // - the _remaining_ gas,
 // - any error that occurred
 func RunPrecompiledContract(p PrecompiledContract, input []byte, suppliedGas uint64) (ret []byte, remainingGas uint64, err error) {
-	// TODO: it'd be nice if the confidential contracts' exact gas usage was calculated with execution
 	gasCost := p.RequiredGas(input)
 	if suppliedGas < gasCost {
 		return nil, 0, ErrOutOfGas
 	}
 	suppliedGas -= gasCost
+	// Confidential precompiles additionally charge for the work done while running
+	if mp, ok := p.(*SuavePrecompiledContractWrapper); ok {
+		return mp.RunMetered(input, suppliedGas)
+	}
 	output, err := p.Run(input)
 	return output, suppliedGas, err
 }
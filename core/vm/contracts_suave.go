@@ -30,6 +30,9 @@ var (
 
 	newBidAddress    = common.HexToAddress("0x42030000")
 	fetchBidsAddress = common.HexToAddress("0x42030001")
+	queryBidsAddress = common.HexToAddress("0x42030002")
+
+	setKeyAccessRuleAddress = common.HexToAddress("0x42030003")
 )
 
 /* General utility precompiles */
@@ -290,6 +293,9 @@ func (c *fetchBids) RunConfidential(suaveContext *SuaveContext, input []byte) ([
 
 func (c *fetchBids) runImpl(suaveContext *SuaveContext, targetBlock uint64, namespace string) ([]types.Bid, error) {
 	bids1 := suaveContext.Backend.ConfidentialStore.FetchBidsByProtocolAndBlock(targetBlock, namespace)
+	if err := suaveContext.gasMeter.chargeBids(len(bids1)); err != nil {
+		return nil, err
+	}
 
 	bids := make([]types.Bid, 0, len(bids1))
 	for _, bid := range bids1 {
@@ -299,6 +305,77 @@ func (c *fetchBids) runImpl(suaveContext *SuaveContext, targetBlock uint64, name
 	return bids, nil
 }
 
+type queryBids struct{}
+
+func (c *queryBids) RequiredGas(input []byte) uint64 {
+	return 1000
+}
+
+func (c *queryBids) Run(input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *queryBids) RunConfidential(suaveContext *SuaveContext, input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *queryBids) runImpl(suaveContext *SuaveContext, fromBlock uint64, toBlock uint64, namespaces []string, cursor uint64, limit uint64) ([]types.Bid, uint64, error) {
+	bids1, nextCursor, err := suaveContext.Backend.ConfidentialStore.FetchBids(suave.BidQuery{
+		FromBlock:  fromBlock,
+		ToBlock:    toBlock,
+		Namespaces: namespaces,
+		Cursor:     cursor,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := suaveContext.gasMeter.chargeBids(len(bids1)); err != nil {
+		return nil, 0, err
+	}
+
+	bids := make([]types.Bid, 0, len(bids1))
+	for _, bid := range bids1 {
+		bids = append(bids, bid.ToInnerBid())
+	}
+
+	return bids, nextCursor, nil
+}
+
+type setKeyAccessRule struct{}
+
+func (c *setKeyAccessRule) RequiredGas(input []byte) uint64 {
+	return 1000
+}
+
+func (c *setKeyAccessRule) Run(input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *setKeyAccessRule) RunConfidential(suaveContext *SuaveContext, input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *setKeyAccessRule) runImpl(suaveContext *SuaveContext, bidId suave.BidId, prefix string, writers []common.Address, readers []common.Address, writeOnce bool) error {
+	bid, err := suaveContext.Backend.ConfidentialStore.FetchBidById(bidId)
+	if err != nil {
+		return suave.ErrBidNotFound
+	}
+
+	caller, err := checkIsPrecompileCallAllowed(suaveContext, setKeyAccessRuleAddress, bid)
+	if err != nil {
+		return err
+	}
+
+	_, err = suaveContext.Backend.ConfidentialStore.SetKeyAccessRule(bidId, caller, suave.KeyAccessRule{
+		Prefix:    prefix,
+		Writers:   writers,
+		Readers:   readers,
+		WriteOnce: writeOnce,
+	})
+	return err
+}
+
 func mustParseAbi(data string) abi.ABI {
 	inoutAbi, err := abi.JSON(strings.NewReader(data))
 	if err != nil {
@@ -360,6 +437,14 @@ func (b *suaveRuntime) fetchBids(cond uint64, namespace string) ([]types.Bid, er
 	return bids, nil
 }
 
+func (b *suaveRuntime) queryBids(fromBlock uint64, toBlock uint64, namespaces []string, cursor uint64, limit uint64) ([]types.Bid, uint64, error) {
+	return (&queryBids{}).runImpl(b.suaveContext, fromBlock, toBlock, namespaces, cursor, limit)
+}
+
+func (b *suaveRuntime) setKeyAccessRule(bidId types.BidId, prefix string, writers []common.Address, readers []common.Address, writeOnce bool) error {
+	return (&setKeyAccessRule{}).runImpl(b.suaveContext, bidId, prefix, writers, readers, writeOnce)
+}
+
 func (b *suaveRuntime) newBid(decryptionCondition uint64, allowedPeekers []common.Address, allowedStores []common.Address, BidType string) (types.Bid, error) {
 	bid, err := (&newBid{}).runImpl(b.suaveContext, BidType, decryptionCondition, allowedPeekers, allowedStores)
 	if err != nil {
@@ -387,3 +472,11 @@ func (b *suaveRuntime) fillMevShareBundle(bidId types.BidId) ([]byte, error) {
 func (b *suaveRuntime) submitBundleJsonRPC(url string, method string, params []byte) ([]byte, error) {
 	return (&submitBundleJsonRPC{}).runImpl(b.suaveContext, url, method, params)
 }
+
+func (b *suaveRuntime) doHTTPRequest(request types.HttpRequest) (types.HttpResponse, error) {
+	return (&doHTTPRequest{}).runImpl(b.suaveContext, request)
+}
+
+func (b *suaveRuntime) simulateEthBundle(blockArgs types.BuildBlockArgs, bundleData []byte) (types.SimulatedBundle, error) {
+	return (&simulateEthBundle{}).runImpl(b.suaveContext, blockArgs, bundleData)
+}
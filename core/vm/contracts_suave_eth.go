@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -23,11 +24,14 @@ import (
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/holiman/uint256"
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/exp/slices"
 
 	builderCapella "github.com/attestantio/go-builder-client/api/capella"
 	builderV1 "github.com/attestantio/go-builder-client/api/v1"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
 	specCapella "github.com/attestantio/go-eth2-client/spec/capella"
+	specDeneb "github.com/attestantio/go-eth2-client/spec/deneb"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	boostTypes "github.com/flashbots/go-boost-utils/types"
 	boostUtils "github.com/flashbots/go-boost-utils/utils"
@@ -36,6 +40,7 @@ import (
 var (
 	signEthTransactionAddress       = common.HexToAddress("0x40100001")
 	simulateBundleAddress           = common.HexToAddress("0x42100000")
+	simulateEthBundleAddress        = common.HexToAddress("0x42100004")
 	extractHintAddress              = common.HexToAddress("0x42100037")
 	buildEthBlockAddress            = common.HexToAddress("0x42100001")
 	submitEthBlockBidToRelayAddress = common.HexToAddress("0x42100002")
@@ -44,6 +49,10 @@ var (
 	fillMevShareBundleAddress  = common.HexToAddress("0x43200001")
 )
 
+// errBundleReferences is returned for bundles referencing other bundles by
+// hash, whose transactions are only known when building a block.
+var errBundleReferences = errors.New("bundle references other bundles by hash")
+
 type signEthTransaction struct{}
 
 func (c *signEthTransaction) RequiredGas(input []byte) uint64 {
@@ -95,7 +104,7 @@ type simulateBundle struct {
 }
 
 func (c *simulateBundle) RequiredGas(input []byte) uint64 {
-	// Should be proportional to bundle gas limit
+	// The gas used by the simulated block is charged when running
 	return 10000
 }
 
@@ -113,26 +122,93 @@ func (c *simulateBundle) RunConfidential(suaveContext *SuaveContext, input []byt
 }
 
 func (c *simulateBundle) runImpl(suaveContext *SuaveContext, input []byte) (*big.Int, error) {
-	var bundle types.SBundle
-	err := json.Unmarshal(input, &bundle)
+	result, err := (&simulateEthBundle{}).simulate(suaveContext, nil, input)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Second))
+	if !result.Success {
+		return nil, fmt.Errorf("bundle simulation failed: %s", result.Error)
+	}
+	if result.GasUsed == 0 {
+		return nil, errors.New("bundle simulation failed: no gas used")
+	}
+
+	egp := new(big.Int).Div(result.CoinbaseDelta, new(big.Int).SetUint64(result.GasUsed))
+	return egp, nil
+}
+
+type simulateEthBundle struct{}
+
+func (c *simulateEthBundle) RequiredGas(input []byte) uint64 {
+	// The gas used by the simulated transactions and the time spent simulating
+	// them are charged when running
+	return 10000
+}
+
+func (c *simulateEthBundle) Run(input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *simulateEthBundle) RunConfidential(suaveContext *SuaveContext, input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+// simulateEthBundleTimeout is the most time a bundle simulation may take.
+const simulateEthBundleTimeout = time.Second
+
+// runImpl simulates the bundle with the block arguments, on top of the current
+// head if they set no parent.
+func (c *simulateEthBundle) runImpl(suaveContext *SuaveContext, blockArgs types.BuildBlockArgs, bundleData []byte) (types.SimulatedBundle, error) {
+	return c.simulate(suaveContext, &blockArgs, bundleData)
+}
+
+// simulate simulates the bundle with the block arguments, or with the default
+// ones following the current head if nil.
+func (c *simulateEthBundle) simulate(suaveContext *SuaveContext, blockArgs *types.BuildBlockArgs, bundleData []byte) (types.SimulatedBundle, error) {
+	var bundle types.SBundle
+	if err := json.Unmarshal(bundleData, &bundle); err != nil {
+		return types.SimulatedBundle{}, fmt.Errorf("could not unmarshal bundle: %w", err)
+	}
+	if bundle.HasReferences() {
+		return types.SimulatedBundle{}, errBundleReferences
+	}
+
+	// The simulation uses at most the gas limit of the transactions of the bundle
+	reserved, err := suaveContext.gasMeter.reserveBundleGas(txsGasLimit(bundle.Txs))
+	if err != nil {
+		return types.SimulatedBundle{}, err
+	}
+	timeout, reservedTime, err := suaveContext.gasMeter.reserveExternalCall(simulateEthBundleTimeout)
+	if err != nil {
+		return types.SimulatedBundle{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	envelope, err := suaveContext.Backend.ConfidentialEthBackend.BuildEthBlock(ctx, nil, bundle.Txs)
+	start := time.Now()
+	result, err := suaveContext.Backend.ConfidentialEthBackend.SimulateBundle(ctx, blockArgs, bundle)
+	if err := suaveContext.gasMeter.settleExternalCall(start, reservedTime); err != nil {
+		return types.SimulatedBundle{}, err
+	}
 	if err != nil {
-		return nil, err
+		return types.SimulatedBundle{}, fmt.Errorf("could not simulate bundle: %w", err)
 	}
 
-	if envelope.ExecutionPayload.GasUsed == 0 {
-		return nil, err
+	if err := suaveContext.gasMeter.settleBundleGas(reserved, result.GasUsed); err != nil {
+		return types.SimulatedBundle{}, err
 	}
 
-	egp := new(big.Int).Div(envelope.BlockValue, big.NewInt(int64(envelope.ExecutionPayload.GasUsed)))
-	return egp, nil
+	if result.CoinbaseDelta == nil {
+		result.CoinbaseDelta = new(big.Int)
+	}
+	for _, tx := range result.Txs {
+		if tx.CoinbaseDelta == nil {
+			tx.CoinbaseDelta = new(big.Int)
+		}
+	}
+	return *result, nil
 }
 
 type extractHint struct{}
@@ -156,33 +232,105 @@ func (c *extractHint) RunConfidential(suaveContext *SuaveContext, input []byte)
 	return c.runImpl(suaveContext, bundleBytes)
 }
 
+// runImpl extracts the MEV-Share hint of the bundle, revealing only what the
+// hints in the bundle's privacy preferences allow. The bundle is simulated on
+// top of the current head when its logs are to be revealed, no logs are
+// revealed if the simulation fails.
 func (c *extractHint) runImpl(suaveContext *SuaveContext, bundleBytes []byte) ([]byte, error) {
 	var bundle types.SBundle
-	err := json.Unmarshal(bundleBytes, &bundle)
+	if err := json.Unmarshal(bundleBytes, &bundle); err != nil {
+		return nil, fmt.Errorf("could not unmarshal bundle: %w", err)
+	}
+	if bundle.HasReferences() {
+		return nil, errBundleReferences
+	}
+	if len(bundle.Txs) == 0 {
+		return nil, errors.New("could not extract hint: bundle has no transactions")
+	}
+
+	var preferences []string
+	if bundle.Privacy != nil {
+		preferences = bundle.Privacy.Hints
+	}
+	hints, err := parseHints(preferences)
 	if err != nil {
-		return []byte(err.Error()), err
+		return nil, err
 	}
 
-	tx := bundle.Txs[0]
-	hint := struct {
-		To   common.Address
-		Data []byte
-	}{
-		To:   *tx.To(),
-		Data: tx.Data(),
+	hint := &types.MevShareHint{Hash: bundle.Hash()}
+
+	if hints[types.HintTxHash] || hints[types.HintContractAddress] || hints[types.HintFunctionSelector] || hints[types.HintCalldata] {
+		for _, tx := range bundle.Txs {
+			txHint := &types.MevShareTxHint{}
+			if hints[types.HintTxHash] {
+				hash := tx.Hash()
+				txHint.Hash = &hash
+			}
+			if hints[types.HintContractAddress] {
+				// Contract creations have no address to reveal
+				txHint.To = tx.To()
+			}
+			if hints[types.HintFunctionSelector] && len(tx.Data()) >= 4 {
+				selector := hexutil.Bytes(tx.Data()[:4])
+				txHint.FunctionSelector = &selector
+			}
+			if hints[types.HintCalldata] {
+				callData := hexutil.Bytes(tx.Data())
+				txHint.CallData = &callData
+			}
+			hint.Txs = append(hint.Txs, txHint)
+		}
 	}
 
-	hintBytes, err := json.Marshal(hint)
-	if err != nil {
-		return []byte(err.Error()), err
+	if hints[types.HintLogs] {
+		result, err := (&simulateEthBundle{}).simulate(suaveContext, nil, bundleBytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not extract logs: %w", err)
+		}
+		// Bundles failing to simulate on top of the head have no logs to reveal
+		if result.Success {
+			hint.Logs = []*types.MevShareLogHint{}
+			for _, tx := range result.Txs {
+				for _, l := range tx.Logs {
+					hint.Logs = append(hint.Logs, &types.MevShareLogHint{Address: l.Addr, Topics: l.Topics, Data: l.Data})
+				}
+			}
+		}
+	}
+
+	return json.Marshal(hint)
+}
+
+// parseHints returns the set of hints to reveal, expanding the default hints.
+// The hash is always revealed.
+func parseHints(preferences []string) (map[string]bool, error) {
+	if len(preferences) == 0 {
+		preferences = []string{types.HintDefault}
+	}
+
+	hints := map[string]bool{types.HintHash: true}
+	for _, hint := range preferences {
+		switch hint {
+		case types.HintDefault:
+			hints[types.HintContractAddress] = true
+			hints[types.HintFunctionSelector] = true
+			hints[types.HintLogs] = true
+		case types.HintCalldata, types.HintContractAddress, types.HintFunctionSelector, types.HintLogs, types.HintHash, types.HintTxHash:
+			hints[hint] = true
+		default:
+			return nil, fmt.Errorf("unknown hint %q", hint)
+		}
 	}
-	return hintBytes, nil
+	return hints, nil
 }
 
+// ethCallTimeout is the most time an eth call made by a precompile may take.
+const ethCallTimeout = 10 * time.Second
+
 type ethCallPrecompile struct{}
 
 func (e *ethCallPrecompile) RequiredGas(input []byte) uint64 {
-	// Should be proportional to bundle gas limit
+	// The time spent waiting on the call is charged when running
 	return 10000
 }
 
@@ -191,18 +339,87 @@ func (e *ethCallPrecompile) Run(input []byte) ([]byte, error) {
 }
 
 func (e *ethCallPrecompile) runImpl(suaveContext *SuaveContext, contractAddr common.Address, input []byte) ([]byte, error) {
-	return suaveContext.Backend.ConfidentialEthBackend.Call(context.Background(), contractAddr, input)
+	timeout, reserved, err := suaveContext.gasMeter.reserveExternalCall(ethCallTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := suaveContext.Backend.ConfidentialEthBackend.Call(ctx, contractAddr, input)
+	if err := suaveContext.gasMeter.settleExternalCall(start, reserved); err != nil {
+		return nil, err
+	}
+	return res, err
 }
 
 func (e *ethCallPrecompile) RunConfidential(suaveContext *SuaveContext, input []byte) ([]byte, error) {
 	return nil, nil
 }
 
+// legacyBuildEthBlockInputs are the inputs of buildEthBlock as encoded by
+// contracts compiled against BuildBlockArgs before the block building
+// algorithm, txpool filling and Cancun fields were added to it.
+var legacyBuildEthBlockInputs = mustParseMethodAbi(`[{"type":"function","name":"buildEthBlock","inputs":[{"name":"blockArgs","type":"tuple","components":[{"name":"slot","type":"uint64"},{"name":"proposerPubkey","type":"bytes"},{"name":"parent","type":"bytes32"},{"name":"timestamp","type":"uint64"},{"name":"feeRecipient","type":"address"},{"name":"gasLimit","type":"uint64"},{"name":"random","type":"bytes32"},{"name":"withdrawals","type":"tuple[]","components":[{"name":"index","type":"uint64"},{"name":"validator","type":"uint64"},{"name":"Address","type":"address"},{"name":"amount","type":"uint64"}]}]},{"name":"bidId","type":"bytes16"},{"name":"namespace","type":"string"}],"outputs":[]}]`, "buildEthBlock").Inputs
+
+// upgradeBuildEthBlockInput re-encodes the buildEthBlock inputs of contracts
+// deployed before BuildBlockArgs was extended, leaving the new fields empty.
+// Inputs which are not the exact legacy encoding are returned as is.
+func upgradeBuildEthBlockInput(input []byte) []byte {
+	inputs := artifacts.SuaveAbi.Methods["buildEthBlock"].Inputs
+	if isExactEncoding(inputs, input) || !isExactEncoding(legacyBuildEthBlockInputs, input) {
+		return input
+	}
+
+	unpacked, err := legacyBuildEthBlockInputs.Unpack(input)
+	if err != nil {
+		return input
+	}
+	var blockArgs types.BuildBlockArgs
+	if err := mapstructure.Decode(unpacked[0], &blockArgs); err != nil {
+		return input
+	}
+	upgraded, err := inputs.Pack(blockArgs, unpacked[1], unpacked[2])
+	if err != nil {
+		return input
+	}
+	return upgraded
+}
+
+// legacyInputUpgrades re-encode the inputs of the precompiles whose
+// arguments changed, for contracts compiled against the old arguments.
+var legacyInputUpgrades = map[string]func([]byte) []byte{
+	"buildEthBlock": upgradeBuildEthBlockInput,
+}
+
+// unpackSuaveInputs decodes the inputs of the precompile method, upgrading
+// legacy encodings first.
+func unpackSuaveInputs(method string, input []byte) ([]interface{}, error) {
+	if upgrade, ok := legacyInputUpgrades[method]; ok {
+		input = upgrade(input)
+	}
+	return artifacts.SuaveAbi.Methods[method].Inputs.Unpack(input)
+}
+
+// isExactEncoding reports whether the input decodes as the arguments and
+// encodes back to itself, which is not the case for the encoding of other
+// arguments that happens to decode.
+func isExactEncoding(args abi.Arguments, input []byte) bool {
+	unpacked, err := args.Unpack(input)
+	if err != nil {
+		return false
+	}
+	packed, err := args.Pack(unpacked...)
+	return err == nil && bytes.Equal(packed, input)
+}
+
 type buildEthBlock struct {
 }
 
 func (c *buildEthBlock) RequiredGas(input []byte) uint64 {
-	// Should be proportional to bundle gas limit
+	// The bids fetched and the gas used by the built block are charged when running
 	return 10000
 }
 
@@ -211,7 +428,7 @@ func (c *buildEthBlock) Run(input []byte) ([]byte, error) {
 }
 
 func (c *buildEthBlock) RunConfidential(suaveContext *SuaveContext, input []byte) ([]byte, error) {
-	unpacked, err := artifacts.SuaveAbi.Methods["buildEthBlock"].Inputs.Unpack(input)
+	unpacked, err := unpackSuaveInputs("buildEthBlock", input)
 	if err != nil {
 		return formatPeekerError("could not unpack inputs: %w", err)
 	}
@@ -231,17 +448,25 @@ func (c *buildEthBlock) RunConfidential(suaveContext *SuaveContext, input []byte
 			Address   common.Address "json:\"Address\""
 			Amount    uint64         "json:\"amount\""
 		} "json:\"withdrawals\""
+		Algorithm             string      "json:\"algorithm\""
+		FillPending           bool        "json:\"fillPending\""
+		ParentBeaconBlockRoot common.Hash "json:\"parentBeaconBlockRoot\""
+		ExcessBlobGas         uint64      "json:\"excessBlobGas\""
 	})
 
 	blockArgs := types.BuildBlockArgs{
-		Slot:           blockArgsRaw.Slot,
-		Parent:         blockArgsRaw.Parent,
-		Timestamp:      blockArgsRaw.Timestamp,
-		FeeRecipient:   blockArgsRaw.FeeRecipient,
-		GasLimit:       blockArgsRaw.GasLimit,
-		Random:         blockArgsRaw.Random,
-		ProposerPubkey: blockArgsRaw.ProposerPubkey,
-		Withdrawals:    types.Withdrawals{},
+		Slot:                  blockArgsRaw.Slot,
+		Parent:                blockArgsRaw.Parent,
+		Timestamp:             blockArgsRaw.Timestamp,
+		FeeRecipient:          blockArgsRaw.FeeRecipient,
+		GasLimit:              blockArgsRaw.GasLimit,
+		Random:                blockArgsRaw.Random,
+		ProposerPubkey:        blockArgsRaw.ProposerPubkey,
+		Withdrawals:           types.Withdrawals{},
+		Algorithm:             blockArgsRaw.Algorithm,
+		FillPending:           blockArgsRaw.FillPending,
+		ParentBeaconBlockRoot: blockArgsRaw.ParentBeaconBlockRoot,
+		ExcessBlobGas:         blockArgsRaw.ExcessBlobGas,
 	}
 
 	for _, w := range blockArgsRaw.Withdrawals {
@@ -278,6 +503,10 @@ func (c *buildEthBlock) runImpl(suaveContext *SuaveContext, blockArgs types.Buil
 		bidIds = append(bidIds, bidId)
 	}
 
+	if err := suaveContext.gasMeter.chargeBids(len(bidIds)); err != nil {
+		return nil, nil, err
+	}
+
 	var bidsToMerge = make([]types.Bid, len(bidIds))
 	for i, bidId := range bidIds {
 		var err error
@@ -310,6 +539,9 @@ func (c *buildEthBlock) runImpl(suaveContext *SuaveContext, blockArgs types.Buil
 			}
 
 			matchBidIds := unpackedBidIds[0].([][16]byte)
+			if err := suaveContext.gasMeter.chargeBids(len(matchBidIds)); err != nil {
+				return nil, nil, err
+			}
 
 			userBundleBytes, err := suaveContext.Backend.ConfidentialStore.Retrieve(matchBidIds[0], buildEthBlockAddress, "mevshare:v0:ethBundles")
 			if err != nil {
@@ -362,19 +594,27 @@ func (c *buildEthBlock) runImpl(suaveContext *SuaveContext, blockArgs types.Buil
 		}
 	}
 
+	// The block uses at most the gas the gas left pays for
+	if allowance := suaveContext.gasMeter.bundleGasAllowance(); blockArgs.GasLimit == 0 || blockArgs.GasLimit > allowance {
+		blockArgs.GasLimit = allowance
+	}
+	reserved, err := suaveContext.gasMeter.reserveBundleGas(blockArgs.GasLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	log.Info("requesting a block be built", "mergedBundles", mergedBundles)
 	envelope, err := suaveContext.Backend.ConfidentialEthBackend.BuildEthBlockFromBundles(context.TODO(), &blockArgs, mergedBundles)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not build eth block: %w", err)
 	}
 
-	log.Info("built block from bundles", "payload", *envelope.ExecutionPayload)
-
-	payload, err := executableDataToCapellaExecutionPayload(envelope.ExecutionPayload)
-	if err != nil {
-		return nil, nil, fmt.Errorf("could not format execution payload as capella payload: %w", err)
+	if err := suaveContext.gasMeter.settleBundleGas(reserved, envelope.ExecutionPayload.GasUsed); err != nil {
+		return nil, nil, err
 	}
 
+	log.Info("built block from bundles", "payload", *envelope.ExecutionPayload)
+
 	blsPk, err := bls.PublicKeyFromSecretKey(suaveContext.Backend.EthBlockSigningKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not get bls pubkey: %w", err)
@@ -394,8 +634,8 @@ func (c *buildEthBlock) runImpl(suaveContext *SuaveContext, blockArgs types.Buil
 
 	blockBidMsg := builderV1.BidTrace{
 		Slot:                 blockArgs.Slot,
-		ParentHash:           payload.ParentHash,
-		BlockHash:            payload.BlockHash,
+		ParentHash:           phase0.Hash32(envelope.ExecutionPayload.ParentHash),
+		BlockHash:            phase0.Hash32(envelope.ExecutionPayload.BlockHash),
 		BuilderPubkey:        pk,
 		ProposerPubkey:       phase0.BLSPubKey(proposerPubkey),
 		ProposerFeeRecipient: bellatrix.ExecutionAddress(blockArgs.FeeRecipient),
@@ -412,15 +652,9 @@ func (c *buildEthBlock) runImpl(suaveContext *SuaveContext, blockArgs types.Buil
 		return nil, nil, fmt.Errorf("could not sign builder bid: %w", err)
 	}
 
-	bidRequest := builderCapella.SubmitBlockRequest{
-		Message:          &blockBidMsg,
-		ExecutionPayload: payload,
-		Signature:        signature,
-	}
-
-	bidBytes, err := bidRequest.MarshalJSON()
+	bidBytes, err := marshalSubmitBlockRequest(envelope, &blockBidMsg, signature)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not marshal builder bid request: %w", err)
+		return nil, nil, err
 	}
 
 	envelopeBytes, err := json.Marshal(envelope)
@@ -454,7 +688,12 @@ func (c *submitEthBlockBidToRelay) RunConfidential(suaveContext *SuaveContext, i
 }
 
 func (c *submitEthBlockBidToRelay) runImpl(suaveContext *SuaveContext, relayUrl string, builderBidJson []byte) ([]byte, error) {
-	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(3*time.Second))
+	timeout, reserved, err := suaveContext.gasMeter.reserveExternalCall(3 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	endpoint := relayUrl + "/relay/v1/builder/blocks"
@@ -466,7 +705,11 @@ func (c *submitEthBlockBidToRelay) runImpl(suaveContext *SuaveContext, relayUrl
 	req.Header.Add("Content-Type", "application/json")
 
 	// Execute request
-	resp, err := http.DefaultClient.Do(req)
+	start := time.Now()
+	resp, err := doEgressRequest(suaveContext, req)
+	if gasErr := suaveContext.gasMeter.settleExternalCall(start, reserved); gasErr != nil {
+		return nil, gasErr
+	}
 	if err != nil {
 		return formatPeekerError("could not send request to relay: %w", err)
 	}
@@ -488,6 +731,112 @@ func (c *submitEthBlockBidToRelay) runImpl(suaveContext *SuaveContext, relayUrl
 	return nil, nil
 }
 
+// marshalSubmitBlockRequest returns the request submitting the block of the
+// envelope to the relay, in the format of the fork of the block. Blocks past
+// Cancun, as decided by the execution node from their timestamp, carry the
+// blob gas fields and are submitted as Deneb blocks with their blobs bundle.
+func marshalSubmitBlockRequest(envelope *engine.ExecutionPayloadEnvelope, message *builderV1.BidTrace, signature phase0.BLSSignature) ([]byte, error) {
+	if !isDenebPayload(envelope.ExecutionPayload) {
+		payload, err := executableDataToCapellaExecutionPayload(envelope.ExecutionPayload)
+		if err != nil {
+			return nil, fmt.Errorf("could not format execution payload as capella payload: %w", err)
+		}
+
+		bidRequest := builderCapella.SubmitBlockRequest{
+			Message:          message,
+			ExecutionPayload: payload,
+			Signature:        signature,
+		}
+		bidBytes, err := bidRequest.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal builder bid request: %w", err)
+		}
+		return bidBytes, nil
+	}
+
+	payload, err := executableDataToDenebExecutionPayload(envelope.ExecutionPayload)
+	if err != nil {
+		return nil, fmt.Errorf("could not format execution payload as deneb payload: %w", err)
+	}
+	// The block hash commits to the parent beacon block root, the relay needs it
+	// to check the block
+	if envelope.ParentBeaconBlockRoot == nil {
+		return nil, errors.New("missing parent beacon block root")
+	}
+
+	blobsBundle := envelope.BlobsBundle
+	if blobsBundle == nil {
+		blobsBundle = &engine.BlobsBundleV1{Commitments: []hexutil.Bytes{}, Proofs: []hexutil.Bytes{}, Blobs: []hexutil.Bytes{}}
+	}
+	if len(blobsBundle.Commitments) != len(blobsBundle.Blobs) || len(blobsBundle.Proofs) != len(blobsBundle.Blobs) {
+		return nil, fmt.Errorf("invalid blobs bundle: %d commitments and %d proofs for %d blobs", len(blobsBundle.Commitments), len(blobsBundle.Proofs), len(blobsBundle.Blobs))
+	}
+
+	bidBytes, err := json.Marshal(&denebSubmitBlockRequest{
+		Message:               message,
+		ExecutionPayload:      payload,
+		BlobsBundle:           blobsBundle,
+		ParentBeaconBlockRoot: *envelope.ParentBeaconBlockRoot,
+		Signature:             fmt.Sprintf("%#x", signature),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal builder bid request: %w", err)
+	}
+	return bidBytes, nil
+}
+
+// denebSubmitBlockRequest is the request from the builder to submit a Deneb
+// block to the relay, with the blobs of its transactions and the parent beacon
+// block root its header commits to.
+type denebSubmitBlockRequest struct {
+	Message               *builderV1.BidTrace         `json:"message"`
+	ExecutionPayload      *specDeneb.ExecutionPayload `json:"execution_payload"`
+	BlobsBundle           *engine.BlobsBundleV1       `json:"blobs_bundle"`
+	ParentBeaconBlockRoot common.Hash                 `json:"parent_beacon_block_root"`
+	Signature             string                      `json:"signature"`
+}
+
+// isDenebPayload returns whether the payload is of a block past Cancun.
+func isDenebPayload(data *engine.ExecutableData) bool {
+	return data.ExcessBlobGas != nil
+}
+
+func executableDataToDenebExecutionPayload(data *engine.ExecutableData) (*specDeneb.ExecutionPayload, error) {
+	if data.BlobGasUsed == nil || data.ExcessBlobGas == nil {
+		return nil, errors.New("missing blob gas fields")
+	}
+
+	payload, err := executableDataToCapellaExecutionPayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	baseFeePerGas, overflow := uint256.FromBig(data.BaseFeePerGas)
+	if overflow {
+		return nil, fmt.Errorf("base fee %v overflows", data.BaseFeePerGas)
+	}
+
+	return &specDeneb.ExecutionPayload{
+		ParentHash:    payload.ParentHash,
+		FeeRecipient:  payload.FeeRecipient,
+		StateRoot:     phase0.Root(payload.StateRoot),
+		ReceiptsRoot:  phase0.Root(payload.ReceiptsRoot),
+		LogsBloom:     payload.LogsBloom,
+		PrevRandao:    payload.PrevRandao,
+		BlockNumber:   payload.BlockNumber,
+		GasLimit:      payload.GasLimit,
+		GasUsed:       payload.GasUsed,
+		Timestamp:     payload.Timestamp,
+		ExtraData:     payload.ExtraData,
+		BaseFeePerGas: baseFeePerGas,
+		BlockHash:     payload.BlockHash,
+		Transactions:  payload.Transactions,
+		Withdrawals:   payload.Withdrawals,
+		DataGasUsed:   *data.BlobGasUsed,
+		ExcessDataGas: *data.ExcessBlobGas,
+	}, nil
+}
+
 func executableDataToCapellaExecutionPayload(data *engine.ExecutableData) (*specCapella.ExecutionPayload, error) {
 	transactionData := make([]bellatrix.Transaction, len(data.Transactions))
 	for i, tx := range data.Transactions {
@@ -545,7 +894,12 @@ func (c *submitBundleJsonRPC) RunConfidential(suaveContext *SuaveContext, input
 }
 
 func (c *submitBundleJsonRPC) runImpl(suaveContext *SuaveContext, url string, method string, params []byte) ([]byte, error) {
-	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(3*time.Second))
+	timeout, reserved, err := suaveContext.gasMeter.reserveExternalCall(3 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	request := map[string]interface{}{
@@ -578,7 +932,11 @@ func (c *submitBundleJsonRPC) runImpl(suaveContext *SuaveContext, url string, me
 	req.Header.Add("X-Flashbots-Signature", signature)
 
 	// Execute request
-	resp, err := http.DefaultClient.Do(req)
+	start := time.Now()
+	resp, err := doEgressRequest(suaveContext, req)
+	if gasErr := suaveContext.gasMeter.settleExternalCall(start, reserved); gasErr != nil {
+		return nil, gasErr
+	}
 	if err != nil {
 		return formatPeekerError("could not send request to relay: %w", err)
 	}
@@ -620,6 +978,10 @@ func (c *fillMevShareBundle) runImpl(suaveContext *SuaveContext, bidId types.Bid
 		return nil, err
 	}
 
+	if err := suaveContext.gasMeter.chargeBids(1); err != nil {
+		return nil, err
+	}
+
 	matchedBundleIdsBytes, err := (&confStoreRetrieve{}).runImpl(suaveContext, bidId, "mevshare:v0:mergedBids")
 	if err != nil {
 		return nil, err
@@ -631,6 +993,9 @@ func (c *fillMevShareBundle) runImpl(suaveContext *SuaveContext, bidId types.Bid
 	}
 
 	matchBidIds := unpackedBidIds[0].([][16]byte)
+	if err := suaveContext.gasMeter.chargeBids(len(matchBidIds)); err != nil {
+		return nil, err
+	}
 
 	userBundleBytes, err := (&confStoreRetrieve{}).runImpl(suaveContext, matchBidIds[0], "mevshare:v0:ethBundles")
 	if err != nil {
@@ -652,36 +1017,39 @@ func (c *fillMevShareBundle) runImpl(suaveContext *SuaveContext, bidId types.Bid
 		return nil, fmt.Errorf("could not unmarshal match bundle data for bidId %v: %w", matchBidIds[1], err)
 	}
 
-	shareBundle := &types.RPCMevShareBundle{
-		Version: "v0.1",
+	shareBundle := &types.SBundle{
+		Version:     types.SBundleVersion,
+		BlockNumber: new(big.Int).SetUint64(bid.DecryptionCondition),
+		Validity:    &types.BundleValidity{},
 	}
 
-	shareBundle.Inclusion.Block = hexutil.EncodeUint64(bid.DecryptionCondition)
-
-	for _, tx := range append(userBundle.Txs, matchBundle.Txs...) {
-		txBytes, err := tx.MarshalBinary()
-		if err != nil {
-			return nil, fmt.Errorf("could not marshal transaction: %w", err)
+	// The user is refunded for each of their transactions, or for their bundle
+	// as a whole if it is in the MEV-Share format
+	refundPercent := 10
+	if userBundle.RefundPercent != nil {
+		refundPercent = *userBundle.RefundPercent
+	}
+	if userBundle.IsMevShare() {
+		shareBundle.Body = append(shareBundle.Body, types.SBundleBody{Bundle: &userBundle})
+		shareBundle.Validity.Refund = append(shareBundle.Validity.Refund, types.RefundConstraint{BodyIdx: 0, Percent: refundPercent})
+	} else {
+		for i, tx := range userBundle.Txs {
+			shareBundle.Body = append(shareBundle.Body, types.SBundleBody{Tx: tx, CanRevert: slices.Contains(userBundle.RevertingHashes, tx.Hash())})
+			shareBundle.Validity.Refund = append(shareBundle.Validity.Refund, types.RefundConstraint{BodyIdx: i, Percent: refundPercent})
 		}
-
-		shareBundle.Body = append(shareBundle.Body, struct {
-			Tx        string `json:"tx"`
-			CanRevert bool   `json:"canRevert"`
-		}{Tx: hexutil.Encode(txBytes)})
 	}
 
-	for i := range userBundle.Txs {
-		refundPercent := 10
-		if userBundle.RefundPercent != nil {
-			refundPercent = *userBundle.RefundPercent
+	if matchBundle.IsMevShare() {
+		shareBundle.Body = append(shareBundle.Body, types.SBundleBody{Bundle: &matchBundle})
+	} else {
+		for _, tx := range matchBundle.Txs {
+			shareBundle.Body = append(shareBundle.Body, types.SBundleBody{Tx: tx, CanRevert: slices.Contains(matchBundle.RevertingHashes, tx.Hash())})
 		}
-		shareBundle.Validity.Refund = append(shareBundle.Validity.Refund, struct {
-			BodyIdx int `json:"bodyIdx"`
-			Percent int `json:"percent"`
-		}{
-			BodyIdx: i,
-			Percent: refundPercent,
-		})
+	}
+
+	// The builder the bundle is sent to may only share it further with the builders the user allows
+	if userBundle.Privacy != nil && len(userBundle.Privacy.Builders) > 0 {
+		shareBundle.Privacy = &types.BundlePrivacy{Builders: userBundle.Privacy.Builders}
 	}
 
 	return json.Marshal(shareBundle)
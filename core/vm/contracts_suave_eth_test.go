@@ -0,0 +1,184 @@
+package vm
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	builderCapella "github.com/attestantio/go-builder-client/api/capella"
+	builderV1 "github.com/attestantio/go-builder-client/api/v1"
+	specDeneb "github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/suave/artifacts"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/holiman/uint256"
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEnvelope(deneb bool) *engine.ExecutionPayloadEnvelope {
+	envelope := &engine.ExecutionPayloadEnvelope{
+		ExecutionPayload: &engine.ExecutableData{
+			ParentHash:    common.Hash{0x1},
+			BlockHash:     common.Hash{0x2},
+			LogsBloom:     make([]byte, types.BloomByteLength),
+			Number:        10,
+			GasLimit:      30_000_000,
+			GasUsed:       21000,
+			Timestamp:     1700000000,
+			BaseFeePerGas: big.NewInt(7),
+			Transactions:  [][]byte{{0x1, 0x2}},
+			Withdrawals:   types.Withdrawals{{Index: 1, Validator: 2, Address: common.Address{0x3}, Amount: 4}},
+		},
+		BlockValue: big.NewInt(100),
+	}
+	if deneb {
+		blobGasUsed, excessBlobGas := uint64(0), uint64(131072)
+		envelope.ExecutionPayload.BlobGasUsed = &blobGasUsed
+		envelope.ExecutionPayload.ExcessBlobGas = &excessBlobGas
+		envelope.BlobsBundle = &engine.BlobsBundleV1{Commitments: []hexutil.Bytes{}, Proofs: []hexutil.Bytes{}, Blobs: []hexutil.Bytes{}}
+		envelope.ParentBeaconBlockRoot = &common.Hash{0x4}
+	}
+	return envelope
+}
+
+func TestSuave_SubmitBlockRequestForks(t *testing.T) {
+	// The mock relay decodes the submitted blocks in the format of their fork
+	var submissions []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/relay/v1/builder/blocks", r.URL.Path)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var submission map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(body, &submission))
+		submissions = append(submissions, submission)
+
+		if _, ok := submission["blobs_bundle"]; ok {
+			var payload specDeneb.ExecutionPayload
+			if err := json.Unmarshal(submission["execution_payload"], &payload); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else {
+			var request builderCapella.SubmitBlockRequest
+			if err := json.Unmarshal(body, &request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	suaveContext := newHTTPTestContext(suave.HTTPConfig{AllowedDomains: []string{"127.0.0.1"}})
+	message := &builderV1.BidTrace{Slot: 1, GasLimit: 30_000_000, GasUsed: 21000, Value: uint256.NewInt(100)}
+
+	for _, deneb := range []bool{false, true} {
+		bidBytes, err := marshalSubmitBlockRequest(newTestEnvelope(deneb), message, phase0.BLSSignature{0x1})
+		require.NoError(t, err)
+
+		_, err = (&submitEthBlockBidToRelay{}).runImpl(suaveContext, srv.URL, bidBytes)
+		require.NoError(t, err)
+	}
+	require.Len(t, submissions, 2)
+
+	// Capella blocks have no blob fields
+	require.NotContains(t, submissions[0], "blobs_bundle")
+	require.NotContains(t, submissions[0], "parent_beacon_block_root")
+	require.NotContains(t, string(submissions[0]["execution_payload"]), "excess_data_gas")
+
+	var payload specDeneb.ExecutionPayload
+	require.NoError(t, json.Unmarshal(submissions[1]["execution_payload"], &payload))
+	require.Equal(t, uint64(131072), payload.ExcessDataGas)
+	require.Equal(t, uint64(0), payload.DataGasUsed)
+	require.Equal(t, uint64(7), payload.BaseFeePerGas.Uint64())
+	require.Len(t, payload.Withdrawals, 1)
+
+	var blobsBundle engine.BlobsBundleV1
+	require.NoError(t, json.Unmarshal(submissions[1]["blobs_bundle"], &blobsBundle))
+	require.Empty(t, blobsBundle.Blobs)
+
+	var beaconRoot common.Hash
+	require.NoError(t, json.Unmarshal(submissions[1]["parent_beacon_block_root"], &beaconRoot))
+	require.Equal(t, common.Hash{0x4}, beaconRoot)
+}
+
+func TestSuave_SubmitBlockRequestInvalidBlobsBundle(t *testing.T) {
+	envelope := newTestEnvelope(true)
+	envelope.BlobsBundle.Blobs = []hexutil.Bytes{{0x1}}
+
+	_, err := marshalSubmitBlockRequest(envelope, &builderV1.BidTrace{Value: uint256.NewInt(1)}, phase0.BLSSignature{})
+	require.ErrorContains(t, err, "invalid blobs bundle")
+
+	// Deneb payloads need both blob gas fields
+	envelope = newTestEnvelope(true)
+	envelope.ExecutionPayload.BlobGasUsed = nil
+	_, err = marshalSubmitBlockRequest(envelope, &builderV1.BidTrace{Value: uint256.NewInt(1)}, phase0.BLSSignature{})
+	require.ErrorContains(t, err, "missing blob gas fields")
+
+	// The header of Deneb blocks commits to the parent beacon block root
+	envelope = newTestEnvelope(true)
+	envelope.ParentBeaconBlockRoot = nil
+	_, err = marshalSubmitBlockRequest(envelope, &builderV1.BidTrace{Value: uint256.NewInt(1)}, phase0.BLSSignature{})
+	require.ErrorContains(t, err, "missing parent beacon block root")
+}
+
+func TestSuave_UpgradeBuildEthBlockInput(t *testing.T) {
+	type legacyWithdrawal struct {
+		Index     uint64
+		Validator uint64
+		Address   common.Address
+		Amount    uint64
+	}
+	type legacyBuildBlockArgs struct {
+		Slot           uint64
+		ProposerPubkey []byte
+		Parent         common.Hash
+		Timestamp      uint64
+		FeeRecipient   common.Address
+		GasLimit       uint64
+		Random         common.Hash
+		Withdrawals    []legacyWithdrawal
+	}
+
+	bidId := suave.BidId{0x1}
+	legacyInput, err := legacyBuildEthBlockInputs.Pack(legacyBuildBlockArgs{
+		Slot:           1,
+		ProposerPubkey: []byte{0x2},
+		Timestamp:      3,
+		FeeRecipient:   common.Address{0x4},
+		Withdrawals:    []legacyWithdrawal{{Index: 5, Address: common.Address{0x6}}},
+	}, bidId, "namespace")
+	require.NoError(t, err)
+
+	// Inputs of contracts compiled against the original struct leave the new fields empty
+	inputs := artifacts.SuaveAbi.Methods["buildEthBlock"].Inputs
+	unpacked, err := inputs.Unpack(upgradeBuildEthBlockInput(legacyInput))
+	require.NoError(t, err)
+
+	var blockArgs types.BuildBlockArgs
+	require.NoError(t, mapstructure.Decode(unpacked[0], &blockArgs))
+	require.Equal(t, types.BuildBlockArgs{
+		Slot:           1,
+		ProposerPubkey: []byte{0x2},
+		Timestamp:      3,
+		FeeRecipient:   common.Address{0x4},
+		Withdrawals:    []*types.Withdrawal{{Index: 5, Address: common.Address{0x6}}},
+	}, blockArgs)
+	require.Equal(t, [16]byte(bidId), unpacked[1])
+	require.Equal(t, "namespace", unpacked[2])
+
+	// Current inputs are left as is
+	input, err := inputs.Pack(types.BuildBlockArgs{Algorithm: "greedy", FillPending: true, ExcessBlobGas: 7}, bidId, "namespace")
+	require.NoError(t, err)
+	require.Equal(t, input, upgradeBuildEthBlockInput(input))
+}
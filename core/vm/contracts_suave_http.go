@@ -0,0 +1,144 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	doHTTPRequestAddress = common.HexToAddress("0x43200002")
+
+	defaultHTTPMaxRequestSize  uint64 = 1 << 20 // 1 MiB
+	defaultHTTPMaxResponseSize uint64 = 4 << 20 // 4 MiB
+	defaultHTTPTimeout                = 3 * time.Second
+	maxHTTPTimeout                    = 10 * time.Second
+)
+
+type doHTTPRequest struct{}
+
+func (c *doHTTPRequest) RequiredGas(input []byte) uint64 {
+	// The time spent waiting on the response is charged when running
+	return 1000
+}
+
+func (c *doHTTPRequest) Run(input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *doHTTPRequest) RunConfidential(suaveContext *SuaveContext, input []byte) ([]byte, error) {
+	return nil, errors.New("not available in this suaveContext")
+}
+
+func (c *doHTTPRequest) runImpl(suaveContext *SuaveContext, request types.HttpRequest) (types.HttpResponse, error) {
+	config := suaveContext.Backend.EgressPolicy.Config()
+
+	endpoint, err := url.Parse(request.Url)
+	if err != nil {
+		return types.HttpResponse{}, fmt.Errorf("invalid http request url: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return types.HttpResponse{}, fmt.Errorf("invalid http request url: unsupported scheme %q", endpoint.Scheme)
+	}
+	if !config.IsDomainAllowed(endpoint.Hostname()) {
+		return types.HttpResponse{}, fmt.Errorf("http request to %s: domain not allowed", endpoint.Hostname())
+	}
+
+	maxRequestSize := config.MaxRequestSize
+	if maxRequestSize == 0 {
+		maxRequestSize = defaultHTTPMaxRequestSize
+	}
+	if uint64(len(request.Body)) > maxRequestSize {
+		return types.HttpResponse{}, fmt.Errorf("http request body too large (%d > %d)", len(request.Body), maxRequestSize)
+	}
+
+	maxResponseSize := config.MaxResponseSize
+	if maxResponseSize == 0 {
+		maxResponseSize = defaultHTTPMaxResponseSize
+	}
+
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	timeout := defaultHTTPTimeout
+	if request.Timeout != 0 {
+		timeout = time.Duration(request.Timeout) * time.Millisecond
+		if timeout > maxHTTPTimeout {
+			timeout = maxHTTPTimeout
+		}
+	}
+
+	timeout, reserved, err := suaveContext.gasMeter.reserveExternalCall(timeout)
+	if err != nil {
+		return types.HttpResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bytes.NewReader(request.Body))
+	if err != nil {
+		return types.HttpResponse{}, fmt.Errorf("could not prepare http request: %w", err)
+	}
+
+	for _, header := range request.Headers {
+		key, value, found := strings.Cut(header, ":")
+		if !found {
+			return types.HttpResponse{}, fmt.Errorf("invalid http request header %q", header)
+		}
+		req.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	// The egress policy checks redirects against the allow list as well
+	start := time.Now()
+	resp, err := doEgressRequest(suaveContext, req)
+	if err != nil {
+		if gasErr := suaveContext.gasMeter.settleExternalCall(start, reserved); gasErr != nil {
+			return types.HttpResponse{}, gasErr
+		}
+		return types.HttpResponse{}, fmt.Errorf("could not send http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxResponseSize)+1))
+	if gasErr := suaveContext.gasMeter.settleExternalCall(start, reserved); gasErr != nil {
+		return types.HttpResponse{}, gasErr
+	}
+	if err != nil {
+		return types.HttpResponse{}, fmt.Errorf("could not read http response: %w", err)
+	}
+	if uint64(len(body)) > maxResponseSize {
+		return types.HttpResponse{}, fmt.Errorf("http response body too large (> %d)", maxResponseSize)
+	}
+
+	keys := make([]string, 0, len(resp.Header))
+	for key := range resp.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var headers []string
+	for _, key := range keys {
+		for _, value := range resp.Header[key] {
+			headers = append(headers, key+": "+value)
+		}
+	}
+
+	return types.HttpResponse{
+		Status:  uint64(resp.StatusCode),
+		Headers: headers,
+		Body:    body,
+	}, nil
+}
@@ -0,0 +1,243 @@
+package vm
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/stretchr/testify/require"
+)
+
+func newHTTPTestContext(config suave.HTTPConfig) *SuaveContext {
+	// The test servers listen on the loopback address
+	config.AllowPrivateIPs = true
+	return &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			EgressPolicy: NewEgressPolicy(config),
+		},
+	}
+}
+
+func TestSuave_DoHTTPRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Header", r.Header.Get("X-Test"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append([]byte("echo:"), body...))
+	}))
+	defer srv.Close()
+
+	c := &doHTTPRequest{}
+	suaveContext := newHTTPTestContext(suave.HTTPConfig{AllowedDomains: []string{"127.0.0.1"}})
+
+	resp, err := c.runImpl(suaveContext, types.HttpRequest{
+		Url:     srv.URL,
+		Method:  "post",
+		Headers: []string{"X-Test: abc"},
+		Body:    []byte("hello"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(http.StatusCreated), resp.Status)
+	require.Equal(t, []byte("echo:hello"), resp.Body)
+	require.Contains(t, resp.Headers, "X-Method: POST")
+	require.Contains(t, resp.Headers, "X-Header: abc")
+
+	// Invalid headers are rejected
+	_, err = c.runImpl(suaveContext, types.HttpRequest{Url: srv.URL, Headers: []string{"X-Test"}})
+	require.ErrorContains(t, err, "invalid http request header")
+
+	// Only http and https are supported
+	_, err = c.runImpl(suaveContext, types.HttpRequest{Url: "file:///etc/passwd"})
+	require.ErrorContains(t, err, "invalid http request url")
+}
+
+func TestSuave_DoHTTPRequestDomainNotAllowed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not be sent")
+	}))
+	defer srv.Close()
+
+	c := &doHTTPRequest{}
+
+	// Nothing is allowed by default
+	_, err := c.runImpl(newHTTPTestContext(suave.HTTPConfig{}), types.HttpRequest{Url: srv.URL})
+	require.ErrorContains(t, err, "domain not allowed")
+
+	_, err = c.runImpl(newHTTPTestContext(suave.HTTPConfig{AllowedDomains: []string{"example.com"}}), types.HttpRequest{Url: srv.URL})
+	require.ErrorContains(t, err, "domain not allowed")
+
+	// The deny list takes precedence
+	_, err = c.runImpl(newHTTPTestContext(suave.HTTPConfig{AllowedDomains: []string{"*"}, DeniedDomains: []string{"127.0.0.1"}}), types.HttpRequest{Url: srv.URL})
+	require.ErrorContains(t, err, "domain not allowed")
+}
+
+func TestSuave_DoHTTPRequestRedirectNotAllowed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	c := &doHTTPRequest{}
+	_, err := c.runImpl(newHTTPTestContext(suave.HTTPConfig{AllowedDomains: []string{"127.0.0.1"}}), types.HttpRequest{Url: srv.URL})
+	require.ErrorContains(t, err, "egress policy: domain example.com not allowed")
+}
+
+func TestSuave_DoHTTPRequestSizeLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer srv.Close()
+
+	c := &doHTTPRequest{}
+	suaveContext := newHTTPTestContext(suave.HTTPConfig{
+		AllowedDomains:  []string{"127.0.0.1"},
+		MaxRequestSize:  10,
+		MaxResponseSize: 100,
+	})
+
+	_, err := c.runImpl(suaveContext, types.HttpRequest{Url: srv.URL, Body: make([]byte, 11)})
+	require.ErrorContains(t, err, "http request body too large")
+
+	resp, err := c.runImpl(suaveContext, types.HttpRequest{Url: srv.URL, Body: make([]byte, 10)})
+	require.NoError(t, err)
+	require.Len(t, resp.Body, 100)
+
+	suaveContext = newHTTPTestContext(suave.HTTPConfig{
+		AllowedDomains:  []string{"127.0.0.1"},
+		MaxResponseSize: 99,
+	})
+	_, err = c.runImpl(suaveContext, types.HttpRequest{Url: srv.URL})
+	require.ErrorContains(t, err, "http response body too large")
+}
+
+func TestHTTPConfigIsDomainAllowed(t *testing.T) {
+	config := suave.HTTPConfig{AllowedDomains: []string{"api.example.com", "*.flashbots.net"}}
+
+	cases := []struct {
+		host    string
+		allowed bool
+	}{
+		{"api.example.com", true},
+		{"API.Example.com", true},
+		{"api.example.com.", true},
+		{"example.com", false},
+		{"other.example.com", false},
+		{"relay.flashbots.net", true},
+		{"a.relay.flashbots.net", true},
+		{"flashbots.net", false},
+		{"evilflashbots.net", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.allowed, config.IsDomainAllowed(c.host), c.host)
+	}
+
+	anyHost := suave.HTTPConfig{AllowedDomains: []string{"*"}, DeniedDomains: []string{"*.internal"}}
+	require.True(t, anyHost.IsDomainAllowed("example.com"))
+	require.False(t, anyHost.IsDomainAllowed(""))
+	require.False(t, anyHost.IsDomainAllowed("redis.internal"))
+	require.True(t, anyHost.IsDomainDenied("redis.internal"))
+}
+
+func TestEgressPolicyBlocksPrivateIPs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not be sent")
+	}))
+	defer srv.Close()
+
+	// The default policy does not connect to private addresses
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	_, err = (*EgressPolicy)(nil).Do(common.Address{0x42}, req)
+	require.ErrorIs(t, err, errEgressPrivateIP)
+
+	// Neither when the host resolves to one
+	req, err = http.NewRequest(http.MethodGet, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), nil)
+	require.NoError(t, err)
+	_, err = NewEgressPolicy(suave.HTTPConfig{AllowedDomains: []string{"localhost"}}).Do(common.Address{0x42}, req)
+	require.ErrorIs(t, err, errEgressPrivateIP)
+
+	for _, ip := range []string{"127.0.0.1", "10.0.0.1", "192.168.1.1", "169.254.169.254", "::1", "fe80::1", "0.0.0.0"} {
+		require.True(t, isPrivateIP(net.ParseIP(ip)), ip)
+	}
+	require.False(t, isPrivateIP(net.ParseIP("1.1.1.1")))
+}
+
+func TestEgressPolicyRelayPrecompiles(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			EgressPolicy: NewEgressPolicy(suave.HTTPConfig{AllowPrivateIPs: true, DeniedDomains: []string{"localhost"}}),
+		},
+	}
+
+	// Any host is allowed when there is no allow list
+	_, err := (&submitEthBlockBidToRelay{}).runImpl(suaveContext, srv.URL, []byte("{}"))
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+
+	_, err = (&submitEthBlockBidToRelay{}).runImpl(suaveContext, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), []byte("{}"))
+	require.ErrorContains(t, err, "egress policy: domain localhost denied")
+
+	// Private addresses are blocked by default
+	suaveContext.Backend.EgressPolicy = nil
+	_, err = (&submitEthBlockBidToRelay{}).runImpl(suaveContext, srv.URL, []byte("{}"))
+	require.ErrorContains(t, err, errEgressPrivateIP.Error())
+	require.Equal(t, 1, requests)
+
+	// No request is sent when egress is disabled
+	suaveContext.Backend.EgressPolicy = NewEgressPolicy(suave.HTTPConfig{AllowPrivateIPs: true, AllowedDomains: []string{"*"}})
+	suaveContext.Backend.EthBundleSigningKey, _ = crypto.GenerateKey()
+	suaveContext.Backend.NoEgress = true
+	_, err = (&submitEthBlockBidToRelay{}).runImpl(suaveContext, srv.URL, []byte("{}"))
+	require.ErrorIs(t, err, errEgressDisabled)
+	_, err = (&submitBundleJsonRPC{}).runImpl(suaveContext, srv.URL, "eth_sendBundle", []byte("{}"))
+	require.ErrorIs(t, err, errEgressDisabled)
+	_, err = (&doHTTPRequest{}).runImpl(suaveContext, types.HttpRequest{Url: srv.URL})
+	require.ErrorIs(t, err, errEgressDisabled)
+	require.Equal(t, 1, requests)
+}
+
+func TestEgressPolicyRateLimitsPerContract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	policy := NewEgressPolicy(suave.HTTPConfig{AllowPrivateIPs: true, RequestsPerSecond: 0.001, RequestBurst: 2})
+	do := func(caller common.Address) error {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := policy.Do(caller, req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, do(common.Address{0x1}))
+	require.NoError(t, do(common.Address{0x1}))
+	require.ErrorIs(t, do(common.Address{0x1}), errEgressRateLimited)
+
+	// Other contracts have their own limit
+	require.NoError(t, do(common.Address{0x2}))
+}
+
+func TestEgressCallerSkipsPrecompile(t *testing.T) {
+	contract := common.Address{0x42}
+	precompile := doHTTPRequestAddress
+	require.Equal(t, contract, egressCaller(&SuaveContext{CallerStack: []*common.Address{&contract, &precompile}}))
+	require.Equal(t, common.Address{}, egressCaller(&SuaveContext{}))
+}
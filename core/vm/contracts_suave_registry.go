@@ -0,0 +1,469 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+// ExecutionNodeRegistryCode is the code of the execution node registry in the
+// genesis. Calls to the registry are executed natively, the code only marks the
+// registry as deployed and makes delegate calls into it fail.
+var ExecutionNodeRegistryCode = []byte{byte(INVALID)}
+
+const (
+	maxRegistryDAKeys       = 8
+	maxRegistryNamespaces   = 32
+	maxRegistryNamespaceLen = 256
+)
+
+var (
+	errRegistryNotRegistered = errors.New("execution node not registered")
+	errRegistryUntrusted     = errors.New("execution node not trusted by the chain")
+	errRegistryDAKeyTaken    = errors.New("DA key registered by another execution node")
+	errRegistryTooManyKeys   = fmt.Errorf("more than %d DA keys", maxRegistryDAKeys)
+	errRegistryTooManyNs     = fmt.Errorf("more than %d namespaces", maxRegistryNamespaces)
+	errRegistryNamespaceLen  = fmt.Errorf("namespace longer than %d bytes", maxRegistryNamespaceLen)
+	errRegistryPayable       = errors.New("registry does not accept value")
+)
+
+// The registry storage follows the Solidity layout of
+//
+//	struct ExecutionNode { bool registered; address[] daKeys; string[] namespaces; }
+//	mapping(address => ExecutionNode) nodes;    // slot 0
+//	mapping(address => address) executionNodeOf; // slot 1
+var (
+	registryNodesSlot  = common.Hash{}
+	registryDAKeysSlot = common.BigToHash(common.Big1)
+)
+
+// IsExecutionNodeRegistryDeployed returns whether the execution node registry
+// was deployed in the genesis of the chain.
+func IsExecutionNodeRegistryDeployed(statedb StateDB) bool {
+	return statedb.GetCodeSize(suave.ExecutionNodeRegistryAddress) > 0
+}
+
+// ReadExecutionNode returns the execution node registered with the address, or
+// nil if there is none.
+func ReadExecutionNode(statedb StateDB, node common.Address) *suave.ExecutionNode {
+	s := &registryStorage{db: statedb}
+	registered, _ := s.readNode(node)
+	return registered
+}
+
+// ReadExecutionNodeByDAKey returns the execution node registered with the DA
+// key, or nil if there is none.
+func ReadExecutionNodeByDAKey(statedb StateDB, daKey common.Address) *suave.ExecutionNode {
+	s := &registryStorage{db: statedb}
+	node, _ := s.get(mappingSlot(daKey, registryDAKeysSlot))
+	if node == (common.Hash{}) {
+		return nil
+	}
+	registered, _ := s.readNode(common.BytesToAddress(node.Bytes()))
+	return registered
+}
+
+// ExecutionNodeRegistryStorage returns the storage of an execution node
+// registry with the nodes registered, for use in genesis allocations.
+func ExecutionNodeRegistryStorage(nodes []suave.ExecutionNode) (map[common.Hash]common.Hash, error) {
+	storage := make(genesisRegistryState)
+	s := &registryStorage{db: storage}
+	for i := range nodes {
+		if err := s.register(&nodes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return storage, nil
+}
+
+// genesisRegistryState is the storage of the registry in a genesis allocation.
+type genesisRegistryState map[common.Hash]common.Hash
+
+func (g genesisRegistryState) GetState(_ common.Address, slot common.Hash) common.Hash {
+	return g[slot]
+}
+
+func (g genesisRegistryState) SetState(_ common.Address, slot common.Hash, value common.Hash) {
+	if value == (common.Hash{}) {
+		delete(g, slot)
+	} else {
+		g[slot] = value
+	}
+}
+
+func (evm *EVM) isExecutionNodeRegistry(addr common.Address) bool {
+	return evm.chainRules.IsSuave && addr == suave.ExecutionNodeRegistryAddress && IsExecutionNodeRegistryDeployed(evm.StateDB)
+}
+
+// runExecutionNodeRegistry executes a call to the execution node registry. The
+// storage accessed is charged as cold SLOADs and new SSTOREs.
+func (evm *EVM) runExecutionNodeRegistry(caller common.Address, input []byte, gas uint64, value *big.Int, readOnly bool) ([]byte, uint64, error) {
+	s := &registryStorage{db: evm.StateDB, gas: gas, metered: true}
+
+	ret, err := evm.executionNodeRegistryCall(s, caller, input, value, readOnly)
+	switch {
+	case errors.Is(err, ErrOutOfGas), errors.Is(err, ErrWriteProtection):
+		return nil, 0, err
+	case err != nil:
+		return revertReason(err.Error()), s.gas, ErrExecutionReverted
+	}
+	return ret, s.gas, nil
+}
+
+func (evm *EVM) executionNodeRegistryCall(s *registryStorage, caller common.Address, input []byte, value *big.Int, readOnly bool) ([]byte, error) {
+	if value != nil && value.Sign() != 0 {
+		return nil, errRegistryPayable
+	}
+	if len(input) < 4 {
+		return nil, errors.New("missing method selector")
+	}
+	method, err := suave.ExecutionNodeRegistryABI.MethodById(input[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	if readOnly && !method.IsConstant() {
+		return nil, ErrWriteProtection
+	}
+
+	switch method.Name {
+	case "register":
+		// Only the execution nodes trusted by the chain config can register,
+		// registering on its own does not make a node trusted
+		if !evm.chainConfig.Suave.IsTrustedExecutionNode(caller) {
+			return nil, fmt.Errorf("%w: %s", errRegistryUntrusted, caller.Hex())
+		}
+		node := &suave.ExecutionNode{
+			Address:    caller,
+			DAKeys:     args[0].([]common.Address),
+			Namespaces: args[1].([]string),
+		}
+		if err := s.register(node); err != nil {
+			return nil, err
+		}
+		evm.registryLog("ExecutionNodeRegistered", caller)
+		return nil, nil
+
+	case "deregister":
+		if err := s.deregister(caller); err != nil {
+			return nil, err
+		}
+		evm.registryLog("ExecutionNodeDeregistered", caller)
+		return nil, nil
+
+	case "isExecutionNode":
+		node, err := s.readNode(args[0].(common.Address))
+		if err != nil {
+			return nil, err
+		}
+		return method.Outputs.Pack(node != nil)
+
+	case "getExecutionNode":
+		node, err := s.readNode(args[0].(common.Address))
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			return nil, errRegistryNotRegistered
+		}
+		return method.Outputs.Pack(node.DAKeys, node.Namespaces)
+
+	case "executionNodeOf":
+		node, err := s.get(mappingSlot(args[0].(common.Address), registryDAKeysSlot))
+		if err != nil {
+			return nil, err
+		}
+		return method.Outputs.Pack(common.BytesToAddress(node.Bytes()))
+	}
+	return nil, fmt.Errorf("method %s not implemented", method.Name)
+}
+
+func (evm *EVM) registryLog(event string, node common.Address) {
+	evm.StateDB.AddLog(&types.Log{
+		Address: suave.ExecutionNodeRegistryAddress,
+		Topics:  []common.Hash{suave.ExecutionNodeRegistryABI.Events[event].ID, common.BytesToHash(node.Bytes())},
+		// This is a non-consensus field, but assigned here because
+		// core/state doesn't know the current block number.
+		BlockNumber: evm.Context.BlockNumber.Uint64(),
+	})
+}
+
+// registryStorage accesses the storage of the execution node registry,
+// charging the gas for it if metered.
+type registryStorage struct {
+	db interface {
+		GetState(common.Address, common.Hash) common.Hash
+		SetState(common.Address, common.Hash, common.Hash)
+	}
+	gas     uint64
+	metered bool
+}
+
+func (s *registryStorage) charge(cost uint64) error {
+	if !s.metered {
+		return nil
+	}
+	if s.gas < cost {
+		s.gas = 0
+		return ErrOutOfGas
+	}
+	s.gas -= cost
+	return nil
+}
+
+func (s *registryStorage) get(slot common.Hash) (common.Hash, error) {
+	if err := s.charge(params.ColdSloadCostEIP2929); err != nil {
+		return common.Hash{}, err
+	}
+	return s.db.GetState(suave.ExecutionNodeRegistryAddress, slot), nil
+}
+
+func (s *registryStorage) set(slot common.Hash, value common.Hash) error {
+	if err := s.charge(params.SstoreSetGasEIP2200); err != nil {
+		return err
+	}
+	s.db.SetState(suave.ExecutionNodeRegistryAddress, slot, value)
+	return nil
+}
+
+func (s *registryStorage) readNode(addr common.Address) (*suave.ExecutionNode, error) {
+	base := mappingSlot(addr, registryNodesSlot)
+	registered, err := s.get(base)
+	if err != nil || registered == (common.Hash{}) {
+		return nil, err
+	}
+	node := &suave.ExecutionNode{Address: addr}
+
+	keys, err := s.readArray(offsetSlot(base, 1))
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		node.DAKeys = append(node.DAKeys, common.BytesToAddress(key.Bytes()))
+	}
+
+	namespaces, err := s.arrayLength(offsetSlot(base, 2))
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < namespaces; i++ {
+		namespace, err := s.readBytes(arraySlot(offsetSlot(base, 2), i))
+		if err != nil {
+			return nil, err
+		}
+		node.Namespaces = append(node.Namespaces, string(namespace))
+	}
+	return node, nil
+}
+
+func (s *registryStorage) register(node *suave.ExecutionNode) error {
+	if len(node.DAKeys) > maxRegistryDAKeys {
+		return errRegistryTooManyKeys
+	}
+	if len(node.Namespaces) > maxRegistryNamespaces {
+		return errRegistryTooManyNs
+	}
+	for _, namespace := range node.Namespaces {
+		if len(namespace) > maxRegistryNamespaceLen {
+			return errRegistryNamespaceLen
+		}
+	}
+	for _, key := range node.DAKeys {
+		owner, err := s.get(mappingSlot(key, registryDAKeysSlot))
+		if err != nil {
+			return err
+		}
+		if owner != (common.Hash{}) && common.BytesToAddress(owner.Bytes()) != node.Address {
+			return fmt.Errorf("%w: %s", errRegistryDAKeyTaken, key.Hex())
+		}
+	}
+
+	// Registering again replaces the previous registration
+	if err := s.deregister(node.Address); err != nil && !errors.Is(err, errRegistryNotRegistered) {
+		return err
+	}
+
+	base := mappingSlot(node.Address, registryNodesSlot)
+	if err := s.set(base, common.BigToHash(common.Big1)); err != nil {
+		return err
+	}
+	keys := make([]common.Hash, len(node.DAKeys))
+	for i, key := range node.DAKeys {
+		keys[i] = common.BytesToHash(key.Bytes())
+		if err := s.set(mappingSlot(key, registryDAKeysSlot), common.BytesToHash(node.Address.Bytes())); err != nil {
+			return err
+		}
+	}
+	if err := s.writeArray(offsetSlot(base, 1), keys); err != nil {
+		return err
+	}
+	if err := s.set(offsetSlot(base, 2), common.BigToHash(new(big.Int).SetInt64(int64(len(node.Namespaces))))); err != nil {
+		return err
+	}
+	for i, namespace := range node.Namespaces {
+		if err := s.writeBytes(arraySlot(offsetSlot(base, 2), uint64(i)), []byte(namespace)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *registryStorage) deregister(addr common.Address) error {
+	node, err := s.readNode(addr)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return errRegistryNotRegistered
+	}
+
+	base := mappingSlot(addr, registryNodesSlot)
+	if err := s.set(base, common.Hash{}); err != nil {
+		return err
+	}
+	for _, key := range node.DAKeys {
+		if err := s.set(mappingSlot(key, registryDAKeysSlot), common.Hash{}); err != nil {
+			return err
+		}
+	}
+	if err := s.clearArray(offsetSlot(base, 1), len(node.DAKeys)); err != nil {
+		return err
+	}
+	for i := range node.Namespaces {
+		if err := s.writeBytes(arraySlot(offsetSlot(base, 2), uint64(i)), nil); err != nil {
+			return err
+		}
+	}
+	return s.set(offsetSlot(base, 2), common.Hash{})
+}
+
+func (s *registryStorage) arrayLength(slot common.Hash) (uint64, error) {
+	length, err := s.get(slot)
+	if err != nil {
+		return 0, err
+	}
+	return length.Big().Uint64(), nil
+}
+
+func (s *registryStorage) readArray(slot common.Hash) ([]common.Hash, error) {
+	length, err := s.arrayLength(slot)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]common.Hash, length)
+	for i := range items {
+		if items[i], err = s.get(arraySlot(slot, uint64(i))); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (s *registryStorage) writeArray(slot common.Hash, items []common.Hash) error {
+	if err := s.set(slot, common.BigToHash(new(big.Int).SetInt64(int64(len(items))))); err != nil {
+		return err
+	}
+	for i, item := range items {
+		if err := s.set(arraySlot(slot, uint64(i)), item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *registryStorage) clearArray(slot common.Hash, length int) error {
+	for i := 0; i < length; i++ {
+		if err := s.set(arraySlot(slot, uint64(i)), common.Hash{}); err != nil {
+			return err
+		}
+	}
+	return s.set(slot, common.Hash{})
+}
+
+// readBytes reads a string stored in the Solidity layout, short strings are
+// stored with their length in the slot, long ones after the hash of the slot.
+func (s *registryStorage) readBytes(slot common.Hash) ([]byte, error) {
+	head, err := s.get(slot)
+	if err != nil {
+		return nil, err
+	}
+	if head[31]&1 == 0 {
+		return common.CopyBytes(head[:head[31]/2]), nil
+	}
+	length := (head.Big().Uint64() - 1) / 2
+	data := make([]byte, 0, length)
+	for i := uint64(0); uint64(len(data)) < length; i++ {
+		chunk, err := s.get(arraySlot(slot, i))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:]...)
+	}
+	return data[:length], nil
+}
+
+// writeBytes writes a string in the Solidity layout, clearing the string
+// stored in the slot before.
+func (s *registryStorage) writeBytes(slot common.Hash, data []byte) error {
+	head, err := s.get(slot)
+	if err != nil {
+		return err
+	}
+	if head[31]&1 == 1 {
+		length := (head.Big().Uint64() - 1) / 2
+		for i := uint64(0); i*32 < length; i++ {
+			if err := s.set(arraySlot(slot, i), common.Hash{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(data) < 32 {
+		var short common.Hash
+		copy(short[:], data)
+		short[31] = byte(len(data) * 2)
+		return s.set(slot, short)
+	}
+	if err := s.set(slot, common.BigToHash(new(big.Int).SetInt64(int64(len(data)*2+1)))); err != nil {
+		return err
+	}
+	for i := 0; i*32 < len(data); i++ {
+		var chunk common.Hash
+		copy(chunk[:], data[i*32:])
+		if err := s.set(arraySlot(slot, uint64(i)), chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mappingSlot returns the slot of the key in the mapping stored at the slot.
+func mappingSlot(key common.Address, slot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(key.Bytes(), 32), slot.Bytes())
+}
+
+// arraySlot returns the slot of the item of the dynamic array stored at the slot.
+func arraySlot(slot common.Hash, index uint64) common.Hash {
+	return offsetSlot(crypto.Keccak256Hash(slot.Bytes()), index)
+}
+
+func offsetSlot(slot common.Hash, offset uint64) common.Hash {
+	return common.BigToHash(new(big.Int).Add(slot.Big(), new(big.Int).SetUint64(offset)))
+}
+
+// revertReason encodes the reason as the Error(string) revert data Solidity uses.
+func revertReason(reason string) []byte {
+	stringType, _ := abi.NewType("string", "", nil)
+	data, _ := abi.Arguments{{Type: stringType}}.Pack(reason)
+	return append(crypto.Keccak256([]byte("Error(string)"))[:4], data...)
+}
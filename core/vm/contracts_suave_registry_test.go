@@ -0,0 +1,190 @@
+package vm
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/stretchr/testify/require"
+)
+
+func newRegistryTestEVM(t *testing.T, deployed bool) (*EVM, *state.StateDB) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	require.NoError(t, err)
+	if deployed {
+		statedb.CreateAccount(suave.ExecutionNodeRegistryAddress)
+		statedb.SetCode(suave.ExecutionNodeRegistryAddress, ExecutionNodeRegistryCode)
+	}
+
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+	}
+	return NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{}), statedb
+}
+
+func callRegistry(t *testing.T, evm *EVM, caller common.Address, method string, args ...interface{}) ([]interface{}, error) {
+	input, err := suave.ExecutionNodeRegistryABI.Pack(method, args...)
+	require.NoError(t, err)
+
+	ret, _, err := evm.Call(AccountRef(caller), suave.ExecutionNodeRegistryAddress, input, 1000000, new(big.Int))
+	if err != nil {
+		if reason, unpackErr := abi.UnpackRevert(ret); unpackErr == nil {
+			return nil, &revertError{err, reason}
+		}
+		return nil, err
+	}
+	return suave.ExecutionNodeRegistryABI.Unpack(method, ret)
+}
+
+type revertError struct {
+	err    error
+	reason string
+}
+
+func (e *revertError) Error() string { return e.err.Error() + ": " + e.reason }
+func (e *revertError) Unwrap() error { return e.err }
+
+func TestExecutionNodeRegistry(t *testing.T) {
+	evm, statedb := newRegistryTestEVM(t, true)
+
+	var (
+		node1         = common.Address{0x1}
+		node2         = common.Address{0x2}
+		key1          = common.Address{0xa}
+		key2          = common.Address{0xb}
+		longNamespace = strings.Repeat("mevshare:v0:", 5)
+	)
+
+	// Register a node
+	_, err := callRegistry(t, evm, node1, "register", []common.Address{key1}, []string{"default:v0:ethBundles", longNamespace})
+	require.NoError(t, err)
+	require.Len(t, statedb.Logs(), 1)
+	require.Equal(t, suave.ExecutionNodeRegistryABI.Events["ExecutionNodeRegistered"].ID, statedb.Logs()[0].Topics[0])
+
+	res, err := callRegistry(t, evm, node2, "isExecutionNode", node1)
+	require.NoError(t, err)
+	require.True(t, res[0].(bool))
+
+	res, err = callRegistry(t, evm, node2, "getExecutionNode", node1)
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{key1}, res[0])
+	require.Equal(t, []string{"default:v0:ethBundles", longNamespace}, res[1])
+
+	res, err = callRegistry(t, evm, node2, "executionNodeOf", key1)
+	require.NoError(t, err)
+	require.Equal(t, node1, res[0])
+
+	node := ReadExecutionNodeByDAKey(statedb, key1)
+	require.Equal(t, &suave.ExecutionNode{Address: node1, DAKeys: []common.Address{key1}, Namespaces: []string{"default:v0:ethBundles", longNamespace}}, node)
+	require.True(t, node.SupportsNamespace(longNamespace))
+	require.False(t, node.SupportsNamespace("default:v0:mergedBids"))
+
+	// DA keys belong to a single node
+	_, err = callRegistry(t, evm, node2, "register", []common.Address{key1}, []string{})
+	require.ErrorIs(t, err, ErrExecutionReverted)
+	require.ErrorContains(t, err, errRegistryDAKeyTaken.Error())
+	require.Nil(t, ReadExecutionNode(statedb, node2))
+
+	// Registering again replaces the registration
+	_, err = callRegistry(t, evm, node1, "register", []common.Address{key2}, []string{})
+	require.NoError(t, err)
+	require.Equal(t, &suave.ExecutionNode{Address: node1, DAKeys: []common.Address{key2}}, ReadExecutionNode(statedb, node1))
+	require.Nil(t, ReadExecutionNodeByDAKey(statedb, key1))
+	require.True(t, ReadExecutionNode(statedb, node1).SupportsNamespace("default:v0:mergedBids"))
+
+	// Deregister the node
+	_, err = callRegistry(t, evm, node1, "deregister")
+	require.NoError(t, err)
+	require.Nil(t, ReadExecutionNode(statedb, node1))
+	require.Nil(t, ReadExecutionNodeByDAKey(statedb, key2))
+
+	_, err = callRegistry(t, evm, node2, "getExecutionNode", node1)
+	require.ErrorContains(t, err, errRegistryNotRegistered.Error())
+
+	_, err = callRegistry(t, evm, node1, "deregister")
+	require.ErrorContains(t, err, errRegistryNotRegistered.Error())
+
+	// Nothing is left in the storage of the registry
+	statedb.Finalise(true)
+	storage, err := statedb.StorageTrie(suave.ExecutionNodeRegistryAddress)
+	require.NoError(t, err)
+	require.Equal(t, types.EmptyRootHash, storage.Hash())
+}
+
+func TestExecutionNodeRegistryCalls(t *testing.T) {
+	evm, statedb := newRegistryTestEVM(t, true)
+
+	input, err := suave.ExecutionNodeRegistryABI.Pack("register", []common.Address{}, []string{})
+	require.NoError(t, err)
+
+	// Static calls can not register
+	_, _, err = evm.StaticCall(AccountRef(common.Address{0x1}), suave.ExecutionNodeRegistryAddress, input, 1000000)
+	require.ErrorIs(t, err, ErrWriteProtection)
+
+	// The storage accessed is charged
+	_, _, err = evm.Call(AccountRef(common.Address{0x1}), suave.ExecutionNodeRegistryAddress, input, params.SstoreSetGasEIP2200, new(big.Int))
+	require.ErrorIs(t, err, ErrOutOfGas)
+
+	// The registry does not accept value
+	_, _, err = evm.Call(AccountRef(common.Address{0x1}), suave.ExecutionNodeRegistryAddress, input, 1000000, big.NewInt(1))
+	require.ErrorIs(t, err, ErrExecutionReverted)
+
+	require.Nil(t, ReadExecutionNode(statedb, common.Address{0x1}))
+
+	// Without the registry in the genesis the address is a regular account
+	evm, statedb = newRegistryTestEVM(t, false)
+	_, _, err = evm.Call(AccountRef(common.Address{0x1}), suave.ExecutionNodeRegistryAddress, input, 1000000, new(big.Int))
+	require.NoError(t, err)
+	require.False(t, IsExecutionNodeRegistryDeployed(statedb))
+	require.Nil(t, ReadExecutionNode(statedb, common.Address{0x1}))
+}
+
+func TestExecutionNodeRegistryTrustedNodes(t *testing.T) {
+	evm, statedb := newRegistryTestEVM(t, true)
+
+	var (
+		trusted   = common.Address{0x1}
+		untrusted = common.Address{0x2}
+	)
+	config := *evm.chainConfig
+	config.Suave = &params.SuaveConfig{ExecutionNodes: []common.Address{trusted}}
+	evm.chainConfig = &config
+
+	// Nodes the chain config does not trust can not register themselves
+	_, err := callRegistry(t, evm, untrusted, "register", []common.Address{{0xb}}, []string{})
+	require.ErrorIs(t, err, ErrExecutionReverted)
+	require.ErrorContains(t, err, errRegistryUntrusted.Error())
+	require.Nil(t, ReadExecutionNode(statedb, untrusted))
+
+	_, err = callRegistry(t, evm, trusted, "register", []common.Address{{0xa}}, []string{})
+	require.NoError(t, err)
+	require.NotNil(t, ReadExecutionNode(statedb, trusted))
+}
+
+func TestExecutionNodeRegistryStorage(t *testing.T) {
+	nodes := []suave.ExecutionNode{
+		{Address: common.Address{0x1}, DAKeys: []common.Address{{0xa}, {0xb}}, Namespaces: []string{strings.Repeat("a", 100)}},
+		{Address: common.Address{0x2}, DAKeys: []common.Address{{0xc}}},
+	}
+	storage, err := ExecutionNodeRegistryStorage(nodes)
+	require.NoError(t, err)
+
+	_, statedb := newRegistryTestEVM(t, true)
+	for slot, value := range storage {
+		statedb.SetState(suave.ExecutionNodeRegistryAddress, slot, value)
+	}
+	require.Equal(t, &nodes[0], ReadExecutionNode(statedb, common.Address{0x1}))
+	require.Equal(t, &nodes[1], ReadExecutionNodeByDAKey(statedb, common.Address{0xc}))
+
+	_, err = ExecutionNodeRegistryStorage([]suave.ExecutionNode{{Address: common.Address{0x1}, DAKeys: []common.Address{{0xa}}}, {Address: common.Address{0x2}, DAKeys: []common.Address{{0xa}}}})
+	require.ErrorIs(t, err, errRegistryDAKeyTaken)
+}
@@ -1,5 +1,5 @@
 // Code generated by suave/gen. DO NOT EDIT.
-// Hash: 23a6dd8b9b224b11b8baea19a80c55c7787c50c0eb2fc69727e66d615c913483
+// Hash: 5341be8db22c7380c1d580248d5fe1bdbbdbcb7d20816a70c774dae8a626db21
 package vm
 
 import (
@@ -21,13 +21,17 @@ type SuaveRuntime interface {
 	confidentialInputs() ([]byte, error)
 	confidentialStoreRetrieve(bidId types.BidId, key string) ([]byte, error)
 	confidentialStoreStore(bidId types.BidId, key string, data1 []byte) error
+	doHTTPRequest(request types.HttpRequest) (types.HttpResponse, error)
 	ethcall(contractAddr common.Address, input1 []byte) ([]byte, error)
 	extractHint(bundleData []byte) ([]byte, error)
 	fetchBids(cond uint64, namespace string) ([]types.Bid, error)
 	fillMevShareBundle(bidId types.BidId) ([]byte, error)
 	newBid(decryptionCondition uint64, allowedPeekers []common.Address, allowedStores []common.Address, bidType string) (types.Bid, error)
+	queryBids(fromBlock uint64, toBlock uint64, namespaces []string, cursor uint64, limit uint64) ([]types.Bid, uint64, error)
+	setKeyAccessRule(bidId types.BidId, prefix string, writers []common.Address, readers []common.Address, writeOnce bool) error
 	signEthTransaction(txn []byte, chainId string, signingKey string) ([]byte, error)
 	simulateBundle(bundleData []byte) (uint64, error)
+	simulateEthBundle(blockArgs types.BuildBlockArgs, bundleData []byte) (types.SimulatedBundle, error)
 	submitBundleJsonRPC(url string, method string, params []byte) ([]byte, error)
 	submitEthBlockBidToRelay(relayUrl string, builderBid []byte) ([]byte, error)
 }
@@ -45,40 +49,40 @@ func (b *SuaveRuntimeAdapter) buildEthBlock(input []byte) (res []byte, err error
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["buildEthBlock"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("buildEthBlock", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		blockArgs types.BuildBlockArgs
-		bidId     types.BidId
-		namespace string
+		inBlockArgs types.BuildBlockArgs
+		inBidId     types.BidId
+		inNamespace string
 	)
 
-	if err = mapstructure.Decode(unpacked[0], &blockArgs); err != nil {
+	if err = mapstructure.Decode(unpacked[0], &inBlockArgs); err != nil {
 		err = errFailedToDecodeField
 		return
 	}
 
-	if err = mapstructure.Decode(unpacked[1], &bidId); err != nil {
+	if err = mapstructure.Decode(unpacked[1], &inBidId); err != nil {
 		err = errFailedToDecodeField
 		return
 	}
 
-	namespace = unpacked[2].(string)
+	inNamespace = unpacked[2].(string)
 
 	var (
-		output1 []byte
-		output2 []byte
+		outOutput1 []byte
+		outOutput2 []byte
 	)
 
-	if output1, output2, err = b.impl.buildEthBlock(blockArgs, bidId, namespace); err != nil {
+	if outOutput1, outOutput2, err = b.impl.buildEthBlock(inBlockArgs, inBidId, inNamespace); err != nil {
 		return
 	}
 
-	result, err = artifacts.SuaveAbi.Methods["buildEthBlock"].Outputs.Pack(output1, output2)
+	result, err = artifacts.SuaveAbi.Methods["buildEthBlock"].Outputs.Pack(outOutput1, outOutput2)
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -96,7 +100,7 @@ func (b *SuaveRuntimeAdapter) confidentialInputs(input []byte) (res []byte, err
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["confidentialInputs"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("confidentialInputs", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
@@ -105,14 +109,14 @@ func (b *SuaveRuntimeAdapter) confidentialInputs(input []byte) (res []byte, err
 	var ()
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.confidentialInputs(); err != nil {
+	if outOutput1, err = b.impl.confidentialInputs(); err != nil {
 		return
 	}
 
-	result = output1
+	result = outOutput1
 	return result, nil
 
 }
@@ -126,33 +130,33 @@ func (b *SuaveRuntimeAdapter) confidentialStoreRetrieve(input []byte) (res []byt
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["confidentialStoreRetrieve"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("confidentialStoreRetrieve", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		bidId types.BidId
-		key   string
+		inBidId types.BidId
+		inKey   string
 	)
 
-	if err = mapstructure.Decode(unpacked[0], &bidId); err != nil {
+	if err = mapstructure.Decode(unpacked[0], &inBidId); err != nil {
 		err = errFailedToDecodeField
 		return
 	}
 
-	key = unpacked[1].(string)
+	inKey = unpacked[1].(string)
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.confidentialStoreRetrieve(bidId, key); err != nil {
+	if outOutput1, err = b.impl.confidentialStoreRetrieve(inBidId, inKey); err != nil {
 		return
 	}
 
-	result = output1
+	result = outOutput1
 	return result, nil
 
 }
@@ -166,29 +170,29 @@ func (b *SuaveRuntimeAdapter) confidentialStoreStore(input []byte) (res []byte,
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["confidentialStoreStore"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("confidentialStoreStore", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		bidId types.BidId
-		key   string
-		data1 []byte
+		inBidId types.BidId
+		inKey   string
+		inData1 []byte
 	)
 
-	if err = mapstructure.Decode(unpacked[0], &bidId); err != nil {
+	if err = mapstructure.Decode(unpacked[0], &inBidId); err != nil {
 		err = errFailedToDecodeField
 		return
 	}
 
-	key = unpacked[1].(string)
-	data1 = unpacked[2].([]byte)
+	inKey = unpacked[1].(string)
+	inData1 = unpacked[2].([]byte)
 
 	var ()
 
-	if err = b.impl.confidentialStoreStore(bidId, key, data1); err != nil {
+	if err = b.impl.confidentialStoreStore(inBidId, inKey, inData1); err != nil {
 		return
 	}
 
@@ -196,6 +200,47 @@ func (b *SuaveRuntimeAdapter) confidentialStoreStore(input []byte) (res []byte,
 
 }
 
+func (b *SuaveRuntimeAdapter) doHTTPRequest(input []byte) (res []byte, err error) {
+	var (
+		unpacked []interface{}
+		result   []byte
+	)
+
+	_ = unpacked
+	_ = result
+
+	unpacked, err = unpackSuaveInputs("doHTTPRequest", input)
+	if err != nil {
+		err = errFailedToUnpackInput
+		return
+	}
+
+	var (
+		inRequest types.HttpRequest
+	)
+
+	if err = mapstructure.Decode(unpacked[0], &inRequest); err != nil {
+		err = errFailedToDecodeField
+		return
+	}
+
+	var (
+		outResponse types.HttpResponse
+	)
+
+	if outResponse, err = b.impl.doHTTPRequest(inRequest); err != nil {
+		return
+	}
+
+	result, err = artifacts.SuaveAbi.Methods["doHTTPRequest"].Outputs.Pack(outResponse)
+	if err != nil {
+		err = errFailedToPackOutput
+		return
+	}
+	return result, nil
+
+}
+
 func (b *SuaveRuntimeAdapter) ethcall(input []byte) (res []byte, err error) {
 	var (
 		unpacked []interface{}
@@ -205,29 +250,29 @@ func (b *SuaveRuntimeAdapter) ethcall(input []byte) (res []byte, err error) {
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["ethcall"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("ethcall", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		contractAddr common.Address
-		input1       []byte
+		inContractAddr common.Address
+		inInput1       []byte
 	)
 
-	contractAddr = unpacked[0].(common.Address)
-	input1 = unpacked[1].([]byte)
+	inContractAddr = unpacked[0].(common.Address)
+	inInput1 = unpacked[1].([]byte)
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.ethcall(contractAddr, input1); err != nil {
+	if outOutput1, err = b.impl.ethcall(inContractAddr, inInput1); err != nil {
 		return
 	}
 
-	result, err = artifacts.SuaveAbi.Methods["ethcall"].Outputs.Pack(output1)
+	result, err = artifacts.SuaveAbi.Methods["ethcall"].Outputs.Pack(outOutput1)
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -245,27 +290,27 @@ func (b *SuaveRuntimeAdapter) extractHint(input []byte) (res []byte, err error)
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["extractHint"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("extractHint", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		bundleData []byte
+		inBundleData []byte
 	)
 
-	bundleData = unpacked[0].([]byte)
+	inBundleData = unpacked[0].([]byte)
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.extractHint(bundleData); err != nil {
+	if outOutput1, err = b.impl.extractHint(inBundleData); err != nil {
 		return
 	}
 
-	result = output1
+	result = outOutput1
 	return result, nil
 
 }
@@ -279,29 +324,29 @@ func (b *SuaveRuntimeAdapter) fetchBids(input []byte) (res []byte, err error) {
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["fetchBids"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("fetchBids", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		cond      uint64
-		namespace string
+		inCond      uint64
+		inNamespace string
 	)
 
-	cond = unpacked[0].(uint64)
-	namespace = unpacked[1].(string)
+	inCond = unpacked[0].(uint64)
+	inNamespace = unpacked[1].(string)
 
 	var (
-		bid []types.Bid
+		outBid []types.Bid
 	)
 
-	if bid, err = b.impl.fetchBids(cond, namespace); err != nil {
+	if outBid, err = b.impl.fetchBids(inCond, inNamespace); err != nil {
 		return
 	}
 
-	result, err = artifacts.SuaveAbi.Methods["fetchBids"].Outputs.Pack(bid)
+	result, err = artifacts.SuaveAbi.Methods["fetchBids"].Outputs.Pack(outBid)
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -319,30 +364,30 @@ func (b *SuaveRuntimeAdapter) fillMevShareBundle(input []byte) (res []byte, err
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["fillMevShareBundle"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("fillMevShareBundle", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		bidId types.BidId
+		inBidId types.BidId
 	)
 
-	if err = mapstructure.Decode(unpacked[0], &bidId); err != nil {
+	if err = mapstructure.Decode(unpacked[0], &inBidId); err != nil {
 		err = errFailedToDecodeField
 		return
 	}
 
 	var (
-		encodedBundle []byte
+		outEncodedBundle []byte
 	)
 
-	if encodedBundle, err = b.impl.fillMevShareBundle(bidId); err != nil {
+	if outEncodedBundle, err = b.impl.fillMevShareBundle(inBidId); err != nil {
 		return
 	}
 
-	result = encodedBundle
+	result = outEncodedBundle
 	return result, nil
 
 }
@@ -356,33 +401,80 @@ func (b *SuaveRuntimeAdapter) newBid(input []byte) (res []byte, err error) {
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["newBid"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("newBid", input)
+	if err != nil {
+		err = errFailedToUnpackInput
+		return
+	}
+
+	var (
+		inDecryptionCondition uint64
+		inAllowedPeekers      []common.Address
+		inAllowedStores       []common.Address
+		inBidType             string
+	)
+
+	inDecryptionCondition = unpacked[0].(uint64)
+	inAllowedPeekers = unpacked[1].([]common.Address)
+	inAllowedStores = unpacked[2].([]common.Address)
+	inBidType = unpacked[3].(string)
+
+	var (
+		outBid types.Bid
+	)
+
+	if outBid, err = b.impl.newBid(inDecryptionCondition, inAllowedPeekers, inAllowedStores, inBidType); err != nil {
+		return
+	}
+
+	result, err = artifacts.SuaveAbi.Methods["newBid"].Outputs.Pack(outBid)
+	if err != nil {
+		err = errFailedToPackOutput
+		return
+	}
+	return result, nil
+
+}
+
+func (b *SuaveRuntimeAdapter) queryBids(input []byte) (res []byte, err error) {
+	var (
+		unpacked []interface{}
+		result   []byte
+	)
+
+	_ = unpacked
+	_ = result
+
+	unpacked, err = unpackSuaveInputs("queryBids", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		decryptionCondition uint64
-		allowedPeekers      []common.Address
-		allowedStores       []common.Address
-		bidType             string
+		inFromBlock  uint64
+		inToBlock    uint64
+		inNamespaces []string
+		inCursor     uint64
+		inLimit      uint64
 	)
 
-	decryptionCondition = unpacked[0].(uint64)
-	allowedPeekers = unpacked[1].([]common.Address)
-	allowedStores = unpacked[2].([]common.Address)
-	bidType = unpacked[3].(string)
+	inFromBlock = unpacked[0].(uint64)
+	inToBlock = unpacked[1].(uint64)
+	inNamespaces = unpacked[2].([]string)
+	inCursor = unpacked[3].(uint64)
+	inLimit = unpacked[4].(uint64)
 
 	var (
-		bid types.Bid
+		outBids       []types.Bid
+		outNextCursor uint64
 	)
 
-	if bid, err = b.impl.newBid(decryptionCondition, allowedPeekers, allowedStores, bidType); err != nil {
+	if outBids, outNextCursor, err = b.impl.queryBids(inFromBlock, inToBlock, inNamespaces, inCursor, inLimit); err != nil {
 		return
 	}
 
-	result, err = artifacts.SuaveAbi.Methods["newBid"].Outputs.Pack(bid)
+	result, err = artifacts.SuaveAbi.Methods["queryBids"].Outputs.Pack(outBids, outNextCursor)
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -391,6 +483,49 @@ func (b *SuaveRuntimeAdapter) newBid(input []byte) (res []byte, err error) {
 
 }
 
+func (b *SuaveRuntimeAdapter) setKeyAccessRule(input []byte) (res []byte, err error) {
+	var (
+		unpacked []interface{}
+		result   []byte
+	)
+
+	_ = unpacked
+	_ = result
+
+	unpacked, err = unpackSuaveInputs("setKeyAccessRule", input)
+	if err != nil {
+		err = errFailedToUnpackInput
+		return
+	}
+
+	var (
+		inBidId     types.BidId
+		inPrefix    string
+		inWriters   []common.Address
+		inReaders   []common.Address
+		inWriteOnce bool
+	)
+
+	if err = mapstructure.Decode(unpacked[0], &inBidId); err != nil {
+		err = errFailedToDecodeField
+		return
+	}
+
+	inPrefix = unpacked[1].(string)
+	inWriters = unpacked[2].([]common.Address)
+	inReaders = unpacked[3].([]common.Address)
+	inWriteOnce = unpacked[4].(bool)
+
+	var ()
+
+	if err = b.impl.setKeyAccessRule(inBidId, inPrefix, inWriters, inReaders, inWriteOnce); err != nil {
+		return
+	}
+
+	return nil, nil
+
+}
+
 func (b *SuaveRuntimeAdapter) signEthTransaction(input []byte) (res []byte, err error) {
 	var (
 		unpacked []interface{}
@@ -400,31 +535,31 @@ func (b *SuaveRuntimeAdapter) signEthTransaction(input []byte) (res []byte, err
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["signEthTransaction"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("signEthTransaction", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		txn        []byte
-		chainId    string
-		signingKey string
+		inTxn        []byte
+		inChainId    string
+		inSigningKey string
 	)
 
-	txn = unpacked[0].([]byte)
-	chainId = unpacked[1].(string)
-	signingKey = unpacked[2].(string)
+	inTxn = unpacked[0].([]byte)
+	inChainId = unpacked[1].(string)
+	inSigningKey = unpacked[2].(string)
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.signEthTransaction(txn, chainId, signingKey); err != nil {
+	if outOutput1, err = b.impl.signEthTransaction(inTxn, inChainId, inSigningKey); err != nil {
 		return
 	}
 
-	result, err = artifacts.SuaveAbi.Methods["signEthTransaction"].Outputs.Pack(output1)
+	result, err = artifacts.SuaveAbi.Methods["signEthTransaction"].Outputs.Pack(outOutput1)
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -442,27 +577,71 @@ func (b *SuaveRuntimeAdapter) simulateBundle(input []byte) (res []byte, err erro
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["simulateBundle"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("simulateBundle", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		bundleData []byte
+		inBundleData []byte
 	)
 
-	bundleData = unpacked[0].([]byte)
+	inBundleData = unpacked[0].([]byte)
+
+	var (
+		outOutput1 uint64
+	)
+
+	if outOutput1, err = b.impl.simulateBundle(inBundleData); err != nil {
+		return
+	}
+
+	result, err = artifacts.SuaveAbi.Methods["simulateBundle"].Outputs.Pack(outOutput1)
+	if err != nil {
+		err = errFailedToPackOutput
+		return
+	}
+	return result, nil
+
+}
+
+func (b *SuaveRuntimeAdapter) simulateEthBundle(input []byte) (res []byte, err error) {
+	var (
+		unpacked []interface{}
+		result   []byte
+	)
+
+	_ = unpacked
+	_ = result
+
+	unpacked, err = unpackSuaveInputs("simulateEthBundle", input)
+	if err != nil {
+		err = errFailedToUnpackInput
+		return
+	}
+
+	var (
+		inBlockArgs  types.BuildBlockArgs
+		inBundleData []byte
+	)
+
+	if err = mapstructure.Decode(unpacked[0], &inBlockArgs); err != nil {
+		err = errFailedToDecodeField
+		return
+	}
+
+	inBundleData = unpacked[1].([]byte)
 
 	var (
-		output1 uint64
+		outSimulation types.SimulatedBundle
 	)
 
-	if output1, err = b.impl.simulateBundle(bundleData); err != nil {
+	if outSimulation, err = b.impl.simulateEthBundle(inBlockArgs, inBundleData); err != nil {
 		return
 	}
 
-	result, err = artifacts.SuaveAbi.Methods["simulateBundle"].Outputs.Pack(output1)
+	result, err = artifacts.SuaveAbi.Methods["simulateEthBundle"].Outputs.Pack(outSimulation)
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -480,31 +659,31 @@ func (b *SuaveRuntimeAdapter) submitBundleJsonRPC(input []byte) (res []byte, err
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["submitBundleJsonRPC"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("submitBundleJsonRPC", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		url    string
-		method string
-		params []byte
+		inUrl    string
+		inMethod string
+		inParams []byte
 	)
 
-	url = unpacked[0].(string)
-	method = unpacked[1].(string)
-	params = unpacked[2].([]byte)
+	inUrl = unpacked[0].(string)
+	inMethod = unpacked[1].(string)
+	inParams = unpacked[2].([]byte)
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.submitBundleJsonRPC(url, method, params); err != nil {
+	if outOutput1, err = b.impl.submitBundleJsonRPC(inUrl, inMethod, inParams); err != nil {
 		return
 	}
 
-	result = output1
+	result = outOutput1
 	return result, nil
 
 }
@@ -518,29 +697,29 @@ func (b *SuaveRuntimeAdapter) submitEthBlockBidToRelay(input []byte) (res []byte
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["submitEthBlockBidToRelay"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("submitEthBlockBidToRelay", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		relayUrl   string
-		builderBid []byte
+		inRelayUrl   string
+		inBuilderBid []byte
 	)
 
-	relayUrl = unpacked[0].(string)
-	builderBid = unpacked[1].([]byte)
+	inRelayUrl = unpacked[0].(string)
+	inBuilderBid = unpacked[1].([]byte)
 
 	var (
-		output1 []byte
+		outOutput1 []byte
 	)
 
-	if output1, err = b.impl.submitEthBlockBidToRelay(relayUrl, builderBid); err != nil {
+	if outOutput1, err = b.impl.submitEthBlockBidToRelay(inRelayUrl, inBuilderBid); err != nil {
 		return
 	}
 
-	result = output1
+	result = outOutput1
 	return result, nil
 
 }
@@ -2,21 +2,26 @@ package vm
 
 import (
 	"context"
+	"encoding/json"
 	"math/big"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/suave/artifacts"
 	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/ethereum/go-ethereum/suave/cstore"
+	"github.com/mitchellh/mapstructure"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +43,14 @@ func (m *mockSuaveBackend) Retrieve(bid suave.Bid, caller common.Address, key st
 	return nil, nil
 }
 
+func (m *mockSuaveBackend) Commit(bids []suave.Bid, writes []cstore.StoreWrite) error {
+	return nil
+}
+
+func (m *mockSuaveBackend) Prune(decryptionConditionBelow uint64) (cstore.PruneResult, error) {
+	return cstore.PruneResult{}, nil
+}
+
 func (m *mockSuaveBackend) SubmitBid(types.Bid) error {
 	return nil
 }
@@ -54,6 +67,10 @@ func (m *mockSuaveBackend) FetchBidsByProtocolAndBlock(blockNumber uint64, names
 	return nil
 }
 
+func (m *mockSuaveBackend) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	return nil, 0, nil
+}
+
 func (m *mockSuaveBackend) BuildEthBlock(ctx context.Context, args *suave.BuildBlockArgs, txs types.Transactions) (*engine.ExecutionPayloadEnvelope, error) {
 	return nil, nil
 }
@@ -62,15 +79,23 @@ func (m *mockSuaveBackend) BuildEthBlockFromBundles(ctx context.Context, args *s
 	return nil, nil
 }
 
+func (m *mockSuaveBackend) SimulateBundle(ctx context.Context, args *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	return &types.SimulatedBundle{}, nil
+}
+
 func (m *mockSuaveBackend) Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error) {
 	return nil, nil
 }
 
+func (m *mockSuaveBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	return 0, nil
+}
+
 func (m *mockSuaveBackend) Subscribe() (<-chan cstore.DAMessage, context.CancelFunc) {
 	return nil, func() {}
 }
 
-func (m *mockSuaveBackend) Publish(cstore.DAMessage) {}
+func (m *mockSuaveBackend) Publish(cstore.DAMessage) error { return nil }
 
 var dummyBlockContext = BlockContext{
 	CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
@@ -110,11 +135,18 @@ func TestSuavePrecompileStub(t *testing.T) {
 		// json error when the precompile expects to decode a json object encoded as []byte
 		// in the precompile input.
 		"invalid character",
+		// json error when the random []byte input to decode is empty
+		"unexpected end of JSON input",
 		"not allowed to store",
 		"not allowed to retrieve",
 		"unknown bid version",
+		// random block ranges and limits are rejected by the confidential store
+		"invalid bid query",
 		// error from a precompile that expects to make an http request from an input value.
 		"could not send request to relay",
+		// errors from doHTTPRequest for random urls
+		"invalid http request url",
+		"domain not allowed",
 		// error in 'buildEthBlock' when it expects to retrieve bids in abi format from the
 		// confidential store.
 		"could not unpack merged bid ids",
@@ -122,6 +154,7 @@ func TestSuavePrecompileStub(t *testing.T) {
 		"precompile fillMevShareBundle (0000000000000000000000000000000043200001) not allowed on 00000000000000000000000000000000",
 		"no caller of confidentialStoreStore (0000000000000000000000000000000042020000) is allowed on 00000000000000000000000000000000",
 		"precompile buildEthBlock (0000000000000000000000000000000042100001) not allowed on 00000000000000000000000000000000",
+		"no caller of setKeyAccessRule (0000000000000000000000000000000042030003) is allowed on 00000000000000000000000000000000",
 	}
 
 	expectedVariableErrors := []*regexp.Regexp{
@@ -253,3 +286,408 @@ func TestSuave_ConfStoreWorkflow(t *testing.T) {
 	_, err = b.confidentialStoreRetrieve(bid.Id, "key")
 	require.Error(t, err)
 }
+
+// gasMockBackend builds blocks and simulates bundles using the configured amount of gas, and answers calls after a delay
+type gasMockBackend struct {
+	mockSuaveBackend
+
+	gasUsed     uint64
+	callDelay   time.Duration
+	simulations int
+}
+
+func (m *gasMockBackend) BuildEthBlock(ctx context.Context, args *suave.BuildBlockArgs, txs types.Transactions) (*engine.ExecutionPayloadEnvelope, error) {
+	return &engine.ExecutionPayloadEnvelope{
+		ExecutionPayload: &engine.ExecutableData{GasUsed: m.gasUsed},
+		BlockValue:       big.NewInt(int64(m.gasUsed)),
+	}, nil
+}
+
+func (m *gasMockBackend) SimulateBundle(ctx context.Context, args *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	m.simulations++
+	select {
+	case <-time.After(m.callDelay):
+		return &types.SimulatedBundle{Success: true, GasUsed: m.gasUsed, CoinbaseDelta: big.NewInt(int64(m.gasUsed))}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *gasMockBackend) Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error) {
+	select {
+	case <-time.After(m.callDelay):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func runMeteredPrecompile(suaveContext *SuaveContext, addr common.Address, input []byte, suppliedGas uint64) (uint64, error) {
+	p := NewSuavePrecompiledContractWrapper(addr, suaveContext, PrecompiledContractsSuave[addr])
+	_, remainingGas, err := RunPrecompiledContract(p, input, suppliedGas)
+	return suppliedGas - remainingGas, err
+}
+
+func TestSuaveGasGrowsWithBidsFetched(t *testing.T) {
+	b := newTestBackend(t)
+
+	input, err := artifacts.SuaveAbi.Methods["fetchBids"].Inputs.Pack(uint64(5), "a")
+	require.NoError(t, err)
+
+	newBids := func(n int) {
+		for i := 0; i < n; i++ {
+			_, err := b.newBid(5, []common.Address{{0x1}}, nil, "a")
+			require.NoError(t, err)
+		}
+	}
+
+	newBids(1)
+	oneBid, err := runMeteredPrecompile(b.suaveContext, fetchBidsAddress, input, 1000000)
+	require.NoError(t, err)
+
+	newBids(9)
+	tenBids, err := runMeteredPrecompile(b.suaveContext, fetchBidsAddress, input, 1000000)
+	require.NoError(t, err)
+
+	require.Equal(t, 9*SuaveBidFetchGas, tenBids-oneBid)
+
+	// The work is charged against the gas supplied
+	_, err = runMeteredPrecompile(b.suaveContext, fetchBidsAddress, input, tenBids-1)
+	require.ErrorIs(t, err, ErrOutOfGas)
+}
+
+func TestSuaveGasGrowsWithBundleGas(t *testing.T) {
+	backend := &gasMockBackend{}
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			ConfidentialEthBackend: backend,
+		},
+	}
+
+	bundle, err := json.Marshal(&types.SBundle{BlockNumber: big.NewInt(1)})
+	require.NoError(t, err)
+	input, err := artifacts.SuaveAbi.Methods["simulateBundle"].Inputs.Pack(bundle)
+	require.NoError(t, err)
+
+	backend.gasUsed = 21000
+	small, err := runMeteredPrecompile(suaveContext, simulateBundleAddress, input, 1000000)
+	require.NoError(t, err)
+
+	backend.gasUsed = 21000 * 500
+	large, err := runMeteredPrecompile(suaveContext, simulateBundleAddress, input, 1000000)
+	require.NoError(t, err)
+
+	require.Equal(t, (21000*500-21000)/SuaveBundleGasDivisor, large-small)
+
+	_, err = runMeteredPrecompile(suaveContext, simulateBundleAddress, input, large-1)
+	require.ErrorIs(t, err, ErrOutOfGas)
+}
+
+func TestSuaveGasGrowsWithExternalCallTime(t *testing.T) {
+	backend := &gasMockBackend{}
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			ConfidentialEthBackend: backend,
+		},
+	}
+
+	input, err := artifacts.SuaveAbi.Methods["ethcall"].Inputs.Pack(common.Address{0x42}, []byte{0x01})
+	require.NoError(t, err)
+
+	fast, err := runMeteredPrecompile(suaveContext, ethcallAddr, input, 1000000)
+	require.NoError(t, err)
+
+	backend.callDelay = 50 * time.Millisecond
+	slow, err := runMeteredPrecompile(suaveContext, ethcallAddr, input, 1000000)
+	require.NoError(t, err)
+
+	require.GreaterOrEqual(t, slow, fast+40*SuaveExternalCallMsGas)
+}
+
+func TestSuaveGasReservesBundleGas(t *testing.T) {
+	backend := &gasMockBackend{gasUsed: 21000}
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			ConfidentialEthBackend: backend,
+		},
+	}
+
+	tx := types.NewTransaction(0, common.Address{0x42}, common.Big0, 1000000, common.Big1, nil)
+	bundle, err := json.Marshal(&types.SBundle{Txs: types.Transactions{tx}, BlockNumber: big.NewInt(1)})
+	require.NoError(t, err)
+	input, err := artifacts.SuaveAbi.Methods["simulateBundle"].Inputs.Pack(bundle)
+	require.NoError(t, err)
+
+	// The gas limit of the transactions is reserved before simulating
+	p := NewSuavePrecompiledContractWrapper(simulateBundleAddress, suaveContext, PrecompiledContractsSuave[simulateBundleAddress])
+	reserved := p.RequiredGas(input) + tx.Gas()/SuaveBundleGasDivisor
+	_, err = runMeteredPrecompile(suaveContext, simulateBundleAddress, input, reserved-1)
+	require.ErrorIs(t, err, ErrOutOfGas)
+	require.Zero(t, backend.simulations)
+
+	// Only the gas used and the time spent simulating are charged in the end
+	used, err := runMeteredPrecompile(suaveContext, simulateBundleAddress, input, reserved+externalCallGas(simulateEthBundleTimeout))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, used, p.RequiredGas(input)+backend.gasUsed/SuaveBundleGasDivisor+SuaveExternalCallMinGas)
+	require.Less(t, used, reserved+externalCallGas(simulateEthBundleTimeout))
+	require.Nil(t, suaveContext.gasMeter)
+}
+
+func TestSuaveGasCapsExternalCallTime(t *testing.T) {
+	backend := &gasMockBackend{callDelay: 5 * time.Second}
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			ConfidentialEthBackend: backend,
+		},
+	}
+
+	input, err := artifacts.SuaveAbi.Methods["ethcall"].Inputs.Pack(common.Address{0x42}, []byte{0x01})
+	require.NoError(t, err)
+
+	// The gas left after the constant cost only pays for waiting 50ms
+	p := NewSuavePrecompiledContractWrapper(ethcallAddr, suaveContext, PrecompiledContractsSuave[ethcallAddr])
+	suppliedGas := p.RequiredGas(input) + 50*SuaveExternalCallMsGas
+
+	start := time.Now()
+	used, err := runMeteredPrecompile(suaveContext, ethcallAddr, input, suppliedGas)
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, suppliedGas, used)
+	require.Nil(t, suaveContext.gasMeter)
+
+	// Bundle simulations are cut at the time the gas left pays for as well
+	bundle, err := json.Marshal(&types.SBundle{})
+	require.NoError(t, err)
+	input, err = artifacts.SuaveAbi.Methods["simulateBundle"].Inputs.Pack(bundle)
+	require.NoError(t, err)
+	p = NewSuavePrecompiledContractWrapper(simulateBundleAddress, suaveContext, PrecompiledContractsSuave[simulateBundleAddress])
+	suppliedGas = p.RequiredGas(input) + 50*SuaveExternalCallMsGas
+
+	start = time.Now()
+	used, err = runMeteredPrecompile(suaveContext, simulateBundleAddress, input, suppliedGas)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, suppliedGas, used)
+}
+
+// simulateMockBackend returns the configured simulation result and records the build args
+type simulateMockBackend struct {
+	mockSuaveBackend
+
+	result *types.SimulatedBundle
+	args   *suave.BuildBlockArgs
+}
+
+func (m *simulateMockBackend) SimulateBundle(ctx context.Context, args *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	m.args = args
+	return m.result, nil
+}
+
+func TestSuave_SimulateEthBundle(t *testing.T) {
+	txHash := common.Hash{0x1}
+	backend := &simulateMockBackend{result: &types.SimulatedBundle{
+		Success:       true,
+		GasUsed:       42000,
+		CoinbaseDelta: big.NewInt(420000),
+		Txs: []*types.SimulatedTransaction{{
+			TxHash:        txHash,
+			Success:       true,
+			GasUsed:       42000,
+			CoinbaseDelta: big.NewInt(420000),
+			Logs:          []*types.SimulatedLog{{Addr: common.Address{0x2}, Topics: []common.Hash{{0x3}}, Data: []byte{0x4}}},
+		}},
+	}}
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			ConfidentialEthBackend: backend,
+		},
+	}
+
+	bundle, err := json.Marshal(&types.SBundle{})
+	require.NoError(t, err)
+
+	method := artifacts.SuaveAbi.Methods["simulateEthBundle"]
+	blockArgs := types.BuildBlockArgs{
+		Timestamp:             100,
+		FeeRecipient:          common.Address{0x6},
+		GasLimit:              1000000,
+		Random:                common.Hash{0x7},
+		ParentBeaconBlockRoot: common.Hash{0x8},
+		ExcessBlobGas:         9,
+	}
+	input, err := method.Inputs.Pack(blockArgs, bundle)
+	require.NoError(t, err)
+
+	p := NewSuavePrecompiledContractWrapper(simulateEthBundleAddress, suaveContext, PrecompiledContractsSuave[simulateEthBundleAddress])
+	output, err := p.Run(input)
+	require.NoError(t, err)
+
+	// Without a parent the block args are passed on, the backend simulates
+	// the bundle on top of the head
+	require.Equal(t, common.Hash{}, backend.args.Parent)
+	require.Equal(t, blockArgs.Timestamp, backend.args.Timestamp)
+	require.Equal(t, blockArgs.FeeRecipient, backend.args.FeeRecipient)
+
+	unpacked, err := method.Outputs.Unpack(output)
+	require.NoError(t, err)
+
+	var result types.SimulatedBundle
+	require.NoError(t, mapstructure.Decode(unpacked[0], &result))
+	require.Equal(t, *backend.result, result)
+
+	// On a given parent, the parent and the other block args are passed on
+	blockArgs.Parent = common.Hash{0x5}
+	input, err = method.Inputs.Pack(blockArgs, bundle)
+	require.NoError(t, err)
+	_, err = p.Run(input)
+	require.NoError(t, err)
+	require.Equal(t, blockArgs.Parent, backend.args.Parent)
+	require.Equal(t, blockArgs.Timestamp, backend.args.Timestamp)
+	require.Equal(t, blockArgs.FeeRecipient, backend.args.FeeRecipient)
+	require.Equal(t, blockArgs.GasLimit, backend.args.GasLimit)
+	require.Equal(t, blockArgs.Random, backend.args.Random)
+	require.Equal(t, blockArgs.ParentBeaconBlockRoot, backend.args.ParentBeaconBlockRoot)
+	require.Equal(t, blockArgs.ExcessBlobGas, backend.args.ExcessBlobGas)
+
+	// simulateBundle reports the effective gas price of the bundle, and fails with it
+	b := &suaveRuntime{suaveContext: suaveContext}
+	egp, err := b.simulateBundle(bundle)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), egp)
+	require.Nil(t, backend.args)
+
+	backend.result = &types.SimulatedBundle{Error: "transaction 0x01 reverted", Txs: backend.result.Txs}
+	_, err = b.simulateBundle(bundle)
+	require.ErrorContains(t, err, "bundle simulation failed: transaction 0x01 reverted")
+}
+
+func TestSuave_ExtractHint(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	var (
+		signer = types.LatestSignerForChainID(big.NewInt(1))
+		to     = common.Address{0x1}
+		callTx = types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce: 0, To: &to, Gas: 100000, GasPrice: big.NewInt(1), Data: []byte{0xa, 0xb, 0xc, 0xd, 0xe},
+		})
+		// Contract creations have no address and a calldata shorter than a selector
+		createTx = types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce: 1, Gas: 100000, GasPrice: big.NewInt(1), Data: []byte{0x60, 0x00},
+		})
+		callHash, createHash = callTx.Hash(), createTx.Hash()
+		selector             = hexutil.Bytes{0xa, 0xb, 0xc, 0xd}
+		callData             = hexutil.Bytes(callTx.Data())
+		createData           = hexutil.Bytes(createTx.Data())
+		logs                 = []*types.MevShareLogHint{{Address: to, Topics: []common.Hash{{0x2}}, Data: hexutil.Bytes{0x3}}}
+	)
+
+	backend := &simulateMockBackend{result: &types.SimulatedBundle{
+		Success: true,
+		Txs: []*types.SimulatedTransaction{
+			{TxHash: callHash, Success: true, Logs: []*types.SimulatedLog{{Addr: to, Topics: []common.Hash{{0x2}}, Data: []byte{0x3}}}},
+			{TxHash: createHash, Success: true},
+		},
+	}}
+	suaveContext := &SuaveContext{
+		Backend: &SuaveExecutionBackend{
+			ConfidentialEthBackend: backend,
+		},
+	}
+
+	extract := func(hints []string) (*types.MevShareHint, error) {
+		bundle, err := json.Marshal(&types.SBundle{
+			Txs:     types.Transactions{callTx, createTx},
+			Privacy: &types.BundlePrivacy{Hints: hints},
+		})
+		require.NoError(t, err)
+
+		hintBytes, err := (&extractHint{}).runImpl(suaveContext, bundle)
+		if err != nil {
+			return nil, err
+		}
+		var hint types.MevShareHint
+		require.NoError(t, json.Unmarshal(hintBytes, &hint))
+		return &hint, nil
+	}
+
+	cases := []struct {
+		name  string
+		hints []string
+		txs   []*types.MevShareTxHint
+		logs  []*types.MevShareLogHint
+	}{
+		{"hash", []string{types.HintHash}, nil, nil},
+		{"tx hash", []string{types.HintTxHash}, []*types.MevShareTxHint{{Hash: &callHash}, {Hash: &createHash}}, nil},
+		{"contract address", []string{types.HintContractAddress}, []*types.MevShareTxHint{{To: &to}, {}}, nil},
+		{"function selector", []string{types.HintFunctionSelector}, []*types.MevShareTxHint{{FunctionSelector: &selector}, {}}, nil},
+		{"calldata", []string{types.HintCalldata}, []*types.MevShareTxHint{{CallData: &callData}, {CallData: &createData}}, nil},
+		{"logs", []string{types.HintLogs}, nil, logs},
+		{"default", []string{types.HintDefault}, []*types.MevShareTxHint{{To: &to, FunctionSelector: &selector}, {}}, logs},
+		{"no preferences", nil, []*types.MevShareTxHint{{To: &to, FunctionSelector: &selector}, {}}, logs},
+		{"combined", []string{types.HintTxHash, types.HintCalldata}, []*types.MevShareTxHint{{Hash: &callHash, CallData: &callData}, {Hash: &createHash, CallData: &createData}}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hint, err := extract(c.hints)
+			require.NoError(t, err)
+
+			// The bundle hash is always revealed
+			require.Equal(t, crypto.Keccak256Hash(callHash.Bytes(), createHash.Bytes()), hint.Hash)
+			require.Equal(t, c.txs, hint.Txs)
+			require.Equal(t, c.logs, hint.Logs)
+		})
+	}
+
+	_, err = extract([]string{"mempool"})
+	require.ErrorContains(t, err, `unknown hint "mempool"`)
+
+	// Logs are only revealed for bundles that simulate successfully, the other
+	// hints are still revealed for the others
+	backend.result = &types.SimulatedBundle{Error: "transaction 0x01 reverted"}
+	hint, err := extract([]string{types.HintLogs})
+	require.NoError(t, err)
+	require.Nil(t, hint.Logs)
+
+	hint, err = extract(nil)
+	require.NoError(t, err)
+	require.Equal(t, []*types.MevShareTxHint{{To: &to, FunctionSelector: &selector}, {}}, hint.Txs)
+	require.Nil(t, hint.Logs)
+
+	// The transactions of the bundles referenced by hash are not known to the precompiles
+	referencing, err := json.Marshal(&types.SBundle{
+		Version: types.SBundleVersion,
+		Body:    []types.SBundleBody{{Hash: &callHash}, {Tx: createTx}},
+	})
+	require.NoError(t, err)
+	_, err = (&extractHint{}).runImpl(suaveContext, referencing)
+	require.ErrorIs(t, err, errBundleReferences)
+	_, err = (&simulateEthBundle{}).runImpl(suaveContext, types.BuildBlockArgs{}, referencing)
+	require.ErrorIs(t, err, errBundleReferences)
+}
+
+func TestSuave_ExtractHintEncoding(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	to := common.Address{0x1}
+	tx := types.MustSignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{
+		To: &to, Gas: 100000, GasPrice: big.NewInt(1), Data: []byte{0xa, 0xb, 0xc, 0xd, 0xe},
+	})
+	bundle, err := json.Marshal(&types.SBundle{
+		Txs:     types.Transactions{tx},
+		Privacy: &types.BundlePrivacy{Hints: []string{types.HintContractAddress, types.HintFunctionSelector}},
+	})
+	require.NoError(t, err)
+
+	hint, err := (&extractHint{}).runImpl(&SuaveContext{}, bundle)
+	require.NoError(t, err)
+
+	// Encoded as the events of the MEV-Share event stream
+	expected := `{
+		"hash": "` + crypto.Keccak256Hash(tx.Hash().Bytes()).Hex() + `",
+		"logs": null,
+		"txs": [{"to": "0x0100000000000000000000000000000000000000", "functionSelector": "0x0a0b0c0d"}]
+	}`
+	require.JSONEq(t, expected, string(hint))
+}
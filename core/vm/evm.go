@@ -251,6 +251,8 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 
 	if isPrecompile {
 		ret, gas, err = RunPrecompiledContract(p, input, gas)
+	} else if evm.isExecutionNodeRegistry(addr) {
+		ret, gas, err = evm.runExecutionNodeRegistry(caller.Address(), input, gas, value, evm.interpreter.readOnly)
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
@@ -411,6 +413,8 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
 		ret, gas, err = RunPrecompiledContract(p, input, gas)
+	} else if evm.isExecutionNodeRegistry(addr) {
+		ret, gas, err = evm.runExecutionNodeRegistry(caller.Address(), input, gas, nil, true)
 	} else {
 		// At this point, we use a copy of address. If we don't, the go compiler will
 		// leak the 'contract' to the outer scope, and make allocation for 'contract'
@@ -8,6 +8,7 @@ import (
 	"golang.org/x/exp/slices"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/suave/artifacts"
@@ -23,6 +24,8 @@ type ConfidentialStore interface {
 	Retrieve(bid types.BidId, caller common.Address, key string) ([]byte, error)
 	FetchBidById(suave.BidId) (suave.Bid, error)
 	FetchBidsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.Bid
+	FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error)
+	SetKeyAccessRule(bidId suave.BidId, caller common.Address, rule suave.KeyAccessRule) (suave.Bid, error)
 }
 
 type SuaveContext struct {
@@ -31,6 +34,9 @@ type SuaveContext struct {
 	ConfidentialComputeRequestTx *types.Transaction
 	ConfidentialInputs           []byte
 	CallerStack                  []*common.Address
+
+	// gasMeter charges for the work done by the precompile call, set by the precompile wrapper
+	gasMeter *suaveGasMeter
 }
 
 type SuaveExecutionBackend struct {
@@ -38,6 +44,8 @@ type SuaveExecutionBackend struct {
 	EthBlockSigningKey     *bls.SecretKey
 	ConfidentialStore      ConfidentialStore
 	ConfidentialEthBackend suave.ConfidentialEthBackend
+	EgressPolicy           *EgressPolicy // Policy of the HTTP requests made by the precompiles, the default policy if nil
+	NoEgress               bool          // Whether the precompiles are denied any HTTP request, set when simulating
 }
 
 func NewRuntimeSuaveContext(evm *EVM, caller common.Address) *SuaveContext {
@@ -65,7 +73,28 @@ func NewSuavePrecompiledContractWrapper(addr common.Address, suaveContext *Suave
 }
 
 func (p *SuavePrecompiledContractWrapper) RequiredGas(input []byte) uint64 {
-	return p.contract.RequiredGas(input)
+	gas, overflow := math.SafeAdd(p.contract.RequiredGas(input), suaveInputGas(input))
+	if overflow {
+		return math.MaxUint64
+	}
+	return gas
+}
+
+// RunMetered runs the precompile and charges the gas for the work it did, such
+// as fetching bids, building blocks and waiting on external calls, from the gas
+// left after RequiredGas. Blocks and external calls are capped to what the gas
+// left pays for.
+func (p *SuavePrecompiledContractWrapper) RunMetered(input []byte, suppliedGas uint64) ([]byte, uint64, error) {
+	// The context is shared by the precompile calls of a frame, the meter only lasts for this one
+	meter := newSuaveGasMeter(suppliedGas)
+	p.suaveContext.gasMeter = meter
+	defer func() { p.suaveContext.gasMeter = nil }()
+
+	ret, err := p.Run(input)
+	if meter.exceeded {
+		return nil, 0, ErrOutOfGas
+	}
+	return ret, suppliedGas - meter.used, err
 }
 
 var (
@@ -111,6 +140,12 @@ func (p *SuavePrecompiledContractWrapper) Run(input []byte) ([]byte, error) {
 	case fetchBidsAddress:
 		ret, err = stub.fetchBids(input)
 
+	case queryBidsAddress:
+		ret, err = stub.queryBids(input)
+
+	case setKeyAccessRuleAddress:
+		ret, err = stub.setKeyAccessRule(input)
+
 	case extractHintAddress:
 		ret, err = stub.extractHint(input)
 
@@ -129,6 +164,12 @@ func (p *SuavePrecompiledContractWrapper) Run(input []byte) ([]byte, error) {
 	case submitBundleJsonRPCAddress:
 		ret, err = stub.submitBundleJsonRPC(input)
 
+	case doHTTPRequestAddress:
+		ret, err = stub.doHTTPRequest(input)
+
+	case simulateEthBundleAddress:
+		ret, err = stub.simulateEthBundle(input)
+
 	case submitEthBlockBidToRelayAddress:
 		ret, err = stub.submitEthBlockBidToRelay(input)
 
@@ -165,8 +206,8 @@ func checkIsPrecompileCallAllowed(suaveContext *SuaveContext, precompile common.
 	// Alternative is to simply allow if any of the callers is allowed
 	isPrecompileAllowed := slices.Contains(bid.AllowedPeekers, precompile)
 
-	// Special case for confStore as those are implicitly allowed
-	if !isPrecompileAllowed && precompile != confStoreStoreAddress && precompile != confStoreRetrieveAddress {
+	// Special case for confStore and key rules as those are implicitly allowed
+	if !isPrecompileAllowed && precompile != confStoreStoreAddress && precompile != confStoreRetrieveAddress && precompile != setKeyAccessRuleAddress {
 		return common.Address{}, fmt.Errorf("precompile %s (%x) not allowed on %x", artifacts.PrecompileAddressToName(precompile), precompile, bid.Id)
 	}
 
@@ -0,0 +1,178 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"golang.org/x/time/rate"
+)
+
+var (
+	errEgressPrivateIP   = errors.New("egress policy: private address not allowed")
+	errEgressRateLimited = errors.New("egress policy: rate limit exceeded")
+	errEgressDisabled    = errors.New("egress policy: external requests disabled")
+
+	egressMaxRedirects   = 5
+	egressLimitersCached = 1024 // Contracts whose rate limiters are kept
+	defaultEgressPolicy  = NewEgressPolicy(suave.HTTPConfig{})
+)
+
+// EgressPolicy is the policy every HTTP request made by the precompiles on
+// behalf of a contract goes through. Requests are only sent to allowed hosts,
+// never to private addresses unless configured, and each contract is rate
+// limited. Private addresses are checked when connecting, after resolving the
+// host, so that a host resolving to an internal address is rejected too.
+type EgressPolicy struct {
+	config    suave.HTTPConfig
+	transport *http.Transport
+
+	lock     sync.Mutex
+	limiters lru.BasicLRU[common.Address, *rate.Limiter]
+}
+
+func NewEgressPolicy(config suave.HTTPConfig) *EgressPolicy {
+	p := &EgressPolicy{
+		config:   config,
+		limiters: lru.NewBasicLRU[common.Address, *rate.Limiter](egressLimitersCached),
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.checkDialAddress,
+	}
+	p.transport = http.DefaultTransport.(*http.Transport).Clone()
+	p.transport.Proxy = nil // The proxy would connect on our behalf, bypassing the address check
+	p.transport.DialContext = dialer.DialContext
+
+	return p
+}
+
+// Config returns the configuration of the policy.
+func (p *EgressPolicy) Config() suave.HTTPConfig {
+	if p == nil {
+		return defaultEgressPolicy.config
+	}
+	return p.config
+}
+
+// checkURL returns an error if requests to the url are not allowed.
+// An empty allow list allows any host that is not denied.
+func (p *EgressPolicy) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("egress policy: unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("egress policy: missing host")
+	}
+	if p.config.IsDomainDenied(host) {
+		return fmt.Errorf("egress policy: domain %s denied", host)
+	}
+	if len(p.config.AllowedDomains) > 0 && !p.config.IsDomainAllowed(host) {
+		return fmt.Errorf("egress policy: domain %s not allowed", host)
+	}
+	return nil
+}
+
+func (p *EgressPolicy) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if p.config.AllowPrivateIPs {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateIP(ip) {
+		return fmt.Errorf("%w: %s", errEgressPrivateIP, host)
+	}
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// allow takes a token from the rate limiter of the contract.
+func (p *EgressPolicy) allow(caller common.Address) bool {
+	if p.config.RequestsPerSecond <= 0 {
+		return true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	limiter, ok := p.limiters.Get(caller)
+	if !ok {
+		burst := p.config.RequestBurst
+		if burst < 1 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(p.config.RequestsPerSecond), burst)
+		p.limiters.Add(caller, limiter)
+	}
+	return limiter.Allow()
+}
+
+// Do sends the request on behalf of the contract if the policy allows it.
+// Redirects are subject to the same policy.
+func (p *EgressPolicy) Do(caller common.Address, req *http.Request) (*http.Response, error) {
+	if p == nil {
+		p = defaultEgressPolicy
+	}
+
+	if err := p.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	if !p.allow(caller) {
+		return nil, fmt.Errorf("%w for %x", errEgressRateLimited, caller)
+	}
+
+	client := &http.Client{
+		Transport: p.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= egressMaxRedirects {
+				return errors.New("egress policy: too many redirects")
+			}
+			return p.checkURL(req.URL)
+		},
+	}
+	return client.Do(req)
+}
+
+// egressCaller returns the contract the precompile is called by, the caller
+// stack ends with the precompile itself.
+func egressCaller(suaveContext *SuaveContext) common.Address {
+	for i := len(suaveContext.CallerStack) - 1; i >= 0; i-- {
+		caller := suaveContext.CallerStack[i]
+		if caller == nil {
+			continue
+		}
+		if _, isPrecompile := PrecompiledContractsSuave[*caller]; !isPrecompile {
+			return *caller
+		}
+	}
+	return common.Address{}
+}
+
+// doEgressRequest sends the request made by a precompile through the egress
+// policy of the backend, unless the backend denies any request.
+func doEgressRequest(suaveContext *SuaveContext, req *http.Request) (*http.Response, error) {
+	if suaveContext.Backend.NoEgress {
+		return nil, errEgressDisabled
+	}
+	return suaveContext.Backend.EgressPolicy.Do(egressCaller(suaveContext), req)
+}
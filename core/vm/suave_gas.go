@@ -0,0 +1,165 @@
+package vm
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Gas schedule of the work done by the SUAVE precompiles on top of their
+// constant cost, charged through SuavePrecompiledContractWrapper against the gas
+// left after the constant cost. Work whose cost is only known once it is done,
+// blocks and external calls, is capped to what the gas left pays for and its
+// worst case is reserved upfront. The part of it the work did not use is
+// refunded once it is done.
+const (
+	SuaveInputByteGas       uint64 = 3    // Per byte of precompile input
+	SuaveBidFetchGas        uint64 = 200  // Per bid fetched from the confidential store
+	SuaveBundleGasDivisor   uint64 = 100  // Gas used by simulated and built blocks per gas charged
+	SuaveExternalCallMsGas  uint64 = 1000 // Per millisecond spent waiting on an external call
+	SuaveExternalCallMinGas uint64 = 1000 // Minimum charged for an external call
+)
+
+// suaveGasMeter accumulates the gas for the work done during a single
+// precompile call. A nil meter does not charge anything.
+type suaveGasMeter struct {
+	limit    uint64
+	used     uint64
+	exceeded bool
+}
+
+func newSuaveGasMeter(limit uint64) *suaveGasMeter {
+	return &suaveGasMeter{limit: limit}
+}
+
+// charge adds the gas to the used gas and fails once more than the limit is used.
+func (m *suaveGasMeter) charge(gas uint64) error {
+	if m == nil {
+		return nil
+	}
+
+	used, overflow := math.SafeAdd(m.used, gas)
+	if overflow || used > m.limit {
+		m.used = m.limit
+		m.exceeded = true
+		return ErrOutOfGas
+	}
+	m.used = used
+	return nil
+}
+
+// chargeBids charges for the number of bids fetched from the confidential store.
+func (m *suaveGasMeter) chargeBids(count int) error {
+	gas, overflow := math.SafeMul(uint64(count), SuaveBidFetchGas)
+	if overflow {
+		gas = math.MaxUint64
+	}
+	return m.charge(gas)
+}
+
+// remaining returns the gas left to charge.
+func (m *suaveGasMeter) remaining() uint64 {
+	if m == nil {
+		return math.MaxUint64
+	}
+	return m.limit - m.used
+}
+
+// settle charges the gas used by work the gas was reserved for upfront, and
+// refunds the part of the reserved gas it did not use.
+func (m *suaveGasMeter) settle(reserved uint64, used uint64) error {
+	if m == nil {
+		return nil
+	}
+	if used > reserved {
+		return m.charge(used - reserved)
+	}
+	m.used -= reserved - used
+	return nil
+}
+
+// bundleGasAllowance returns the most gas a simulated or built block may use
+// with the gas left.
+func (m *suaveGasMeter) bundleGasAllowance() uint64 {
+	gas, overflow := math.SafeMul(m.remaining(), SuaveBundleGasDivisor)
+	if overflow {
+		return math.MaxUint64
+	}
+	return gas
+}
+
+// reserveBundleGas charges upfront for a simulated or built block using up to
+// the given gas, and returns the gas reserved.
+func (m *suaveGasMeter) reserveBundleGas(gasLimit uint64) (uint64, error) {
+	if m == nil {
+		return 0, nil
+	}
+	reserved := gasLimit / SuaveBundleGasDivisor
+	return reserved, m.charge(reserved)
+}
+
+// settleBundleGas charges for the gas used by a simulated or built block
+// against the gas reserved for it.
+func (m *suaveGasMeter) settleBundleGas(reserved uint64, gasUsed uint64) error {
+	return m.settle(reserved, gasUsed/SuaveBundleGasDivisor)
+}
+
+// txsGasLimit returns the gas limit of the transactions, the most gas applying
+// them may use.
+func txsGasLimit(txs types.Transactions) uint64 {
+	var gas uint64
+	for _, tx := range txs {
+		var overflow bool
+		if gas, overflow = math.SafeAdd(gas, tx.Gas()); overflow {
+			return math.MaxUint64
+		}
+	}
+	return gas
+}
+
+// reserveExternalCall charges upfront for waiting on an external call for up to
+// the timeout, capped to the time the gas left pays for. It returns the capped
+// timeout the call must be made with and the gas reserved.
+func (m *suaveGasMeter) reserveExternalCall(timeout time.Duration) (time.Duration, uint64, error) {
+	if m == nil {
+		return timeout, 0, nil
+	}
+	if affordable := time.Duration(m.remaining()/SuaveExternalCallMsGas) * time.Millisecond; affordable < timeout {
+		timeout = affordable
+	}
+	reserved := externalCallGas(timeout)
+	return timeout, reserved, m.charge(reserved)
+}
+
+// settleExternalCall charges for the time spent waiting on an external call
+// started at the given time against the gas reserved for it. The call is cut at
+// the timeout it was reserved for, so it is never charged more.
+func (m *suaveGasMeter) settleExternalCall(start time.Time, reserved uint64) error {
+	gas := externalCallGas(time.Since(start))
+	if gas > reserved {
+		gas = reserved
+	}
+	return m.settle(reserved, gas)
+}
+
+// externalCallGas is the cost of waiting on an external call for the duration.
+func externalCallGas(elapsed time.Duration) uint64 {
+	gas, overflow := math.SafeMul(uint64(elapsed.Milliseconds()), SuaveExternalCallMsGas)
+	if overflow {
+		gas = math.MaxUint64
+	}
+	if gas < SuaveExternalCallMinGas {
+		gas = SuaveExternalCallMinGas
+	}
+	return gas
+}
+
+// suaveInputGas is the cost of the input of a precompile call, charged upfront.
+func suaveInputGas(input []byte) uint64 {
+	gas, overflow := math.SafeMul(uint64(len(input)), SuaveInputByteGas)
+	if overflow {
+		return math.MaxUint64
+	}
+	return gas
+}
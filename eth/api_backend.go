@@ -58,6 +58,8 @@ type EthAPIBackend struct {
 	suaveEthBlockSigningKey  *bls.SecretKey
 	suaveEngine              *cstore.ConfidentialStoreEngine
 	suaveEthBackend          suave.ConfidentialEthBackend
+	suaveEgressPolicy        *vm.EgressPolicy
+	suaveScheduler           *ethapi.ConfidentialScheduler
 }
 
 // For testing purposes
@@ -295,6 +297,8 @@ func (b *EthAPIBackend) GetMEVM(ctx context.Context, msg *core.Message, state *s
 		EthBlockSigningKey:     suaveCtx.Backend.EthBlockSigningKey,
 		ConfidentialStore:      storeTransaction,
 		ConfidentialEthBackend: b.suaveEthBackend,
+		EgressPolicy:           suaveCtx.Backend.EgressPolicy,
+		NoEgress:               suaveCtx.Backend.NoEgress,
 	}
 	return vm.NewConfidentialEVM(suaveCtxCopy, context, txContext, state, b.eth.blockchain.Config(), *vmConfig), storeTransaction.Finalize, state.Error
 }
@@ -327,6 +331,14 @@ func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthAPIBackend) ReserveConfidentialRequest(request *types.Transaction) error {
+	return b.eth.txPool.ReserveConfidentialRequest(request)
+}
+
+func (b *EthAPIBackend) ReleaseConfidentialRequest(request *types.Transaction) {
+	b.eth.txPool.ReleaseConfidentialRequest(request)
+}
+
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending := b.eth.txPool.Pending(false)
 	var txs types.Transactions
@@ -440,6 +452,10 @@ func (b *EthAPIBackend) StartMining() error {
 	return b.eth.StartMining()
 }
 
+func (b *EthAPIBackend) ConfidentialScheduler() *ethapi.ConfidentialScheduler {
+	return b.suaveScheduler
+}
+
 func (b *EthAPIBackend) SuaveContext(requestTx *types.Transaction, ccr *types.ConfidentialComputeRequest) vm.SuaveContext {
 	storeTransaction := b.suaveEngine.NewTransactionalStore(requestTx)
 	return vm.SuaveContext{
@@ -451,18 +467,23 @@ func (b *EthAPIBackend) SuaveContext(requestTx *types.Transaction, ccr *types.Co
 			EthBlockSigningKey:     b.suaveEthBlockSigningKey,
 			ConfidentialStore:      storeTransaction,
 			ConfidentialEthBackend: b.suaveEthBackend,
+			EgressPolicy:           b.suaveEgressPolicy,
 		},
 	}
 }
 
-func (b *EthAPIBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+func (b *EthAPIBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	return b.eth.Miner().BuildBlockFromTxs(ctx, buildArgs, txs)
 }
 
-func (b *EthAPIBackend) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error) {
+func (b *EthAPIBackend) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
 	return b.eth.Miner().BuildBlockFromBundles(ctx, buildArgs, bundles)
 }
 
+func (b *EthAPIBackend) SimulateBundle(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	return b.eth.Miner().SimulateBundle(ctx, buildArgs, bundle)
+}
+
 func (b *EthAPIBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (*state.StateDB, tracers.StateReleaseFunc, error) {
 	return b.eth.StateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
 }
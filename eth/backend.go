@@ -80,6 +80,9 @@ type Ethereum struct {
 	snapDialCandidates enode.Iterator
 	merger             *consensus.Merger
 
+	// Confidential store transport gossiped over devp2p, if enabled
+	confidentialStoreP2PTransport *cstore.DevP2PTransport
+
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
 
@@ -249,9 +252,28 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		confidentialStoreBackend = cstore.NewLocalConfidentialStore()
 	}
 
+	if config.Suave.EncryptionKeyFile != "" || len(config.Suave.EncryptionKey) != 0 {
+		key, previousKeys, err := cstore.LoadEncryptionKeys(config.Suave)
+		if err != nil {
+			return nil, err
+		}
+		encryptedStoreBackend, err := cstore.NewEncryptedStoreBackend(confidentialStoreBackend, key, previousKeys...)
+		if err != nil {
+			return nil, err
+		}
+		// Migrate the values written before encryption was enabled or the key rotated
+		encryptedStoreBackend.ReencryptInBackground()
+		confidentialStoreBackend = encryptedStoreBackend
+	}
+
+	suaveDaSigner := &cstore.AccountManagerDASigner{Manager: eth.AccountManager()}
+
 	var confidentialStoreTransport cstore.StoreTransportTopic
 	if config.Suave.RedisStorePubsubUri != "" {
 		confidentialStoreTransport = cstore.NewRedisPubSubTransport(config.Suave.RedisStorePubsubUri)
+	} else if config.Suave.P2PStoreTransport {
+		eth.confidentialStoreP2PTransport = cstore.NewDevP2PTransport(suaveDaSigner)
+		confidentialStoreTransport = eth.confidentialStoreP2PTransport
 	} else {
 		confidentialStoreTransport = cstore.MockTransport{}
 	}
@@ -283,11 +305,30 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		return nil, err
 	}
 
-	suaveDaSigner := &cstore.AccountManagerDASigner{Manager: eth.AccountManager()}
-
 	confidentialStoreEngine := cstore.NewConfidentialStoreEngine(confidentialStoreBackend, confidentialStoreTransport, suaveDaSigner, types.LatestSigner(chainConfig))
+	if config.Suave.ConfidentialStoreRetention != 0 {
+		// Decryption conditions are blocks of the target chain, not of this one
+		confidentialStoreEngine.SetRetentionPolicy(cstore.RetentionPolicy{
+			GracePeriod:  config.Suave.ConfidentialStoreRetention,
+			CurrentBlock: suave_backends.NewTargetBlockNumber(suaveEthBackend, suave_backends.TargetBlockTime).Get,
+		})
+	}
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil, suaveEthBundleSigningKey, suaveEthBlockSigningKey, confidentialStoreEngine, suaveEthBackend}
+	// Keep the messages to publish in the chain database until the transport accepts them
+	confidentialStoreOutbox, err := cstore.NewOutbox(eth.chainDb)
+	if err != nil {
+		return nil, err
+	}
+	confidentialStoreEngine.SetOutbox(confidentialStoreOutbox)
+
+	// Only accept messages from registered execution nodes if the chain deployed the registry
+	if statedb, err := eth.blockchain.State(); err == nil && vm.IsExecutionNodeRegistryDeployed(statedb) {
+		confidentialStoreEngine.SetExecutionNodeRegistry(&executionNodeRegistry{eth.blockchain})
+	}
+
+	confidentialScheduler := ethapi.NewConfidentialScheduler(config.Suave.Execution)
+
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil, suaveEthBundleSigningKey, suaveEthBlockSigningKey, confidentialStoreEngine, suaveEthBackend, vm.NewEgressPolicy(config.Suave.HTTP), confidentialScheduler}
 	if eth.APIBackend.allowUnprotectedTxs {
 		log.Info("Unprotected transactions allowed")
 	}
@@ -316,6 +357,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	stack.RegisterProtocols(eth.Protocols())
 	stack.RegisterLifecycle(eth)
 	stack.RegisterLifecycle(confidentialStoreEngine)
+	stack.RegisterLifecycle(confidentialScheduler)
 
 	// Successful startup; push a marker and check previous unclean shutdowns.
 	eth.shutdownTracker.MarkStartup()
@@ -349,6 +391,9 @@ func (s *Ethereum) APIs() []rpc.API {
 	apis = append(apis, rpc.API{
 		Namespace: "suavex",
 		Service:   backends.NewEthBackendServer(s.APIBackend),
+	}, rpc.API{
+		Namespace: "suavex",
+		Service:   cstore.NewOutboxAPI(s.APIBackend.SuaveEngine()),
 	})
 
 	// Append any APIs exposed explicitly by the consensus engine
@@ -541,6 +586,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.confidentialStoreP2PTransport != nil {
+		protos = append(protos, s.confidentialStoreP2PTransport.Protocols()...)
+	}
 	return protos
 }
 
@@ -593,3 +641,17 @@ func (s *Ethereum) Stop() error {
 
 	return nil
 }
+
+// executionNodeRegistry looks up the execution node registry in the state of
+// the head of the chain.
+type executionNodeRegistry struct {
+	chain *core.BlockChain
+}
+
+func (r *executionNodeRegistry) ExecutionNodeByDAKey(daKey common.Address) (*suave.ExecutionNode, error) {
+	statedb, err := r.chain.State()
+	if err != nil {
+		return nil, err
+	}
+	return vm.ReadExecutionNodeByDAKey(statedb, daKey), nil
+}
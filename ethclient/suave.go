@@ -0,0 +1,112 @@
+package ethclient
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ConfidentialRequestSimulation is the outcome of simulating a confidential compute request.
+type ConfidentialRequestSimulation struct {
+	Result      hexutil.Bytes            `json:"result"`     // Carried by the SuaveTransaction
+	ReturnData  hexutil.Bytes            `json:"returnData"` // Of the call to the contract
+	Logs        []*types.Log             `json:"logs"`
+	StoreWrites []ConfidentialStoreWrite `json:"storeWrites"`
+	GasUsed     hexutil.Uint64           `json:"gasUsed"`
+	Error       string                   `json:"error,omitempty"`  // Set if the execution failed
+	Revert      hexutil.Bytes            `json:"revert,omitempty"` // Set if the execution reverted
+}
+
+// ConfidentialStoreWrite is a write of a simulated confidential compute request
+// to the confidential store. The value is not reported.
+type ConfidentialStoreWrite struct {
+	BidId  types.BidId    `json:"bidId"`
+	Caller common.Address `json:"caller"`
+	Key    string         `json:"key"`
+}
+
+// ConfidentialRequestStatus is the status of a confidential compute request, one
+// of "unknown", "queued", "executing", "cancelled", "failed", "pending" and "included".
+type ConfidentialRequestStatus struct {
+	Status      string          `json:"status"`
+	ResultHash  *common.Hash    `json:"resultHash,omitempty"` // Of the SuaveTransaction holding the result
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// SendConfidentialRequest sends a signed confidential compute request to the
+// execution node it names. It returns the hash of the SuaveTransaction holding
+// the result of the request, submitted by the execution node.
+func (ec *Client) SendConfidentialRequest(ctx context.Context, tx *types.Transaction) (common.Hash, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	err = ec.c.CallContext(ctx, &hash, "suave_sendConfidentialRequest", hexutil.Encode(data))
+	return hash, err
+}
+
+// SubmitConfidentialRequest sends a signed confidential compute request to the
+// execution node it names, without waiting for its execution. It returns the
+// hash of the request, whose status is available through ConfidentialRequestStatus
+// and SubscribeConfidentialRequestStatus.
+func (ec *Client) SubmitConfidentialRequest(ctx context.Context, tx *types.Transaction) (common.Hash, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	err = ec.c.CallContext(ctx, &hash, "suave_submitConfidentialRequest", hexutil.Encode(data))
+	return hash, err
+}
+
+// CancelConfidentialRequest cancels a confidential compute request queued or
+// executing on the node, given the hash of the request and the personal_sign
+// signature of its sender over the hash. It returns whether there was such a
+// request of the sender.
+func (ec *Client) CancelConfidentialRequest(ctx context.Context, hash common.Hash, signature []byte) (bool, error) {
+	var cancelled bool
+	err := ec.c.CallContext(ctx, &cancelled, "suave_cancelConfidentialRequest", hash, hexutil.Bytes(signature))
+	return cancelled, err
+}
+
+// SimulateConfidentialRequest executes a signed confidential compute request on
+// the execution node it names, without submitting its result nor keeping its
+// confidential store writes.
+func (ec *Client) SimulateConfidentialRequest(ctx context.Context, tx *types.Transaction) (*ConfidentialRequestSimulation, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var simulation ConfidentialRequestSimulation
+	if err := ec.c.CallContext(ctx, &simulation, "suave_simulateConfidentialRequest", hexutil.Encode(data)); err != nil {
+		return nil, err
+	}
+	return &simulation, nil
+}
+
+// ConfidentialRequestStatus returns the status of a confidential compute request
+// sent with SendConfidentialRequest, given the hash of the request.
+func (ec *Client) ConfidentialRequestStatus(ctx context.Context, hash common.Hash) (*ConfidentialRequestStatus, error) {
+	var status ConfidentialRequestStatus
+	if err := ec.c.CallContext(ctx, &status, "suave_getConfidentialRequestStatus", hash); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SubscribeConfidentialRequestStatus subscribes to the status of a confidential
+// compute request sent to the node, given the hash of the request. The status is
+// notified every time it changes, until the request fails, is cancelled or its
+// result is included.
+func (ec *Client) SubscribeConfidentialRequestStatus(ctx context.Context, hash common.Hash, ch chan<- *ConfidentialRequestStatus) (ethereum.Subscription, error) {
+	sub, err := ec.c.Subscribe(ctx, "suave", ch, "confidentialRequestStatus", hash)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
@@ -48,6 +48,7 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/ethereum/go-ethereum/suave/cstore"
 	"github.com/tyler-smith/go-bip39"
 )
 
@@ -1868,28 +1869,6 @@ func (s *TransactionAPI) SendTransaction(ctx context.Context, args TransactionAr
 		return common.Hash{}, err
 	}
 
-	if tx.Type() == types.ConfidentialComputeRequestTxType {
-		state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
-		if state == nil || err != nil {
-			return common.Hash{}, err
-		}
-
-		msg, err := core.TransactionToMessage(tx, s.signer, header.BaseFee)
-		if err != nil {
-			return common.Hash{}, err
-		}
-
-		ntx, _, finalize, err := runMEVM(ctx, s.b, state, header, signed, msg, false)
-		if err != nil {
-			return common.Hash{}, err
-		}
-
-		if err = finalize(); err != nil {
-			log.Error("could not finalize confidential store", "err", err)
-			return tx.Hash(), err
-		}
-		signed = ntx
-	}
 	return SubmitTransaction(ctx, s.b, signed)
 }
 
@@ -1919,32 +1898,67 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	}
 
 	if _, ok := types.CastTxInner[*types.ConfidentialComputeRequest](tx); ok {
-		state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
-		if state == nil || err != nil {
-			return common.Hash{}, err
-		}
-
-		msg, err := core.TransactionToMessage(tx, s.signer, header.BaseFee)
-		if err != nil {
-			return common.Hash{}, err
-		}
-
-		ntx, _, finalize, err := runMEVM(ctx, s.b, state, header, tx, msg, false)
+		// Executed by the scheduler, the request is cancelled if the call is
+		job, err := scheduleConfidentialRequest(s.b, s.signer, tx)
 		if err != nil {
 			return tx.Hash(), err
 		}
-		if err = finalize(); err != nil {
-			log.Error("could not finalize confidential store", "err", err)
+		hash, err := job.Wait(ctx)
+		if err != nil {
 			return tx.Hash(), err
 		}
-		tx = ntx
+		return hash, nil
 	}
 
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
-// TODO: should be its own api
+// runMEVM executes the confidential compute request and returns the
+// SuaveTransaction holding its result, signed by the execution node, along with
+// the function finalizing the confidential store writes of the execution.
 func runMEVM(ctx context.Context, b Backend, state *state.StateDB, header *types.Header, tx *types.Transaction, msg *core.Message, isCall bool) (*types.Transaction, *core.ExecutionResult, func() error, error) {
+	execution, err := executeConfidentialRequest(ctx, b, state, header, tx, msg, isCall, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if execution.result.Failed() {
+		return nil, nil, nil, fmt.Errorf("%w: %s", execution.result.Err, hexutil.Encode(execution.result.Revert()))
+	}
+
+	signed, err := execution.sign(tx.ChainId())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// will copy the inner tx again!
+	return signed, execution.result, execution.finalize, nil
+}
+
+// confidentialExecution is the outcome of executing a confidential compute request.
+type confidentialExecution struct {
+	request       *types.ConfidentialComputeRequest
+	result        *core.ExecutionResult
+	computeResult []byte // Carried by the SuaveTransaction
+	logs          []*types.Log
+	writes        []cstore.StoreWrite // Pending until finalized
+	finalize      func() error
+
+	wallet  accounts.Wallet // Of the execution node
+	account accounts.Account
+}
+
+// sign returns the SuaveTransaction holding the result of the execution,
+// signed by the execution node.
+func (e *confidentialExecution) sign(chainID *big.Int) (*types.Transaction, error) {
+	suaveResultTxData := &types.SuaveTransaction{ExecutionNode: e.request.ExecutionNode, ConfidentialComputeRequest: e.request.ConfidentialComputeRecord, ConfidentialComputeResult: e.computeResult}
+	return e.wallet.SignTx(e.account, types.NewTx(suaveResultTxData), chainID)
+}
+
+// executeConfidentialRequest executes the confidential compute request on top
+// of the state. Executions that fail are returned with their result, only the
+// errors preventing the execution are returned as such. The precompiles are
+// denied any external request if noEgress is set.
+func executeConfidentialRequest(ctx context.Context, b Backend, state *state.StateDB, header *types.Header, tx *types.Transaction, msg *core.Message, isCall bool, noEgress bool) (*confidentialExecution, error) {
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
@@ -1952,18 +1966,19 @@ func runMEVM(ctx context.Context, b Backend, state *state.StateDB, header *types
 	// TODO: copy the inner, but only once
 	confidentialRequest, ok := types.CastTxInner[*types.ConfidentialComputeRequest](tx)
 	if !ok {
-		return nil, nil, nil, errors.New("invalid transaction passed")
+		return nil, errors.New("invalid transaction passed")
 	}
 
 	// Look up the wallet containing the requested execution node
 	account := accounts.Account{Address: confidentialRequest.ExecutionNode}
 	wallet, err := b.AccountManager().Find(account)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
 	blockCtx := core.NewEVMBlockContext(header, NewChainContext(ctx, b), nil)
 	suaveCtx := b.SuaveContext(tx, confidentialRequest)
+	suaveCtx.Backend.NoEgress = noEgress
 	evm, storeFinalize, vmError := b.GetMEVM(ctx, msg, state, header, &vm.Config{IsConfidential: true, NoBaseFee: isCall}, &blockCtx, &suaveCtx)
 
 	// Wait for the context to be done and cancel the evm. Even if the
@@ -1977,43 +1992,41 @@ func runMEVM(ctx context.Context, b Backend, state *state.StateDB, header *types
 	gp := new(core.GasPool).AddGas(header.GasLimit)
 
 	msg.SkipAccountChecks = true // validate elsewhere!
+	state.SetTxContext(tx.Hash(), 0)
 	result, err := core.ApplyMessage(evm, msg, gp)
 	// If the timer caused an abort, return an appropriate error message
 	if evm.Cancelled() {
-		return nil, nil, nil, fmt.Errorf("execution aborted")
+		return nil, fmt.Errorf("execution aborted")
 	}
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
+		return nil, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
 	}
 	if err := vmError(); err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
-	if result.Failed() {
-		return nil, nil, nil, fmt.Errorf("%w: %s", result.Err, hexutil.Encode(result.Revert()))
+	execution := &confidentialExecution{
+		request:  confidentialRequest,
+		result:   result,
+		logs:     state.GetLogs(tx.Hash(), header.Number.Uint64(), common.Hash{}),
+		finalize: storeFinalize,
+		wallet:   wallet,
+		account:  account,
+	}
+	if store, ok := evm.SuaveContext.Backend.ConfidentialStore.(interface{ PendingWrites() []cstore.StoreWrite }); ok {
+		execution.writes = store.PendingWrites()
 	}
 
 	// Check for call in return
-	var computeResult []byte
-
 	args := abi.Arguments{abi.Argument{Type: abi.Type{T: abi.BytesTy}}}
 	unpacked, err := args.Unpack(result.ReturnData)
 	if err == nil && len(unpacked[0].([]byte))%32 == 4 {
 		// This is supposed to be the case for all confidential compute!
-		computeResult = unpacked[0].([]byte)
+		execution.computeResult = unpacked[0].([]byte)
 	} else {
-		computeResult = result.ReturnData // Or should it be nil maybe in this case?
-	}
-
-	suaveResultTxData := &types.SuaveTransaction{ExecutionNode: confidentialRequest.ExecutionNode, ConfidentialComputeRequest: confidentialRequest.ConfidentialComputeRecord, ConfidentialComputeResult: computeResult}
-
-	signed, err := wallet.SignTx(account, types.NewTx(suaveResultTxData), tx.ChainId())
-	if err != nil {
-		return nil, nil, nil, err
+		execution.computeResult = result.ReturnData // Or should it be nil maybe in this case?
 	}
-
-	// will copy the inner tx again!
-	return signed, result, storeFinalize, nil
+	return execution, nil
 }
 
 // Sign calculates an ECDSA signature for:
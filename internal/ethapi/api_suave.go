@@ -0,0 +1,348 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Statuses of the confidential compute requests sent through the suave namespace.
+const (
+	ConfidentialRequestUnknown   = "unknown"   // Not sent to this node, or forgotten
+	ConfidentialRequestQueued    = "queued"    // Waiting for a worker of the scheduler
+	ConfidentialRequestExecuting = "executing" // Being executed
+	ConfidentialRequestCancelled = "cancelled" // Cancelled before its result was submitted
+	ConfidentialRequestFailed    = "failed"    // The execution failed or its result could not be submitted
+	ConfidentialRequestPending   = "pending"   // The SuaveTransaction holding the result is in the txpool
+	ConfidentialRequestIncluded  = "included"  // The SuaveTransaction holding the result is in a block
+)
+
+var errConfidentialUnsupported = errors.New("confidential compute requests not supported")
+
+// SuaveAPI provides an API to execute confidential compute requests.
+type SuaveAPI struct {
+	b      Backend
+	signer types.Signer
+}
+
+// NewSuaveAPI creates a new SUAVE API.
+func NewSuaveAPI(b Backend) *SuaveAPI {
+	return &SuaveAPI{
+		b:      b,
+		signer: types.LatestSigner(b.ChainConfig()),
+	}
+}
+
+// ConfidentialRequestSimulation is the outcome of simulating a confidential compute request.
+type ConfidentialRequestSimulation struct {
+	Result      hexutil.Bytes            `json:"result"`     // Carried by the SuaveTransaction
+	ReturnData  hexutil.Bytes            `json:"returnData"` // Of the call to the contract
+	Logs        []*types.Log             `json:"logs"`
+	StoreWrites []ConfidentialStoreWrite `json:"storeWrites"`
+	GasUsed     hexutil.Uint64           `json:"gasUsed"`
+	Error       string                   `json:"error,omitempty"`
+	Revert      hexutil.Bytes            `json:"revert,omitempty"`
+}
+
+// ConfidentialStoreWrite is a write to the confidential store. The value is
+// confidential and not reported.
+type ConfidentialStoreWrite struct {
+	BidId  types.BidId    `json:"bidId"`
+	Caller common.Address `json:"caller"`
+	Key    string         `json:"key"`
+}
+
+// ConfidentialRequestStatus is the status of a confidential compute request.
+type ConfidentialRequestStatus struct {
+	Status      string          `json:"status"`
+	ResultHash  *common.Hash    `json:"resultHash,omitempty"` // Of the SuaveTransaction holding the result
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// SendConfidentialRequest executes the signed confidential compute request and
+// submits the SuaveTransaction holding its result, whose hash it returns. The
+// store writes of the execution are finalized. Executions that revert fail
+// with the revert data in the error. The request is cancelled if the call is.
+func (s *SuaveAPI) SendConfidentialRequest(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx, err := decodeConfidentialRequest(input)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	job, err := scheduleConfidentialRequest(s.b, s.signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return job.Wait(ctx)
+}
+
+// SubmitConfidentialRequest schedules the signed confidential compute request
+// like SendConfidentialRequest, but returns the hash of the request without
+// waiting for its execution. Its outcome is available through
+// GetConfidentialRequestStatus and the confidentialRequestStatus subscription.
+func (s *SuaveAPI) SubmitConfidentialRequest(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx, err := decodeConfidentialRequest(input)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	_, err = scheduleConfidentialRequest(s.b, s.signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// CancelConfidentialRequest cancels the queued or executing confidential
+// compute request with the given hash, and returns whether there was one. Only
+// the sender of the request may cancel it: the signature is the personal_sign
+// signature of the sender over the hash of the request.
+func (s *SuaveAPI) CancelConfidentialRequest(ctx context.Context, hash common.Hash, signature hexutil.Bytes) (bool, error) {
+	scheduler := s.b.ConfidentialScheduler()
+	if scheduler == nil {
+		return false, errConfidentialUnsupported
+	}
+	sender, err := recoverCancellationSender(hash, signature)
+	if err != nil {
+		return false, err
+	}
+	return scheduler.Cancel(hash, sender), nil
+}
+
+// recoverCancellationSender returns the account which signed the cancellation
+// of the confidential compute request with the given hash.
+func recoverCancellationSender(hash common.Hash, signature hexutil.Bytes) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	if signature[crypto.RecoveryIDOffset] != 27 && signature[crypto.RecoveryIDOffset] != 28 {
+		return common.Address{}, errors.New("invalid Ethereum signature (V is not 27 or 28)")
+	}
+	sig := common.CopyBytes(signature)
+	sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+
+	pubkey, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// sendConfidentialRequest executes the confidential compute request on top of
+// the latest block, finalizes its store writes and submits the SuaveTransaction
+// holding its result once commit succeeds. Executions that revert fail with the
+// revert data in the error. Both the suave and the eth namespaces send requests
+// through it.
+func sendConfidentialRequest(ctx context.Context, b Backend, signer types.Signer, tx *types.Transaction, commit func() error) (common.Hash, error) {
+	execution, err := executeLatest(ctx, b, signer, tx, false)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if result := execution.result; result.Failed() {
+		if errors.Is(result.Err, vm.ErrExecutionReverted) {
+			return common.Hash{}, newRevertError(result)
+		}
+		return common.Hash{}, result.Err
+	}
+
+	signed, err := execution.sign(tx.ChainId())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Executions given up on by the scheduler must not submit their result
+	if err := commit(); err != nil {
+		return common.Hash{}, err
+	}
+	if err := execution.finalize(); err != nil {
+		log.Error("could not finalize confidential store", "err", err)
+		return common.Hash{}, err
+	}
+	return SubmitTransaction(ctx, b, signed)
+}
+
+// SimulateConfidentialRequest executes the signed confidential compute request
+// without finalizing its store writes nor submitting its result. The external
+// requests of the precompiles are denied, so that simulating has no side effect.
+func (s *SuaveAPI) SimulateConfidentialRequest(ctx context.Context, input hexutil.Bytes) (*ConfidentialRequestSimulation, error) {
+	tx, err := decodeConfidentialRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	execution, err := executeLatest(ctx, s.b, s.signer, tx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation := &ConfidentialRequestSimulation{
+		Result:      execution.computeResult,
+		ReturnData:  execution.result.ReturnData,
+		Logs:        execution.logs,
+		StoreWrites: make([]ConfidentialStoreWrite, 0, len(execution.writes)),
+		GasUsed:     hexutil.Uint64(execution.result.UsedGas),
+	}
+	if simulation.Logs == nil {
+		simulation.Logs = []*types.Log{}
+	}
+	for _, write := range execution.writes {
+		simulation.StoreWrites = append(simulation.StoreWrites, ConfidentialStoreWrite{
+			BidId:  write.Bid.Id,
+			Caller: write.Caller,
+			Key:    write.Key,
+		})
+	}
+	if execution.result.Failed() {
+		simulation.Error = execution.result.Err.Error()
+		simulation.Revert = execution.result.Revert()
+	}
+	return simulation, nil
+}
+
+// GetConfidentialRequestStatus returns the status of a confidential compute
+// request sent through this node, given its hash.
+func (s *SuaveAPI) GetConfidentialRequestStatus(ctx context.Context, hash common.Hash) (*ConfidentialRequestStatus, error) {
+	scheduler := s.b.ConfidentialScheduler()
+	if scheduler == nil {
+		return nil, errConfidentialUnsupported
+	}
+	return s.confidentialRequestStatus(ctx, scheduler, hash)
+}
+
+// ConfidentialRequestStatus notifies the status of a confidential compute
+// request sent through this node, given its hash, every time it changes until
+// the request fails, is cancelled or its result is included.
+func (s *SuaveAPI) ConfidentialRequestStatus(ctx context.Context, hash common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	scheduler := s.b.ConfidentialScheduler()
+	if scheduler == nil {
+		return &rpc.Subscription{}, errConfidentialUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan ConfidentialRequestEvent, 16)
+		eventsSub := scheduler.SubscribeEvents(events)
+		defer eventsSub.Unsubscribe()
+
+		heads := make(chan core.ChainHeadEvent, 16)
+		headsSub := s.b.SubscribeChainHeadEvent(heads)
+		defer headsSub.Unsubscribe()
+
+		var last string
+		for {
+			status, err := s.confidentialRequestStatus(context.Background(), scheduler, hash)
+			if err == nil && status.Status != last {
+				notifier.Notify(rpcSub.ID, status)
+				last = status.Status
+			}
+			switch last {
+			case ConfidentialRequestFailed, ConfidentialRequestCancelled, ConfidentialRequestIncluded:
+				return
+			}
+
+			// Only notify the changes of the status, which are checked for on
+			// every event since they may be received out of order
+			select {
+			case <-events:
+			case <-heads:
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// confidentialRequestStatus returns the status of the request recorded by the
+// scheduler, looking up whether the submitted result has been included since.
+func (s *SuaveAPI) confidentialRequestStatus(ctx context.Context, scheduler *ConfidentialScheduler, hash common.Hash) (*ConfidentialRequestStatus, error) {
+	status, ok := scheduler.Status(hash)
+	if !ok {
+		return &ConfidentialRequestStatus{Status: ConfidentialRequestUnknown}, nil
+	}
+	if status.Status != ConfidentialRequestPending {
+		return status, nil
+	}
+
+	tx, blockHash, blockNumber, _, err := s.b.GetTransaction(ctx, *status.ResultHash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil || blockHash == (common.Hash{}) {
+		return status, nil
+	}
+	number := hexutil.Uint64(blockNumber)
+	return &ConfidentialRequestStatus{Status: ConfidentialRequestIncluded, ResultHash: status.ResultHash, BlockNumber: &number}, nil
+}
+
+// executeLatest executes the confidential compute request on top of the latest block.
+func executeLatest(ctx context.Context, b Backend, signer types.Signer, tx *types.Transaction, simulate bool) (*confidentialExecution, error) {
+	state, header, err := b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	return executeConfidentialRequest(ctx, b, state, header, tx, msg, false, simulate)
+}
+
+// scheduleConfidentialRequest schedules the confidential compute request on the
+// scheduler of the backend, to be sent by sendConfidentialRequest. The nonce of the request
+// is reserved in the txpool until the request is done, so that requests reusing
+// a nonce are rejected before they are executed rather than once their result
+// is submitted.
+func scheduleConfidentialRequest(b Backend, signer types.Signer, tx *types.Transaction) (*ConfidentialJob, error) {
+	scheduler := b.ConfidentialScheduler()
+	if scheduler == nil {
+		return nil, errConfidentialUnsupported
+	}
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.ReserveConfidentialRequest(tx); err != nil {
+		return nil, err
+	}
+	release := func() { b.ReleaseConfidentialRequest(tx) }
+	execute := func(ctx context.Context, commit func() error) (common.Hash, error) {
+		return sendConfidentialRequest(ctx, b, signer, tx, commit)
+	}
+	job, err := scheduler.schedule(tx.Hash(), sender, execute, release)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return job, nil
+}
+
+func decodeConfidentialRequest(input hexutil.Bytes) (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	if tx.Type() != types.ConfidentialComputeRequestTxType {
+		return nil, errors.New("not a confidential compute request")
+	}
+	return tx, nil
+}
@@ -301,6 +301,11 @@ func (b testBackend) GetEVM(ctx context.Context, msg *core.Message, state *state
 func (b testBackend) SuaveContext(requestTx *types.Transaction, ccr *types.ConfidentialComputeRequest) vm.SuaveContext {
 	return vm.SuaveContext{}
 }
+func (b testBackend) ConfidentialScheduler() *ConfidentialScheduler { return nil }
+func (b testBackend) ReserveConfidentialRequest(request *types.Transaction) error {
+	panic("implement me")
+}
+func (b testBackend) ReleaseConfidentialRequest(request *types.Transaction) { panic("implement me") }
 func (b testBackend) GetMEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext, suaveCtx *vm.SuaveContext) (*vm.EVM, func() error, func() error) {
 	return nil, nil, nil
 }
@@ -353,11 +358,11 @@ func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.Match
 	panic("implement me")
 }
 
-func (b testBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+func (b testBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	panic("implement me")
 }
 
-func (b testBackend) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error) {
+func (b testBackend) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
 	panic("implement me")
 }
 
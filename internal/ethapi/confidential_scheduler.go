@@ -0,0 +1,489 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+var (
+	confidentialQueueGauge     = metrics.NewRegisteredGauge("suave/execution/queue", nil)
+	confidentialExecutingGauge = metrics.NewRegisteredGauge("suave/execution/executing", nil)
+	confidentialQueueTimer     = metrics.NewRegisteredTimer("suave/execution/queue/latency", nil)
+	confidentialExecutionTimer = metrics.NewRegisteredTimer("suave/execution/time", nil)
+	confidentialRejectedMeter  = metrics.NewRegisteredMeter("suave/execution/rejected", nil)
+	confidentialCancelledMeter = metrics.NewRegisteredMeter("suave/execution/cancelled", nil)
+	confidentialFailedMeter    = metrics.NewRegisteredMeter("suave/execution/failed", nil)
+	confidentialSucceededMeter = metrics.NewRegisteredMeter("suave/execution/succeeded", nil)
+)
+
+const (
+	confidentialStatusLimit = 4096 // Number of requests whose status is kept
+
+	defaultConfidentialWorkers         = 4
+	defaultConfidentialQueueSize       = 1024
+	defaultConfidentialSenderQueueSize = 64
+	defaultConfidentialTimeout         = 30 * time.Second
+)
+
+var (
+	errConfidentialQueueFull        = errors.New("confidential execution queue is full")
+	errConfidentialSenderQueueFull  = errors.New("too many confidential compute requests queued for sender")
+	errConfidentialRequestKnown     = errors.New("confidential compute request already scheduled")
+	errConfidentialRequestCancelled = errors.New("confidential compute request cancelled")
+	errConfidentialRequestTimeout   = errors.New("confidential compute request execution timed out")
+	errConfidentialSchedulerStopped = errors.New("confidential execution scheduler stopped")
+)
+
+// ConfidentialExecuteFn executes a confidential compute request and returns the
+// hash of the SuaveTransaction holding its result. The execution calls commit
+// before it has any effect, such as finalizing its store writes or submitting
+// its result, and gives up if commit fails: the scheduler has then given up on
+// the execution.
+type ConfidentialExecuteFn func(ctx context.Context, commit func() error) (common.Hash, error)
+
+// ConfidentialRequestEvent is posted when the status of a scheduled confidential
+// compute request changes.
+type ConfidentialRequestEvent struct {
+	Hash   common.Hash
+	Status *ConfidentialRequestStatus
+}
+
+// ConfidentialScheduler executes confidential compute requests with a bounded
+// pool of workers, off the goroutines of the RPC callers. Requests wait for a
+// worker in a bounded queue and are picked round-robin across their senders,
+// so that a sender queueing many requests does not starve the others.
+type ConfidentialScheduler struct {
+	workers         int
+	queueSize       int
+	senderQueueSize int
+	timeout         time.Duration
+
+	lock     sync.Mutex
+	wake     *sync.Cond
+	queues   map[common.Address][]*ConfidentialJob // Queued requests of each sender, oldest first
+	senders  []common.Address                      // Senders with queued requests, in round-robin order
+	queued   int
+	jobs     map[common.Hash]*ConfidentialJob // Queued and executing requests
+	statuses lru.BasicLRU[common.Hash, *ConfidentialRequestStatus]
+	closed   bool
+
+	feed    event.Feed
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// ConfidentialJob is a confidential compute request scheduled for execution.
+type ConfidentialJob struct {
+	hash    common.Hash
+	sender  common.Address
+	execute ConfidentialExecuteFn
+	release func() // Called once the job is done, see finish
+	queued  time.Time
+
+	// committed is taken either by the execution before it has any effect, or
+	// by the scheduler when it gives up on the execution, whichever is first
+	committed atomic.Bool
+	executed  bool // The execution started, and releases the job once it returns
+
+	scheduler *ConfidentialScheduler
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	result    common.Hash
+	err       error
+}
+
+// NewConfidentialScheduler creates a scheduler, whose workers run once started.
+// Zero values of the config are replaced by defaults.
+func NewConfidentialScheduler(config suave.ExecutionConfig) *ConfidentialScheduler {
+	s := &ConfidentialScheduler{
+		workers:         config.Workers,
+		queueSize:       config.QueueSize,
+		senderQueueSize: config.SenderQueueSize,
+		timeout:         config.Timeout,
+		queues:          make(map[common.Address][]*ConfidentialJob),
+		jobs:            make(map[common.Hash]*ConfidentialJob),
+		statuses:        lru.NewBasicLRU[common.Hash, *ConfidentialRequestStatus](confidentialStatusLimit),
+	}
+	if s.workers <= 0 {
+		s.workers = defaultConfidentialWorkers
+	}
+	if s.queueSize <= 0 {
+		s.queueSize = defaultConfidentialQueueSize
+	}
+	if s.senderQueueSize <= 0 {
+		s.senderQueueSize = defaultConfidentialSenderQueueSize
+	}
+	if s.timeout <= 0 {
+		s.timeout = defaultConfidentialTimeout
+	}
+	s.wake = sync.NewCond(&s.lock)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Start starts the workers.
+func (s *ConfidentialScheduler) Start() error {
+	for i := 0; i < s.workers; i++ {
+		s.running.Add(1)
+		go s.loop()
+	}
+	log.Info("Confidential execution scheduler started", "workers", s.workers, "queue", s.queueSize, "senderQueue", s.senderQueueSize, "timeout", s.timeout)
+	return nil
+}
+
+// Stop cancels the executing requests, fails the queued ones and waits for the
+// workers to return.
+func (s *ConfidentialScheduler) Stop() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var queued []*ConfidentialJob
+	for _, queue := range s.queues {
+		queued = append(queued, queue...)
+	}
+	s.queues = make(map[common.Address][]*ConfidentialJob)
+	s.senders = nil
+	s.queued = 0
+	confidentialQueueGauge.Update(0)
+	s.wake.Broadcast()
+	s.lock.Unlock()
+
+	s.cancel()
+	for _, job := range queued {
+		s.finish(job, common.Hash{}, errConfidentialSchedulerStopped)
+	}
+	s.running.Wait()
+	return nil
+}
+
+// Schedule queues the request with the given hash and sender for execution.
+// Requests already queued or executing, and requests beyond the limits of the
+// queue are rejected.
+func (s *ConfidentialScheduler) Schedule(hash common.Hash, sender common.Address, execute ConfidentialExecuteFn) (*ConfidentialJob, error) {
+	return s.schedule(hash, sender, execute, nil)
+}
+
+// schedule queues the request like Schedule, calling release once the request
+// is done unless it is rejected.
+func (s *ConfidentialScheduler) schedule(hash common.Hash, sender common.Address, execute ConfidentialExecuteFn, release func()) (*ConfidentialJob, error) {
+	s.lock.Lock()
+	switch {
+	case s.closed:
+		s.lock.Unlock()
+		return nil, errConfidentialSchedulerStopped
+	case s.jobs[hash] != nil:
+		s.lock.Unlock()
+		return nil, errConfidentialRequestKnown
+	case s.queued >= s.queueSize:
+		s.lock.Unlock()
+		confidentialRejectedMeter.Mark(1)
+		return nil, errConfidentialQueueFull
+	case len(s.queues[sender]) >= s.senderQueueSize:
+		s.lock.Unlock()
+		confidentialRejectedMeter.Mark(1)
+		return nil, errConfidentialSenderQueueFull
+	}
+
+	job := &ConfidentialJob{
+		hash:      hash,
+		sender:    sender,
+		execute:   execute,
+		release:   release,
+		queued:    time.Now(),
+		scheduler: s,
+		done:      make(chan struct{}),
+	}
+	job.ctx, job.cancel = context.WithCancel(s.ctx)
+
+	if len(s.queues[sender]) == 0 {
+		s.senders = append(s.senders, sender)
+	}
+	s.queues[sender] = append(s.queues[sender], job)
+	s.queued++
+	s.jobs[hash] = job
+	confidentialQueueGauge.Update(int64(s.queued))
+
+	// Record the status before a worker may pick the request
+	status := &ConfidentialRequestStatus{Status: ConfidentialRequestQueued}
+	s.statuses.Add(hash, status)
+	s.wake.Signal()
+	s.lock.Unlock()
+
+	s.feed.Send(ConfidentialRequestEvent{Hash: hash, Status: status})
+	return job, nil
+}
+
+// Cancel cancels the queued or executing request of the sender with the given
+// hash and returns whether there was one. Executions are aborted, but the
+// result of a request that completes regardless is kept.
+func (s *ConfidentialScheduler) Cancel(hash common.Hash, sender common.Address) bool {
+	s.lock.Lock()
+	job, ok := s.jobs[hash]
+	if !ok || job.sender != sender {
+		s.lock.Unlock()
+		return false
+	}
+	dequeued := s.dequeue(job)
+	s.lock.Unlock()
+
+	job.cancel()
+	if dequeued {
+		s.finish(job, common.Hash{}, errConfidentialRequestCancelled)
+	}
+	return true
+}
+
+// Status returns the status of the request with the given hash, if it is known.
+func (s *ConfidentialScheduler) Status(hash common.Hash) (*ConfidentialRequestStatus, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.statuses.Get(hash)
+}
+
+// SubscribeEvents subscribes to the status changes of the scheduled requests.
+// Events of concurrent changes may be received out of order, Status returns
+// the latest status.
+func (s *ConfidentialScheduler) SubscribeEvents(ch chan<- ConfidentialRequestEvent) event.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// loop executes the queued requests until the scheduler is stopped.
+func (s *ConfidentialScheduler) loop() {
+	defer s.running.Done()
+
+	for {
+		job := s.next()
+		if job == nil {
+			return
+		}
+		s.run(job)
+	}
+}
+
+// next waits for a queued request and returns it, taking the senders in turns.
+// It returns nil once the scheduler is stopped.
+func (s *ConfidentialScheduler) next() *ConfidentialJob {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for s.queued == 0 && !s.closed {
+		s.wake.Wait()
+	}
+	if s.closed {
+		return nil
+	}
+
+	sender := s.senders[0]
+	s.senders = s.senders[1:]
+
+	queue := s.queues[sender]
+	job := queue[0]
+	if len(queue) == 1 {
+		delete(s.queues, sender)
+	} else {
+		s.queues[sender] = queue[1:]
+		s.senders = append(s.senders, sender)
+	}
+	s.queued--
+	confidentialQueueGauge.Update(int64(s.queued))
+	return job
+}
+
+// dequeue removes the job from the queue of its sender and returns whether it
+// was queued. It must be called with the lock held.
+func (s *ConfidentialScheduler) dequeue(job *ConfidentialJob) bool {
+	queue := s.queues[job.sender]
+	for i, queued := range queue {
+		if queued != job {
+			continue
+		}
+		if len(queue) == 1 {
+			delete(s.queues, job.sender)
+			for j, sender := range s.senders {
+				if sender == job.sender {
+					s.senders = append(s.senders[:j], s.senders[j+1:]...)
+					break
+				}
+			}
+		} else {
+			s.queues[job.sender] = append(queue[:i:i], queue[i+1:]...)
+		}
+		s.queued--
+		confidentialQueueGauge.Update(int64(s.queued))
+		return true
+	}
+	return false
+}
+
+func (s *ConfidentialScheduler) run(job *ConfidentialJob) {
+	confidentialQueueTimer.UpdateSince(job.queued)
+	if job.ctx.Err() != nil {
+		s.finish(job, common.Hash{}, s.cancelled())
+		return
+	}
+
+	s.setStatus(job.hash, &ConfidentialRequestStatus{Status: ConfidentialRequestExecuting})
+	confidentialExecutingGauge.Inc(1)
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(job.ctx, s.timeout)
+	defer cancel()
+
+	// Executions blocked past their deadline, such as on an external call not
+	// watching the context, are given up on unless they committed. Executions
+	// given up on keep the request reserved and the worker busy until they
+	// return, but can no longer commit.
+	commit := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !job.committed.CompareAndSwap(false, true) {
+			return ctx.Err() // Only given up on once done
+		}
+		return nil
+	}
+	type outcome struct {
+		result common.Hash
+		err    error
+	}
+	executed := make(chan outcome, 1)
+	job.executed = true
+	go func() {
+		result, err := job.execute(ctx, commit)
+		if job.release != nil {
+			job.release()
+		}
+		executed <- outcome{result, err}
+	}()
+	var (
+		out     outcome
+		givenUp bool
+	)
+	select {
+	case out = <-executed:
+	case <-ctx.Done():
+		select {
+		case out = <-executed:
+		default:
+			if job.committed.CompareAndSwap(false, true) {
+				out.err = ctx.Err()
+				givenUp = true
+			} else {
+				// Committed before the deadline, its result is being submitted
+				out = <-executed
+			}
+		}
+	}
+	confidentialExecutionTimer.UpdateSince(start)
+
+	if out.err != nil {
+		switch {
+		case job.ctx.Err() != nil:
+			out.err = s.cancelled()
+		case ctx.Err() != nil:
+			out.err = errConfidentialRequestTimeout
+		}
+	}
+	s.finish(job, out.result, out.err)
+
+	// The worker only takes the next request once the execution given up on
+	// returned, so that executions never outnumber the workers
+	if givenUp {
+		select {
+		case <-executed:
+		case <-s.ctx.Done(): // Stop() called
+		}
+	}
+	confidentialExecutingGauge.Dec(1)
+}
+
+// cancelled returns the error of the requests whose execution is cancelled.
+func (s *ConfidentialScheduler) cancelled() error {
+	if s.ctx.Err() != nil {
+		return errConfidentialSchedulerStopped
+	}
+	return errConfidentialRequestCancelled
+}
+
+// finish records the outcome of the job and releases its waiters. Jobs which
+// were not executed are released first, executed ones are released by their
+// execution once it returns: before finish, unless it was given up on.
+func (s *ConfidentialScheduler) finish(job *ConfidentialJob, result common.Hash, err error) {
+	job.cancel()
+
+	s.lock.Lock()
+	delete(s.jobs, job.hash)
+	s.lock.Unlock()
+
+	switch {
+	case err == nil:
+		confidentialSucceededMeter.Mark(1)
+		s.setStatus(job.hash, &ConfidentialRequestStatus{Status: ConfidentialRequestPending, ResultHash: &result})
+	case errors.Is(err, errConfidentialRequestCancelled):
+		confidentialCancelledMeter.Mark(1)
+		s.setStatus(job.hash, &ConfidentialRequestStatus{Status: ConfidentialRequestCancelled, Error: err.Error()})
+	default:
+		confidentialFailedMeter.Mark(1)
+		s.setStatus(job.hash, &ConfidentialRequestStatus{Status: ConfidentialRequestFailed, Error: err.Error()})
+	}
+
+	if !job.executed && job.release != nil {
+		job.release()
+	}
+	job.result, job.err = result, err
+	close(job.done)
+}
+
+func (s *ConfidentialScheduler) setStatus(hash common.Hash, status *ConfidentialRequestStatus) {
+	s.lock.Lock()
+	s.statuses.Add(hash, status)
+	s.lock.Unlock()
+
+	s.feed.Send(ConfidentialRequestEvent{Hash: hash, Status: status})
+}
+
+// Hash returns the hash of the scheduled request.
+func (j *ConfidentialJob) Hash() common.Hash {
+	return j.hash
+}
+
+// Done returns a channel closed once the request is executed, cancelled or failed.
+func (j *ConfidentialJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Result waits for the request to be done and returns the hash of the
+// SuaveTransaction holding its result, or the error it failed with.
+func (j *ConfidentialJob) Result() (common.Hash, error) {
+	<-j.done
+	return j.result, j.err
+}
+
+// Wait waits for the request to be done. The request is cancelled if the
+// context is done first.
+func (j *ConfidentialJob) Wait(ctx context.Context) (common.Hash, error) {
+	select {
+	case <-j.done:
+		return j.result, j.err
+	case <-ctx.Done():
+		j.scheduler.Cancel(j.hash, j.sender)
+		return common.Hash{}, ctx.Err()
+	}
+}
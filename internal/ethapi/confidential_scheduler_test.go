@@ -0,0 +1,564 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+// blockingExecution returns an execution blocking until released, or cancelled.
+func blockingExecution(release <-chan struct{}) ConfidentialExecuteFn {
+	return func(ctx context.Context, commit func() error) (common.Hash, error) {
+		select {
+		case <-release:
+			return common.Hash{0x1}, nil
+		case <-ctx.Done():
+			return common.Hash{}, ctx.Err()
+		}
+	}
+}
+
+func waitStatus(t *testing.T, s *ConfidentialScheduler, hash common.Hash, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, ok := s.Status(hash); ok && status.Status == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	status, _ := s.Status(hash)
+	t.Fatalf("request %x did not reach status %s: %+v", hash, want, status)
+}
+
+func TestConfidentialSchedulerFairness(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	var (
+		lock  sync.Mutex
+		order []common.Hash
+	)
+	record := func(hash common.Hash) ConfidentialExecuteFn {
+		return func(ctx context.Context, commit func() error) (common.Hash, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			order = append(order, hash)
+			return hash, nil
+		}
+	}
+
+	// Keep the only worker busy while the requests are queued
+	release := make(chan struct{})
+	if _, err := s.Schedule(common.Hash{0xff}, common.Address{0xff}, blockingExecution(release)); err != nil {
+		t.Fatal(err)
+	}
+	waitStatus(t, s, common.Hash{0xff}, ConfidentialRequestExecuting)
+
+	var (
+		senderA = common.Address{0xa}
+		senderB = common.Address{0xb}
+		jobs    []*ConfidentialJob
+	)
+	for _, request := range []struct {
+		hash   common.Hash
+		sender common.Address
+	}{
+		{common.Hash{0xa1}, senderA},
+		{common.Hash{0xa2}, senderA},
+		{common.Hash{0xa3}, senderA},
+		{common.Hash{0xb1}, senderB},
+	} {
+		job, err := s.Schedule(request.hash, request.sender, record(request.hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+	close(release)
+
+	for _, job := range jobs {
+		result, err := job.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != job.Hash() {
+			t.Fatalf("wrong result, got %x, want %x", result, job.Hash())
+		}
+	}
+
+	want := []common.Hash{{0xa1}, {0xb1}, {0xa2}, {0xa3}}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("wrong execution order, got %x, want %x", order, want)
+		}
+	}
+
+	status, ok := s.Status(common.Hash{0xb1})
+	if !ok || status.Status != ConfidentialRequestPending || *status.ResultHash != (common.Hash{0xb1}) {
+		t.Fatalf("wrong status: %+v", status)
+	}
+}
+
+func TestConfidentialSchedulerLimits(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1, QueueSize: 3, SenderQueueSize: 2})
+	defer s.Stop()
+
+	// Not started, all the requests stay queued
+	release := make(chan struct{})
+	schedule := func(hash common.Hash, sender common.Address) error {
+		_, err := s.Schedule(hash, sender, blockingExecution(release))
+		return err
+	}
+
+	if err := schedule(common.Hash{0x1}, common.Address{0xa}); err != nil {
+		t.Fatal(err)
+	}
+	if err := schedule(common.Hash{0x1}, common.Address{0xa}); !errors.Is(err, errConfidentialRequestKnown) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestKnown, err)
+	}
+	if err := schedule(common.Hash{0x2}, common.Address{0xa}); err != nil {
+		t.Fatal(err)
+	}
+	if err := schedule(common.Hash{0x3}, common.Address{0xa}); !errors.Is(err, errConfidentialSenderQueueFull) {
+		t.Fatalf("expected %v, got %v", errConfidentialSenderQueueFull, err)
+	}
+	if err := schedule(common.Hash{0x3}, common.Address{0xb}); err != nil {
+		t.Fatal(err)
+	}
+	if err := schedule(common.Hash{0x4}, common.Address{0xc}); !errors.Is(err, errConfidentialQueueFull) {
+		t.Fatalf("expected %v, got %v", errConfidentialQueueFull, err)
+	}
+
+	// Cancelled requests free their slot
+	if !s.Cancel(common.Hash{0x2}, common.Address{0xa}) {
+		t.Fatal("queued request not cancelled")
+	}
+	if err := schedule(common.Hash{0x4}, common.Address{0xc}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfidentialSchedulerCancel(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	release := make(chan struct{})
+	executing, err := s.Schedule(common.Hash{0x1}, common.Address{0xa}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStatus(t, s, common.Hash{0x1}, ConfidentialRequestExecuting)
+
+	queued, err := s.Schedule(common.Hash{0x2}, common.Address{0xa}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests are only cancelled by their sender
+	if s.Cancel(common.Hash{0x2}, common.Address{0xb}) {
+		t.Fatal("request cancelled by another sender")
+	}
+
+	// Queued requests are cancelled without being executed
+	if !s.Cancel(common.Hash{0x2}, common.Address{0xa}) {
+		t.Fatal("queued request not cancelled")
+	}
+	if _, err := queued.Result(); !errors.Is(err, errConfidentialRequestCancelled) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestCancelled, err)
+	}
+	waitStatus(t, s, common.Hash{0x2}, ConfidentialRequestCancelled)
+
+	// Executing requests are aborted
+	if !s.Cancel(common.Hash{0x1}, common.Address{0xa}) {
+		t.Fatal("executing request not cancelled")
+	}
+	if _, err := executing.Result(); !errors.Is(err, errConfidentialRequestCancelled) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestCancelled, err)
+	}
+	waitStatus(t, s, common.Hash{0x1}, ConfidentialRequestCancelled)
+
+	if s.Cancel(common.Hash{0x1}, common.Address{0xa}) {
+		t.Fatal("done request cancelled")
+	}
+
+	// Requests are cancelled when the caller stops waiting
+	waiting, err := s.Schedule(common.Hash{0x3}, common.Address{0xa}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := waiting.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if _, err := waiting.Result(); !errors.Is(err, errConfidentialRequestCancelled) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestCancelled, err)
+	}
+}
+
+func TestConfidentialSchedulerStop(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	executing, err := s.Schedule(common.Hash{0x1}, common.Address{0xa}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStatus(t, s, common.Hash{0x1}, ConfidentialRequestExecuting)
+
+	queued, err := s.Schedule(common.Hash{0x2}, common.Address{0xa}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range []*ConfidentialJob{executing, queued} {
+		if _, err := job.Result(); !errors.Is(err, errConfidentialSchedulerStopped) {
+			t.Fatalf("expected %v, got %v", errConfidentialSchedulerStopped, err)
+		}
+	}
+	if _, err := s.Schedule(common.Hash{0x3}, common.Address{0xa}, blockingExecution(release)); !errors.Is(err, errConfidentialSchedulerStopped) {
+		t.Fatalf("expected %v, got %v", errConfidentialSchedulerStopped, err)
+	}
+}
+
+func TestConfidentialSchedulerRelease(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1, SenderQueueSize: 2})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	var (
+		lock     sync.Mutex
+		released = make(map[common.Hash]int)
+	)
+	schedule := func(hash common.Hash, execute ConfidentialExecuteFn) (*ConfidentialJob, error) {
+		return s.schedule(hash, common.Address{0xa}, execute, func() {
+			lock.Lock()
+			defer lock.Unlock()
+			released[hash]++
+		})
+	}
+	checkReleased := func(hash common.Hash, want int) {
+		t.Helper()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if released[hash] != want {
+			t.Fatalf("request %x released %d times, want %d", hash, released[hash], want)
+		}
+	}
+
+	release := make(chan struct{})
+	executing, err := schedule(common.Hash{0x1}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStatus(t, s, common.Hash{0x1}, ConfidentialRequestExecuting)
+
+	queued, err := schedule(common.Hash{0x2}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schedule(common.Hash{0x1}, blockingExecution(release)); !errors.Is(err, errConfidentialRequestKnown) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestKnown, err)
+	}
+	checkReleased(common.Hash{0x1}, 0)
+
+	// Cancelled requests are released before their waiters
+	s.Cancel(common.Hash{0x2}, common.Address{0xa})
+	if _, err := queued.Result(); !errors.Is(err, errConfidentialRequestCancelled) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestCancelled, err)
+	}
+	checkReleased(common.Hash{0x2}, 1)
+
+	// So are executed ones
+	close(release)
+	if _, err := executing.Result(); err != nil {
+		t.Fatal(err)
+	}
+	checkReleased(common.Hash{0x1}, 1)
+}
+
+func TestConfidentialSchedulerTimeout(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1, Timeout: 50 * time.Millisecond})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	// The execution ignores its context, like one blocked on an external call
+	hung := make(chan struct{})
+	committed := make(chan error, 1)
+
+	released := make(chan struct{})
+	job, err := s.schedule(common.Hash{0x1}, common.Address{0xa}, func(ctx context.Context, commit func() error) (common.Hash, error) {
+		<-hung
+		err := commit()
+		committed <- err
+		return common.Hash{0x1}, err
+	}, func() { close(released) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := job.Result(); !errors.Is(err, errConfidentialRequestTimeout) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestTimeout, err)
+	}
+	waitStatus(t, s, common.Hash{0x1}, ConfidentialRequestFailed)
+
+	// The request stays reserved, and the worker busy, until the execution returns
+	select {
+	case <-released:
+		t.Fatal("request released while executing")
+	default:
+	}
+
+	release := make(chan struct{})
+	close(release)
+	next, err := s.Schedule(common.Hash{0x2}, common.Address{0xa}, blockingExecution(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if status, _ := s.Status(common.Hash{0x2}); status.Status != ConfidentialRequestQueued {
+		t.Fatalf("next request %s while the worker is busy", status.Status)
+	}
+
+	// The execution given up on cannot commit its result
+	close(hung)
+	if err := <-committed; err == nil {
+		t.Fatal("execution committed after its timeout")
+	}
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("request not released")
+	}
+
+	// The worker is then free for the next requests
+	if _, err := next.Result(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfidentialSchedulerTimeoutConcurrency(t *testing.T) {
+	const workers, requests = 2, 6
+
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: workers, Timeout: 20 * time.Millisecond})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	// The executions ignore their context, like ones blocked on a slow relay
+	var (
+		lock             sync.Mutex
+		running, maxSeen int
+	)
+	hung := make(chan struct{})
+	execute := func(ctx context.Context, commit func() error) (common.Hash, error) {
+		lock.Lock()
+		running++
+		if running > maxSeen {
+			maxSeen = running
+		}
+		lock.Unlock()
+
+		<-hung
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return common.Hash{}, commit()
+	}
+
+	var jobs []*ConfidentialJob
+	for i := 0; i < requests; i++ {
+		job, err := s.Schedule(common.Hash{byte(i + 1)}, common.Address{byte(i + 1)}, execute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	// The executions given up on keep their workers busy
+	for _, job := range jobs[:workers] {
+		if _, err := job.Result(); !errors.Is(err, errConfidentialRequestTimeout) {
+			t.Fatalf("expected %v, got %v", errConfidentialRequestTimeout, err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, job := range jobs[workers:] {
+		waitStatus(t, s, job.Hash(), ConfidentialRequestQueued)
+	}
+
+	close(hung)
+	for _, job := range jobs[workers:] {
+		if _, err := job.Result(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if maxSeen > workers {
+		t.Fatalf("%d concurrent executions with %d workers", maxSeen, workers)
+	}
+}
+
+func TestConfidentialSchedulerCommittedTimeout(t *testing.T) {
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1, Timeout: 50 * time.Millisecond})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	// The execution commits, then submits its result past the deadline
+	job, err := s.Schedule(common.Hash{0x1}, common.Address{0xa}, func(ctx context.Context, commit func() error) (common.Hash, error) {
+		if err := commit(); err != nil {
+			return common.Hash{}, err
+		}
+		time.Sleep(200 * time.Millisecond)
+		return common.Hash{0x1}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := job.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (common.Hash{0x1}) {
+		t.Fatalf("unexpected result %x", result)
+	}
+	waitStatus(t, s, common.Hash{0x1}, ConfidentialRequestPending)
+}
+
+func TestConfidentialSchedulerTimeoutReservation(t *testing.T) {
+	config := *params.TestChainConfig
+	config.SuaveBlock = big.NewInt(0)
+
+	key, _ := crypto.GenerateKey()
+	nodeKey, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	genesis := &core.Genesis{
+		Config: &config,
+		Alloc:  core.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+	}
+	backend := newTestBackend(t, 0, genesis, nil)
+
+	poolConfig := txpool.DefaultConfig
+	poolConfig.Journal = ""
+	pool := txpool.NewTxPool(poolConfig, &config, backend.chain)
+	defer pool.Stop()
+
+	s := NewConfidentialScheduler(suave.ExecutionConfig{Workers: 1, Timeout: 50 * time.Millisecond})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	signer := types.NewSuaveSigner(config.ChainID)
+	request := func(inputs []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				GasPrice:               big.NewInt(params.GWei),
+				Gas:                    100000,
+				To:                     &common.Address{0x1},
+				Value:                  big.NewInt(0),
+				ExecutionNode:          crypto.PubkeyToAddress(nodeKey.PublicKey),
+				ConfidentialInputsHash: crypto.Keccak256Hash(inputs),
+				ChainID:                config.ChainID,
+			},
+			ConfidentialInputs: inputs,
+		}), signer, key)
+		return tx
+	}
+
+	// The execution is blocked past its timeout, then submits its result
+	req := request([]byte{0x1})
+	inner, _ := types.CastTxInner[*types.ConfidentialComputeRequest](req)
+	result, _ := types.SignTx(types.NewTx(&types.SuaveTransaction{
+		ExecutionNode:              inner.ExecutionNode,
+		ConfidentialComputeRequest: inner.ConfidentialComputeRecord,
+		ChainID:                    config.ChainID,
+	}), signer, nodeKey)
+
+	hung := make(chan struct{})
+	submitted := make(chan error, 1)
+	if err := pool.ReserveConfidentialRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.schedule(req.Hash(), from, func(ctx context.Context, commit func() error) (common.Hash, error) {
+		<-hung
+		err := commit()
+		if err == nil {
+			err = pool.AddLocal(result)
+		}
+		submitted <- err
+		return result.Hash(), err
+	}, func() { pool.ReleaseConfidentialRequest(req) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := job.Result(); !errors.Is(err, errConfidentialRequestTimeout) {
+		t.Fatalf("expected %v, got %v", errConfidentialRequestTimeout, err)
+	}
+
+	// The nonce is not handed out to another request meanwhile
+	other := request([]byte{0x2})
+	if err, want := pool.ReserveConfidentialRequest(other), txpool.ErrNonceReserved; !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+	if err, want := pool.ReserveConfidentialRequest(req), txpool.ErrConfidentialRequestKnown; !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+
+	// Nor is the result of the execution given up on submitted
+	close(hung)
+	if err := <-submitted; err == nil {
+		t.Fatal("result submitted after the timeout")
+	}
+	if pool.Has(result.Hash()) {
+		t.Fatal("result pooled after the timeout")
+	}
+
+	// Once it returned, the nonce is released
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := pool.ReserveConfidentialRequest(other)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("nonce not released: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	pool.ReleaseConfidentialRequest(other)
+}
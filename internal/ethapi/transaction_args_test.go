@@ -312,6 +312,9 @@ func (b *backendMock) GetEVM(ctx context.Context, msg *core.Message, state *stat
 func (b *backendMock) SuaveContext(requestTx *types.Transaction, ccr *types.ConfidentialComputeRequest) vm.SuaveContext {
 	return vm.SuaveContext{}
 }
+func (b *backendMock) ConfidentialScheduler() *ConfidentialScheduler               { return nil }
+func (b *backendMock) ReserveConfidentialRequest(request *types.Transaction) error { return nil }
+func (b *backendMock) ReleaseConfidentialRequest(request *types.Transaction)       {}
 func (b *backendMock) GetMEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext, suaveCtx *vm.SuaveContext) (*vm.EVM, func() error, func() error) {
 	return nil, nil, nil
 }
@@ -351,10 +354,10 @@ func (b *backendMock) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent)
 
 func (b *backendMock) Engine() consensus.Engine { return nil }
 
-func (b *backendMock) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+func (b *backendMock) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	return nil, nil, errors.New("not implemented")
 }
 
-func (b *backendMock) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error) {
-	return nil, nil, errors.New("not implemented")
+func (b *backendMock) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
+	return nil, nil, nil, errors.New("not implemented")
 }
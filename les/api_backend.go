@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -201,6 +202,16 @@ func (b *LesApiBackend) SuaveContext(requestTx *types.Transaction, ccr *types.Co
 	return vm.SuaveContext{}
 }
 
+func (b *LesApiBackend) ConfidentialScheduler() *ethapi.ConfidentialScheduler {
+	return nil
+}
+
+func (b *LesApiBackend) ReserveConfidentialRequest(request *types.Transaction) error {
+	return errors.New("confidential compute requests not supported")
+}
+
+func (b *LesApiBackend) ReleaseConfidentialRequest(request *types.Transaction) {}
+
 func (b *LesApiBackend) GetMEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext, suaveCtx *vm.SuaveContext) (*vm.EVM, func() error, func() error) {
 	return nil, nil, nil
 }
@@ -346,10 +357,10 @@ func (b *LesApiBackend) StateAtTransaction(ctx context.Context, block *types.Blo
 	return b.eth.stateAtTransaction(ctx, block, txIndex, reexec)
 }
 
-func (b *LesApiBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+func (b *LesApiBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	return nil, nil, errors.New("not implemented")
 }
 
-func (b *LesApiBackend) BuildBlockFromBundles(context.Context, *types.BuildBlockArgs, []types.SBundle) (*types.Block, *big.Int, error) {
-	return nil, nil, errors.New("not implemented")
+func (b *LesApiBackend) BuildBlockFromBundles(context.Context, *types.BuildBlockArgs, []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
+	return nil, nil, nil, errors.New("not implemented")
 }
@@ -0,0 +1,305 @@
+package miner
+
+import (
+	"container/heap"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Block building algorithms BuildBlockArgs can choose from.
+const (
+	AlgorithmSequential       = "sequential"        // The bundles in the order they are given, the default
+	AlgorithmGreedy           = "greedy"            // The bundles by effective gas price
+	AlgorithmGreedyResimulate = "greedy-resimulate" // The bundles by effective gas price, re-simulated on conflict
+)
+
+// BlockBuildingAlgorithm decides in which order the bundles are committed to a
+// block built from bundles.
+type BlockBuildingAlgorithm interface {
+	// Build commits the bundles to the block with the builder. Bundles that can
+	// not be applied are skipped by the builder, Build only fails if a required
+	// bundle fails or the context is done.
+	Build(ctx context.Context, builder *bundleBuilder, bundles []*types.SBundle) error
+}
+
+var blockBuildingAlgorithms = map[string]BlockBuildingAlgorithm{
+	"":                        &sequentialAlgorithm{},
+	AlgorithmSequential:       &sequentialAlgorithm{},
+	AlgorithmGreedy:           &greedyAlgorithm{},
+	AlgorithmGreedyResimulate: &greedyResimulateAlgorithm{},
+}
+
+// blockBuildingAlgorithm returns the algorithm with the given name.
+func blockBuildingAlgorithm(name string) (BlockBuildingAlgorithm, error) {
+	algorithm, ok := blockBuildingAlgorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown block building algorithm %q", name)
+	}
+	return algorithm, nil
+}
+
+// bundleBuilder applies bundles on top of the block being built, keeping track
+// of the result of each bundle.
+type bundleBuilder struct {
+	w     *worker
+	env   *environment
+	key   *ecdsa.PrivateKey // Of the coinbase, to pay the refunds
+	known map[common.Hash]*types.SBundle
+
+	results map[*types.SBundle]*types.BundleResult
+	applied map[common.Hash]bool // Referenced bundles applied as part of the bundles committed
+}
+
+func newBundleBuilder(w *worker, env *environment, key *ecdsa.PrivateKey, known map[common.Hash]*types.SBundle) *bundleBuilder {
+	return &bundleBuilder{
+		w:       w,
+		env:     env,
+		key:     key,
+		known:   known,
+		results: make(map[*types.SBundle]*types.BundleResult),
+		applied: make(map[common.Hash]bool),
+	}
+}
+
+// bundleSimulation is the outcome of applying a bundle on top of the block.
+// The bundle stays applied until the simulation is accepted or undone.
+type bundleSimulation struct {
+	snapshot *envSnapshot // Of the block before the bundle
+	profit   *big.Int     // Paid to the coinbase, net of the refunds
+	gasUsed  uint64
+	refunds  []*types.BundleRefund
+	applied  map[common.Hash]bool
+}
+
+// effectiveGasPrice returns the profit of the bundle per gas it uses.
+func (s *bundleSimulation) effectiveGasPrice() *big.Int {
+	if s.gasUsed == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(s.profit, new(big.Int).SetUint64(s.gasUsed))
+}
+
+// simulate applies the bundle on top of the block, which has to be accepted or
+// undone before anything else is applied. A bundle that fails is undone.
+func (b *bundleBuilder) simulate(bundle *types.SBundle) (*bundleSimulation, error) {
+	snapshot, err := b.env.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		profitPre = b.env.state.GetBalance(b.env.coinbase)
+		gasPre    = b.env.header.GasUsed
+		applied   = make(map[common.Hash]bool)
+	)
+	refunds, _, err := b.w.commitBundle(b.env, bundle, b.known, b.key, applied, 0)
+	if err != nil {
+		if revertErr := b.env.revertToSnapshot(snapshot); revertErr != nil {
+			return nil, revertErr
+		}
+		return nil, err
+	}
+	return &bundleSimulation{
+		snapshot: snapshot,
+		profit:   balanceIncrease(profitPre, b.env.state.GetBalance(b.env.coinbase)),
+		gasUsed:  b.env.header.GasUsed - gasPre,
+		refunds:  refunds,
+		applied:  applied,
+	}, nil
+}
+
+// undo removes the simulated bundle from the block.
+func (b *bundleBuilder) undo(simulation *bundleSimulation) error {
+	return b.env.revertToSnapshot(simulation.snapshot)
+}
+
+// commit applies the bundle on top of the block. A bundle that fails is
+// skipped, unless it is required.
+func (b *bundleBuilder) commit(bundle *types.SBundle) error {
+	simulation, err := b.simulate(bundle)
+	if err != nil {
+		return b.skip(bundle, err)
+	}
+	b.accept(bundle, simulation)
+	return nil
+}
+
+// accept keeps the simulated bundle in the block.
+func (b *bundleBuilder) accept(bundle *types.SBundle, simulation *bundleSimulation) {
+	result := b.result(bundle)
+	result.Included = true
+	result.GasUsed = hexutil.Uint64(simulation.gasUsed)
+	result.Profit = (*hexutil.Big)(simulation.profit)
+	result.Refunds = simulation.refunds
+
+	for hash := range simulation.applied {
+		b.applied[hash] = true
+	}
+	b.env.discardSnapshot()
+}
+
+// skip records why the bundle is left out of the block. It fails if the bundle is required.
+func (b *bundleBuilder) skip(bundle *types.SBundle, err error) error {
+	result := b.result(bundle)
+	if bundle.Required {
+		return fmt.Errorf("required bundle %s failed: %w", result.Hash, err)
+	}
+	log.Debug("Skipping failed bundle", "hash", result.Hash, "err", err)
+	result.Error = err.Error()
+	return nil
+}
+
+func (b *bundleBuilder) result(bundle *types.SBundle) *types.BundleResult {
+	result, ok := b.results[bundle]
+	if !ok {
+		result = &types.BundleResult{Hash: bundle.Hash(), Profit: new(hexutil.Big)}
+		b.results[bundle] = result
+	}
+	return result
+}
+
+// sequentialAlgorithm commits the bundles in the order they are given.
+type sequentialAlgorithm struct{}
+
+func (a *sequentialAlgorithm) Build(ctx context.Context, builder *bundleBuilder, bundles []*types.SBundle) error {
+	for _, bundle := range bundles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := builder.commit(bundle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// greedyAlgorithm commits the bundles by decreasing effective gas price, as
+// simulated on top of the parent block. Bundles that fail the simulation are
+// skipped.
+type greedyAlgorithm struct{}
+
+func (a *greedyAlgorithm) Build(ctx context.Context, builder *bundleBuilder, bundles []*types.SBundle) error {
+	queue, err := simulateBundles(ctx, builder, bundles)
+	if err != nil {
+		return err
+	}
+	sort.Sort(queue)
+
+	for _, queued := range queue {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := builder.commit(queued.bundle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// greedyResimulateAlgorithm commits the bundles by decreasing effective gas
+// price like the greedy algorithm, but re-simulates each bundle on top of the
+// bundles committed before it. A bundle whose price dropped because it
+// conflicts with them goes back in line at its new price.
+type greedyResimulateAlgorithm struct{}
+
+func (a *greedyResimulateAlgorithm) Build(ctx context.Context, builder *bundleBuilder, bundles []*types.SBundle) error {
+	queue, err := simulateBundles(ctx, builder, bundles)
+	if err != nil {
+		return err
+	}
+	heap.Init(&queue)
+
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		next := heap.Pop(&queue).(*queuedBundle)
+		simulation, err := builder.simulate(next.bundle)
+		if err != nil {
+			if err := builder.skip(next.bundle, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The price only changes when bundles were committed since the last
+		// simulation, so that each bundle goes back in line once per commit at most
+		price := simulation.effectiveGasPrice()
+		if price.Cmp(next.price) < 0 && queue.Len() > 0 && price.Cmp(queue[0].price) < 0 {
+			if err := builder.undo(simulation); err != nil {
+				return err
+			}
+			next.price = price
+			heap.Push(&queue, next)
+			continue
+		}
+		builder.accept(next.bundle, simulation)
+	}
+	return nil
+}
+
+// simulateBundles simulates the bundles on top of the block, skipping the ones
+// that fail. The block is left unchanged.
+func simulateBundles(ctx context.Context, builder *bundleBuilder, bundles []*types.SBundle) (bundleQueue, error) {
+	queue := make(bundleQueue, 0, len(bundles))
+	for i, bundle := range bundles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		simulation, err := builder.simulate(bundle)
+		if err != nil {
+			if err := builder.skip(bundle, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := builder.undo(simulation); err != nil {
+			return nil, err
+		}
+		queue = append(queue, &queuedBundle{bundle: bundle, index: i, price: simulation.effectiveGasPrice()})
+	}
+	return queue, nil
+}
+
+type queuedBundle struct {
+	bundle *types.SBundle
+	index  int // In the bundles given, to keep the order of bundles paying the same
+	price  *big.Int
+}
+
+// bundleQueue orders bundles by decreasing effective gas price. It implements
+// both sort.Interface and heap.Interface.
+type bundleQueue []*queuedBundle
+
+func (q bundleQueue) Len() int { return len(q) }
+
+func (q bundleQueue) Less(i, j int) bool {
+	if cmp := q[i].price.Cmp(q[j].price); cmp != 0 {
+		return cmp > 0
+	}
+	return q[i].index < q[j].index
+}
+
+func (q bundleQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *bundleQueue) Push(x interface{}) {
+	*q = append(*q, x.(*queuedBundle))
+}
+
+func (q *bundleQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*q = old[0 : n-1]
+	return x
+}
@@ -0,0 +1,223 @@
+package miner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// Searchers sending the bundles of the block building tests
+	testSearcherKeys = newTestSearcherKeys(16)
+
+	// testOpportunityAddress pays its balance to the coinbase of the first
+	// transaction calling it: COINBASE SELFDESTRUCT
+	testOpportunityAddress = common.Address{0x0f, 0xf0}
+	testOpportunityFunds   = big.NewInt(params.Ether / 100)
+
+	// Clears a storage slot when paid, which is refunded
+	testRefundingAddress = common.Address{0x0f, 0xf1}
+
+	testSearcherAlloc = newTestSearcherAlloc()
+)
+
+func newTestSearcherKeys(n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	return keys
+}
+
+func newTestSearcherAlloc() core.GenesisAlloc {
+	alloc := core.GenesisAlloc{
+		testOpportunityAddress: {Balance: testOpportunityFunds, Code: common.FromHex("0x41ff")},
+		testRefundingAddress:   {Balance: common.Big0, Code: common.FromHex("0x6000600055"), Storage: map[common.Hash]common.Hash{{}: {0x1}}},
+	}
+	for _, key := range testSearcherKeys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	return alloc
+}
+
+// newSearcherBundle returns a bundle with a transaction of the searcher to the
+// address, paying the tip per gas on top of the initial base fee.
+func newSearcherBundle(searcher int, to common.Address, tip int64) types.SBundle {
+	tx := types.MustSignNewTx(testSearcherKeys[searcher], types.LatestSigner(ethashChainConfig), &types.LegacyTx{
+		To:       &to,
+		Value:    big.NewInt(0),
+		Gas:      100000,
+		GasPrice: big.NewInt(params.InitialBaseFee + tip),
+	})
+	return types.SBundle{Txs: types.Transactions{tx}}
+}
+
+func newBundleBuildArgs(w *worker, algorithm string) *types.BuildBlockArgs {
+	head := w.chain.CurrentBlock()
+	return &types.BuildBlockArgs{
+		Parent:       head.Hash(),
+		Timestamp:    head.Time + 12,
+		FeeRecipient: common.Address{0x42},
+		GasLimit:     params.GenesisGasLimit,
+		Algorithm:    algorithm,
+	}
+}
+
+func TestBlockBuildingAlgorithms(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	bundles := []types.SBundle{
+		newSearcherBundle(0, testUserAddress, 2*params.GWei),
+		newSearcherBundle(1, testUserAddress, 5*params.GWei),
+		newSearcherBundle(2, testUserAddress, 3*params.GWei),
+	}
+
+	cases := []struct {
+		algorithm string
+		order     []int
+	}{
+		{"", []int{0, 1, 2}},
+		{AlgorithmSequential, []int{0, 1, 2}},
+		{AlgorithmGreedy, []int{1, 2, 0}},
+		{AlgorithmGreedyResimulate, []int{1, 2, 0}},
+	}
+	for _, c := range cases {
+		block, _, results, err := w.buildBlockFromBundles(context.Background(), newBundleBuildArgs(w, c.algorithm), bundles)
+		if err != nil {
+			t.Fatalf("%q: failed to build block: %v", c.algorithm, err)
+		}
+		txs := block.Transactions()
+		if len(txs) != len(c.order)+1 {
+			t.Fatalf("%q: unexpected number of transactions: have %d, want %d", c.algorithm, len(txs), len(c.order)+1)
+		}
+		for i, bundle := range c.order {
+			if txs[i].Hash() != bundles[bundle].Txs[0].Hash() {
+				t.Errorf("%q: unexpected transaction %d: have %s, want bundle %d", c.algorithm, i, txs[i].Hash(), bundle)
+			}
+		}
+		// Results are reported in the order the bundles are given
+		for i, result := range results {
+			if !result.Included || result.Hash != bundles[i].Hash() {
+				t.Errorf("%q: unexpected result %d: %+v", c.algorithm, i, result)
+			}
+		}
+	}
+
+	if _, _, _, err := w.buildBlockFromBundles(context.Background(), newBundleBuildArgs(w, "random"), bundles); err == nil || !strings.Contains(err.Error(), "unknown block building algorithm") {
+		t.Errorf("expected unknown algorithm to fail, got %v", err)
+	}
+}
+
+func TestBlockBuildingAlgorithmsResimulateOnConflict(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	// Both the first bundles go for the opportunity, only the first of them
+	// committed gets it and the price of the other one drops to its tip
+	bundles := []types.SBundle{
+		newSearcherBundle(0, testOpportunityAddress, 1*params.GWei),
+		newSearcherBundle(1, testOpportunityAddress, 2*params.GWei),
+		newSearcherBundle(2, testUserAddress, 10*params.GWei),
+	}
+
+	cases := []struct {
+		algorithm string
+		order     []int
+	}{
+		{AlgorithmSequential, []int{0, 1, 2}},
+		{AlgorithmGreedy, []int{1, 0, 2}},
+		{AlgorithmGreedyResimulate, []int{1, 2, 0}},
+	}
+	for _, c := range cases {
+		block, _, results, err := w.buildBlockFromBundles(context.Background(), newBundleBuildArgs(w, c.algorithm), bundles)
+		if err != nil {
+			t.Fatalf("%q: failed to build block: %v", c.algorithm, err)
+		}
+		txs := block.Transactions()
+		if len(txs) != len(c.order)+1 {
+			t.Fatalf("%q: unexpected number of transactions: have %d, want %d", c.algorithm, len(txs), len(c.order)+1)
+		}
+		for i, bundle := range c.order {
+			if txs[i].Hash() != bundles[bundle].Txs[0].Hash() {
+				t.Errorf("%q: unexpected transaction %d: have %s, want bundle %d", c.algorithm, i, txs[i].Hash(), bundle)
+			}
+		}
+		if winner := results[c.order[0]]; winner.Profit.ToInt().Cmp(testOpportunityFunds) <= 0 {
+			t.Errorf("%q: opportunity not taken by the first bundle: %+v", c.algorithm, winner)
+		}
+	}
+}
+
+// benchmarkBundleSets returns the sets of bundles the algorithms are compared on.
+// The sets are synthetic, sent by the test searchers, as bundles recorded from
+// live orderflow would not apply on top of the state of the test chain.
+func benchmarkBundleSets() map[string][]types.SBundle {
+	sets := make(map[string][]types.SBundle)
+
+	// Independent transfers paying various tips
+	for i := range testSearcherKeys {
+		tip := int64(i*7%len(testSearcherKeys)+1) * params.GWei
+		sets["transfers"] = append(sets["transfers"], newSearcherBundle(i, testUserAddress, tip))
+	}
+
+	// Searchers competing for the opportunity, the ones given first asking for
+	// the largest refunds, mixed with transfers
+	for i := 0; i < len(testSearcherKeys)/2; i++ {
+		bundle := newSearcherBundle(i, testOpportunityAddress, int64(i+1)*params.GWei)
+		sets["opportunity"] = append(sets["opportunity"], types.SBundle{
+			Version:     types.SBundleVersion,
+			BlockNumber: common.Big1,
+			Body:        []types.SBundleBody{{Tx: bundle.Txs[0]}},
+			Validity:    &types.BundleValidity{Refund: []types.RefundConstraint{{BodyIdx: 0, Percent: 90 - 10*i}}},
+		})
+	}
+	for i := len(testSearcherKeys) / 2; i < len(testSearcherKeys); i++ {
+		tip := int64(i%4+1) * 5 * params.GWei
+		sets["opportunity"] = append(sets["opportunity"], newSearcherBundle(i, testUserAddress, tip))
+	}
+	return sets
+}
+
+// BenchmarkBlockBuildingAlgorithms reports the profit paid to the proposer by
+// the block each algorithm builds, in gwei.
+func BenchmarkBlockBuildingAlgorithms(b *testing.B) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(b, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	for name, bundles := range benchmarkBundleSets() {
+		for _, algorithm := range []string{AlgorithmSequential, AlgorithmGreedy, AlgorithmGreedyResimulate} {
+			b.Run(name+"/"+algorithm, func(b *testing.B) {
+				var profit *big.Int
+				for i := 0; i < b.N; i++ {
+					_, value, _, err := w.buildBlockFromBundles(context.Background(), newBundleBuildArgs(w, algorithm), bundles)
+					if err != nil {
+						b.Fatalf("failed to build block: %v", err)
+					}
+					profit = value.Profit
+				}
+				gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(profit), big.NewFloat(params.GWei)).Float64()
+				b.ReportMetric(gwei, "profit-gwei")
+			})
+		}
+	}
+}
@@ -254,10 +254,16 @@ func (miner *Miner) BuildPayload(args *BuildPayloadArgs) (*Payload, error) {
 	return miner.worker.buildPayload(args)
 }
 
-func (miner *Miner) BuildBlockFromTxs(ctx context.Context, buildArgs *types.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+func (miner *Miner) BuildBlockFromTxs(ctx context.Context, buildArgs *types.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	return miner.worker.buildBlockFromTxs(ctx, buildArgs, txs)
 }
 
-func (miner *Miner) BuildBlockFromBundles(ctx context.Context, buildArgs *types.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error) {
+func (miner *Miner) BuildBlockFromBundles(ctx context.Context, buildArgs *types.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
 	return miner.worker.buildBlockFromBundles(ctx, buildArgs, bundles)
 }
+
+// SimulateBundle applies the bundle on top of the parent block in buildArgs and
+// returns the result of each of its transactions, without building a block.
+func (miner *Miner) SimulateBundle(ctx context.Context, buildArgs *types.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	return miner.worker.simulateBundle(ctx, buildArgs, bundle)
+}
@@ -18,6 +18,7 @@ package miner
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
@@ -26,17 +27,21 @@ import (
 	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
+	"golang.org/x/exp/slices"
 )
 
 const (
@@ -130,6 +135,55 @@ func (env *environment) copy() *environment {
 	return cpy
 }
 
+// envSnapshot is the part of the environment changed by applying transactions,
+// besides the state which is snapshotted along with it.
+type envSnapshot struct {
+	gasPool  uint64
+	gasUsed  uint64
+	tcount   int
+	txs      int
+	receipts int
+}
+
+// snapshot takes a snapshot of the environment spanning the transactions
+// applied after it, which is cheaper than copying the environment. It must be
+// reverted or discarded before the block is assembled.
+func (env *environment) snapshot() (*envSnapshot, error) {
+	if err := env.state.MultiTxSnapshot(); err != nil {
+		return nil, err
+	}
+	snap := &envSnapshot{
+		gasUsed:  env.header.GasUsed,
+		tcount:   env.tcount,
+		txs:      len(env.txs),
+		receipts: len(env.receipts),
+	}
+	if env.gasPool != nil {
+		snap.gasPool = env.gasPool.Gas()
+	}
+	return snap, nil
+}
+
+// revertToSnapshot removes the transactions applied since the snapshot.
+func (env *environment) revertToSnapshot(snap *envSnapshot) error {
+	if err := env.state.RevertToMultiTxSnapshot(); err != nil {
+		return err
+	}
+	if env.gasPool != nil {
+		env.gasPool.SetGas(snap.gasPool)
+	}
+	env.header.GasUsed = snap.gasUsed
+	env.tcount = snap.tcount
+	env.txs = env.txs[:snap.txs]
+	env.receipts = env.receipts[:snap.receipts]
+	return nil
+}
+
+// discardSnapshot keeps the transactions applied since the snapshot.
+func (env *environment) discardSnapshot() {
+	env.state.DiscardMultiTxSnapshot()
+}
+
 // unclelist returns the contained uncles as the list format.
 func (env *environment) unclelist() []*types.Header {
 	var uncles []*types.Header
@@ -880,6 +934,31 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 }
 
 func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *atomic.Int32) error {
+	coalescedLogs, err := w.applyTransactions(env, txs, interrupt)
+	if err != nil {
+		return err
+	}
+	if !w.isRunning() && len(coalescedLogs) > 0 {
+		// We don't push the pendingLogsEvent while we are sealing. The reason is that
+		// when we are sealing, the worker will regenerate a sealing block every 3 seconds.
+		// In order to avoid pushing the repeated pendingLog, we disable the pending log pushing.
+
+		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
+		// logs by filling in the block hash when the block was mined by the local miner. This can
+		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
+		cpy := make([]*types.Log, len(coalescedLogs))
+		for i, l := range coalescedLogs {
+			cpy[i] = new(types.Log)
+			*cpy[i] = *l
+		}
+		w.pendingLogsFeed.Send(cpy)
+	}
+	return nil
+}
+
+// applyTransactions applies the transactions to the environment until the gas
+// runs out or the building is interrupted, and returns the logs they emitted.
+func (w *worker) applyTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *atomic.Int32) ([]*types.Log, error) {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -890,7 +969,7 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 		// Check interruption signal and abort building if it's fired.
 		if interrupt != nil {
 			if signal := interrupt.Load(); signal != commitInterruptNone {
-				return signalToErr(signal)
+				return nil, signalToErr(signal)
 			}
 		}
 		// If we don't have enough gas for any further transactions then we're done.
@@ -938,22 +1017,7 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 		}
 	}
-	if !w.isRunning() && len(coalescedLogs) > 0 {
-		// We don't push the pendingLogsEvent while we are sealing. The reason is that
-		// when we are sealing, the worker will regenerate a sealing block every 3 seconds.
-		// In order to avoid pushing the repeated pendingLog, we disable the pending log pushing.
-
-		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
-		// logs by filling in the block hash when the block was mined by the local miner. This can
-		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
-		cpy := make([]*types.Log, len(coalescedLogs))
-		for i, l := range coalescedLogs {
-			cpy[i] = new(types.Log)
-			*cpy[i] = *l
-		}
-		w.pendingLogsFeed.Send(cpy)
-	}
-	return nil
+	return coalescedLogs, nil
 }
 
 // generateParams wraps various of settings for generating sealing task.
@@ -967,6 +1031,9 @@ type generateParams struct {
 	withdrawals types.Withdrawals // List of withdrawals to include in block.
 	noUncle     bool              // Flag whether the uncle block inclusion is allowed
 	noTxs       bool              // Flag whether an empty block without any transaction is expected
+
+	excessBlobGas uint64      // The excess blob gas of the block, set from Cancun on
+	beaconRoot    common.Hash // The root of the parent beacon block, set from Cancun on
 }
 
 // prepareWork constructs the sealing task according to the given parameters,
@@ -1018,6 +1085,16 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
 		}
 	}
+	// Set the blob gas fields and the parent beacon block root if we are past
+	// Cancun. The excess blob gas is given by the caller as the parent header may
+	// predate the blob gas fields, the blob gas used is set once the block is
+	// filled.
+	if w.chainConfig.IsCancun(header.Number, header.Time) {
+		beaconRoot := genParams.beaconRoot
+		header.DataGasUsed = new(uint64)
+		header.ExcessDataGas = new(big.Int).SetUint64(genParams.excessBlobGas)
+		header.ParentBeaconRoot = &beaconRoot
+	}
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for sealing", "err", err)
@@ -1101,6 +1178,7 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, e
 			log.Warn("Block building is interrupted", "allowance", common.PrettyDuration(w.newpayloadTimeout))
 		}
 	}
+	setDataGasUsed(work)
 	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, params.withdrawals)
 	if err != nil {
 		return nil, nil, err
@@ -1186,6 +1264,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
+		setDataGasUsed(env)
 		// Withdrawals are set to nil here, because this is only called in PoW.
 		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts, nil)
 		if err != nil {
@@ -1244,7 +1323,9 @@ func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase
 	}
 }
 
-func (w *worker) buildBlockFromTxs(ctx context.Context, args *types.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+// buildBlockFromTxs applies the transactions on top of the parent block, filling
+// the rest of the block from the txpool if the args ask for it.
+func (w *worker) buildBlockFromTxs(ctx context.Context, args *types.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	params := &generateParams{
 		timestamp:   args.Timestamp,
 		forceTime:   true,
@@ -1255,6 +1336,9 @@ func (w *worker) buildBlockFromTxs(ctx context.Context, args *types.BuildBlockAr
 		withdrawals: args.Withdrawals,
 		noUncle:     true,
 		noTxs:       false,
+
+		excessBlobGas: args.ExcessBlobGas,
+		beaconRoot:    args.ParentBeaconBlockRoot,
 	}
 
 	work, err := w.prepareWork(params)
@@ -1263,26 +1347,131 @@ func (w *worker) buildBlockFromTxs(ctx context.Context, args *types.BuildBlockAr
 	}
 	defer work.discard()
 
+	if err := checkCancunArgs(work.header, args); err != nil {
+		return nil, nil, err
+	}
+	work.gasPool = suaveGasPool(work.header, args.GasLimit)
+
 	profitPre := work.state.GetBalance(args.FeeRecipient)
 
 	if err := w.rawCommitTransactions(work, txs); err != nil {
 		return nil, nil, err
 	}
 
+	value := &types.BlockValue{
+		Bundles: balanceIncrease(profitPre, work.state.GetBalance(args.FeeRecipient)),
+		Public:  new(big.Int),
+	}
+	if args.FillPending {
+		value.Public = w.fillPendingTransactions(work, 0)
+	}
+
 	profitPost := work.state.GetBalance(args.FeeRecipient)
+	setDataGasUsed(work)
 	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, params.withdrawals)
 	if err != nil {
 		return nil, nil, err
 	}
-	blockProfit := new(big.Int).Sub(profitPost, profitPre)
-	return block, blockProfit, nil
+	value.Profit = new(big.Int).Sub(profitPost, profitPre)
+	return block, value, nil
+}
+
+// checkCancunArgs checks the args of a block past Cancun carry the root of the
+// parent beacon block, as the engine API requires from Cancun on.
+func checkCancunArgs(header *types.Header, args *types.BuildBlockArgs) error {
+	if header.ExcessDataGas != nil && args.ParentBeaconBlockRoot == (common.Hash{}) {
+		return errors.New("missing parent beacon block root")
+	}
+	return nil
+}
+
+// setDataGasUsed sets the blob gas used by the transactions of the block in its
+// header, if the block is past Cancun.
+func setDataGasUsed(env *environment) {
+	if env.header.DataGasUsed == nil {
+		return
+	}
+	var used uint64
+	for _, tx := range env.txs {
+		used += tx.BlobGas()
+	}
+	env.header.DataGasUsed = &used
+}
+
+// suaveGasPool returns the gas pool of a block built for SUAVE, holding the gas
+// limit of the block capped to the gas limit of the args if set. The precompiles
+// cap the latter to the gas the confidential compute request pays for.
+func suaveGasPool(header *types.Header, gasLimit uint64) *core.GasPool {
+	if gasLimit == 0 || gasLimit > header.GasLimit {
+		gasLimit = header.GasLimit
+	}
+	return new(core.GasPool).AddGas(gasLimit)
+}
+
+// fillPendingTransactions fills the gas left in the block with transactions
+// from the txpool, ordered by tip, keeping the reserved gas aside. It returns
+// the value the transactions paid to the coinbase. The block is only built for
+// SUAVE, so no pending logs are sent for the transactions.
+func (w *worker) fillPendingTransactions(env *environment, reserve uint64) *big.Int {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	if env.gasPool.Gas() <= reserve {
+		return new(big.Int)
+	}
+	env.gasPool.SubGas(reserve)
+	defer env.gasPool.AddGas(reserve)
+
+	interrupt := new(atomic.Int32)
+	timer := time.AfterFunc(w.newpayloadTimeout, func() {
+		interrupt.Store(commitInterruptTimeout)
+	})
+	defer timer.Stop()
+
+	balancePre := env.state.GetBalance(env.coinbase)
+	txs := types.NewTransactionsByPriceAndNonce(env.signer, pendingAt(env.state, w.eth.TxPool().Pending(true)), env.header.BaseFee)
+	if _, err := w.applyTransactions(env, txs, interrupt); errors.Is(err, errBlockInterruptedByTimeout) {
+		log.Warn("Filling the block from the txpool is interrupted", "allowance", common.PrettyDuration(w.newpayloadTimeout))
+	}
+	return balanceIncrease(balancePre, env.state.GetBalance(env.coinbase))
+}
+
+// pendingAt returns the pending transactions of the txpool which continue the
+// nonces of their senders in the state. The txpool keeps them pending against
+// the local head, while the block may be built on another parent and already
+// holds the transactions of the bundles.
+func pendingAt(state *state.StateDB, pending map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	filtered := make(map[common.Address]types.Transactions, len(pending))
+	for addr, txs := range pending {
+		nonce := state.GetNonce(addr)
+		for len(txs) > 0 && txs[0].Nonce() < nonce {
+			txs = txs[1:]
+		}
+		if len(txs) > 0 && txs[0].Nonce() == nonce {
+			filtered[addr] = txs
+		}
+	}
+	return filtered
 }
 
-func (w *worker) buildBlockFromBundles(ctx context.Context, args *types.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error) {
+// bundlePaymentMaxGas is the most gas a refund or proposer payment to a contract may use.
+const bundlePaymentMaxGas = 100000
+
+// buildBlockFromBundles applies the bundles on top of the parent block, in the
+// order the block building algorithm of the args chooses, paying the refunds
+// they require and sending the profit of the block to the fee recipient.
+// Bundles that can not be applied are skipped, unless they are required, and
+// the outcome of each bundle is reported.
+func (w *worker) buildBlockFromBundles(ctx context.Context, args *types.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
+	algorithm, err := blockBuildingAlgorithm(args.Algorithm)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// create ephemeral addr and private key for payment txn
 	ephemeralPrivKey, err := crypto.GenerateKey()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	ephemeralAddr := crypto.PubkeyToAddress(ephemeralPrivKey.PublicKey)
 
@@ -1296,97 +1485,515 @@ func (w *worker) buildBlockFromBundles(ctx context.Context, args *types.BuildBlo
 		withdrawals: args.Withdrawals,
 		noUncle:     true,
 		noTxs:       false,
+
+		excessBlobGas: args.ExcessBlobGas,
+		beaconRoot:    args.ParentBeaconBlockRoot,
 	}
 
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	defer work.discard()
 
-	// Assume static 28000 gas transfers for both mev-share and proposer payments
-	refundTransferCost := new(big.Int).Mul(big.NewInt(28000), work.header.BaseFee)
+	if err := checkCancunArgs(work.header, args); err != nil {
+		return nil, nil, nil, err
+	}
+
+	work.gasPool = suaveGasPool(work.header, args.GasLimit)
+
+	// Bundles referenced by hash are applied as part of the bundles referencing
+	// them, which can be included in the block
+	known := make(map[common.Hash]*types.SBundle, len(bundles))
+	for i := range bundles {
+		known[bundles[i].Hash()] = &bundles[i]
+	}
+	referenced, includable := make(map[common.Hash]bool), make(map[common.Hash]bool)
+	for i := range bundles {
+		collectBundleReferences(&bundles[i], known, referenced)
+		if bundles[i].IsValidAt(work.header.Number) {
+			collectBundleReferences(&bundles[i], known, includable)
+		}
+	}
 
-	profitPre := work.state.GetBalance(params.coinbase)
+	var (
+		profitPre  = work.state.GetBalance(params.coinbase)
+		builder    = newBundleBuilder(w, work, ephemeralPrivKey, known)
+		results    = make([]*types.BundleResult, len(bundles))
+		candidates = make([]*types.SBundle, 0, len(bundles))
+	)
+	for i := range bundles {
+		bundle := &bundles[i]
+		results[i] = builder.result(bundle)
 
-	for _, bundle := range bundles {
-		// NOTE: failing bundles will cause the block to not be built!
+		if err := bundle.ResolveReferences(known); err != nil {
+			if err := builder.skip(bundle, err); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
+		}
+		switch {
+		case referenced[results[i].Hash]:
+			// Applied by the bundles referencing it
+		case !bundle.IsValidAt(work.header.Number):
+			results[i].Error = fmt.Sprintf("bundle can not be included in block %d", work.header.Number)
+		default:
+			candidates = append(candidates, bundle)
+		}
+	}
+	if err := algorithm.Build(ctx, builder, candidates); err != nil {
+		return nil, nil, nil, err
+	}
+	work = builder.env
 
-		// apply bundle
-		profitPreBundle := work.state.GetBalance(params.coinbase)
-		if err := w.rawCommitTransactions(work, bundle.Txs); err != nil {
-			return nil, nil, err
+	// Referenced bundles are included if one of the bundles referencing them is,
+	// and fail like any other bundle otherwise
+	for i, result := range results {
+		if !referenced[result.Hash] || result.Error != "" {
+			continue
+		}
+		if result.Included = builder.applied[result.Hash]; result.Included {
+			continue
 		}
-		profitPostBundle := work.state.GetBalance(params.coinbase)
-
-		// calc & refund user if bundle has multiple txns and wants refund
-		if len(bundle.Txs) > 1 && bundle.RefundPercent != nil {
-			// Note: PoC logic, this could be gamed by not sending any eth to coinbase
-			refundPrct := *bundle.RefundPercent
-			if refundPrct == 0 {
-				// default refund
-				refundPrct = 10
+		err := errors.New("no bundle referencing the bundle was included")
+		if !includable[result.Hash] {
+			err = fmt.Errorf("no bundle referencing the bundle can be included in block %d", work.header.Number)
+		}
+		if err := builder.skip(&bundles[i], err); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	value := &types.BlockValue{
+		Bundles: balanceIncrease(profitPre, work.state.GetBalance(params.coinbase)),
+		Public:  new(big.Int),
+	}
+	if args.FillPending {
+		// Gas is kept for the proposer payment
+		value.Public = w.fillPendingTransactions(work, bundlePaymentMaxGas)
+	}
+
+	profit := new(big.Int).Add(value.Bundles, value.Public)
+	payment, err := w.payFromCoinbase(work, ephemeralPrivKey, args.FeeRecipient, profit)
+	switch {
+	case err != nil:
+		return nil, nil, nil, fmt.Errorf("could not pay the proposer: %w", err)
+	case payment == nil:
+		// The profit does not cover the payment
+		value.Profit = new(big.Int)
+	default:
+		value.Profit = payment.Value.ToInt()
+	}
+
+	log.Info("buildBlockFromBundles", "num_bundles", len(bundles), "num_txns", len(work.txs), "profit", value.Profit, "public", value.Public)
+	setDataGasUsed(work)
+	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, params.withdrawals)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return block, value, results, nil
+}
+
+// collectBundleReferences marks the known bundles referenced by hash in the body
+// of the bundle or of its nested bundles.
+func collectBundleReferences(bundle *types.SBundle, known map[common.Hash]*types.SBundle, referenced map[common.Hash]bool) {
+	for _, body := range bundle.Body {
+		switch {
+		case body.Hash != nil:
+			if _, ok := known[*body.Hash]; ok {
+				referenced[*body.Hash] = true
 			}
-			bundleProfit := new(big.Int).Sub(profitPostBundle, profitPreBundle)
-			refundAmt := new(big.Int).Div(bundleProfit, big.NewInt(int64(refundPrct)))
-			// subtract payment txn transfer costs
-			refundAmt = new(big.Int).Sub(refundAmt, refundTransferCost)
-
-			currNonce := work.state.GetNonce(ephemeralAddr)
-			// HACK to include payment txn
-			// multi refund block untested
-			bidTx := bundle.Txs[0] // NOTE : assumes first txn is refund recipient
-			refundAddr, err := types.Sender(types.LatestSignerForChainID(bidTx.ChainId()), bidTx)
+		case body.Bundle != nil:
+			collectBundleReferences(body.Bundle, known, referenced)
+		}
+	}
+}
+
+// bundleBody returns the body of the bundle, made of its transactions for
+// bundles in the simplified format.
+func bundleBody(bundle *types.SBundle) []types.SBundleBody {
+	if bundle.IsMevShare() {
+		return bundle.Body
+	}
+
+	body := make([]types.SBundleBody, 0, len(bundle.Txs))
+	for _, tx := range bundle.Txs {
+		body = append(body, types.SBundleBody{Tx: tx, CanRevert: slices.Contains(bundle.RevertingHashes, tx.Hash())})
+	}
+	return body
+}
+
+// bundleRefunds returns the refunds the bundle requires. Bundles in the
+// simplified format with more than one transaction refund their first one,
+// 10 percent of the profit unless the bundle says otherwise.
+func bundleRefunds(bundle *types.SBundle) []types.RefundConstraint {
+	if bundle.IsMevShare() {
+		if bundle.Validity == nil {
+			return nil
+		}
+		return bundle.Validity.Refund
+	}
+
+	if len(bundle.Txs) < 2 || bundle.RefundPercent == nil {
+		return nil
+	}
+	percent := *bundle.RefundPercent
+	if percent == 0 {
+		percent = 10
+	}
+	return []types.RefundConstraint{{BodyIdx: 0, Percent: percent}}
+}
+
+// refundAmounts splits percent of the profit between the recipients, each
+// getting their percent of it. The remainder of the divisions stays with the block.
+func refundAmounts(profit *big.Int, percent int, recipients []types.RefundConfig) []*big.Int {
+	refund := new(big.Int).Mul(profit, big.NewInt(int64(percent)))
+	refund.Div(refund, big.NewInt(100))
+
+	amounts := make([]*big.Int, len(recipients))
+	for i, recipient := range recipients {
+		amounts[i] = new(big.Int).Mul(refund, big.NewInt(int64(recipient.Percent)))
+		amounts[i].Div(amounts[i], big.NewInt(100))
+	}
+	return amounts
+}
+
+// paymentValue returns the value transferred by a payment of the amount, which
+// covers the gas cost of the transfer. Zero if the amount does not cover it.
+func paymentValue(amount *big.Int, gas uint64, baseFee *big.Int) (value *big.Int, gasCost *big.Int) {
+	gasCost = new(big.Int).Mul(new(big.Int).SetUint64(gas), baseFee)
+	if amount.Cmp(gasCost) <= 0 {
+		return new(big.Int), gasCost
+	}
+	return new(big.Int).Sub(amount, gasCost), gasCost
+}
+
+// commitBundle applies the body of the bundle, paying the refunds it requires
+// from its profit. It returns the refunds paid, and who is refunded when the
+// bundle is part of the body of another bundle. The hashes of the referenced
+// bundles applied are added to applied.
+func (w *worker) commitBundle(env *environment, bundle *types.SBundle, known map[common.Hash]*types.SBundle, key *ecdsa.PrivateKey, applied map[common.Hash]bool, depth int) ([]*types.BundleRefund, []types.RefundConfig, error) {
+	if depth > types.MaxSBundleDepth {
+		return nil, nil, fmt.Errorf("bundle nested more than %d levels deep", types.MaxSBundleDepth)
+	}
+	if !bundle.IsValidAt(env.header.Number) {
+		return nil, nil, fmt.Errorf("bundle %s can not be included in block %d", bundle.Hash(), env.header.Number)
+	}
+
+	body := bundleBody(bundle)
+	if len(body) == 0 {
+		return nil, nil, errors.New("bundle is empty")
+	}
+
+	var (
+		profitPre  = env.state.GetBalance(env.coinbase)
+		refunds    []*types.BundleRefund
+		recipients = make([][]types.RefundConfig, len(body))
+	)
+	for i, b := range body {
+		switch {
+		case b.Tx != nil:
+			if err := w.commitBundleTransaction(env, b.Tx, b.CanRevert); err != nil {
+				return nil, nil, err
+			}
+			sender, err := types.Sender(env.signer, b.Tx)
 			if err != nil {
 				return nil, nil, err
 			}
-			paymentTx, err := types.SignTx(types.NewTx(&types.LegacyTx{
-				Nonce:    currNonce,
-				To:       &refundAddr,
-				Value:    refundAmt,
-				Gas:      28000,
-				GasPrice: work.header.BaseFee,
-			}), work.signer, ephemeralPrivKey)
+			recipients[i] = []types.RefundConfig{{Address: sender, Percent: 100}}
 
+		case b.Hash != nil, b.Bundle != nil:
+			nested := b.Bundle
+			if b.Hash != nil {
+				if nested = known[*b.Hash]; nested == nil {
+					return nil, nil, fmt.Errorf("unknown bundle %s", b.Hash)
+				}
+				applied[*b.Hash] = true
+			}
+			nestedRefunds, nestedRecipients, err := w.commitBundle(env, nested, known, key, applied, depth+1)
 			if err != nil {
 				return nil, nil, err
 			}
+			refunds = append(refunds, nestedRefunds...)
+			recipients[i] = nestedRecipients
+		}
+	}
 
-			// commit payment txn
-			if err := w.rawCommitTransactions(work, types.Transactions{paymentTx}); err != nil {
-				return nil, nil, err
+	if constraints := bundleRefunds(bundle); len(constraints) > 0 {
+		profit := balanceIncrease(profitPre, env.state.GetBalance(env.coinbase))
+		for _, refund := range constraints {
+			if refund.BodyIdx < 0 || refund.BodyIdx >= len(recipients) {
+				return nil, nil, fmt.Errorf("refund to body %d out of range", refund.BodyIdx)
+			}
+
+			amounts := refundAmounts(profit, refund.Percent, recipients[refund.BodyIdx])
+			for j, recipient := range recipients[refund.BodyIdx] {
+				payment, err := w.payFromCoinbase(env, key, recipient.Address, amounts[j])
+				if err != nil {
+					return nil, nil, fmt.Errorf("could not refund %s: %w", recipient.Address, err)
+				}
+				if payment != nil {
+					refunds = append(refunds, payment)
+				}
 			}
 		}
 	}
 
-	profitPost := work.state.GetBalance(params.coinbase)
-	proposerProfit := new(big.Int).Set(profitPost) // = post-pre-transfer_cost
-	proposerProfit = proposerProfit.Sub(profitPost, profitPre)
-	proposerProfit = proposerProfit.Sub(proposerProfit, refundTransferCost)
+	if bundle.Validity != nil && len(bundle.Validity.RefundConfig) > 0 {
+		return refunds, bundle.Validity.RefundConfig, nil
+	}
+	return refunds, recipients[0], nil
+}
+
+// commitBundleTransaction applies a transaction of a bundle, failing if it
+// could not be included or reverted without being allowed to.
+func (w *worker) commitBundleTransaction(env *environment, tx *types.Transaction, canRevert bool) error {
+	// The blobs of the transaction are not part of the bundle, the block could
+	// not be submitted with them
+	if tx.Type() == types.BlobTxType {
+		return fmt.Errorf("transaction %s is a blob transaction, not supported in bundles", tx.Hash())
+	}
+	included := len(env.receipts)
+	if err := w.rawCommitTransactions(env, types.Transactions{tx}); err != nil {
+		return fmt.Errorf("transaction %s could not be applied: %w", tx.Hash(), err)
+	}
+	if len(env.receipts) == included {
+		return fmt.Errorf("transaction %s could not be applied: not enough gas", tx.Hash())
+	}
+	if receipt := env.receipts[len(env.receipts)-1]; receipt.Status == types.ReceiptStatusFailed && !canRevert {
+		return fmt.Errorf("transaction %s reverted", tx.Hash())
+	}
+	return nil
+}
+
+// payFromCoinbase transfers the amount from the coinbase, whose key is given,
+// to the recipient. The gas cost of the transfer is deducted from the amount so
+// that at most the amount leaves the coinbase, exactly the amount unless the
+// transfer uses less than its gas limit. Nothing is transferred if the amount
+// does not cover the gas cost, in which case the payment is nil.
+func (w *worker) payFromCoinbase(env *environment, key *ecdsa.PrivateKey, to common.Address, amount *big.Int) (*types.BundleRefund, error) {
+	gas, err := w.paymentGas(env, key, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := paymentValue(amount, gas, env.header.BaseFee)
+	if value.Sign() == 0 {
+		return nil, nil
+	}
 
-	currNonce := work.state.GetNonce(ephemeralAddr)
-	paymentTx, err := types.SignTx(types.NewTx(&types.LegacyTx{
-		Nonce:    currNonce,
-		To:       &args.FeeRecipient,
-		Value:    proposerProfit,
-		Gas:      28000,
-		GasPrice: work.header.BaseFee,
-	}), work.signer, ephemeralPrivKey)
+	paymentTx, err := w.signPayment(env, key, to, value, gas)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not sign proposer payment: %w", err)
+		return nil, err
+	}
+	if err := w.commitBundleTransaction(env, paymentTx, false); err != nil {
+		return nil, err
 	}
+	receipt := env.receipts[len(env.receipts)-1]
+	gasCost := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), env.header.BaseFee)
+	return &types.BundleRefund{Recipient: to, Value: (*hexutil.Big)(value), GasCost: (*hexutil.Big)(gasCost)}, nil
+}
 
-	// commit payment txn
-	if err := w.rawCommitTransactions(work, types.Transactions{paymentTx}); err != nil {
-		return nil, nil, fmt.Errorf("could not sign proposer payment: %w", err)
+// paymentGas returns the gas limit of a transfer of the amount to the recipient.
+// Transfers to contracts and precompiles are simulated, and given the gas they
+// used before refunds with headroom for the gas calls keep, since the gas they
+// use net of refunds may not be enough to run them. Their gas limit is checked
+// by simulating them with it, and is the most gas the amount pays for, up to
+// bundlePaymentMaxGas, if that fails.
+func (w *worker) paymentGas(env *environment, key *ecdsa.PrivateKey, to common.Address, amount *big.Int) (uint64, error) {
+	rules := w.chainConfig.Rules(env.header.Number, env.header.Difficulty.Sign() == 0, env.header.Time)
+	if env.state.GetCodeSize(to) == 0 && !slices.Contains(vm.ActivePrecompiles(rules), to) {
+		return params.TxGas, nil
+	}
+
+	// Some value must be left to transfer once the gas is paid for
+	maxGas := uint64(bundlePaymentMaxGas)
+	if affordable := new(big.Int).Div(amount, env.header.BaseFee); affordable.IsUint64() && affordable.Uint64() <= maxGas {
+		if affordable.Uint64() <= params.TxGas {
+			// Not even a plain transfer is paid for
+			return params.TxGas, nil
+		}
+		maxGas = affordable.Uint64() - 1
 	}
 
-	log.Info("buildBlockFromBundles", "num_bundles", len(bundles), "num_txns", len(work.txs), "profit", proposerProfit)
-	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, params.withdrawals)
+	gasBeforeRefund, err := w.simulatePayment(env, key, to, amount, maxGas)
 	if err != nil {
-		return nil, nil, err
+		return 0, err
+	}
+	gas := gasBeforeRefund + gasBeforeRefund/paymentGasHeadroomQuotient
+	if gas >= maxGas {
+		return maxGas, nil
+	}
+	if _, err := w.simulatePayment(env, key, to, amount, gas); err != nil {
+		return maxGas, nil
+	}
+	return gas, nil
+}
+
+// paymentGasHeadroomQuotient bounds the headroom given to the gas of a transfer
+// to a contract, as a fraction of the gas it used.
+const paymentGasHeadroomQuotient = 16
+
+// simulatePayment simulates the transfer of the amount to the recipient with
+// the gas limit, and returns the gas it used before refunds. The environment is
+// left unchanged.
+func (w *worker) simulatePayment(env *environment, key *ecdsa.PrivateKey, to common.Address, amount *big.Int, gas uint64) (uint64, error) {
+	value, _ := paymentValue(amount, gas, env.header.BaseFee)
+	if value.Sign() == 0 {
+		return params.TxGas, nil
+	}
+	paymentTx, err := w.signPayment(env, key, to, value, gas)
+	if err != nil {
+		return 0, err
+	}
+	msg, err := core.TransactionToMessage(paymentTx, env.signer, env.header.BaseFee)
+	if err != nil {
+		return 0, err
+	}
+
+	snap := env.state.Snapshot()
+	defer env.state.RevertToSnapshot(snap)
+
+	gasPool := new(core.GasPool).AddGas(env.header.GasLimit)
+	if env.gasPool != nil {
+		gasPool = new(core.GasPool).AddGas(env.gasPool.Gas())
+	}
+	env.state.SetTxContext(paymentTx.Hash(), env.tcount)
+	blockContext := core.NewEVMBlockContext(env.header, w.chain, &env.coinbase)
+	evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), env.state, w.chainConfig, *w.chain.GetVMConfig())
+
+	result, err := core.ApplyMessage(evm, msg, gasPool)
+	if err != nil {
+		return 0, err
+	}
+	if result.Failed() {
+		return 0, fmt.Errorf("payment to %s failed: %w", to, result.Err)
+	}
+	// The refund counter is only cleared once the transaction is finalised
+	return result.UsedGas + env.state.GetRefund(), nil
+}
+
+func (w *worker) signPayment(env *environment, key *ecdsa.PrivateKey, to common.Address, value *big.Int, gas uint64) (*types.Transaction, error) {
+	return types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    env.state.GetNonce(crypto.PubkeyToAddress(key.PublicKey)),
+		To:       &to,
+		Value:    value,
+		Gas:      gas,
+		GasPrice: env.header.BaseFee,
+	}), env.signer, key)
+}
+
+// simulateBundle applies the transactions of the bundle on top of the parent
+// block and reports the result of each of them. A failing transaction makes the
+// bundle fail unless it reverted and its hash is in the bundle's reverting hashes.
+func (w *worker) simulateBundle(ctx context.Context, args *types.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	params := &generateParams{
+		timestamp:   args.Timestamp,
+		forceTime:   true,
+		parentHash:  args.Parent,
+		coinbase:    args.FeeRecipient,
+		gasLimit:    args.GasLimit,
+		random:      args.Random,
+		withdrawals: args.Withdrawals,
+		noUncle:     true,
+		noTxs:       false,
+
+		excessBlobGas: args.ExcessBlobGas,
+		beaconRoot:    args.ParentBeaconBlockRoot,
+	}
+
+	work, err := w.prepareWork(params)
+	if err != nil {
+		return nil, err
+	}
+	defer work.discard()
+
+	work.gasPool = suaveGasPool(work.header, args.GasLimit)
+
+	result := &types.SimulatedBundle{Success: true}
+	profitPre := work.state.GetBalance(work.coinbase)
+
+	for _, tx := range bundle.Txs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		txResult, err := w.simulateTransaction(work, tx)
+		result.Txs = append(result.Txs, txResult)
+		result.GasUsed += txResult.GasUsed
+
+		if !result.Success {
+			continue
+		}
+		if err != nil {
+			// Transactions that can not be included always fail the bundle
+			result.Success = false
+			result.Error = fmt.Sprintf("transaction %s could not be applied: %s", tx.Hash(), txResult.Error)
+		} else if !txResult.Success && !slices.Contains(bundle.RevertingHashes, tx.Hash()) {
+			result.Success = false
+			result.Error = fmt.Sprintf("transaction %s reverted: %s", tx.Hash(), txResult.Error)
+		}
+	}
+
+	result.CoinbaseDelta = balanceIncrease(profitPre, work.state.GetBalance(work.coinbase))
+	return result, nil
+}
+
+// simulateTransaction applies the transaction to the environment and reports
+// its result. The error is set if the transaction could not be applied at all,
+// in which case the environment is left unchanged.
+func (w *worker) simulateTransaction(env *environment, tx *types.Transaction) (*types.SimulatedTransaction, error) {
+	result := &types.SimulatedTransaction{TxHash: tx.Hash(), CoinbaseDelta: new(big.Int)}
+
+	msg, err := core.TransactionToMessage(tx, env.signer, env.header.BaseFee)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	var (
+		snap       = env.state.Snapshot()
+		gp         = env.gasPool.Gas()
+		balancePre = env.state.GetBalance(env.coinbase)
+	)
+
+	env.state.SetTxContext(tx.Hash(), env.tcount)
+	blockContext := core.NewEVMBlockContext(env.header, w.chain, &env.coinbase)
+	evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), env.state, w.chainConfig, *w.chain.GetVMConfig())
+
+	execResult, err := core.ApplyMessage(evm, msg, env.gasPool)
+	if err != nil {
+		env.state.RevertToSnapshot(snap)
+		env.gasPool.SetGas(gp)
+		result.Error = err.Error()
+		return result, err
+	}
+	env.state.Finalise(w.chainConfig.IsEIP158(env.header.Number))
+	env.header.GasUsed += execResult.UsedGas
+	env.tcount++
+
+	result.Success = !execResult.Failed()
+	result.GasUsed = execResult.UsedGas
+	result.CoinbaseDelta = balanceIncrease(balancePre, env.state.GetBalance(env.coinbase))
+
+	if execResult.Failed() {
+		result.Error = execResult.Err.Error()
+		if reason, err := abi.UnpackRevert(execResult.Revert()); err == nil {
+			result.Error += ": " + reason
+		}
+	}
+
+	for _, l := range env.state.GetLogs(tx.Hash(), env.header.Number.Uint64(), common.Hash{}) {
+		result.Logs = append(result.Logs, &types.SimulatedLog{Addr: l.Address, Topics: l.Topics, Data: l.Data})
+	}
+	return result, nil
+}
+
+// balanceIncrease returns how much the balance increased, zero if it decreased.
+func balanceIncrease(pre, post *big.Int) *big.Int {
+	if post.Cmp(pre) <= 0 {
+		return new(big.Int)
 	}
-	return block, proposerProfit, nil
+	return new(big.Int).Sub(post, pre)
 }
 
 func (w *worker) rawCommitTransactions(env *environment, txs types.Transactions) error {
@@ -17,14 +17,17 @@
 package miner
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"math/big"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/clique"
@@ -39,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
 )
 
 const (
@@ -116,11 +120,14 @@ type testWorkerBackend struct {
 	uncleBlock *types.Block
 }
 
-func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, n int) *testWorkerBackend {
+func newTestWorkerBackend(t testing.TB, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, n int) *testWorkerBackend {
 	var gspec = &core.Genesis{
 		Config: chainConfig,
 		Alloc:  core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
 	}
+	for address, account := range testSearcherAlloc {
+		gspec.Alloc[address] = account
+	}
 	switch e := engine.(type) {
 	case *clique.Clique:
 		gspec.ExtraData = make([]byte, 32+common.AddressLength+crypto.SignatureLength)
@@ -200,7 +207,7 @@ func (b *testWorkerBackend) newRandomTx(creation bool) *types.Transaction {
 	return tx
 }
 
-func newTestWorker(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, blocks int) (*worker, *testWorkerBackend) {
+func newTestWorker(t testing.TB, chainConfig *params.ChainConfig, engine consensus.Engine, db ethdb.Database, blocks int) (*worker, *testWorkerBackend) {
 	backend := newTestWorkerBackend(t, chainConfig, engine, db, blocks)
 	backend.txPool.AddLocals(pendingTxs)
 	w := newWorker(testConfig, chainConfig, engine, backend, new(event.TypeMux), nil, false)
@@ -660,3 +667,564 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+func TestSimulateBundle(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		coinbase = common.Address{0x42}
+		gasPrice = big.NewInt(2 * params.InitialBaseFee)
+		head     = b.chain.CurrentBlock()
+		args     = &types.BuildBlockArgs{
+			Parent:       head.Hash(),
+			Timestamp:    head.Time + 12,
+			FeeRecipient: coinbase,
+			GasLimit:     params.GenesisGasLimit,
+		}
+	)
+
+	newTx := func(nonce uint64, to *common.Address, data []byte) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       to,
+			Value:    big.NewInt(0),
+			Gas:      100000,
+			GasPrice: gasPrice,
+			Data:     data,
+		})
+	}
+
+	var (
+		transferTx = newTx(0, &testUserAddress, nil)
+		// PUSH1 0 PUSH1 0 LOG0
+		logTx = newTx(1, nil, common.FromHex("0x60006000a0"))
+		// Reverts with Error("nope"), copied from the end of the code
+		revertTx = newTx(2, nil, common.FromHex("0x6064600c60003960646000fd"+
+			"08c379a0"+
+			"0000000000000000000000000000000000000000000000000000000000000020"+
+			"0000000000000000000000000000000000000000000000000000000000000004"+
+			"6e6f706500000000000000000000000000000000000000000000000000000000"))
+	)
+
+	result, err := w.simulateBundle(context.Background(), args, types.SBundle{Txs: types.Transactions{transferTx, logTx, revertTx}})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if result.Success {
+		t.Fatal("bundle with a reverting transaction succeeded")
+	}
+	if len(result.Txs) != 3 {
+		t.Fatalf("unexpected number of transaction results: have %d, want 3", len(result.Txs))
+	}
+
+	totalGas, totalDelta := uint64(0), new(big.Int)
+	for i, tx := range []*types.Transaction{transferTx, logTx, revertTx} {
+		txResult := result.Txs[i]
+		if txResult.TxHash != tx.Hash() {
+			t.Errorf("tx %d: unexpected hash: have %s, want %s", i, txResult.TxHash, tx.Hash())
+		}
+		if txResult.GasUsed == 0 || txResult.CoinbaseDelta.Sign() <= 0 {
+			t.Errorf("tx %d: expected gas used and coinbase payment, have %d and %s", i, txResult.GasUsed, txResult.CoinbaseDelta)
+		}
+		totalGas += txResult.GasUsed
+		totalDelta.Add(totalDelta, txResult.CoinbaseDelta)
+	}
+	if result.GasUsed != totalGas || result.CoinbaseDelta.Cmp(totalDelta) != 0 {
+		t.Errorf("bundle totals mismatch: have %d and %s, want %d and %s", result.GasUsed, result.CoinbaseDelta, totalGas, totalDelta)
+	}
+
+	if !result.Txs[0].Success || result.Txs[0].GasUsed != params.TxGas {
+		t.Errorf("unexpected transfer result: %+v", result.Txs[0])
+	}
+	logs := result.Txs[1].Logs
+	if !result.Txs[1].Success || len(logs) != 1 || logs[0].Addr != crypto.CreateAddress(testBankAddress, 1) {
+		t.Errorf("unexpected log result: %+v", result.Txs[1])
+	}
+	if result.Txs[2].Success || result.Txs[2].Error != "execution reverted: nope" {
+		t.Errorf("unexpected revert result: %+v", result.Txs[2])
+	}
+
+	// The reverting transaction is allowed to revert
+	result, err = w.simulateBundle(context.Background(), args, types.SBundle{
+		Txs:             types.Transactions{transferTx, logTx, revertTx},
+		RevertingHashes: []common.Hash{revertTx.Hash()},
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if !result.Success || result.Error != "" {
+		t.Errorf("bundle allowed to revert failed: %s", result.Error)
+	}
+
+	// Transactions that can not be applied fail the bundle, even if allowed to revert
+	invalidTx := newTx(5, &testUserAddress, nil)
+	result, err = w.simulateBundle(context.Background(), args, types.SBundle{
+		Txs:             types.Transactions{invalidTx},
+		RevertingHashes: []common.Hash{invalidTx.Hash()},
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if result.Success || result.Txs[0].GasUsed != 0 || !strings.Contains(result.Txs[0].Error, "nonce too high") {
+		t.Errorf("unexpected invalid transaction result: %+v", result.Txs[0])
+	}
+}
+
+func TestBuildBlockFromMevShareBundles(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer = types.LatestSigner(ethashChainConfig)
+		head   = b.chain.CurrentBlock()
+		number = new(big.Int).Add(head.Number, common.Big1)
+		args   = &types.BuildBlockArgs{
+			Parent:       head.Hash(),
+			Timestamp:    head.Time + 12,
+			FeeRecipient: common.Address{0x42},
+			GasLimit:     params.GenesisGasLimit,
+		}
+		recipient1, recipient2 = common.Address{0x11}, common.Address{0x12}
+	)
+
+	newTx := func(nonce uint64, to *common.Address, gasPrice *big.Int, data []byte) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       to,
+			Value:    big.NewInt(0),
+			Gas:      100000,
+			GasPrice: gasPrice,
+			Data:     data,
+		})
+	}
+
+	userTx := newTx(0, &testUserAddress, big.NewInt(params.InitialBaseFee), nil)
+	backrunTx := newTx(1, &testUserAddress, big.NewInt(params.GWei*1000), nil)
+	// PUSH1 0 PUSH1 0 REVERT
+	revertTx := newTx(2, nil, big.NewInt(params.InitialBaseFee), common.FromHex("0x60006000fd"))
+
+	userBundle := types.SBundle{
+		Version:     types.SBundleVersion,
+		BlockNumber: number,
+		Body:        []types.SBundleBody{{Tx: userTx}},
+		Validity: &types.BundleValidity{RefundConfig: []types.RefundConfig{
+			{Address: recipient1, Percent: 60},
+			{Address: recipient2, Percent: 40},
+		}},
+	}
+	userHash := userBundle.Hash()
+	backrunBundle := types.SBundle{
+		Version:     types.SBundleVersion,
+		BlockNumber: number,
+		Body:        []types.SBundleBody{{Hash: &userHash}, {Tx: backrunTx}},
+		Validity:    &types.BundleValidity{Refund: []types.RefundConstraint{{BodyIdx: 0, Percent: 50}}},
+	}
+	futureBundle := types.SBundle{
+		Version:     types.SBundleVersion,
+		BlockNumber: new(big.Int).Add(number, common.Big1),
+		Body:        []types.SBundleBody{{Tx: revertTx, CanRevert: true}},
+	}
+
+	block, _, results, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{userBundle, backrunBundle, futureBundle})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+
+	// The user bundle is only applied as part of the backrun, the future bundle is skipped
+	txs := block.Transactions()
+	if len(txs) != 5 {
+		t.Fatalf("unexpected number of transactions: have %d, want 5", len(txs))
+	}
+	if txs[0].Hash() != userTx.Hash() || txs[1].Hash() != backrunTx.Hash() {
+		t.Fatalf("unexpected bundle transactions: have %s and %s", txs[0].Hash(), txs[1].Hash())
+	}
+	if len(results) != 3 || !results[0].Included || !results[1].Included || results[2].Included || results[2].Error == "" {
+		t.Fatalf("unexpected bundle results: %+v", results)
+	}
+
+	// Half the profit of the backrun bundle is refunded, split according to the
+	// refund config, and the gas of each transfer is paid from the refund
+	var (
+		baseFee      = block.BaseFee()
+		transferCost = new(big.Int).Mul(big.NewInt(int64(params.TxGas)), baseFee)
+		profit       = new(big.Int)
+	)
+	for _, tx := range txs[:2] {
+		tip := new(big.Int).Sub(tx.GasPrice(), baseFee)
+		profit.Add(profit, tip.Mul(tip, big.NewInt(int64(params.TxGas))))
+	}
+	refunded := new(big.Int)
+	for i, refund := range []struct {
+		to      common.Address
+		percent int64
+	}{{recipient1, 60}, {recipient2, 40}} {
+		want := new(big.Int).Div(new(big.Int).Mul(profit, big.NewInt(50)), big.NewInt(100))
+		want.Div(want.Mul(want, big.NewInt(refund.percent)), big.NewInt(100))
+		refunded.Add(refunded, want)
+		want.Sub(want, transferCost)
+
+		tx := txs[2+i]
+		if *tx.To() != refund.to || tx.Value().Cmp(want) != 0 {
+			t.Errorf("unexpected refund %d: have %d to %s, want %d to %s", i, tx.Value(), tx.To(), want, refund.to)
+		}
+		reported := results[1].Refunds[i]
+		if reported.Recipient != refund.to || reported.Value.ToInt().Cmp(want) != 0 || reported.GasCost.ToInt().Cmp(transferCost) != 0 {
+			t.Errorf("unexpected reported refund %d: %+v", i, reported)
+		}
+	}
+	if want := new(big.Int).Sub(profit, refunded); results[1].Profit.ToInt().Cmp(want) != 0 {
+		t.Errorf("unexpected bundle profit: have %d, want %d", results[1].Profit.ToInt(), want)
+	}
+	if *txs[4].To() != args.FeeRecipient {
+		t.Errorf("unexpected proposer payment recipient: %s", txs[4].To())
+	}
+
+	// Transactions may only revert if the bundle allows them to, failing bundles are skipped
+	revertBundle := types.SBundle{
+		Version:     types.SBundleVersion,
+		BlockNumber: number,
+		Body:        []types.SBundleBody{{Tx: userTx}, {Tx: backrunTx}, {Tx: revertTx}},
+	}
+	block, _, results, err = w.buildBlockFromBundles(context.Background(), args, []types.SBundle{revertBundle})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if results[0].Included || !strings.Contains(results[0].Error, "reverted") || len(block.Transactions()) != 0 {
+		t.Errorf("expected the reverting bundle to be skipped, got %+v", results[0])
+	}
+	revertBundle.Body[2].CanRevert = true
+	if _, _, results, err = w.buildBlockFromBundles(context.Background(), args, []types.SBundle{revertBundle}); err != nil || !results[0].Included {
+		t.Errorf("failed to build block with a transaction allowed to revert: %v", err)
+	}
+
+	// Required bundles fail the block
+	revertBundle.Body[2].CanRevert = false
+	revertBundle.Required = true
+	if _, _, _, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{revertBundle}); err == nil || !strings.Contains(err.Error(), "reverted") {
+		t.Errorf("expected the required reverting bundle to fail the block, got %v", err)
+	}
+
+	// Bundles referencing unknown bundles can not be applied
+	unknown := common.Hash{0x1}
+	unknownBundle := types.SBundle{Version: types.SBundleVersion, BlockNumber: number, Body: []types.SBundleBody{{Hash: &unknown}}}
+	if _, _, results, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{unknownBundle}); err != nil || !strings.Contains(results[0].Error, "unknown bundle") {
+		t.Errorf("expected the unknown bundle reference to be skipped, got %v", err)
+	}
+
+	// Bundles only referenced by bundles which can not be included fail
+	backrunBundle.BlockNumber = futureBundle.BlockNumber
+	if _, _, results, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{userBundle, backrunBundle}); err != nil || results[0].Included || !strings.Contains(results[0].Error, "can be included in block") {
+		t.Errorf("expected the bundle referenced by a future bundle to be skipped, got %v", err)
+	}
+	userBundle.Required = true
+	if _, _, _, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{userBundle, backrunBundle}); err == nil {
+		t.Errorf("expected the required bundle referenced by a future bundle to fail the block")
+	}
+}
+
+func TestBuildBlockFromBundlesSkipsFailedBundles(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer = types.LatestSigner(ethashChainConfig)
+		head   = b.chain.CurrentBlock()
+		args   = &types.BuildBlockArgs{
+			Parent:       head.Hash(),
+			Timestamp:    head.Time + 12,
+			FeeRecipient: common.Address{0x42},
+			GasLimit:     params.GenesisGasLimit,
+		}
+	)
+
+	newTx := func(nonce uint64, data []byte) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(0),
+			Gas:      100000,
+			GasPrice: big.NewInt(params.GWei * 10),
+			Data:     data,
+		})
+	}
+
+	// The first transaction of the failing bundle is rolled back with it,
+	// so that the nonces of the next bundle still apply
+	failing := types.SBundle{Txs: types.Transactions{newTx(0, nil), newTx(5, nil)}}
+	valid := types.SBundle{Txs: types.Transactions{newTx(0, nil), newTx(1, nil)}}
+
+	block, value, results, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{failing, valid})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if len(block.Transactions()) != 3 || block.Transactions()[0].Hash() != valid.Txs[0].Hash() {
+		t.Fatalf("unexpected block transactions: %d", len(block.Transactions()))
+	}
+	if results[0].Included || !strings.Contains(results[0].Error, "nonce too high") {
+		t.Errorf("unexpected failed bundle result: %+v", results[0])
+	}
+	if !results[1].Included || uint64(results[1].GasUsed) != 2*params.TxGas {
+		t.Errorf("unexpected bundle result: %+v", results[1])
+	}
+
+	// The proposer is paid the profit of the block less the gas of the payment
+	payment := new(big.Int).Mul(big.NewInt(int64(params.TxGas)), block.BaseFee())
+	if want := new(big.Int).Sub(results[1].Profit.ToInt(), payment); value.Profit.Cmp(want) != 0 {
+		t.Errorf("unexpected proposer profit: have %d, want %d", value.Profit, want)
+	}
+	if value.Bundles.Cmp(results[1].Profit.ToInt()) != 0 || value.Public.Sign() != 0 {
+		t.Errorf("unexpected block value: %+v", value)
+	}
+}
+
+func TestBuildBlockFillPending(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	// The txpool holds the pending transaction of the bank
+	bundle := newSearcherBundle(0, testUserAddress, params.GWei)
+	args := newBundleBuildArgs(w, "")
+	args.FillPending = true
+
+	block, value, _, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{bundle})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	txs := block.Transactions()
+	if len(txs) != 3 || txs[0].Hash() != bundle.Txs[0].Hash() || txs[1].Hash() != pendingTxs[0].Hash() {
+		t.Fatalf("unexpected block transactions: %d", len(txs))
+	}
+	if *txs[2].To() != args.FeeRecipient {
+		t.Errorf("unexpected proposer payment recipient: %s", txs[2].To())
+	}
+
+	// The value of the bundles and of the txpool transactions is reported apart
+	tip := func(tx *types.Transaction) *big.Int {
+		tip := new(big.Int).Sub(tx.GasPrice(), block.BaseFee())
+		return tip.Mul(tip, big.NewInt(int64(params.TxGas)))
+	}
+	if value.Bundles.Cmp(tip(txs[0])) != 0 || value.Public.Cmp(tip(txs[1])) != 0 {
+		t.Errorf("unexpected block value: bundles %d, public %d", value.Bundles, value.Public)
+	}
+	payment := new(big.Int).Mul(big.NewInt(int64(params.TxGas)), block.BaseFee())
+	if want := new(big.Int).Sub(new(big.Int).Add(value.Bundles, value.Public), payment); value.Profit.Cmp(want) != 0 {
+		t.Errorf("unexpected proposer profit: have %d, want %d", value.Profit, want)
+	}
+
+	// Blocks built from transactions are filled the same way
+	block, value, err = w.buildBlockFromTxs(context.Background(), args, bundle.Txs)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if len(block.Transactions()) != 2 || value.Public.Cmp(tip(pendingTxs[0])) != 0 || value.Profit.Cmp(new(big.Int).Add(value.Bundles, value.Public)) != 0 {
+		t.Errorf("unexpected block from transactions: %d transactions, value %+v", len(block.Transactions()), value)
+	}
+
+	// Nothing is taken from the txpool unless asked for
+	args.FillPending = false
+	if block, _, _, err = w.buildBlockFromBundles(context.Background(), args, []types.SBundle{bundle}); err != nil || len(block.Transactions()) != 2 {
+		t.Errorf("unexpected block without filling: %v", err)
+	}
+}
+
+func TestBuildBlockPaysRefundingContract(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	// The payment uses less gas net of the refund than it needs to run
+	bundle := newSearcherBundle(0, testUserAddress, params.GWei)
+	args := newBundleBuildArgs(w, "")
+	args.FeeRecipient = testRefundingAddress
+
+	block, value, results, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{bundle})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	txs := block.Transactions()
+	if len(txs) != 2 || *txs[1].To() != testRefundingAddress || txs[1].Value().Cmp(value.Profit) != 0 {
+		t.Fatalf("unexpected block transactions: %d", len(txs))
+	}
+	if value.Profit.Sign() <= 0 {
+		t.Fatalf("unexpected proposer profit: %d", value.Profit)
+	}
+
+	// At most the profit of the block leaves the coinbase
+	paymentGas := block.GasUsed() - params.TxGas
+	if paymentGas >= txs[1].Gas() {
+		t.Errorf("payment gas used %d, limit %d", paymentGas, txs[1].Gas())
+	}
+	paid := new(big.Int).Add(value.Profit, new(big.Int).Mul(new(big.Int).SetUint64(paymentGas), block.BaseFee()))
+	if paid.Cmp(results[0].Profit.ToInt()) > 0 {
+		t.Errorf("paid %d, more than the profit %d", paid, results[0].Profit.ToInt())
+	}
+}
+
+func TestPendingAt(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetNonce(testBankAddress, 1)
+	statedb.SetNonce(testUserAddress, 3)
+
+	newTxs := func(nonces ...uint64) types.Transactions {
+		var txs types.Transactions
+		for _, nonce := range nonces {
+			txs = append(txs, types.NewTransaction(nonce, common.Address{}, common.Big0, params.TxGas, common.Big1, nil))
+		}
+		return txs
+	}
+	pending := map[common.Address]types.Transactions{
+		testBankAddress: newTxs(0, 1, 2), // Nonce 0 is used in the state
+		testUserAddress: newTxs(1, 2),    // All nonces are used in the state
+		{0x1}:           newTxs(1, 2),    // Nonce 0 is missing
+	}
+	filtered := pendingAt(statedb, pending)
+	if len(filtered) != 1 || len(filtered[testBankAddress]) != 2 || filtered[testBankAddress][0].Nonce() != 1 {
+		t.Errorf("unexpected pending transactions: %v", filtered)
+	}
+}
+
+func TestBuildBlockCancun(t *testing.T) {
+	ethashEngine := ethash.NewFaker()
+	defer ethashEngine.Close()
+
+	// Blocks are only built, ethash does not need to verify them
+	cancunTime := uint64(1)
+	chainConfig := *ethashChainConfig
+	chainConfig.ShanghaiTime = &cancunTime
+	chainConfig.CancunTime = &cancunTime
+
+	w, _ := newTestWorker(t, &chainConfig, ethashEngine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	bundle := newSearcherBundle(0, testUserAddress, params.GWei)
+	args := newBundleBuildArgs(w, "")
+	args.ExcessBlobGas = 2 * params.BlobTxDataGasPerBlob
+
+	// The root of the parent beacon block is required past Cancun
+	if _, _, _, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{bundle}); err == nil || !strings.Contains(err.Error(), "missing parent beacon block root") {
+		t.Fatalf("expected missing parent beacon block root, got %v", err)
+	}
+
+	args.ParentBeaconBlockRoot = common.Hash{0x1}
+	block, value, _, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{bundle})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if block.Header().ExcessDataGas == nil || block.Header().ExcessDataGas.Uint64() != args.ExcessBlobGas {
+		t.Errorf("unexpected excess data gas: %v", block.Header().ExcessDataGas)
+	}
+	if root := block.Header().ParentBeaconRoot; root == nil || *root != args.ParentBeaconBlockRoot {
+		t.Errorf("unexpected parent beacon root: %v", root)
+	}
+	if used := block.Header().DataGasUsed; used == nil || *used != 0 {
+		t.Errorf("unexpected data gas used: %v", used)
+	}
+
+	envelope := engine.BlockToExecutableData(block, value.Profit)
+	if data := envelope.ExecutionPayload; data.ExcessBlobGas == nil || *data.ExcessBlobGas != args.ExcessBlobGas || data.BlobGasUsed == nil || *data.BlobGasUsed != 0 {
+		t.Errorf("unexpected blob gas fields: %v, %v", data.ExcessBlobGas, data.BlobGasUsed)
+	}
+	if envelope.BlobsBundle == nil || len(envelope.BlobsBundle.Blobs) != 0 {
+		t.Errorf("unexpected blobs bundle: %v", envelope.BlobsBundle)
+	}
+	if root := envelope.ParentBeaconBlockRoot; root == nil || *root != args.ParentBeaconBlockRoot {
+		t.Errorf("unexpected envelope parent beacon root: %v", root)
+	}
+	// The block hash commits to the root, which is not part of the payload
+	if envelope.ExecutionPayload.BlockHash != block.Hash() {
+		t.Errorf("unexpected payload block hash: %x", envelope.ExecutionPayload.BlockHash)
+	}
+	header := block.Header()
+	header.ParentBeaconRoot = nil
+	if header.Hash() == block.Hash() {
+		t.Errorf("block hash does not commit to the parent beacon root")
+	}
+
+	// Blob transactions are not accepted in bundles
+	blobTx := types.MustSignNewTx(testSearcherKeys[1], types.LatestSigner(&chainConfig), &types.BlobTx{
+		ChainID:    uint256.MustFromBig(chainConfig.ChainID),
+		GasTipCap:  uint256.NewInt(params.GWei),
+		GasFeeCap:  uint256.NewInt(10 * params.GWei),
+		Gas:        params.TxGas,
+		To:         &testUserAddress,
+		Value:      uint256.NewInt(0),
+		BlobFeeCap: uint256.NewInt(params.GWei),
+		BlobHashes: []common.Hash{{0x1}},
+	})
+	_, _, results, err := w.buildBlockFromBundles(context.Background(), args, []types.SBundle{{Txs: types.Transactions{blobTx}}})
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if results[0].Included || !strings.Contains(results[0].Error, "blob transaction") {
+		t.Errorf("unexpected blob transaction bundle result: %+v", results[0])
+	}
+}
+
+func TestRefundAmounts(t *testing.T) {
+	recipients := []types.RefundConfig{{Address: common.Address{0x1}, Percent: 60}, {Address: common.Address{0x2}, Percent: 40}}
+
+	cases := []struct {
+		profit     int64
+		percent    int
+		recipients []types.RefundConfig
+		want       []int64
+	}{
+		{profit: 1000, percent: 100, recipients: recipients[:1], want: []int64{600}},
+		{profit: 1000, percent: 50, recipients: recipients, want: []int64{300, 200}},
+		{profit: 1000, percent: 0, recipients: recipients, want: []int64{0, 0}},
+		// The remainder of the divisions is not refunded
+		{profit: 999, percent: 50, recipients: recipients, want: []int64{299, 199}},
+		{profit: 7, percent: 10, recipients: recipients, want: []int64{0, 0}},
+		{profit: 0, percent: 90, recipients: recipients, want: []int64{0, 0}},
+	}
+	for i, c := range cases {
+		amounts := refundAmounts(big.NewInt(c.profit), c.percent, c.recipients)
+		if len(amounts) != len(c.want) {
+			t.Fatalf("case %d: unexpected number of refunds: have %d, want %d", i, len(amounts), len(c.want))
+		}
+		for j, amount := range amounts {
+			if amount.Int64() != c.want[j] {
+				t.Errorf("case %d: unexpected refund %d: have %d, want %d", i, j, amount, c.want[j])
+			}
+		}
+	}
+}
+
+func TestPaymentValue(t *testing.T) {
+	cases := []struct {
+		amount  int64
+		gas     uint64
+		baseFee int64
+		value   int64
+		gasCost int64
+	}{
+		{amount: 100000, gas: 21000, baseFee: 1, value: 79000, gasCost: 21000},
+		{amount: 100000, gas: 30000, baseFee: 2, value: 40000, gasCost: 60000},
+		// Amounts that do not cover the gas are not paid
+		{amount: 21000, gas: 21000, baseFee: 1, value: 0, gasCost: 21000},
+		{amount: 100, gas: 21000, baseFee: 1, value: 0, gasCost: 21000},
+		{amount: 0, gas: 21000, baseFee: 7, value: 0, gasCost: 147000},
+	}
+	for i, c := range cases {
+		value, gasCost := paymentValue(big.NewInt(c.amount), c.gas, big.NewInt(c.baseFee))
+		if value.Int64() != c.value || gasCost.Int64() != c.gasCost {
+			t.Errorf("case %d: have value %d and gas cost %d, want %d and %d", i, value, gasCost, c.value, c.gasCost)
+		}
+	}
+}
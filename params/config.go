@@ -336,6 +336,8 @@ type ChainConfig struct {
 	GrayGlacierBlock    *big.Int `json:"grayGlacierBlock,omitempty"`    // Eip-5133 (bomb delay) switch block (nil = no fork, 0 = already activated)
 	MergeNetsplitBlock  *big.Int `json:"mergeNetsplitBlock,omitempty"`  // Virtual fork after The Merge to use as a network splitter
 
+	SuaveValidationBlock *big.Int `json:"suaveValidationBlock,omitempty"` // SuaveTransaction validation switch block (nil = no fork, 0 = already activated)
+
 	// Fork scheduling was switched from blocks to timestamps here
 
 	ShanghaiTime *uint64 `json:"shanghaiTime,omitempty"` // Shanghai switch time (nil = no fork, 0 = already on shanghai)
@@ -354,6 +356,9 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+
+	// SUAVE specific parameters
+	Suave *SuaveConfig `json:"suave,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -375,6 +380,29 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// SuaveConfig holds the SUAVE specific chain parameters.
+type SuaveConfig struct {
+	// ExecutionNodes are the execution nodes trusted by the chain. Only they can
+	// register in the execution node registry, and only their results of
+	// confidential compute requests are accepted. Any execution node is trusted
+	// if empty.
+	ExecutionNodes []common.Address `json:"executionNodes,omitempty"`
+}
+
+// IsTrustedExecutionNode returns whether the execution node is trusted, which
+// all of them are if no execution node is configured.
+func (c *SuaveConfig) IsTrustedExecutionNode(node common.Address) bool {
+	if c == nil || len(c.ExecutionNodes) == 0 {
+		return true
+	}
+	for _, trusted := range c.ExecutionNodes {
+		if trusted == node {
+			return true
+		}
+	}
+	return false
+}
+
 // Description returns a human-readable description of ChainConfig.
 func (c *ChainConfig) Description() string {
 	var banner string
@@ -525,6 +553,12 @@ func (c *ChainConfig) IsSuave(num *big.Int) bool {
 	return isBlockForked(c.SuaveBlock, num)
 }
 
+// IsSuaveValidation returns whether num is either equal to the block from which
+// on the results of confidential compute requests are validated or greater.
+func (c *ChainConfig) IsSuaveValidation(num *big.Int) bool {
+	return isBlockForked(c.SuaveValidationBlock, num)
+}
+
 // IsLondon returns whether num is either equal to the London fork block or greater.
 func (c *ChainConfig) IsLondon(num *big.Int) bool {
 	return isBlockForked(c.LondonBlock, num)
@@ -702,6 +736,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkBlockIncompatible(c.LondonBlock, newcfg.LondonBlock, headNumber) {
 		return newBlockCompatError("London fork block", c.LondonBlock, newcfg.LondonBlock)
 	}
+	if isForkBlockIncompatible(c.SuaveValidationBlock, newcfg.SuaveValidationBlock, headNumber) {
+		return newBlockCompatError("SUAVE validation fork block", c.SuaveValidationBlock, newcfg.SuaveValidationBlock)
+	}
 	if isForkBlockIncompatible(c.ArrowGlacierBlock, newcfg.ArrowGlacierBlock, headNumber) {
 		return newBlockCompatError("Arrow Glacier fork block", c.ArrowGlacierBlock, newcfg.ArrowGlacierBlock)
 	}
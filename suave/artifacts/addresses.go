@@ -1,5 +1,5 @@
 // Code generated by suave/gen. DO NOT EDIT.
-// Hash: 23a6dd8b9b224b11b8baea19a80c55c7787c50c0eb2fc69727e66d615c913483
+// Hash: 5341be8db22c7380c1d580248d5fe1bdbbdbcb7d20816a70c774dae8a626db21
 package artifacts
 
 import (
@@ -12,13 +12,17 @@ var (
 	confidentialInputsAddr        = common.HexToAddress("0x0000000000000000000000000000000042010001")
 	confidentialStoreRetrieveAddr = common.HexToAddress("0x0000000000000000000000000000000042020001")
 	confidentialStoreStoreAddr    = common.HexToAddress("0x0000000000000000000000000000000042020000")
+	doHTTPRequestAddr             = common.HexToAddress("0x0000000000000000000000000000000043200002")
 	ethcallAddr                   = common.HexToAddress("0x0000000000000000000000000000000042100003")
 	extractHintAddr               = common.HexToAddress("0x0000000000000000000000000000000042100037")
 	fetchBidsAddr                 = common.HexToAddress("0x0000000000000000000000000000000042030001")
 	fillMevShareBundleAddr        = common.HexToAddress("0x0000000000000000000000000000000043200001")
 	newBidAddr                    = common.HexToAddress("0x0000000000000000000000000000000042030000")
+	queryBidsAddr                 = common.HexToAddress("0x0000000000000000000000000000000042030002")
+	setKeyAccessRuleAddr          = common.HexToAddress("0x0000000000000000000000000000000042030003")
 	signEthTransactionAddr        = common.HexToAddress("0x0000000000000000000000000000000040100001")
 	simulateBundleAddr            = common.HexToAddress("0x0000000000000000000000000000000042100000")
+	simulateEthBundleAddr         = common.HexToAddress("0x0000000000000000000000000000000042100004")
 	submitBundleJsonRPCAddr       = common.HexToAddress("0x0000000000000000000000000000000043000001")
 	submitEthBlockBidToRelayAddr  = common.HexToAddress("0x0000000000000000000000000000000042100002")
 )
@@ -28,13 +32,17 @@ var SuaveMethods = map[string]common.Address{
 	"confidentialInputs":        confidentialInputsAddr,
 	"confidentialStoreRetrieve": confidentialStoreRetrieveAddr,
 	"confidentialStoreStore":    confidentialStoreStoreAddr,
+	"doHTTPRequest":             doHTTPRequestAddr,
 	"ethcall":                   ethcallAddr,
 	"extractHint":               extractHintAddr,
 	"fetchBids":                 fetchBidsAddr,
 	"fillMevShareBundle":        fillMevShareBundleAddr,
 	"newBid":                    newBidAddr,
+	"queryBids":                 queryBidsAddr,
+	"setKeyAccessRule":          setKeyAccessRuleAddr,
 	"signEthTransaction":        signEthTransactionAddr,
 	"simulateBundle":            simulateBundleAddr,
+	"simulateEthBundle":         simulateEthBundleAddr,
 	"submitBundleJsonRPC":       submitBundleJsonRPCAddr,
 	"submitEthBlockBidToRelay":  submitEthBlockBidToRelayAddr,
 }
@@ -49,6 +57,8 @@ func PrecompileAddressToName(addr common.Address) string {
 		return "confidentialStoreRetrieve"
 	case confidentialStoreStoreAddr:
 		return "confidentialStoreStore"
+	case doHTTPRequestAddr:
+		return "doHTTPRequest"
 	case ethcallAddr:
 		return "ethcall"
 	case extractHintAddr:
@@ -59,10 +69,16 @@ func PrecompileAddressToName(addr common.Address) string {
 		return "fillMevShareBundle"
 	case newBidAddr:
 		return "newBid"
+	case queryBidsAddr:
+		return "queryBids"
+	case setKeyAccessRuleAddr:
+		return "setKeyAccessRule"
 	case signEthTransactionAddr:
 		return "signEthTransaction"
 	case simulateBundleAddr:
 		return "simulateBundle"
+	case simulateEthBundleAddr:
+		return "simulateEthBundle"
 	case submitBundleJsonRPCAddr:
 		return "submitBundleJsonRPC"
 	case submitEthBlockBidToRelayAddr:
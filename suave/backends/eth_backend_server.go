@@ -2,7 +2,6 @@ package backends
 
 import (
 	"context"
-	"math/big"
 
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
@@ -14,7 +13,9 @@ import (
 type EthBackend interface {
 	BuildEthBlock(ctx context.Context, buildArgs *types.BuildBlockArgs, txs types.Transactions) (*engine.ExecutionPayloadEnvelope, error)
 	BuildEthBlockFromBundles(ctx context.Context, buildArgs *types.BuildBlockArgs, bundles []types.SBundle) (*engine.ExecutionPayloadEnvelope, error)
+	SimulateBundle(ctx context.Context, buildArgs *types.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error)
 	Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error)
+	BlockNumber(ctx context.Context) (uint64, error)
 }
 
 var _ EthBackend = &EthBackendServer{}
@@ -23,8 +24,9 @@ var _ EthBackend = &EthBackendServer{}
 // to resolve the EthBackend server queries
 type EthBackendServerBackend interface {
 	CurrentHeader() *types.Header
-	BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error)
-	BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error)
+	BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error)
+	BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error)
+	SimulateBundle(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error)
 	Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error)
 }
 
@@ -36,48 +38,74 @@ func NewEthBackendServer(b EthBackendServerBackend) *EthBackendServer {
 	return &EthBackendServer{b}
 }
 
+// defaultBuildArgs returns the arguments to build the block following the current head with.
+func (e *EthBackendServer) defaultBuildArgs() *types.BuildBlockArgs {
+	head := e.b.CurrentHeader()
+	return &types.BuildBlockArgs{
+		Parent:       head.Hash(),
+		Timestamp:    head.Time + uint64(12),
+		FeeRecipient: common.Address{0x42},
+		GasLimit:     30000000,
+		Random:       head.Root,
+		Withdrawals:  nil,
+	}
+}
+
 func (e *EthBackendServer) BuildEthBlock(ctx context.Context, buildArgs *types.BuildBlockArgs, txs types.Transactions) (*engine.ExecutionPayloadEnvelope, error) {
 	if buildArgs == nil {
-		head := e.b.CurrentHeader()
-		buildArgs = &types.BuildBlockArgs{
-			Parent:       head.Hash(),
-			Timestamp:    head.Time + uint64(12),
-			FeeRecipient: common.Address{0x42},
-			GasLimit:     30000000,
-			Random:       head.Root,
-			Withdrawals:  nil,
-		}
+		buildArgs = e.defaultBuildArgs()
 	}
 
-	block, profit, err := e.b.BuildBlockFromTxs(ctx, buildArgs, txs)
+	block, value, err := e.b.BuildBlockFromTxs(ctx, buildArgs, txs)
 	if err != nil {
 		return nil, err
 	}
 
-	return engine.BlockToExecutableData(block, profit), nil
+	return blockToEnvelope(block, value), nil
 }
 
 func (e *EthBackendServer) BuildEthBlockFromBundles(ctx context.Context, buildArgs *types.BuildBlockArgs, bundles []types.SBundle) (*engine.ExecutionPayloadEnvelope, error) {
 	if buildArgs == nil {
-		head := e.b.CurrentHeader()
-		buildArgs = &types.BuildBlockArgs{
-			Parent:       head.Hash(),
-			Timestamp:    head.Time + uint64(12),
-			FeeRecipient: common.Address{0x42},
-			GasLimit:     30000000,
-			Random:       head.Root,
-			Withdrawals:  nil,
-		}
+		buildArgs = e.defaultBuildArgs()
 	}
 
-	block, profit, err := e.b.BuildBlockFromBundles(ctx, buildArgs, bundles)
+	block, value, results, err := e.b.BuildBlockFromBundles(ctx, buildArgs, bundles)
 	if err != nil {
 		return nil, err
 	}
 
-	return engine.BlockToExecutableData(block, profit), nil
+	envelope := blockToEnvelope(block, value)
+	envelope.BundleResults = results
+	return envelope, nil
+}
+
+// blockToEnvelope returns the envelope of the block, reporting where its value comes from.
+func blockToEnvelope(block *types.Block, value *types.BlockValue) *engine.ExecutionPayloadEnvelope {
+	envelope := engine.BlockToExecutableData(block, value.Profit)
+	envelope.BundleValue = value.Bundles
+	envelope.PublicValue = value.Public
+	return envelope
+}
+
+// SimulateBundle simulates the bundle with buildArgs, on top of the current head if they set no parent, or with
+// the default arguments if nil.
+func (e *EthBackendServer) SimulateBundle(ctx context.Context, buildArgs *types.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	if buildArgs == nil {
+		buildArgs = e.defaultBuildArgs()
+	} else if buildArgs.Parent == (common.Hash{}) {
+		args := *buildArgs
+		args.Parent = e.b.CurrentHeader().Hash()
+		buildArgs = &args
+	}
+
+	return e.b.SimulateBundle(ctx, buildArgs, bundle)
 }
 
 func (e *EthBackendServer) Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error) {
 	return e.b.Call(ctx, contractAddr, input)
 }
+
+// BlockNumber returns the number of the current head.
+func (e *EthBackendServer) BlockNumber(ctx context.Context) (uint64, error) {
+	return e.b.CurrentHeader().Number.Uint64(), nil
+}
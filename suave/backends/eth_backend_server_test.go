@@ -2,8 +2,10 @@ package backends
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/types"
 	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/ethereum/go-ethereum/suave/cstore"
 )
 
 func TestEthBackend_Compatibility(t *testing.T) {
@@ -32,27 +35,131 @@ func TestEthBackend_Compatibility(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestEthBackend_SimulateBundle(t *testing.T) {
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("suavex", NewEthBackendServer(&mockBackend{})))
+
+	clt := &RemoteEthBackend{client: rpc.DialInProc(srv)}
+
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000})
+	bundle := types.SBundle{
+		Txs:             types.Transactions{tx},
+		RevertingHashes: []common.Hash{tx.Hash()},
+	}
+
+	// The results of the transactions survive the round trip
+	result, err := clt.SimulateBundle(context.Background(), nil, bundle)
+	require.NoError(t, err)
+	require.False(t, result.Success)
+	require.Equal(t, "transaction reverted", result.Error)
+	require.Equal(t, uint64(21000), result.GasUsed)
+	require.Equal(t, big.NewInt(12345), result.CoinbaseDelta)
+	require.Len(t, result.Txs, 1)
+	require.Equal(t, tx.Hash(), result.Txs[0].TxHash)
+	require.Equal(t, []*types.SimulatedLog{{Addr: common.Address{0x1}, Topics: []common.Hash{{0x2}}, Data: []byte{0x3}}}, result.Txs[0].Logs)
+}
+
+func TestEthBackend_SimulateBundleDefaultsParent(t *testing.T) {
+	backend := &mockBackend{number: 10}
+	srv := NewEthBackendServer(backend)
+
+	// Only the parent defaults to the current head
+	args := &types.BuildBlockArgs{Timestamp: 100, FeeRecipient: common.Address{0x1}, GasLimit: 1000000, Random: common.Hash{0x2}}
+	_, err := srv.SimulateBundle(context.Background(), args, types.SBundle{})
+	require.NoError(t, err)
+
+	want := *args
+	want.Parent = backend.CurrentHeader().Hash()
+	require.Equal(t, &want, backend.simulateArgs)
+	require.Equal(t, common.Hash{}, args.Parent)
+
+	// A given parent is kept
+	args.Parent = common.Hash{0x3}
+	_, err = srv.SimulateBundle(context.Background(), args, types.SBundle{})
+	require.NoError(t, err)
+	require.Equal(t, args, backend.simulateArgs)
+}
+
 // mockBackend is a backend for the EthBackendServer that returns mock data
-type mockBackend struct{}
+type mockBackend struct {
+	number uint64
+
+	simulateArgs *suave.BuildBlockArgs // Build args of the last simulation
+}
 
 func (n *mockBackend) CurrentHeader() *types.Header {
-	return &types.Header{}
+	return &types.Header{Number: new(big.Int).SetUint64(n.number)}
 }
 
-func (n *mockBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *big.Int, error) {
+func (n *mockBackend) BuildBlockFromTxs(ctx context.Context, buildArgs *suave.BuildBlockArgs, txs types.Transactions) (*types.Block, *types.BlockValue, error) {
 	block := types.NewBlock(&types.Header{GasUsed: 1000, BaseFee: big.NewInt(1)}, txs, nil, nil, trie.NewStackTrie(nil))
-	return block, big.NewInt(11000), nil
+	return block, &types.BlockValue{Profit: big.NewInt(11000)}, nil
 }
 
-func (n *mockBackend) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *big.Int, error) {
+func (n *mockBackend) BuildBlockFromBundles(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundles []types.SBundle) (*types.Block, *types.BlockValue, []*types.BundleResult, error) {
 	var txs types.Transactions
 	for _, bundle := range bundles {
 		txs = append(txs, bundle.Txs...)
 	}
 	block := types.NewBlock(&types.Header{GasUsed: 1000, BaseFee: big.NewInt(1)}, txs, nil, nil, trie.NewStackTrie(nil))
-	return block, big.NewInt(11000), nil
+	return block, &types.BlockValue{Profit: big.NewInt(11000)}, nil, nil
+}
+
+// SimulateBundle reverts every transaction
+func (n *mockBackend) SimulateBundle(ctx context.Context, buildArgs *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	if buildArgs == nil {
+		return nil, errors.New("missing build args")
+	}
+	n.simulateArgs = buildArgs
+
+	result := &types.SimulatedBundle{Error: "transaction reverted", GasUsed: 21000, CoinbaseDelta: big.NewInt(12345)}
+	for _, tx := range bundle.Txs {
+		result.Txs = append(result.Txs, &types.SimulatedTransaction{
+			TxHash:        tx.Hash(),
+			GasUsed:       21000,
+			Error:         "execution reverted",
+			CoinbaseDelta: new(big.Int),
+			Logs:          []*types.SimulatedLog{{Addr: common.Address{0x1}, Topics: []common.Hash{{0x2}}, Data: []byte{0x3}}},
+		})
+	}
+	return result, nil
 }
 
 func (n *mockBackend) Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error) {
 	return []byte{0x1}, nil
 }
+
+func TestTargetBlockNumberRetention(t *testing.T) {
+	// The target chain is at block 1000, while the SUAVE chain is barely started
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("suavex", NewEthBackendServer(&mockBackend{number: 1000})))
+
+	clt := &RemoteEthBackend{client: rpc.DialInProc(srv)}
+	number, err := clt.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), number)
+
+	store := cstore.NewLocalConfidentialStore()
+	engine := cstore.NewConfidentialStoreEngine(store, cstore.MockTransport{}, cstore.MockSigner{}, cstore.MockChainSigner{})
+	engine.SetRetentionPolicy(cstore.RetentionPolicy{
+		GracePeriod:   10,
+		SweepInterval: 5 * time.Millisecond,
+		CurrentBlock:  NewTargetBlockNumber(clt, time.Minute).Get,
+	})
+
+	// Bids refer to blocks of the target chain, way past the SUAVE chain
+	expiredBid := suave.Bid{Id: suave.RandomBidId(), DecryptionCondition: 989, Version: "v0-test"}
+	liveBid := suave.Bid{Id: suave.RandomBidId(), DecryptionCondition: 990, Version: "v0-test"}
+	require.NoError(t, store.Commit([]suave.Bid{expiredBid, liveBid}, nil))
+
+	require.NoError(t, engine.Start())
+	t.Cleanup(func() { engine.Stop() })
+
+	require.Eventually(t, func() bool {
+		_, err := engine.FetchBidById(expiredBid.Id)
+		return errors.Is(err, suave.ErrBidNotFound)
+	}, time.Second, 5*time.Millisecond)
+
+	_, err = engine.FetchBidById(liveBid.Id)
+	require.NoError(t, err)
+}
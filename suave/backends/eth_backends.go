@@ -3,6 +3,8 @@ package backends
 import (
 	"context"
 	"math/big"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +19,14 @@ var (
 	_ EthBackend = &RemoteEthBackend{}
 )
 
+var (
+	// TargetBlockTime is the time between the blocks of the target chain
+	TargetBlockTime = 12 * time.Second
+
+	// targetBlockNumberTimeout bounds the requests for the head of the target chain
+	targetBlockNumberTimeout = 5 * time.Second
+)
+
 type EthMock struct{}
 
 func (e *EthMock) BuildEthBlock(ctx context.Context, args *suave.BuildBlockArgs, txs types.Transactions) (*engine.ExecutionPayloadEnvelope, error) {
@@ -33,10 +43,22 @@ func (e *EthMock) BuildEthBlockFromBundles(ctx context.Context, args *suave.Buil
 	return engine.BlockToExecutableData(block, big.NewInt(11000)), nil
 }
 
+func (e *EthMock) SimulateBundle(ctx context.Context, args *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	result := &types.SimulatedBundle{Success: true, GasUsed: 1000, CoinbaseDelta: big.NewInt(11000)}
+	for _, tx := range bundle.Txs {
+		result.Txs = append(result.Txs, &types.SimulatedTransaction{TxHash: tx.Hash(), Success: true, CoinbaseDelta: new(big.Int)})
+	}
+	return result, nil
+}
+
 func (e *EthMock) Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error) {
 	return nil, nil
 }
 
+func (e *EthMock) BlockNumber(ctx context.Context) (uint64, error) {
+	return 0, nil
+}
+
 type RemoteEthBackend struct {
 	endpoint string
 	client   *rpc.Client
@@ -84,9 +106,63 @@ func (e *RemoteEthBackend) BuildEthBlockFromBundles(ctx context.Context, args *s
 	return &result, err
 }
 
+func (e *RemoteEthBackend) SimulateBundle(ctx context.Context, args *suave.BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error) {
+	var result types.SimulatedBundle
+	// The bundle is passed by reference to be encoded with its json marshaller
+	err := e.call(ctx, &result, "suavex_simulateBundle", args, &bundle)
+
+	return &result, err
+}
+
 func (e *RemoteEthBackend) Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error) {
 	var result []byte
 	err := e.call(ctx, &result, "suavex_call", contractAddr, input)
 
 	return result, err
 }
+
+func (e *RemoteEthBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := e.call(ctx, &result, "suavex_blockNumber")
+
+	return result, err
+}
+
+// TargetBlockNumber returns the number of the head of the target chain of a
+// backend, fetching it at most once per maxAge, so that it can be called for
+// every message of the confidential store.
+type TargetBlockNumber struct {
+	backend suave.ConfidentialEthBackend
+	maxAge  time.Duration
+
+	lock    sync.Mutex
+	number  uint64
+	fetched time.Time
+}
+
+func NewTargetBlockNumber(backend suave.ConfidentialEthBackend, maxAge time.Duration) *TargetBlockNumber {
+	return &TargetBlockNumber{
+		backend: backend,
+		maxAge:  maxAge,
+	}
+}
+
+// Get returns the last fetched block number, or fetches it if it is older than maxAge.
+func (t *TargetBlockNumber) Get() (uint64, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.fetched.IsZero() && time.Since(t.fetched) < t.maxAge {
+		return t.number, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), targetBlockNumberTimeout)
+	defer cancel()
+
+	number, err := t.backend.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	t.number, t.fetched = number, time.Now()
+	return number, nil
+}
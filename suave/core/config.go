@@ -1,12 +1,79 @@
 package suave
 
+import (
+	"strings"
+	"time"
+)
+
 type Config struct {
 	SuaveEthRemoteBackendEndpoint string
 	RedisStorePubsubUri           string
+	P2PStoreTransport             bool // Gossip confidential store messages over devp2p, alternative to RedisStorePubsubUri
 	RedisStoreUri                 string
 	PebbleDbPath                  string
+	ConfidentialStoreRetention    uint64   // Blocks of the target chain past a bid's decryption condition to keep it for, 0 keeps bids forever
+	EncryptionKeyFile             string   // File holding the key confidential store values are encrypted with
+	EncryptionKey                 []byte   `toml:"-"` // Key derived from a keystore account, alternative to EncryptionKeyFile
+	PreviousEncryptionKeyFiles    []string // Previous encryption keys, values are re-encrypted with the current key
 	EthBundleSigningKeyHex        string
 	EthBlockSigningKeyHex         string
+	HTTP                          HTTPConfig      // Egress policy of the HTTP requests made by confidential contracts
+	Execution                     ExecutionConfig // Scheduling of the confidential compute requests received over RPC
 }
 
 var DefaultConfig = Config{}
+
+// ExecutionConfig limits the confidential compute requests received over RPC
+// that are executed at once, that wait to be executed, and their execution time.
+type ExecutionConfig struct {
+	Workers         int           // Requests executed at once, 0 for the default
+	QueueSize       int           // Requests waiting to be executed, 0 for the default
+	SenderQueueSize int           // Requests of a single sender waiting to be executed, 0 for the default
+	Timeout         time.Duration // Execution time of a single request, 0 for the default
+}
+
+// HTTPConfig limits the HTTP requests confidential contracts make through the
+// precompiles, such as doHTTPRequest and the relay submissions.
+type HTTPConfig struct {
+	AllowedDomains    []string // Hosts contracts may send requests to, "*.example.com" matches subdomains and "*" any host
+	DeniedDomains     []string // Hosts contracts may never send requests to, takes precedence over AllowedDomains
+	AllowPrivateIPs   bool     // Allow requests to loopback, private and link-local addresses
+	RequestsPerSecond float64  // Requests per second each contract may send, 0 for no limit
+	RequestBurst      int      // Requests each contract may send at once above RequestsPerSecond
+	MaxRequestSize    uint64   // Maximum request body size in bytes, 0 for the default
+	MaxResponseSize   uint64   // Maximum response body size in bytes, 0 for the default
+}
+
+// IsDomainAllowed returns whether the host is in the allow list and not in the deny list.
+// No domain is allowed unless configured.
+func (c *HTTPConfig) IsDomainAllowed(host string) bool {
+	return matchDomain(c.AllowedDomains, host) && !c.IsDomainDenied(host)
+}
+
+// IsDomainDenied returns whether the host is in the deny list.
+func (c *HTTPConfig) IsDomainDenied(host string) bool {
+	return matchDomain(c.DeniedDomains, host)
+}
+
+func matchDomain(domains []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		switch {
+		case domain == "*":
+			return true
+		case strings.HasPrefix(domain, "*."):
+			if strings.HasSuffix(host, domain[1:]) {
+				return true
+			}
+		case host == domain:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package suave
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/exp/slices"
+)
+
+// ExecutionNodeRegistryAddress is the address the execution node registry is
+// deployed at in the genesis of SUAVE chains.
+var ExecutionNodeRegistryAddress = common.HexToAddress("0x42000000")
+
+// ExecutionNodeRegistryABI is the ABI of the execution node registry, see
+// suave/sol/libraries/ExecutionNodeRegistry.sol.
+var ExecutionNodeRegistryABI = mustParseABI(`[
+	{"type":"function","name":"register","stateMutability":"nonpayable","inputs":[{"name":"daKeys","type":"address[]"},{"name":"namespaces","type":"string[]"}],"outputs":[]},
+	{"type":"function","name":"deregister","stateMutability":"nonpayable","inputs":[],"outputs":[]},
+	{"type":"function","name":"isExecutionNode","stateMutability":"view","inputs":[{"name":"node","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"getExecutionNode","stateMutability":"view","inputs":[{"name":"node","type":"address"}],"outputs":[{"name":"daKeys","type":"address[]"},{"name":"namespaces","type":"string[]"}]},
+	{"type":"function","name":"executionNodeOf","stateMutability":"view","inputs":[{"name":"daKey","type":"address"}],"outputs":[{"name":"","type":"address"}]},
+	{"type":"event","name":"ExecutionNodeRegistered","anonymous":false,"inputs":[{"indexed":true,"name":"node","type":"address"}]},
+	{"type":"event","name":"ExecutionNodeDeregistered","anonymous":false,"inputs":[{"indexed":true,"name":"node","type":"address"}]}
+]`)
+
+// ExecutionNode is an execution node registered in the execution node registry.
+type ExecutionNode struct {
+	Address common.Address
+	// DAKeys are the addresses the node signs its confidential store messages with
+	DAKeys []common.Address
+	// Namespaces are the bid namespaces the node stores, any if empty
+	Namespaces []string
+}
+
+// SupportsNamespace returns whether the node stores bids of the namespace.
+func (n *ExecutionNode) SupportsNamespace(namespace string) bool {
+	return len(n.Namespaces) == 0 || slices.Contains(n.Namespaces, namespace)
+}
+
+func mustParseABI(data string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(data))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
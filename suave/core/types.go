@@ -3,12 +3,14 @@ package suave
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/node"
+	"golang.org/x/exp/slices"
 )
 
 var AllowedPeekerAny = common.HexToAddress("0xC8df3686b4Afb2BB53e60EAe97EF043FE03Fb829") // "*"
@@ -24,9 +26,51 @@ type Bid struct {
 	AllowedStores       []common.Address
 	Version             string
 	CreationTx          *types.Transaction
+	KeyRules            []KeyAccessRule `json:",omitempty"`
 	Signature           []byte
 }
 
+// KeyAccessRule restricts access to the keys of a bid starting with Prefix.
+// Empty Writers or Readers fall back to the bid's AllowedPeekers.
+type KeyAccessRule struct {
+	Prefix    string
+	Writers   []common.Address `json:",omitempty"`
+	Readers   []common.Address `json:",omitempty"`
+	WriteOnce bool             `json:",omitempty"`
+}
+
+// KeyRule returns the rule with the longest prefix matching the key, if any.
+func (b *Bid) KeyRule(key string) *KeyAccessRule {
+	var match *KeyAccessRule
+	for i := range b.KeyRules {
+		rule := &b.KeyRules[i]
+		if strings.HasPrefix(key, rule.Prefix) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// CanWrite returns whether the caller may write the key according to the bid's key rules.
+// It does not check AllowedPeekers.
+func (b *Bid) CanWrite(caller common.Address, key string) bool {
+	rule := b.KeyRule(key)
+	return rule == nil || len(rule.Writers) == 0 || slices.Contains(rule.Writers, caller) || slices.Contains(rule.Writers, AllowedPeekerAny)
+}
+
+// CanRead returns whether the caller may read the key according to the bid's key rules.
+// It does not check AllowedPeekers.
+func (b *Bid) CanRead(caller common.Address, key string) bool {
+	rule := b.KeyRule(key)
+	return rule == nil || len(rule.Readers) == 0 || slices.Contains(rule.Readers, caller) || slices.Contains(rule.Readers, AllowedPeekerAny)
+}
+
+// IsWriteOnce returns whether the key may only be written once.
+func (b *Bid) IsWriteOnce(key string) bool {
+	rule := b.KeyRule(key)
+	return rule != nil && rule.WriteOnce
+}
+
 func (b *Bid) ToInnerBid() types.Bid {
 	return types.Bid{
 		Id:                  b.Id,
@@ -40,6 +84,18 @@ func (b *Bid) ToInnerBid() types.Bid {
 
 type MEVMBid = types.Bid
 
+// BidQuery selects bids whose decryption condition falls within
+// [FromBlock, ToBlock] in any of the namespaces. Results are ordered by block,
+// then by the order of Namespaces, then by insertion order. Cursor is the
+// number of results to skip, and at most Limit results are returned.
+type BidQuery struct {
+	FromBlock  uint64
+	ToBlock    uint64
+	Namespaces []string
+	Cursor     uint64
+	Limit      uint64
+}
+
 type BuildBlockArgs = types.BuildBlockArgs
 
 var ConfStoreAllowedAny common.Address = common.HexToAddress("0x42")
@@ -48,6 +104,9 @@ var (
 	ErrBidAlreadyPresent = errors.New("bid already present")
 	ErrBidNotFound       = errors.New("bid not found")
 	ErrUnsignedFinalize  = errors.New("finalize called with unsigned transaction, refusing to propagate")
+	ErrInvalidBidQuery   = errors.New("invalid bid query")
+	ErrKeyAccessDenied   = errors.New("key access denied")
+	ErrKeyWriteOnce      = errors.New("key is write-once and already written")
 )
 
 type ConfidentialStoreBackend interface {
@@ -63,5 +122,9 @@ type ConfidentialStoreBackend interface {
 type ConfidentialEthBackend interface {
 	BuildEthBlock(ctx context.Context, args *BuildBlockArgs, txs types.Transactions) (*engine.ExecutionPayloadEnvelope, error)
 	BuildEthBlockFromBundles(ctx context.Context, args *BuildBlockArgs, bundles []types.SBundle) (*engine.ExecutionPayloadEnvelope, error)
+	SimulateBundle(ctx context.Context, args *BuildBlockArgs, bundle types.SBundle) (*types.SimulatedBundle, error)
 	Call(ctx context.Context, contractAddr common.Address, input []byte) ([]byte, error)
+	// BlockNumber returns the number of the head of the target chain, which
+	// the decryption conditions of the bids refer to.
+	BlockNumber(ctx context.Context) (uint64, error)
 }
@@ -1,6 +1,8 @@
 package cstore
 
 import (
+	"bytes"
+	"sync"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -34,3 +36,279 @@ func testBackendStore(t *testing.T, store ConfidentialStorageBackend) {
 	require.Len(t, bids, 1)
 	require.Equal(t, bid, bids[0])
 }
+
+func testBackendCommit(t *testing.T, store ConfidentialStorageBackend) {
+	existingBid := suave.Bid{
+		Id:                  suave.RandomBidId(),
+		DecryptionCondition: 11,
+		AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+		Version:             "default:v0:ethBundles",
+	}
+	require.NoError(t, store.InitializeBid(existingBid))
+
+	newBid := suave.Bid{
+		Id:                  suave.RandomBidId(),
+		DecryptionCondition: 12,
+		AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+		Version:             "default:v0:ethBundles",
+	}
+	writes := []StoreWrite{
+		{Bid: newBid, Caller: newBid.AllowedPeekers[0], Key: "xx", Value: []byte{0x43, 0x14}},
+		{Bid: existingBid, Caller: existingBid.AllowedPeekers[0], Key: "xx", Value: []byte{0x43, 0x15}},
+	}
+
+	// The second bid is already present, the whole commit must fail and leave no trace of the first one
+	err := store.Commit([]suave.Bid{newBid, existingBid}, writes)
+	require.ErrorIs(t, err, suave.ErrBidAlreadyPresent)
+
+	_, err = store.FetchBidById(newBid.Id)
+	require.Error(t, err)
+	require.Empty(t, store.FetchBidsByProtocolAndBlock(12, "default:v0:ethBundles"))
+	require.Len(t, store.FetchBidsByProtocolAndBlock(11, "default:v0:ethBundles"), 1)
+
+	_, err = store.Retrieve(newBid, newBid.AllowedPeekers[0], "xx")
+	require.Error(t, err)
+	_, err = store.Retrieve(existingBid, existingBid.AllowedPeekers[0], "xx")
+	require.Error(t, err)
+
+	// Duplicate bids within a single commit are rejected as well
+	err = store.Commit([]suave.Bid{newBid, newBid}, nil)
+	require.ErrorIs(t, err, suave.ErrBidAlreadyPresent)
+	_, err = store.FetchBidById(newBid.Id)
+	require.Error(t, err)
+
+	require.NoError(t, store.Commit([]suave.Bid{newBid}, writes))
+
+	bidRes, err := store.FetchBidById(newBid.Id)
+	require.NoError(t, err)
+	require.Equal(t, newBid, bidRes)
+
+	bids := store.FetchBidsByProtocolAndBlock(12, "default:v0:ethBundles")
+	require.Len(t, bids, 1)
+	require.Equal(t, newBid, bids[0])
+
+	retrievedData, err := store.Retrieve(newBid, newBid.AllowedPeekers[0], "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43, 0x14}, retrievedData)
+
+	retrievedData, err = store.Retrieve(existingBid, existingBid.AllowedPeekers[0], "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43, 0x15}, retrievedData)
+}
+
+func testBackendConcurrentCommit(t *testing.T, store ConfidentialStorageBackend) {
+	const committers, bidsPerCommitter = 4, 5
+
+	// All of the bids share the same index key, concurrent commits must not lose any of them
+	var wg sync.WaitGroup
+	errs := make(chan error, committers*bidsPerCommitter)
+	for i := 0; i < committers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < bidsPerCommitter; j++ {
+				errs <- store.InitializeBid(suave.Bid{
+					Id:                  suave.RandomBidId(),
+					DecryptionCondition: 13,
+					AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+					Version:             "default:v0:ethBundles",
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, store.FetchBidsByProtocolAndBlock(13, "default:v0:ethBundles"), committers*bidsPerCommitter)
+}
+
+func testBackendPrune(t *testing.T, store ConfidentialStorageBackend) {
+	newBid := func(decryptionCondition uint64) suave.Bid {
+		bid := suave.Bid{
+			Id:                  suave.RandomBidId(),
+			DecryptionCondition: decryptionCondition,
+			AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+			Version:             "default:v0:ethBundles",
+		}
+		require.NoError(t, store.InitializeBid(bid))
+		_, err := store.Store(bid, bid.AllowedPeekers[0], "xx", []byte{0x43, 0x14})
+		require.NoError(t, err)
+		_, err = store.Store(bid, bid.AllowedPeekers[0], "xy", []byte{0x43, 0x15})
+		require.NoError(t, err)
+		return bid
+	}
+
+	expiredBid1 := newBid(20)
+	expiredBid2 := newBid(20)
+	retainedBid := newBid(21)
+
+	res, err := store.Prune(21)
+	require.NoError(t, err)
+	require.Equal(t, PruneResult{Bids: 2, Values: 4, IndexEntries: 1}, res)
+
+	for _, bid := range []suave.Bid{expiredBid1, expiredBid2} {
+		_, err = store.FetchBidById(bid.Id)
+		require.Error(t, err)
+		_, err = store.Retrieve(bid, bid.AllowedPeekers[0], "xx")
+		require.Error(t, err)
+	}
+	require.Empty(t, store.FetchBidsByProtocolAndBlock(20, "default:v0:ethBundles"))
+
+	bidRes, err := store.FetchBidById(retainedBid.Id)
+	require.NoError(t, err)
+	require.Equal(t, retainedBid, bidRes)
+	require.Len(t, store.FetchBidsByProtocolAndBlock(21, "default:v0:ethBundles"), 1)
+
+	retrievedData, err := store.Retrieve(retainedBid, retainedBid.AllowedPeekers[0], "xy")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43, 0x15}, retrievedData)
+
+	// Pruning again is a no-op
+	res, err = store.Prune(21)
+	require.NoError(t, err)
+	require.Equal(t, PruneResult{}, res)
+}
+
+func testBackendQuery(t *testing.T, store ConfidentialStorageBackend) {
+	newBid := func(decryptionCondition uint64, namespace string) suave.Bid {
+		bid := suave.Bid{
+			Id:                  suave.RandomBidId(),
+			DecryptionCondition: decryptionCondition,
+			AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+			Version:             namespace,
+		}
+		require.NoError(t, store.InitializeBid(bid))
+		return bid
+	}
+
+	bid1 := newBid(30, "ns-a")
+	bid2 := newBid(30, "ns-b")
+	bid3 := newBid(31, "ns-a")
+	bid4 := newBid(31, "ns-a")
+	newBid(31, "ns-c")
+	newBid(33, "ns-a")
+
+	query := suave.BidQuery{FromBlock: 30, ToBlock: 32, Namespaces: []string{"ns-b", "ns-a"}, Limit: 2}
+
+	bids, cursor, err := store.FetchBids(query)
+	require.NoError(t, err)
+	require.Equal(t, []suave.Bid{bid2, bid1}, bids)
+	require.NotZero(t, cursor)
+
+	query.Cursor = cursor
+	bids, cursor, err = store.FetchBids(query)
+	require.NoError(t, err)
+	require.Equal(t, []suave.Bid{bid3, bid4}, bids)
+	require.Zero(t, cursor)
+
+	query.Cursor = 0
+	query.Limit = 10
+	bids, cursor, err = store.FetchBids(query)
+	require.NoError(t, err)
+	require.Equal(t, []suave.Bid{bid2, bid1, bid3, bid4}, bids)
+	require.Zero(t, cursor)
+
+	_, _, err = store.FetchBids(suave.BidQuery{FromBlock: 32, ToBlock: 30, Namespaces: []string{"ns-a"}, Limit: 1})
+	require.ErrorIs(t, err, suave.ErrInvalidBidQuery)
+
+	_, _, err = store.FetchBids(suave.BidQuery{FromBlock: 0, ToBlock: MaxBidQueryBlockRange, Namespaces: []string{"ns-a"}, Limit: 1})
+	require.ErrorIs(t, err, suave.ErrInvalidBidQuery)
+
+	_, _, err = store.FetchBids(suave.BidQuery{FromBlock: 30, ToBlock: 30, Namespaces: []string{"ns-a"}, Limit: 0})
+	require.ErrorIs(t, err, suave.ErrInvalidBidQuery)
+}
+
+func testBackendIterateValues(t *testing.T, store ConfidentialStorageBackend) {
+	iterator, ok := store.(ConfidentialValueIterator)
+	require.True(t, ok)
+
+	bid := suave.Bid{
+		Id:                  suave.RandomBidId(),
+		DecryptionCondition: 10,
+		AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+		Version:             "default:v0:ethBundles",
+	}
+	require.NoError(t, store.InitializeBid(bid))
+
+	_, err := store.Store(bid, bid.AllowedPeekers[0], "xx", []byte{0x43, 0x14})
+	require.NoError(t, err)
+	_, err = store.Store(bid, bid.AllowedPeekers[0], "yy-zz", []byte{0x43, 0x15})
+	require.NoError(t, err)
+
+	values := map[string][]byte{}
+	require.NoError(t, iterator.IterateValues(func(bidId suave.BidId, key string, value []byte) error {
+		require.NotEqual(t, mempoolConfStoreId, bidId)
+		if bidId == bid.Id {
+			values[key] = value
+		}
+		return nil
+	}))
+	require.Equal(t, map[string][]byte{"xx": {0x43, 0x14}, "yy-zz": {0x43, 0x15}}, values)
+}
+
+func testBackendEncryption(t *testing.T, store ConfidentialStorageBackend) {
+	oldKey, newKey := make([]byte, EncryptionKeyLength), make([]byte, EncryptionKeyLength)
+	oldKey[0], newKey[0] = 0x01, 0x02
+
+	bid := suave.Bid{
+		Id:                  suave.RandomBidId(),
+		DecryptionCondition: 10,
+		AllowedPeekers:      []common.Address{common.HexToAddress("0x424344")},
+		Version:             "default:v0:ethBundles",
+	}
+	require.NoError(t, store.InitializeBid(bid))
+
+	// Written before encryption was enabled
+	_, err := store.Store(bid, bid.AllowedPeekers[0], "xx", []byte{0x43, 0x14})
+	require.NoError(t, err)
+
+	encryptedStore, err := NewEncryptedStoreBackend(store, oldKey)
+	require.NoError(t, err)
+
+	// Plaintext values stay readable until re-encrypted
+	retrieved, err := encryptedStore.Retrieve(bid, bid.AllowedPeekers[0], "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43, 0x14}, retrieved)
+
+	reencrypted, err := encryptedStore.Reencrypt()
+	require.NoError(t, err)
+	require.Equal(t, 1, reencrypted)
+
+	_, err = encryptedStore.Store(bid, bid.AllowedPeekers[0], "yy", []byte{0x43, 0x15})
+	require.NoError(t, err)
+
+	for key, value := range map[string][]byte{"xx": {0x43, 0x14}, "yy": {0x43, 0x15}} {
+		stored, err := store.Retrieve(bid, bid.AllowedPeekers[0], key)
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(stored, encryptedValueMagic))
+		require.NotEqual(t, value, stored)
+
+		retrieved, err := encryptedStore.Retrieve(bid, bid.AllowedPeekers[0], key)
+		require.NoError(t, err)
+		require.Equal(t, value, retrieved)
+	}
+
+	// Rotate the key, values stay readable and are re-encrypted with the new key
+	rotatedStore, err := NewEncryptedStoreBackend(store, newKey, oldKey)
+	require.NoError(t, err)
+
+	retrieved, err = rotatedStore.Retrieve(bid, bid.AllowedPeekers[0], "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43, 0x14}, retrieved)
+
+	reencrypted, err = rotatedStore.Reencrypt()
+	require.NoError(t, err)
+	require.Equal(t, 2, reencrypted)
+
+	newKeyOnlyStore, err := NewEncryptedStoreBackend(store, newKey)
+	require.NoError(t, err)
+	retrieved, err = newKeyOnlyStore.Retrieve(bid, bid.AllowedPeekers[0], "yy")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43, 0x15}, retrieved)
+
+	_, err = encryptedStore.Retrieve(bid, bid.AllowedPeekers[0], "yy")
+	require.ErrorIs(t, err, errUnknownStoreKey)
+}
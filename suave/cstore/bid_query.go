@@ -0,0 +1,87 @@
+package cstore
+
+import (
+	"fmt"
+
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+var (
+	// MaxBidQueryBlockRange is the widest block range a single bid query may span.
+	MaxBidQueryBlockRange uint64 = 256
+	// MaxBidQueryLimit is the largest page a single bid query may return.
+	MaxBidQueryLimit uint64 = 1000
+)
+
+func validateBidQuery(query suave.BidQuery) error {
+	if query.FromBlock > query.ToBlock {
+		return fmt.Errorf("%w: from block %d is after to block %d", suave.ErrInvalidBidQuery, query.FromBlock, query.ToBlock)
+	}
+	if query.ToBlock-query.FromBlock >= MaxBidQueryBlockRange {
+		return fmt.Errorf("%w: block range exceeds %d blocks", suave.ErrInvalidBidQuery, MaxBidQueryBlockRange)
+	}
+	if len(query.Namespaces) == 0 {
+		return fmt.Errorf("%w: no namespaces", suave.ErrInvalidBidQuery)
+	}
+	if query.Limit == 0 || query.Limit > MaxBidQueryLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", suave.ErrInvalidBidQuery, MaxBidQueryLimit)
+	}
+	return nil
+}
+
+// queryBids walks the (block, namespace) index in query order and returns the
+// requested page together with the cursor of the next page, which is zero once
+// the results are exhausted. Backends provide the index lookup and bid fetch.
+func queryBids(query suave.BidQuery, fetchBidIds func(blockNumber uint64, namespace string) []suave.BidId, fetchBid func(suave.BidId) (suave.Bid, error)) ([]suave.Bid, uint64, error) {
+	if err := validateBidQuery(query); err != nil {
+		return nil, 0, err
+	}
+
+	namespaces := dedupNamespaces(query.Namespaces)
+
+	// The cursor counts index entries rather than results so that it stays
+	// stable even if some of the bids were pruned in the meantime
+	bids := []suave.Bid{}
+	position := uint64(0)
+	for blockNumber := query.FromBlock; ; blockNumber++ {
+		for _, namespace := range namespaces {
+			for _, bidId := range fetchBidIds(blockNumber, namespace) {
+				if position < query.Cursor {
+					position++
+					continue
+				}
+
+				if uint64(len(bids)) == query.Limit {
+					// There is at least one more result
+					return bids, position, nil
+				}
+				position++
+
+				bid, err := fetchBid(bidId)
+				if err != nil {
+					continue
+				}
+				bids = append(bids, bid)
+			}
+		}
+
+		if blockNumber == query.ToBlock {
+			break
+		}
+	}
+
+	return bids, 0, nil
+}
+
+func dedupNamespaces(namespaces []string) []string {
+	seen := make(map[string]struct{}, len(namespaces))
+	res := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		if _, found := seen[namespace]; found {
+			continue
+		}
+		seen[namespace] = struct{}{}
+		res = append(res, namespace)
+	}
+	return res
+}
@@ -0,0 +1,309 @@
+package cstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DevP2PTransportName is the name of the devp2p sub-protocol confidential
+	// store messages are gossiped over.
+	DevP2PTransportName    = "suave"
+	DevP2PTransportVersion = 1
+
+	daMessageMsg          = 0x00
+	devp2pTransportLength = 1
+
+	maxDAMessageSize = 10 * 1024 * 1024
+
+	devp2pSeenMessages     = 32768 // Hashes of recent messages kept for deduplication
+	devp2pPeerQueueSize    = 128   // Messages queued for sending to a peer before dropping
+	devp2pPeerMessageRate  = 50    // Messages per second accepted from a peer
+	devp2pPeerMessageBurst = 200
+)
+
+var (
+	errNoDevP2PPeers = errors.New("devp2p transport: no peer to send the message to")
+
+	devp2pInMeter           = metrics.NewRegisteredMeter("suave/cstore/devp2p/in", nil)
+	devp2pOutMeter          = metrics.NewRegisteredMeter("suave/cstore/devp2p/out", nil)
+	devp2pDuplicateMeter    = metrics.NewRegisteredMeter("suave/cstore/devp2p/duplicate", nil)
+	devp2pRateLimitedMeter  = metrics.NewRegisteredMeter("suave/cstore/devp2p/ratelimited", nil)
+	devp2pBadSignatureMeter = metrics.NewRegisteredMeter("suave/cstore/devp2p/badsignature", nil)
+)
+
+// DevP2PTransport gossips confidential store messages to the peers of the
+// node's devp2p server which support the suave sub-protocol. Messages are
+// deduplicated by hash and relayed to all other peers, peers sending messages
+// with invalid signatures are disconnected.
+type DevP2PTransport struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	daSigner DASigner
+
+	seenLock sync.Mutex
+	seen     lru.BasicLRU[common.Hash, struct{}]
+
+	peersLock sync.RWMutex
+	peers     map[enode.ID]*devp2pPeer
+
+	subscribersLock sync.RWMutex
+	subscribers     map[chan DAMessage]struct{}
+}
+
+type devp2pPeer struct {
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	limiter *rate.Limiter
+	queue   chan []byte
+	term    chan struct{}
+}
+
+func NewDevP2PTransport(daSigner DASigner) *DevP2PTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DevP2PTransport{
+		ctx:         ctx,
+		cancel:      cancel,
+		daSigner:    daSigner,
+		seen:        lru.NewBasicLRU[common.Hash, struct{}](devp2pSeenMessages),
+		peers:       make(map[enode.ID]*devp2pPeer),
+		subscribers: make(map[chan DAMessage]struct{}),
+	}
+}
+
+// Protocols returns the devp2p sub-protocol to register with the node.
+func (t *DevP2PTransport) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    DevP2PTransportName,
+		Version: DevP2PTransportVersion,
+		Length:  devp2pTransportLength,
+		Run:     t.runPeer,
+	}}
+}
+
+func (t *DevP2PTransport) Start() error {
+	return nil
+}
+
+func (t *DevP2PTransport) Stop() error {
+	t.cancel()
+	return nil
+}
+
+func (t *DevP2PTransport) Subscribe() (<-chan DAMessage, context.CancelFunc) {
+	ch := make(chan DAMessage, 16)
+	ctx, cancel := context.WithCancel(t.ctx)
+
+	t.subscribersLock.Lock()
+	t.subscribers[ch] = struct{}{}
+	t.subscribersLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		t.subscribersLock.Lock()
+		delete(t.subscribers, ch)
+		t.subscribersLock.Unlock()
+
+		close(ch)
+	}()
+
+	return ch, cancel
+}
+
+func (t *DevP2PTransport) Publish(message DAMessage) error {
+	log.Trace("Devp2p transport: publishing", "message", message)
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Error("Devp2p transport: could not marshal message", "err", err)
+		return err
+	}
+
+	t.markSeen(crypto.Keccak256Hash(payload))
+	if t.broadcast(payload, enode.ID{}) == 0 {
+		return errNoDevP2PPeers
+	}
+	return nil
+}
+
+// markSeen records the message hash and returns false if it was already seen.
+func (t *DevP2PTransport) markSeen(hash common.Hash) bool {
+	t.seenLock.Lock()
+	defer t.seenLock.Unlock()
+
+	if t.seen.Contains(hash) {
+		return false
+	}
+	t.seen.Add(hash, struct{}{})
+	return true
+}
+
+// broadcast queues the message for sending to all peers except the one it was
+// received from, and returns the number of peers it was queued for.
+func (t *DevP2PTransport) broadcast(payload []byte, from enode.ID) int {
+	t.peersLock.RLock()
+	defer t.peersLock.RUnlock()
+
+	queued := 0
+	for id, peer := range t.peers {
+		if id == from {
+			continue
+		}
+
+		select {
+		case peer.queue <- payload:
+			queued++
+		default:
+			log.Debug("Devp2p transport: dropping message to slow peer", "peer", id)
+		}
+	}
+	return queued
+}
+
+func (t *DevP2PTransport) deliver(message DAMessage) {
+	t.subscribersLock.RLock()
+	defer t.subscribersLock.RUnlock()
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- message:
+		default:
+			log.Error("dropping transport message due to channel being blocked")
+		}
+	}
+}
+
+func (t *DevP2PTransport) runPeer(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	peer := &devp2pPeer{
+		Peer:    p,
+		rw:      rw,
+		limiter: rate.NewLimiter(devp2pPeerMessageRate, devp2pPeerMessageBurst),
+		queue:   make(chan []byte, devp2pPeerQueueSize),
+		term:    make(chan struct{}),
+	}
+
+	t.peersLock.Lock()
+	t.peers[p.ID()] = peer
+	t.peersLock.Unlock()
+
+	defer func() {
+		t.peersLock.Lock()
+		delete(t.peers, p.ID())
+		t.peersLock.Unlock()
+
+		close(peer.term)
+	}()
+
+	go peer.sendLoop()
+
+	for {
+		if err := t.handleMsg(peer); err != nil {
+			log.Debug("Devp2p transport: peer dropped", "peer", p.ID(), "err", err)
+			return err
+		}
+	}
+}
+
+func (t *DevP2PTransport) handleMsg(peer *devp2pPeer) error {
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Code != daMessageMsg {
+		return fmt.Errorf("devp2p transport: invalid message code %d", msg.Code)
+	}
+	if msg.Size > maxDAMessageSize {
+		return fmt.Errorf("devp2p transport: message too large (%d > %d)", msg.Size, maxDAMessageSize)
+	}
+
+	if !peer.limiter.Allow() {
+		devp2pRateLimitedMeter.Mark(1)
+		log.Trace("Devp2p transport: peer exceeded the message rate, dropping message", "peer", peer.ID())
+		return nil
+	}
+
+	var payload []byte
+	if err := msg.Decode(&payload); err != nil {
+		return fmt.Errorf("devp2p transport: could not decode message: %w", err)
+	}
+	devp2pInMeter.Mark(1)
+
+	if !t.markSeen(crypto.Keccak256Hash(payload)) {
+		devp2pDuplicateMeter.Mark(1)
+		return nil
+	}
+
+	var message DAMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return fmt.Errorf("devp2p transport: could not parse message: %w", err)
+	}
+
+	if err := verifyMessageSignatures(t.daSigner, &message); err != nil {
+		devp2pBadSignatureMeter.Mark(1)
+		return fmt.Errorf("devp2p transport: %w", err)
+	}
+
+	t.deliver(message)
+	t.broadcast(payload, peer.ID())
+	return nil
+}
+
+func (p *devp2pPeer) sendLoop() {
+	for {
+		select {
+		case payload := <-p.queue:
+			if err := p2p.Send(p.rw, daMessageMsg, payload); err != nil {
+				log.Debug("Devp2p transport: could not send message", "peer", p.ID(), "err", err)
+				return
+			}
+			devp2pOutMeter.Mark(1)
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// verifyMessageSignatures checks the signatures of the message and of its key
+// announcements, without validating the bids the message writes to.
+func verifyMessageSignatures(daSigner DASigner, message *DAMessage) error {
+	for i := range message.KeyAnnouncements {
+		if err := verifyKeyAnnouncement(daSigner, &message.KeyAnnouncements[i]); err != nil {
+			return err
+		}
+	}
+
+	if message.SyncRequest != nil {
+		if err := verifySyncRequest(daSigner, message.SyncRequest); err != nil {
+			return err
+		}
+	}
+
+	if message.SyncResponse != nil {
+		_, err := verifySyncResponseSigner(daSigner, message)
+		return err
+	}
+
+	if message.SourceTx == nil && len(message.StoreWrites) == 0 {
+		// Key announcements and sync requests only
+		return nil
+	}
+
+	_, err := verifyMessageSigner(daSigner, message)
+	return err
+}
@@ -0,0 +1,133 @@
+package cstore
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newDevP2PTestPeer(t *testing.T, transport *DevP2PTransport, id enode.ID) (*p2p.MsgPipeRW, <-chan error) {
+	local, remote := p2p.MsgPipe()
+	t.Cleanup(func() { remote.Close() })
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- transport.runPeer(p2p.NewPeer(id, "test", nil), local)
+	}()
+
+	return remote, errc
+}
+
+func newDevP2PTestMessage(t *testing.T, signer common.Address) []byte {
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	sourceTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	message := DAMessage{SourceTx: sourceTx, StoreUUID: uuid.New()}
+	msgBytes, err := SerializeMessageForSigning(&message)
+	require.NoError(t, err)
+
+	message.Signature, err = FakeDASigner{}.Sign(signer, msgBytes)
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(message)
+	require.NoError(t, err)
+	return payload
+}
+
+func TestDevP2PTransport(t *testing.T) {
+	transport := NewDevP2PTransport(FakeDASigner{})
+	t.Cleanup(func() { transport.Stop() })
+
+	messages, cancel := transport.Subscribe()
+	t.Cleanup(cancel)
+
+	peerA, errA := newDevP2PTestPeer(t, transport, enode.ID{0x01})
+	peerB, _ := newDevP2PTestPeer(t, transport, enode.ID{0x02})
+
+	require.Eventually(t, func() bool {
+		transport.peersLock.RLock()
+		defer transport.peersLock.RUnlock()
+		return len(transport.peers) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// A valid message is delivered and relayed to the other peers
+	payload := newDevP2PTestMessage(t, common.Address{0x42})
+	require.NoError(t, p2p.Send(peerA, daMessageMsg, payload))
+	require.NoError(t, p2p.ExpectMsg(peerB, daMessageMsg, payload))
+
+	select {
+	case msg := <-messages:
+		require.Equal(t, common.Address{0x42}, common.BytesToAddress(msg.Signature))
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	// Duplicates are neither delivered nor relayed
+	require.NoError(t, p2p.Send(peerA, daMessageMsg, payload))
+
+	// Published messages are sent to all peers, and are not relayed back by them
+	published := DAMessage{StoreUUID: uuid.New()}
+	require.NoError(t, transport.Publish(published))
+
+	publishedPayload, err := json.Marshal(published)
+	require.NoError(t, err)
+	require.NoError(t, p2p.ExpectMsg(peerB, daMessageMsg, publishedPayload))
+	require.NoError(t, p2p.ExpectMsg(peerA, daMessageMsg, publishedPayload))
+	require.NoError(t, p2p.Send(peerB, daMessageMsg, publishedPayload))
+
+	select {
+	case msg := <-messages:
+		t.Fatalf("unexpected message delivered: %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A peer sending a message with a bad signature is dropped
+	require.NoError(t, p2p.Send(peerA, daMessageMsg, newDevP2PTestMessage(t, common.Address{0x43})))
+	select {
+	case err := <-errA:
+		require.ErrorContains(t, err, "message signer")
+	case <-time.After(time.Second):
+		t.Fatal("peer not dropped")
+	}
+}
+
+func TestDevP2PTransportRateLimit(t *testing.T) {
+	transport := NewDevP2PTransport(FakeDASigner{})
+	t.Cleanup(func() { transport.Stop() })
+
+	peer, _ := newDevP2PTestPeer(t, transport, enode.ID{0x01})
+
+	seenMessages := func() int {
+		transport.seenLock.Lock()
+		defer transport.seenLock.Unlock()
+		return transport.seen.Len()
+	}
+
+	// Send a burst of distinct messages, the ones over the limit are dropped before processing
+	for i := 0; i < 2*devp2pPeerMessageBurst; i++ {
+		payload, err := json.Marshal(DAMessage{StoreUUID: uuid.New()})
+		require.NoError(t, err)
+		require.NoError(t, p2p.Send(peer, daMessageMsg, payload))
+	}
+
+	require.Eventually(t, func() bool {
+		return seenMessages() >= devp2pPeerMessageBurst
+	}, time.Second, 10*time.Millisecond)
+	require.Less(t, seenMessages(), 2*devp2pPeerMessageBurst)
+}
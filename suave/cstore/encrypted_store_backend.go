@@ -0,0 +1,451 @@
+package cstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+var _ ConfidentialStorageBackend = &EncryptedStoreBackend{}
+
+var (
+	reencryptedValuesMeter = metrics.NewRegisteredMeter("suave/cstore/encryption/reencrypted", nil)
+	reencryptFailuresMeter = metrics.NewRegisteredMeter("suave/cstore/encryption/failures", nil)
+)
+
+var (
+	errValueNotEncrypted = errors.New("value is not encrypted")
+	errUnknownStoreKey   = errors.New("value is encrypted with an unknown key")
+)
+
+const (
+	// EncryptionKeyLength is the length of the node key used to encrypt store values (AES-256).
+	EncryptionKeyLength = 32
+
+	encryptionKeyIdLength = 8
+	wrappedDataKeyLength  = 12 + EncryptionKeyLength + 16 // nonce | encrypted data key | tag
+)
+
+// encryptedValueMagic prefixes every value written by the encrypted backend,
+// followed by a format version byte.
+var encryptedValueMagic = []byte{0x00, 's', 'c', 'e', 0x01}
+
+// ConfidentialValueIterator is implemented by storage backends able to
+// enumerate the values they hold, which is needed to re-encrypt them.
+type ConfidentialValueIterator interface {
+	IterateValues(fn func(bidId suave.BidId, key string, value []byte) error) error
+}
+
+// EncryptedStoreBackend wraps a storage backend and encrypts every stored value
+// with AES-GCM. Each value is encrypted with a fresh data key, which is in turn
+// encrypted (wrapped) with the node key, so rotating the node key only requires
+// re-wrapping data keys. Bids themselves are stored as-is.
+type EncryptedStoreBackend struct {
+	ConfidentialStorageBackend
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// reencryptLock serializes re-encryption of a value with regular writes,
+	// which would otherwise race with reading and re-writing it
+	reencryptLock sync.RWMutex
+	reencryptWg   sync.WaitGroup
+
+	keysLock   sync.RWMutex
+	currentKey encryptionKey
+	keys       map[[encryptionKeyIdLength]byte]encryptionKey
+}
+
+type encryptionKey struct {
+	id   [encryptionKeyIdLength]byte
+	aead cipher.AEAD
+}
+
+func newEncryptionKey(key []byte) (encryptionKey, error) {
+	if len(key) != EncryptionKeyLength {
+		return encryptionKey{}, fmt.Errorf("invalid encryption key length %d, expected %d", len(key), EncryptionKeyLength)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return encryptionKey{}, err
+	}
+
+	res := encryptionKey{aead: aead}
+	copy(res.id[:], crypto.Keccak256(key))
+	return res, nil
+}
+
+// NewEncryptedStoreBackend wraps the backend, encrypting values with the given key.
+// Values encrypted with any of the previous keys, and plaintext values written
+// before encryption was enabled, are still readable until they are re-encrypted
+// with the current key, see Reencrypt.
+func NewEncryptedStoreBackend(backend ConfidentialStorageBackend, key []byte, previousKeys ...[]byte) (*EncryptedStoreBackend, error) {
+	currentKey, err := newEncryptionKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &EncryptedStoreBackend{
+		ConfidentialStorageBackend: backend,
+		ctx:                        ctx,
+		cancel:                     cancel,
+		currentKey:                 currentKey,
+		keys:                       map[[encryptionKeyIdLength]byte]encryptionKey{currentKey.id: currentKey},
+	}
+
+	for _, previousKey := range previousKeys {
+		k, err := newEncryptionKey(previousKey)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid previous key: %w", err)
+		}
+		e.keys[k.id] = k
+	}
+
+	return e, nil
+}
+
+func (e *EncryptedStoreBackend) Stop() error {
+	e.cancel()
+	e.reencryptWg.Wait()
+	return e.ConfidentialStorageBackend.Stop()
+}
+
+func (e *EncryptedStoreBackend) Store(bid suave.Bid, caller common.Address, key string, value []byte) (suave.Bid, error) {
+	e.reencryptLock.RLock()
+	defer e.reencryptLock.RUnlock()
+
+	encrypted, err := e.encrypt(bid.Id, key, value)
+	if err != nil {
+		return suave.Bid{}, err
+	}
+
+	return e.ConfidentialStorageBackend.Store(bid, caller, key, encrypted)
+}
+
+func (e *EncryptedStoreBackend) Commit(bids []suave.Bid, writes []StoreWrite) error {
+	e.reencryptLock.RLock()
+	defer e.reencryptLock.RUnlock()
+
+	encryptedWrites := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		encrypted, err := e.encrypt(sw.Bid.Id, sw.Key, sw.Value)
+		if err != nil {
+			return err
+		}
+		sw.Value = encrypted
+		encryptedWrites = append(encryptedWrites, sw)
+	}
+
+	return e.ConfidentialStorageBackend.Commit(bids, encryptedWrites)
+}
+
+func (e *EncryptedStoreBackend) Retrieve(bid suave.Bid, caller common.Address, key string) ([]byte, error) {
+	encrypted, err := e.ConfidentialStorageBackend.Retrieve(bid, caller, key)
+	if err != nil {
+		return nil, err
+	}
+	// Values written before encryption was enabled are read as-is until re-encrypted
+	if !bytes.HasPrefix(encrypted, encryptedValueMagic) {
+		return encrypted, nil
+	}
+
+	value, err := e.decrypt(bid.Id, key, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data for bid %x and key %s: %w", bid.Id, key, err)
+	}
+
+	return value, nil
+}
+
+// RotateKey makes the given key the current one. Values encrypted with
+// previous keys stay readable and are re-encrypted in the background.
+func (e *EncryptedStoreBackend) RotateKey(key []byte) error {
+	newKey, err := newEncryptionKey(key)
+	if err != nil {
+		return err
+	}
+
+	e.keysLock.Lock()
+	e.currentKey = newKey
+	e.keys[newKey.id] = newKey
+	e.keysLock.Unlock()
+
+	e.ReencryptInBackground()
+	return nil
+}
+
+// ReencryptInBackground runs Reencrypt in the background until done or stopped.
+func (e *EncryptedStoreBackend) ReencryptInBackground() {
+	e.reencryptWg.Add(1)
+	go func() {
+		defer e.reencryptWg.Done()
+
+		n, err := e.Reencrypt()
+		if err != nil {
+			log.Warn("Confidential store: could not re-encrypt values", "reencrypted", n, "err", err)
+			return
+		}
+		log.Info("Confidential store: re-encrypted values with the current key", "reencrypted", n)
+	}()
+}
+
+// Reencrypt re-encrypts every value not encrypted with the current key,
+// including plaintext values written before encryption was enabled.
+// It returns the number of values re-encrypted.
+func (e *EncryptedStoreBackend) Reencrypt() (int, error) {
+	iterator, ok := e.ConfidentialStorageBackend.(ConfidentialValueIterator)
+	if !ok {
+		return 0, errors.New("storage backend does not support iterating over values")
+	}
+
+	// Collect first, re-encrypting while iterating would modify the underlying store under the iterator
+	type valueKey struct {
+		bidId suave.BidId
+		key   string
+	}
+	var stale []valueKey
+	err := iterator.IterateValues(func(bidId suave.BidId, key string, value []byte) error {
+		if !e.isCurrent(value) {
+			stale = append(stale, valueKey{bidId, key})
+		}
+		return e.ctx.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reencrypted := 0
+	for _, vk := range stale {
+		if e.ctx.Err() != nil {
+			return reencrypted, e.ctx.Err()
+		}
+
+		done, err := e.reencryptValue(vk.bidId, vk.key)
+		if err != nil {
+			reencryptFailuresMeter.Mark(1)
+			return reencrypted, fmt.Errorf("could not re-encrypt data for bid %x and key %s: %w", vk.bidId, vk.key, err)
+		}
+		if done {
+			reencryptedValuesMeter.Mark(1)
+			reencrypted++
+		}
+	}
+
+	return reencrypted, nil
+}
+
+func (e *EncryptedStoreBackend) reencryptValue(bidId suave.BidId, key string) (bool, error) {
+	e.reencryptLock.Lock()
+	defer e.reencryptLock.Unlock()
+
+	bid := suave.Bid{Id: bidId}
+
+	// Re-read under the lock, the value could have been overwritten or pruned since
+	stored, err := e.ConfidentialStorageBackend.Retrieve(bid, common.Address{}, key)
+	if err != nil {
+		return false, nil
+	}
+	if e.isCurrent(stored) {
+		return false, nil
+	}
+
+	value := stored
+	if bytes.HasPrefix(stored, encryptedValueMagic) {
+		value, err = e.decrypt(bidId, key, stored)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	encrypted, err := e.encrypt(bidId, key, value)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := e.ConfidentialStorageBackend.Store(bid, common.Address{}, key, encrypted); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (e *EncryptedStoreBackend) isCurrent(value []byte) bool {
+	if !bytes.HasPrefix(value, encryptedValueMagic) || len(value) < len(encryptedValueMagic)+encryptionKeyIdLength {
+		return false
+	}
+
+	e.keysLock.RLock()
+	defer e.keysLock.RUnlock()
+	return bytes.Equal(value[len(encryptedValueMagic):len(encryptedValueMagic)+encryptionKeyIdLength], e.currentKey.id[:])
+}
+
+// encrypt returns magic | key id | wrapped data key | nonce | ciphertext.
+// The bid id and key are authenticated so values cannot be moved around.
+func (e *EncryptedStoreBackend) encrypt(bidId suave.BidId, key string, value []byte) ([]byte, error) {
+	e.keysLock.RLock()
+	nodeKey := e.currentKey
+	e.keysLock.RUnlock()
+
+	dataKey := make([]byte, EncryptionKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ad := encryptionAdditionalData(bidId, key)
+
+	res := make([]byte, 0, len(encryptedValueMagic)+encryptionKeyIdLength+wrappedDataKeyLength+dataAEAD.NonceSize()+len(value)+dataAEAD.Overhead())
+	res = append(res, encryptedValueMagic...)
+	res = append(res, nodeKey.id[:]...)
+
+	if res, err = sealAEAD(nodeKey.aead, res, dataKey, ad); err != nil {
+		return nil, err
+	}
+	return sealAEAD(dataAEAD, res, value, ad)
+}
+
+func (e *EncryptedStoreBackend) decrypt(bidId suave.BidId, key string, encrypted []byte) ([]byte, error) {
+	if !bytes.HasPrefix(encrypted, encryptedValueMagic) {
+		return nil, errValueNotEncrypted
+	}
+	encrypted = encrypted[len(encryptedValueMagic):]
+
+	if len(encrypted) < encryptionKeyIdLength+wrappedDataKeyLength {
+		return nil, errors.New("encrypted value too short")
+	}
+
+	var keyId [encryptionKeyIdLength]byte
+	copy(keyId[:], encrypted)
+	encrypted = encrypted[encryptionKeyIdLength:]
+
+	e.keysLock.RLock()
+	nodeKey, found := e.keys[keyId]
+	e.keysLock.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%w %x", errUnknownStoreKey, keyId)
+	}
+
+	ad := encryptionAdditionalData(bidId, key)
+
+	dataKey, err := openAEAD(nodeKey.aead, encrypted[:wrappedDataKeyLength], ad)
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return openAEAD(dataAEAD, encrypted[wrappedDataKeyLength:], ad)
+}
+
+func encryptionAdditionalData(bidId suave.BidId, key string) []byte {
+	return append(common.CopyBytes(bidId[:]), key...)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealAEAD appends nonce | ciphertext to dst
+func sealAEAD(aead cipher.AEAD, dst []byte, plaintext []byte, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	dst = append(dst, nonce...)
+	return aead.Seal(dst, nonce, plaintext, ad), nil
+}
+
+func openAEAD(aead cipher.AEAD, sealed []byte, ad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted value too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], ad)
+}
+
+// LoadEncryptionKeyFile reads a hex encoded 32 byte encryption key from the file.
+func LoadEncryptionKeyFile(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read encryption key file: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(raw)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode encryption key file %s: %w", path, err)
+	}
+	if len(key) != EncryptionKeyLength {
+		return nil, fmt.Errorf("invalid encryption key length %d in %s, expected %d", len(key), path, EncryptionKeyLength)
+	}
+
+	return key, nil
+}
+
+// LoadEncryptionKeys returns the current and previous encryption keys configured.
+func LoadEncryptionKeys(config suave.Config) ([]byte, [][]byte, error) {
+	key := config.EncryptionKey
+	if config.EncryptionKeyFile != "" {
+		var err error
+		if key, err = LoadEncryptionKeyFile(config.EncryptionKeyFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(key) == 0 {
+		return nil, nil, errors.New("no confidential store encryption key configured")
+	}
+
+	previousKeys := make([][]byte, 0, len(config.PreviousEncryptionKeyFiles))
+	for _, path := range config.PreviousEncryptionKeyFiles {
+		previousKey, err := LoadEncryptionKeyFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		previousKeys = append(previousKeys, previousKey)
+	}
+
+	return key, previousKeys, nil
+}
+
+// encryptionKeyDerivationDomain separates the store key from other uses of the account key.
+var encryptionKeyDerivationDomain = []byte("suave confidential store encryption key")
+
+// DeriveEncryptionKey derives the encryption key from the private key of a keystore account.
+func DeriveEncryptionKey(ks *keystore.KeyStore, account common.Address, passphrase string) ([]byte, error) {
+	keyJson, err := ks.Export(accounts.Account{Address: account}, passphrase, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not export account %x: %w", account, err)
+	}
+
+	key, err := keystore.DecryptKey(keyJson, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt account %x: %w", account, err)
+	}
+
+	return crypto.Keccak256(encryptionKeyDerivationDomain, crypto.FromECDSA(key.PrivateKey)), nil
+}
@@ -0,0 +1,55 @@
+package cstore
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncryptedStore(t *testing.T) (*EncryptedStoreBackend, *LocalConfidentialStore) {
+	inner := NewLocalConfidentialStore()
+	store, err := NewEncryptedStoreBackend(inner, make([]byte, EncryptionKeyLength))
+	require.NoError(t, err)
+	return store, inner
+}
+
+func TestEncryptedStore_StoreSuite(t *testing.T) {
+	store, _ := newTestEncryptedStore(t)
+	testBackendStore(t, store)
+}
+
+func TestEncryptedStore_Commit(t *testing.T) {
+	store, _ := newTestEncryptedStore(t)
+	testBackendCommit(t, store)
+}
+
+func TestEncryptedStore_ValueBoundToBidAndKey(t *testing.T) {
+	store, inner := newTestEncryptedStore(t)
+
+	bid := suave.Bid{Id: suave.RandomBidId(), AllowedPeekers: []common.Address{{0x42}}}
+	otherBid := suave.Bid{Id: suave.RandomBidId(), AllowedPeekers: []common.Address{{0x42}}}
+	require.NoError(t, store.Commit([]suave.Bid{bid, otherBid}, []StoreWrite{{Bid: bid, Caller: common.Address{0x42}, Key: "xx", Value: []byte{0x43}}}))
+
+	encrypted, err := inner.Retrieve(bid, common.Address{0x42}, "xx")
+	require.NoError(t, err)
+
+	_, err = inner.Store(bid, common.Address{0x42}, "yy", encrypted)
+	require.NoError(t, err)
+	_, err = store.Retrieve(bid, common.Address{0x42}, "yy")
+	require.Error(t, err)
+
+	_, err = inner.Store(otherBid, common.Address{0x42}, "xx", encrypted)
+	require.NoError(t, err)
+	_, err = store.Retrieve(otherBid, common.Address{0x42}, "xx")
+	require.Error(t, err)
+}
+
+func TestEncryptedStore_InvalidKey(t *testing.T) {
+	_, err := NewEncryptedStoreBackend(NewLocalConfidentialStore(), []byte{0x01})
+	require.Error(t, err)
+
+	_, err = NewEncryptedStoreBackend(NewLocalConfidentialStore(), make([]byte, EncryptionKeyLength), []byte{0x01})
+	require.Error(t, err)
+}
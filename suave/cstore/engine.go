@@ -1,18 +1,25 @@
 package cstore
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/google/uuid"
 	"golang.org/x/exp/slices"
+	"golang.org/x/time/rate"
 )
 
 // ConfidentialStorageBackend is the interface that must be implemented by a
@@ -23,6 +30,15 @@ type ConfidentialStorageBackend interface {
 	Retrieve(bid suave.Bid, caller common.Address, key string) ([]byte, error)
 	FetchBidById(suave.BidId) (suave.Bid, error)
 	FetchBidsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.Bid
+	// FetchBids returns a page of bids matching the query and the cursor of
+	// the next page, zero if there are no more results.
+	FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error)
+	// Commit initializes the bids and applies the store writes atomically,
+	// either all of them are persisted or none are.
+	Commit(bids []suave.Bid, writes []StoreWrite) error
+	// Prune removes bids with a decryption condition below the given block
+	// number along with their data and index entries.
+	Prune(decryptionConditionBelow uint64) (PruneResult, error)
 	Stop() error
 }
 
@@ -31,14 +47,22 @@ type ConfidentialStorageBackend interface {
 type StoreTransportTopic interface {
 	node.Lifecycle
 	Subscribe() (<-chan DAMessage, context.CancelFunc)
-	Publish(DAMessage)
+	// Publish returns nil once the transport has accepted the message for delivery
+	Publish(DAMessage) error
 }
 
 type DAMessage struct {
 	SourceTx    *types.Transaction `json:"sourceTx"`
 	StoreWrites []StoreWrite       `json:"storeWrites"`
 	StoreUUID   uuid.UUID          `json:"storeUUID"`
+	Sequence    uint64             `json:"sequence"`
 	Signature   suave.Bytes        `json:"signature"`
+
+	// KeyAnnouncements are signed individually and are not covered by the message signature
+	KeyAnnouncements []StoreKeyAnnouncement `json:"keyAnnouncements,omitempty"`
+
+	SyncRequest  *SyncRequest  `json:"syncRequest,omitempty"`
+	SyncResponse *SyncResponse `json:"syncResponse,omitempty"`
 }
 
 type StoreWrite struct {
@@ -46,6 +70,13 @@ type StoreWrite struct {
 	Caller common.Address `json:"caller"`
 	Key    string         `json:"key"`
 	Value  suave.Bytes    `json:"value"`
+
+	// Version orders conflicting writes to the same key, the highest one is kept
+	Version WriteVersion `json:"version"`
+
+	// EncryptedValues holds the value encrypted to each of the bid's allowed stores,
+	// writes sent over the transport carry these instead of the plaintext value
+	EncryptedValues map[common.Address]suave.Bytes `json:"encryptedValues,omitempty"`
 }
 
 type DASigner interface {
@@ -70,6 +101,32 @@ type ConfidentialStoreEngine struct {
 
 	storeUUID      uuid.UUID
 	localAddresses map[common.Address]struct{}
+
+	// transportKey is the key other stores encrypt writes sent to this store to
+	transportKey      *ecdsa.PrivateKey
+	transportKeyEpoch uint64
+	storeKeysLock     sync.RWMutex
+	storeKeys         map[common.Address]announcedKey
+
+	// deferredMessages holds the messages to send to stores once they announce their key
+	deferredLock     sync.Mutex
+	deferredMessages []deferredMessage
+
+	// sequenceLock protects the replay protection state and the Lamport clock
+	sequenceLock sync.Mutex
+	clock        uint64
+
+	retention *RetentionPolicy
+	outbox    *Outbox
+	registry  ExecutionNodeRegistry
+
+	// The sync log is kept in memory only, a restarted store only answers sync
+	// requests with the writes it has signed since
+	syncLock        sync.Mutex
+	syncLog         []syncLogEntry
+	syncSequence    uint64
+	syncedSequences map[uuid.UUID]uint64 // Sequence of the last write synced from each store
+	syncLimiters    lru.BasicLRU[common.Address, *rate.Limiter]
 }
 
 func NewConfidentialStoreEngine(backend ConfidentialStorageBackend, transportTopic StoreTransportTopic, daSigner DASigner, chainSigner ChainSigner) *ConfidentialStoreEngine {
@@ -78,13 +135,24 @@ func NewConfidentialStoreEngine(backend ConfidentialStorageBackend, transportTop
 		localAddresses[addr] = struct{}{}
 	}
 
+	transportKey, err := crypto.GenerateKey()
+	if err != nil {
+		panic(fmt.Sprintf("confidential engine: could not generate transport key: %v", err))
+	}
+
 	return &ConfidentialStoreEngine{
-		storage:        backend,
-		transportTopic: transportTopic,
-		daSigner:       daSigner,
-		chainSigner:    chainSigner,
-		storeUUID:      uuid.New(),
-		localAddresses: localAddresses,
+		storage:           backend,
+		transportTopic:    transportTopic,
+		daSigner:          daSigner,
+		chainSigner:       chainSigner,
+		storeUUID:         uuid.New(),
+		localAddresses:    localAddresses,
+		transportKey:      transportKey,
+		transportKeyEpoch: uint64(time.Now().UnixMilli()),
+		storeKeys:         make(map[common.Address]announcedKey),
+
+		syncedSequences: make(map[uuid.UUID]uint64),
+		syncLimiters:    lru.NewBasicLRU[common.Address, *rate.Limiter](syncLimitersCached),
 	}
 }
 
@@ -108,7 +176,23 @@ func (e *ConfidentialStoreEngine) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	e.cancel = cancel
 	e.ctx = ctx
-	go e.ProcessMessages()
+	// Subscribe before requesting a sync so that no response is missed
+	ch, unsubscribe := e.transportTopic.Subscribe()
+	go e.processMessages(ch, unsubscribe)
+	go e.announceStoreKeys()
+
+	// Catch up on the writes missed while not subscribed to the transport
+	if err := e.RequestSync(e.retainedSinceBlock()); err != nil {
+		log.Warn("Confidential engine: could not request sync", "err", err)
+	}
+
+	if e.retention != nil {
+		go e.sweep()
+	}
+
+	if e.outbox != nil {
+		e.outbox.start(ctx, e.transportTopic, e.sealOutboxMessage)
+	}
 
 	return nil
 }
@@ -120,6 +204,11 @@ func (e *ConfidentialStoreEngine) Stop() error {
 
 	e.cancel()
 
+	if e.outbox != nil {
+		// Do not publish to a stopped transport
+		e.outbox.wait()
+	}
+
 	if err := e.transportTopic.Stop(); err != nil {
 		log.Warn("Confidential engine: error while stopping transport", "err", err)
 	}
@@ -138,6 +227,10 @@ func (e *ConfidentialStoreEngine) Backend() ConfidentialStorageBackend {
 
 func (e *ConfidentialStoreEngine) ProcessMessages() {
 	ch, cancel := e.transportTopic.Subscribe()
+	e.processMessages(ch, cancel)
+}
+
+func (e *ConfidentialStoreEngine) processMessages(ch <-chan DAMessage, cancel context.CancelFunc) {
 	defer cancel()
 
 	for {
@@ -181,22 +274,31 @@ func (e *ConfidentialStoreEngine) InitializeBid(bid types.Bid, creationTx *types
 		CreationTx:          creationTx,
 	}
 
-	bidBytes, err := SerializeBidForSigning(&initializedBid)
+	if err := e.signBid(&initializedBid); err != nil {
+		return suave.Bid{}, err
+	}
+
+	return initializedBid, nil
+}
+
+// signBid (re-)signs the bid with the execution node of its creation transaction.
+func (e *ConfidentialStoreEngine) signBid(bid *suave.Bid) error {
+	bidBytes, err := SerializeBidForSigning(bid)
 	if err != nil {
-		return suave.Bid{}, fmt.Errorf("confidential engine: could not hash bid for signing: %w", err)
+		return fmt.Errorf("confidential engine: could not hash bid for signing: %w", err)
 	}
 
-	signingAccount, err := ExecutionNodeFromTransaction(creationTx)
+	signingAccount, err := ExecutionNodeFromTransaction(bid.CreationTx)
 	if err != nil {
-		return suave.Bid{}, fmt.Errorf("confidential engine: could not recover execution node from creation transaction: %w", err)
+		return fmt.Errorf("confidential engine: could not recover execution node from creation transaction: %w", err)
 	}
 
-	initializedBid.Signature, err = e.daSigner.Sign(signingAccount, bidBytes)
+	bid.Signature, err = e.daSigner.Sign(signingAccount, bidBytes)
 	if err != nil {
-		return suave.Bid{}, fmt.Errorf("confidential engine: could not sign initialized bid: %w", err)
+		return fmt.Errorf("confidential engine: could not sign initialized bid: %w", err)
 	}
 
-	return initializedBid, nil
+	return nil
 }
 
 func (e *ConfidentialStoreEngine) FetchBidById(bidId suave.BidId) (suave.Bid, error) {
@@ -207,6 +309,10 @@ func (e *ConfidentialStoreEngine) FetchBidsByProtocolAndBlock(blockNumber uint64
 	return e.storage.FetchBidsByProtocolAndBlock(blockNumber, namespace)
 }
 
+func (e *ConfidentialStoreEngine) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	return e.storage.FetchBids(query)
+}
+
 func (e *ConfidentialStoreEngine) Retrieve(bidId suave.BidId, caller common.Address, key string) ([]byte, error) {
 	bid, err := e.storage.FetchBidById(bidId)
 	if err != nil {
@@ -217,77 +323,173 @@ func (e *ConfidentialStoreEngine) Retrieve(bidId suave.BidId, caller common.Addr
 		return []byte{}, fmt.Errorf("confidential engine: %x not allowed to retrieve %s on %x", caller, key, bidId)
 	}
 
+	if isReservedKey(key) {
+		return []byte{}, fmt.Errorf("confidential engine: %s on %x: %w", key, bidId, errReservedKey)
+	}
+
+	if !bid.CanRead(caller, key) {
+		return []byte{}, fmt.Errorf("confidential engine: %x not allowed to read %s on %x: %w", caller, key, bidId, suave.ErrKeyAccessDenied)
+	}
+
 	return e.storage.Retrieve(bid, caller, key)
 }
 
 func (e *ConfidentialStoreEngine) Finalize(tx *types.Transaction, newBids map[suave.BidId]suave.Bid, stores []StoreWrite) error {
-	//
+	bids := make([]suave.Bid, 0, len(newBids))
 	for _, bid := range newBids {
-		err := e.storage.InitializeBid(bid)
+		bids = append(bids, bid)
+	}
+
+	// Only writes which are propagated are versioned, the version is the sequence of the message
+	_, sigErr := e.chainSigner.Sender(tx)
+
+	var (
+		signingAccount common.Address
+		sequence       uint64
+		commitWrites   = stores
+	)
+	if sigErr == nil {
+		var err error
+		signingAccount, err = ExecutionNodeFromTransaction(tx)
 		if err != nil {
-			// TODO: deinitialize!
-			return fmt.Errorf("confidential engine: store backend failed to initialize bid: %w", err)
+			return fmt.Errorf("confidential engine: could not recover execution node from source transaction: %w", err)
 		}
-	}
 
-	for _, sw := range stores {
-		if _, err := e.storage.Store(sw.Bid, sw.Caller, sw.Key, sw.Value); err != nil {
-			// TODO: deinitialize and deStore!
-			return fmt.Errorf("failed to store data: %w", err)
+		sequence, err = e.nextSequence(signingAccount)
+		if err != nil {
+			return err
+		}
+
+		versioned := make([]StoreWrite, 0, len(stores))
+		for _, sw := range stores {
+			sw.Version = WriteVersion{Sequence: sequence, Signer: signingAccount}
+			versioned = append(versioned, sw)
+		}
+		stores = versioned
+
+		versions, err := versionWrites(stores)
+		if err != nil {
+			return fmt.Errorf("confidential engine: could not encode write versions: %w", err)
+		}
+		seen := seenWrites(stores)
+		commitWrites = make([]StoreWrite, 0, len(stores)+len(versions)+len(seen))
+		commitWrites = append(append(append(commitWrites, stores...), versions...), seen...)
+
+		unkeyedStores, err := e.unkeyedStores(stores)
+		if err != nil {
+			return err
+		}
+		// Registered stores announce their key, nothing is committed unless each of them can be
+		// sent the writes. Without a registry the writes are sent once the stores announce theirs.
+		if e.registry != nil && len(unkeyedStores) > 0 {
+			return fmt.Errorf("confidential engine: could not send writes to stores %v: %w", unkeyedStores, errNoStoreKey)
 		}
 	}
 
-	// Sign and propagate the message
-	pwMsg := DAMessage{
-		SourceTx:    tx,
-		StoreWrites: stores,
-		StoreUUID:   e.storeUUID,
+	if err := e.storage.Commit(bids, commitWrites); err != nil {
+		return fmt.Errorf("confidential engine: store backend failed to commit: %w", err)
 	}
 
-	if _, sigErr := e.chainSigner.Sender(tx); sigErr != nil {
+	if sigErr != nil {
 		log.Info("confidential engine: refusing to send writes based on unsigned transaction", "hash", tx.Hash().Hex(), "err", sigErr)
 		return suave.ErrUnsignedFinalize
 	}
 
-	msgBytes, err := SerializeMessageForSigning(&pwMsg)
+	e.recordWrites(stores)
+
+	message := DAMessage{
+		SourceTx:    tx,
+		StoreWrites: stores,
+		StoreUUID:   e.storeUUID,
+		Sequence:    sequence,
+	}
+
+	if e.outbox != nil {
+		// Published in the background once persisted, retried until the transport accepts it.
+		// The values are left out of the persisted message, they are read from the storage and
+		// encrypted to the keys the stores have when it is published, which they may have
+		// regenerated in the meantime. The writes are committed first: if the message cannot be
+		// persisted, other stores only get the writes by requesting a sync.
+		return e.outbox.Add(DAMessage{
+			SourceTx:    tx,
+			StoreWrites: withoutValues(stores),
+			StoreUUID:   e.storeUUID,
+			Sequence:    sequence,
+		})
+	}
+
+	// Sign and propagate the message
+	sealed, err := e.sealMessage(message, nil)
 	if err != nil {
-		return fmt.Errorf("confidential engine: could not hash message for signing: %w", err)
+		return err
+	}
+
+	// TODO: avoid marshalling twice
+	go e.publish(sealed)
+
+	return nil
+}
+
+// sealOutboxMessage reads the values of the writes of a message persisted in
+// the outbox and seals it for publishing. Messages persisted sealed are
+// published as they are.
+func (e *ConfidentialStoreEngine) sealOutboxMessage(message DAMessage) (DAMessage, error) {
+	if len(message.Signature) > 0 {
+		return message, nil
 	}
 
-	signingAccount, err := ExecutionNodeFromTransaction(tx)
+	message.StoreWrites = e.loadStoreWrites(message.StoreWrites)
+	return e.sealMessage(message, nil)
+}
+
+// signMessage signs the message with the given account.
+func (e *ConfidentialStoreEngine) signMessage(account common.Address, message *DAMessage) error {
+	msgBytes, err := SerializeMessageForSigning(message)
 	if err != nil {
-		return fmt.Errorf("confidential engine: could not recover execution node from source transaction: %w", err)
+		return fmt.Errorf("confidential engine: could not hash message for signing: %w", err)
 	}
 
-	pwMsg.Signature, err = e.daSigner.Sign(signingAccount, msgBytes)
+	message.Signature, err = e.daSigner.Sign(account, msgBytes)
 	if err != nil {
 		return fmt.Errorf("confidential engine: could not sign message: %w", err)
 	}
 
-	// TODO: avoid marshalling twice
-	go e.transportTopic.Publish(pwMsg)
-
 	return nil
 }
 
+// publish sends the message without retrying, failures are only logged.
+func (e *ConfidentialStoreEngine) publish(message DAMessage) {
+	if err := e.transportTopic.Publish(message); err != nil {
+		log.Warn("Confidential engine: could not publish message", "err", err)
+	}
+}
+
 func (e *ConfidentialStoreEngine) NewMessage(message DAMessage) error {
 	// Note the validation is a work in progress and not guaranteed to be correct!
 
-	// Message-level validation
-	msgBytes, err := SerializeMessageForSigning(&message)
-	if err != nil {
-		return fmt.Errorf("confidential engine: could not hash received message: %w", err)
+	if len(message.KeyAnnouncements) > 0 && message.StoreUUID != e.storeUUID {
+		if err := e.handleKeyAnnouncements(message.KeyAnnouncements); err != nil {
+			return err
+		}
 	}
-	recoveredMessageSigner, err := e.daSigner.Sender(msgBytes, message.Signature)
-	if err != nil {
-		return fmt.Errorf("confidential engine: incorrect message signature: %w", err)
+
+	if message.SyncRequest != nil && message.StoreUUID != e.storeUUID {
+		return e.handleSyncRequest(message.SyncRequest)
 	}
-	expectedMessageSigner, err := ExecutionNodeFromTransaction(message.SourceTx)
-	if err != nil {
-		return fmt.Errorf("confidential engine: could not recover signer from message: %w", err)
+
+	if message.SyncResponse != nil {
+		return e.handleSyncResponse(message)
 	}
-	if recoveredMessageSigner != expectedMessageSigner {
-		return fmt.Errorf("confidential engine: message signer %x, expected %x", recoveredMessageSigner, expectedMessageSigner)
+
+	if message.SourceTx == nil && len(message.StoreWrites) == 0 {
+		// Key announcements only
+		return nil
+	}
+
+	// Message-level validation
+	recoveredMessageSigner, err := verifyMessageSigner(e.daSigner, &message)
+	if err != nil {
+		return err
 	}
 
 	if message.StoreUUID == e.storeUUID {
@@ -303,11 +505,54 @@ func (e *ConfidentialStoreEngine) NewMessage(message DAMessage) error {
 		return fmt.Errorf("confidential engine: source tx for message is not signed properly: %w", err)
 	}
 
-	// TODO: check if message.SourceTx is valid and insert it into the mempool!
+	if err := e.validateExecutionNode(recoveredMessageSigner, message.StoreWrites); err != nil {
+		return err
+	}
 
-	// Bid level validation
+	// TODO: check if message.SourceTx is valid and insert it into the mempool!
 
+	messageVersion := WriteVersion{Sequence: message.Sequence, Signer: recoveredMessageSigner}
 	for _, sw := range message.StoreWrites {
+		if sw.Version != messageVersion {
+			return fmt.Errorf("confidential engine: write version %v does not match the message %v", sw.Version, messageVersion)
+		}
+	}
+
+	writes, err := e.validateStoreWrites(recoveredMessageSigner, message.StoreWrites)
+	if err != nil {
+		return err
+	}
+
+	if err := e.acceptWrites(recoveredMessageSigner, message.Sequence, writes); err != nil {
+		return err
+	}
+
+	e.applyWrites(writes)
+
+	return nil
+}
+
+// validateStoreWrites runs the bid level validation of writes received from
+// the given store, and returns the decrypted writes meant for this store.
+// Writes to bids past their retention are rejected, as the bids may have been
+// pruned together with the marks of the writes seen.
+func (e *ConfidentialStoreEngine) validateStoreWrites(signer common.Address, storeWrites []StoreWrite) ([]StoreWrite, error) {
+	retainedSince := e.retainedSinceBlock()
+
+	writes := make([]StoreWrite, 0, len(storeWrites))
+	for _, sw := range storeWrites {
+		if isReservedKey(sw.Key) {
+			return nil, fmt.Errorf("confidential engine: %s on bid %x: %w", sw.Key, sw.Bid.Id, errReservedKey)
+		}
+
+		if sw.Bid.DecryptionCondition < retainedSince {
+			return nil, fmt.Errorf("confidential engine: write of %s on bid %x with decryption condition %d: %w", sw.Key, sw.Bid.Id, sw.Bid.DecryptionCondition, errExpiredBid)
+		}
+
+		if sw.Version.Sequence == 0 {
+			return nil, fmt.Errorf("confidential engine: write of %s on bid %x has no version", sw.Key, sw.Bid.Id)
+		}
+
 		expectedId, err := calculateBidId(types.Bid{
 			Id:                  sw.Bid.Id,
 			Salt:                sw.Bid.Salt,
@@ -317,46 +562,83 @@ func (e *ConfidentialStoreEngine) NewMessage(message DAMessage) error {
 			Version:             sw.Bid.Version,
 		})
 		if err != nil {
-			return fmt.Errorf("confidential engine: could not calculate received bids id: %w", err)
+			return nil, fmt.Errorf("confidential engine: could not calculate received bids id: %w", err)
 		}
 
 		if expectedId != sw.Bid.Id {
-			return fmt.Errorf("confidential engine: received bids id (%x) does not match the expected (%x)", sw.Bid.Id, expectedId)
+			return nil, fmt.Errorf("confidential engine: received bids id (%x) does not match the expected (%x)", sw.Bid.Id, expectedId)
 		}
 
 		bidBytes, err := SerializeBidForSigning(&sw.Bid)
 		if err != nil {
-			return fmt.Errorf("confidential engine: could not hash received bid: %w", err)
+			return nil, fmt.Errorf("confidential engine: could not hash received bid: %w", err)
 		}
 		recoveredBidSigner, err := e.daSigner.Sender(bidBytes, sw.Bid.Signature)
 		if err != nil {
-			return fmt.Errorf("confidential engine: incorrect bid signature: %w", err)
+			return nil, fmt.Errorf("confidential engine: incorrect bid signature: %w", err)
 		}
 		expectedBidSigner, err := ExecutionNodeFromTransaction(sw.Bid.CreationTx)
 		if err != nil {
-			return fmt.Errorf("confidential engine: could not recover signer from bid: %w", err)
+			return nil, fmt.Errorf("confidential engine: could not recover signer from bid: %w", err)
 		}
 		if recoveredBidSigner != expectedBidSigner {
-			return fmt.Errorf("confidential engine: bid signer %x, expected %x", recoveredBidSigner, expectedBidSigner)
+			return nil, fmt.Errorf("confidential engine: bid signer %x, expected %x", recoveredBidSigner, expectedBidSigner)
 		}
 
-		if !slices.Contains(sw.Bid.AllowedStores, recoveredMessageSigner) {
-			return fmt.Errorf("confidential engine: sw signer %x not allowed to store on bid %x", recoveredMessageSigner, sw.Bid.Id)
+		if !slices.Contains(sw.Bid.AllowedStores, signer) {
+			return nil, fmt.Errorf("confidential engine: sw signer %x not allowed to store on bid %x", signer, sw.Bid.Id)
 		}
 
 		if !slices.Contains(sw.Bid.AllowedPeekers, sw.Caller) && !slices.Contains(sw.Bid.AllowedPeekers, suave.AllowedPeekerAny) {
-			return fmt.Errorf("confidential engine: caller %x not allowed on bid %x", sw.Caller, sw.Bid.Id)
+			return nil, fmt.Errorf("confidential engine: caller %x not allowed on bid %x", sw.Caller, sw.Bid.Id)
+		}
+
+		value, forThisStore, err := e.decryptStoreWrite(sw)
+		if err != nil {
+			return nil, err
+		}
+
+		// Key rules are not part of the bid id, prefer the ones we already know about
+		rulesBid := sw.Bid
+		if storedBid, err := e.storage.FetchBidById(sw.Bid.Id); err == nil {
+			rulesBid = storedBid
+		}
+
+		if !rulesBid.CanWrite(sw.Caller, sw.Key) {
+			return nil, fmt.Errorf("confidential engine: caller %x not allowed to write %s on bid %x: %w", sw.Caller, sw.Key, sw.Bid.Id, suave.ErrKeyAccessDenied)
+		}
+
+		if rulesBid.IsWriteOnce(sw.Key) {
+			if existing, err := e.storage.Retrieve(rulesBid, sw.Caller, sw.Key); err == nil && forThisStore && !bytes.Equal(existing, value) {
+				return nil, fmt.Errorf("confidential engine: %s on bid %x: %w", sw.Key, sw.Bid.Id, suave.ErrKeyWriteOnce)
+			}
 		}
 
 		// TODO: move to types.Sender()
 		_, err = e.chainSigner.Sender(sw.Bid.CreationTx)
 		if err != nil {
-			return fmt.Errorf("confidential engine: creation tx for bid id %x is not signed properly: %w", sw.Bid.Id, err)
+			return nil, fmt.Errorf("confidential engine: creation tx for bid id %x is not signed properly: %w", sw.Bid.Id, err)
+		}
+
+		if !forThisStore {
+			// Meant for other stores only
+			continue
 		}
+
+		sw.Value = value
+		sw.EncryptedValues = nil
+		writes = append(writes, sw)
 	}
 
-	for _, sw := range message.StoreWrites {
-		err = e.storage.InitializeBid(sw.Bid)
+	return writes, nil
+}
+
+// applyWrites stores the writes unless a write with a higher version was
+// already applied to the same key, so that the order writes arrive in does not
+// change the outcome.
+func (e *ConfidentialStoreEngine) applyWrites(writes []StoreWrite) {
+	for _, sw := range writes {
+		err := e.storage.InitializeBid(sw.Bid)
 		if err != nil {
 			if !errors.Is(err, suave.ErrBidAlreadyPresent) {
 				log.Error("confidential engine: unexpected error while initializing bid from transport: %w", err)
@@ -364,14 +646,59 @@ func (e *ConfidentialStoreEngine) NewMessage(message DAMessage) error {
 			}
 		}
 
-		_, err = e.storage.Store(sw.Bid, sw.Caller, sw.Key, sw.Value)
+		e.sequenceLock.Lock()
+		e.observeSequence(sw.Version.Sequence)
+		e.sequenceLock.Unlock()
+
+		if current, found := e.fetchWriteVersion(sw.Bid, sw.Key); found && sw.Version.Less(current) {
+			log.Debug("Confidential engine: dropping write superseded by a newer one", "bid", sw.Bid.Id, "key", sw.Key, "version", sw.Version, "current", current)
+			continue
+		}
+
+		versions, err := versionWrites([]StoreWrite{sw})
 		if err != nil {
+			log.Error("confidential engine: could not encode write version", "err", err)
+			continue
+		}
+
+		if err := e.storage.Commit(nil, append([]StoreWrite{sw}, versions...)); err != nil {
 			log.Error("confidential engine: unexpected error while storing: %w", err)
 			continue // Don't abandon!
 		}
 	}
+}
+
+// withoutValues returns the writes without their values, for them to be read
+// from the storage with loadStoreWrites.
+func withoutValues(writes []StoreWrite) []StoreWrite {
+	res := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		res = append(res, StoreWrite{Bid: sw.Bid, Caller: sw.Caller, Key: sw.Key, Version: sw.Version})
+	}
+	return res
+}
 
-	return nil
+// loadStoreWrites returns the writes with their values read from the storage.
+// Writes superseded by a newer write to the same key since, or to bids pruned
+// since, are dropped.
+func (e *ConfidentialStoreEngine) loadStoreWrites(writes []StoreWrite) []StoreWrite {
+	res := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		if current, found := e.fetchWriteVersion(sw.Bid, sw.Key); !found || current != sw.Version {
+			log.Debug("Confidential engine: not sending superseded write", "bid", sw.Bid.Id, "key", sw.Key, "version", sw.Version)
+			continue
+		}
+
+		value, err := e.storage.Retrieve(sw.Bid, sw.Caller, sw.Key)
+		if err != nil {
+			log.Debug("Confidential engine: not sending write which is no longer stored", "bid", sw.Bid.Id, "key", sw.Key, "err", err)
+			continue
+		}
+
+		sw.Value = value
+		res = append(res, sw)
+	}
+	return res
 }
 
 func SerializeBidForSigning(bid *suave.Bid) ([]byte, error) {
@@ -383,6 +710,7 @@ func SerializeBidForSigning(bid *suave.Bid) ([]byte, error) {
 		AllowedStores:       bid.AllowedStores,
 		Version:             bid.Version,
 		CreationTx:          bid.CreationTx,
+		KeyRules:            bid.KeyRules,
 	})
 	if err != nil {
 		return []byte{}, err
@@ -391,12 +719,41 @@ func SerializeBidForSigning(bid *suave.Bid) ([]byte, error) {
 	return []byte(fmt.Sprintf("\x19Suave Signed Message:\n%d%s", len(bidBytes), string(bidBytes))), nil
 }
 
+// verifyMessageSigner checks that the message is signed by the execution node
+// of its source transaction and returns the signer.
+func verifyMessageSigner(daSigner DASigner, message *DAMessage) (common.Address, error) {
+	if message.SourceTx == nil {
+		return common.Address{}, errors.New("confidential engine: message has no source transaction")
+	}
+
+	msgBytes, err := SerializeMessageForSigning(message)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("confidential engine: could not hash received message: %w", err)
+	}
+	recoveredMessageSigner, err := daSigner.Sender(msgBytes, message.Signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("confidential engine: incorrect message signature: %w", err)
+	}
+	expectedMessageSigner, err := ExecutionNodeFromTransaction(message.SourceTx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("confidential engine: could not recover signer from message: %w", err)
+	}
+	if recoveredMessageSigner != expectedMessageSigner {
+		return common.Address{}, fmt.Errorf("confidential engine: message signer %x, expected %x", recoveredMessageSigner, expectedMessageSigner)
+	}
+
+	return recoveredMessageSigner, nil
+}
+
 func SerializeMessageForSigning(message *DAMessage) ([]byte, error) {
 	msgBytes, err := json.Marshal(DAMessage{
 		SourceTx:    message.SourceTx,
 		StoreWrites: message.StoreWrites,
 		StoreUUID:   message.StoreUUID,
+		Sequence:    message.Sequence,
 		Signature:   nil,
+
+		SyncResponse: message.SyncResponse,
 	})
 	if err != nil {
 		return []byte{}, err
@@ -413,7 +770,7 @@ func (MockTransport) Stop() error  { return nil }
 func (MockTransport) Subscribe() (<-chan DAMessage, context.CancelFunc) {
 	return nil, func() {}
 }
-func (MockTransport) Publish(DAMessage) {}
+func (MockTransport) Publish(DAMessage) error { return nil }
 
 type MockSigner struct{}
 
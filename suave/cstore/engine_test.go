@@ -1,13 +1,16 @@
 package cstore
 
 import (
+	"crypto/rand"
 	"errors"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
 	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -40,6 +43,19 @@ func (*FakeStoreBackend) FetchEngineBidById(bidId suave.BidId) (suave.Bid, error
 func (b *FakeStoreBackend) Store(bid suave.Bid, caller common.Address, key string, value []byte) (suave.Bid, error) {
 	return b.OnStore(bid, caller, key, value)
 }
+func (b *FakeStoreBackend) Commit(bids []suave.Bid, writes []StoreWrite) error {
+	for _, sw := range writes {
+		if _, err := b.OnStore(sw.Bid, sw.Caller, sw.Key, sw.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*FakeStoreBackend) Prune(decryptionConditionBelow uint64) (PruneResult, error) {
+	return PruneResult{}, nil
+}
+
 func (*FakeStoreBackend) Retrieve(bid suave.Bid, caller common.Address, key string) ([]byte, error) {
 	return nil, errors.New("not implemented")
 }
@@ -52,6 +68,10 @@ func (*FakeStoreBackend) FetchBidsByProtocolAndBlock(blockNumber uint64, namespa
 	return nil
 }
 
+func (*FakeStoreBackend) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	return nil, 0, nil
+}
+
 func (*FakeStoreBackend) SubmitBid(types.Bid) error {
 	return nil
 }
@@ -95,10 +115,18 @@ func TestOwnMessageDropping(t *testing.T) {
 
 	*wasCalled = false
 
+	encryptedValue, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(&engine.transportKey.PublicKey), nil, nil, encryptionAdditionalData(testBid.Id, ""))
+	require.NoError(t, err)
+
 	daMessage := DAMessage{
-		SourceTx:    dummyCreationTx,
-		StoreUUID:   engine.storeUUID,
-		StoreWrites: []StoreWrite{{Bid: testBid}},
+		SourceTx:  dummyCreationTx,
+		StoreUUID: engine.storeUUID,
+		StoreWrites: []StoreWrite{{
+			Bid:             testBid,
+			Version:         WriteVersion{Sequence: 1, Signer: common.Address{0x42}},
+			EncryptedValues: map[common.Address]suave.Bytes{{0x42}: encryptedValue},
+		}},
+		Sequence: 1,
 	}
 
 	daMessageBytes, err := SerializeMessageForSigning(&daMessage)
@@ -124,3 +152,333 @@ func TestOwnMessageDropping(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, *wasCalled)
 }
+
+func TestFinalizeIsAtomic(t *testing.T) {
+	backend := NewLocalConfidentialStore()
+	engine := NewConfidentialStoreEngine(backend, MockTransport{}, MockSigner{}, MockChainSigner{})
+
+	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	dummyCreationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	tstore := engine.NewTransactionalStore(dummyCreationTx)
+
+	firstBid, err := tstore.InitializeBid(types.Bid{
+		Salt:                RandomBidId(),
+		DecryptionCondition: 46,
+		AllowedPeekers:      []common.Address{{0x43}},
+		Version:             "v0-test",
+	})
+	require.NoError(t, err)
+
+	secondBid, err := tstore.InitializeBid(types.Bid{
+		Salt:                RandomBidId(),
+		DecryptionCondition: 46,
+		AllowedPeekers:      []common.Address{{0x43}},
+		Version:             "v0-test",
+	})
+	require.NoError(t, err)
+
+	_, err = tstore.Store(firstBid.Id, firstBid.AllowedPeekers[0], "xx", []byte{0x44})
+	require.NoError(t, err)
+	_, err = tstore.Store(secondBid.Id, secondBid.AllowedPeekers[0], "xx", []byte{0x45})
+	require.NoError(t, err)
+
+	// Inject a conflicting bid directly into the backend so that committing one of the bids fails
+	conflictingBid, err := tstore.FetchBidById(secondBid.Id)
+	require.NoError(t, err)
+	require.NoError(t, backend.InitializeBid(conflictingBid))
+
+	require.ErrorIs(t, tstore.Finalize(), suave.ErrBidAlreadyPresent)
+
+	// Nothing from the failed finalize should be visible
+	_, err = engine.FetchBidById(firstBid.Id)
+	require.Error(t, err)
+	require.Len(t, engine.FetchBidsByProtocolAndBlock(46, "v0-test"), 1)
+	_, err = engine.Retrieve(firstBid.Id, firstBid.AllowedPeekers[0], "xx")
+	require.Error(t, err)
+	_, err = engine.Retrieve(secondBid.Id, secondBid.AllowedPeekers[0], "xx")
+	require.Error(t, err)
+}
+
+func TestRetentionSweeper(t *testing.T) {
+	backend := NewLocalConfidentialStore()
+	engine := NewConfidentialStoreEngine(backend, MockTransport{}, MockSigner{}, MockChainSigner{})
+	engine.SetRetentionPolicy(RetentionPolicy{
+		GracePeriod:   5,
+		SweepInterval: 5 * time.Millisecond,
+		CurrentBlock:  func() (uint64, error) { return 30, nil },
+	})
+
+	expiredBid := suave.Bid{Id: suave.RandomBidId(), DecryptionCondition: 24, Version: "v0-test"}
+	retainedBid := suave.Bid{Id: suave.RandomBidId(), DecryptionCondition: 25, Version: "v0-test"}
+	require.NoError(t, backend.Commit([]suave.Bid{expiredBid, retainedBid}, []StoreWrite{{Bid: expiredBid, Key: "xx", Value: []byte{0x44}}}))
+
+	require.NoError(t, engine.Start())
+	t.Cleanup(func() { engine.Stop() })
+
+	require.Eventually(t, func() bool {
+		_, err := engine.FetchBidById(expiredBid.Id)
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+
+	_, err := backend.Retrieve(expiredBid, common.Address{}, "xx")
+	require.Error(t, err)
+
+	_, err = engine.FetchBidById(retainedBid.Id)
+	require.NoError(t, err)
+}
+
+func TestNewMessageKeyRules(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+
+	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	dummyCreationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	owner, other := common.Address{0x43}, common.Address{0x44}
+
+	tstore := engine.NewTransactionalStore(dummyCreationTx)
+	testBid, err := tstore.InitializeBid(types.Bid{
+		Salt:                RandomBidId(),
+		DecryptionCondition: 46,
+		AllowedPeekers:      []common.Address{owner, other},
+		AllowedStores:       []common.Address{{0x42}},
+		Version:             "v0-test",
+	})
+	require.NoError(t, err)
+
+	_, err = tstore.SetKeyAccessRule(testBid.Id, owner, suave.KeyAccessRule{Prefix: "secret", Writers: []common.Address{owner}})
+	require.NoError(t, err)
+	_, err = tstore.SetKeyAccessRule(testBid.Id, owner, suave.KeyAccessRule{Prefix: "once", WriteOnce: true})
+	require.NoError(t, err)
+	_, err = tstore.Store(testBid.Id, owner, "once", []byte{0x01})
+	require.NoError(t, err)
+	require.NoError(t, tstore.Finalize())
+
+	ruledBid, err := engine.FetchBidById(testBid.Id)
+	require.NoError(t, err)
+
+	// Sequences below are past the one the engine used for its own message
+	sequence := uint64(100)
+	newSignedMessage := func(sw StoreWrite) DAMessage {
+		sequence++
+		sw.Version = WriteVersion{Sequence: sequence, Signer: common.Address{0x42}}
+
+		encryptedValue, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(&engine.transportKey.PublicKey), sw.Value, nil, encryptionAdditionalData(sw.Bid.Id, sw.Key))
+		require.NoError(t, err)
+		sw.Value = nil
+		sw.EncryptedValues = map[common.Address]suave.Bytes{{0x42}: encryptedValue}
+
+		daMessage := DAMessage{
+			SourceTx:    dummyCreationTx,
+			StoreUUID:   uuid.New(),
+			StoreWrites: []StoreWrite{sw},
+			Sequence:    sequence,
+		}
+
+		daMessageBytes, err := SerializeMessageForSigning(&daMessage)
+		require.NoError(t, err)
+
+		daMessage.Signature, err = MockSigner{}.Sign(common.Address{0x42}, daMessageBytes)
+		require.NoError(t, err)
+		return daMessage
+	}
+
+	err = engine.NewMessage(newSignedMessage(StoreWrite{Bid: ruledBid, Caller: other, Key: "secret-key", Value: []byte{0x02}}))
+	require.ErrorIs(t, err, suave.ErrKeyAccessDenied)
+
+	// Stripping the rules from the bid does not help as the stored bid is used
+	strippedBid := ruledBid
+	strippedBid.KeyRules = nil
+	strippedBidBytes, err := SerializeBidForSigning(&strippedBid)
+	require.NoError(t, err)
+	strippedBid.Signature, err = MockSigner{}.Sign(common.Address{0x42}, strippedBidBytes)
+	require.NoError(t, err)
+
+	err = engine.NewMessage(newSignedMessage(StoreWrite{Bid: strippedBid, Caller: other, Key: "secret-key", Value: []byte{0x02}}))
+	require.ErrorIs(t, err, suave.ErrKeyAccessDenied)
+
+	err = engine.NewMessage(newSignedMessage(StoreWrite{Bid: ruledBid, Caller: other, Key: "once", Value: []byte{0x03}}))
+	require.ErrorIs(t, err, suave.ErrKeyWriteOnce)
+
+	// Replaying the same value is fine
+	require.NoError(t, engine.NewMessage(newSignedMessage(StoreWrite{Bid: ruledBid, Caller: owner, Key: "once", Value: []byte{0x01}})))
+	require.NoError(t, engine.NewMessage(newSignedMessage(StoreWrite{Bid: ruledBid, Caller: owner, Key: "secret-key", Value: []byte{0x02}})))
+}
+
+func TestFinalizeWithoutStoreKey(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	bid, err := engine.InitializeBid(types.Bid{
+		AllowedPeekers: []common.Address{{0x41}},
+		AllowedStores:  []common.Address{{0x42}, {0x43}},
+	}, creationTx)
+	require.NoError(t, err)
+
+	// Addresses which are not stores are not sent the writes
+	engine.SetExecutionNodeRegistry(fakeExecutionNodeRegistry{})
+	_, unkeyedStores, err := engine.encryptStoreWrites([]StoreWrite{{Bid: bid, Key: "xx", Value: []byte{0x43}}}, nil)
+	require.NoError(t, err)
+	require.Empty(t, unkeyedStores)
+
+	// The other store has not announced its key, nothing is committed
+	engine.SetExecutionNodeRegistry(fakeExecutionNodeRegistry{
+		{0x43}: {Address: common.Address{0x1}, DAKeys: []common.Address{{0x43}}},
+	})
+	err = engine.Finalize(creationTx, map[suave.BidId]suave.Bid{bid.Id: bid}, []StoreWrite{{
+		Bid:    bid,
+		Caller: common.Address{0x41},
+		Key:    "xx",
+		Value:  []byte{0x43},
+	}})
+	require.ErrorIs(t, err, errNoStoreKey)
+
+	_, err = engine.FetchBidById(bid.Id)
+	require.ErrorIs(t, err, suave.ErrBidNotFound)
+}
+
+func TestTransportEncryption(t *testing.T) {
+	sender := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+	receiver := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+
+	announcement := StoreKeyAnnouncement{Store: common.Address{0x43}, PublicKey: crypto.FromECDSAPub(&receiver.transportKey.PublicKey)}
+	announcementBytes, err := SerializeKeyAnnouncementForSigning(&announcement)
+	require.NoError(t, err)
+
+	// Announcing a key for another store is rejected
+	announcement.Signature, err = FakeDASigner{}.Sign(common.Address{0x44}, announcementBytes)
+	require.NoError(t, err)
+	require.Error(t, sender.handleKeyAnnouncements([]StoreKeyAnnouncement{announcement}))
+
+	announcement.Signature, err = FakeDASigner{}.Sign(common.Address{0x43}, announcementBytes)
+	require.NoError(t, err)
+	require.NoError(t, sender.handleKeyAnnouncements([]StoreKeyAnnouncement{announcement}))
+
+	// Stores which have not announced their key are not encrypted to
+	sender.SetExecutionNodeRegistry(fakeExecutionNodeRegistry{
+		{0x44}: {Address: common.Address{0x1}, DAKeys: []common.Address{{0x44}}},
+	})
+	bid := suave.Bid{Id: suave.RandomBidId(), AllowedStores: []common.Address{{0x42}, {0x43}, {0x44}}}
+	_, unkeyedStores, err := sender.encryptStoreWrites([]StoreWrite{{Bid: bid, Key: "xx", Value: []byte{0x01}}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{{0x44}}, unkeyedStores)
+
+	bid.AllowedStores = []common.Address{{0x42}, {0x43}}
+	writes, _, err := sender.encryptStoreWrites([]StoreWrite{{Bid: bid, Key: "xx", Value: []byte{0x01}}}, nil)
+	require.NoError(t, err)
+	require.Len(t, writes, 1)
+	require.Empty(t, writes[0].Value)
+	// The sender stores locally
+	require.Len(t, writes[0].EncryptedValues, 1)
+
+	value, forThisStore, err := receiver.decryptStoreWrite(writes[0])
+	require.NoError(t, err)
+	require.True(t, forThisStore)
+	require.Equal(t, []byte{0x01}, value)
+
+	_, forThisStore, err = sender.decryptStoreWrite(writes[0])
+	require.NoError(t, err)
+	require.False(t, forThisStore)
+
+	// The encrypted value is bound to the bid and key
+	movedWrite := writes[0]
+	movedWrite.Key = "yy"
+	_, _, err = receiver.decryptStoreWrite(movedWrite)
+	require.Error(t, err)
+}
+
+func TestFinalizeDefersWritesWithoutRegistry(t *testing.T) {
+	transport := &recordingTransport{}
+	sender := NewConfidentialStoreEngine(NewLocalConfidentialStore(), transport, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+	receiver := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	bid, err := sender.InitializeBid(types.Bid{
+		AllowedPeekers: []common.Address{{0x41}},
+		AllowedStores:  []common.Address{{0x43}},
+	}, creationTx)
+	require.NoError(t, err)
+
+	// Without a registry the store has to be sent the write once it announces its key
+	require.NoError(t, sender.Finalize(creationTx, map[suave.BidId]suave.Bid{bid.Id: bid}, []StoreWrite{{
+		Bid:    bid,
+		Caller: common.Address{0x41},
+		Key:    "xx",
+		Value:  []byte{0x43},
+	}}))
+
+	require.Eventually(t, func() bool { return len(transport.sourceMessages()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Empty(t, transport.sourceMessages()[0].StoreWrites[0].EncryptedValues)
+
+	announcements, err := receiver.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, sender.handleKeyAnnouncements(announcements))
+
+	require.Eventually(t, func() bool { return len(transport.sourceMessages()) == 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, receiver.NewMessage(transport.sourceMessages()[1]))
+
+	value, err := receiver.Retrieve(bid.Id, common.Address{0x41}, "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43}, value)
+
+	// Sent once only
+	sender.deferredLock.Lock()
+	require.Empty(t, sender.deferredMessages)
+	sender.deferredLock.Unlock()
+}
+
+func TestKeyAnnouncementReplay(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+
+	store := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+	oldAnnouncements, err := store.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, engine.handleKeyAnnouncements(oldAnnouncements))
+
+	// The store restarts with a new key
+	restarted := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+	restarted.transportKeyEpoch = store.transportKeyEpoch + 1
+	announcements, err := restarted.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, engine.handleKeyAnnouncements(announcements))
+
+	// Replaying the announcement of the old key does not bring it back
+	require.NoError(t, engine.handleKeyAnnouncements(oldAnnouncements))
+
+	bid := suave.Bid{Id: suave.RandomBidId(), AllowedStores: []common.Address{{0x42}, {0x43}}}
+	writes, _, err := engine.encryptStoreWrites([]StoreWrite{{Bid: bid, Key: "xx", Value: []byte{0x01}}}, nil)
+	require.NoError(t, err)
+
+	value, _, err := restarted.decryptStoreWrite(writes[0])
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01}, value)
+
+	_, _, err = store.decryptStoreWrite(writes[0])
+	require.Error(t, err)
+}
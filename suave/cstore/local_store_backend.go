@@ -1,7 +1,7 @@
 package cstore
 
 import (
-	"errors"
+	"encoding/hex"
 	"fmt"
 	"sync"
 
@@ -13,17 +13,19 @@ import (
 var _ ConfidentialStorageBackend = &LocalConfidentialStore{}
 
 type LocalConfidentialStore struct {
-	lock    sync.Mutex
-	bids    map[suave.BidId]suave.Bid
-	dataMap map[string][]byte
-	index   map[string][]suave.BidId
+	lock      sync.Mutex
+	bids      map[suave.BidId]suave.Bid
+	dataMap   map[string][]byte
+	index     map[string][]suave.BidId
+	valueKeys map[suave.BidId]map[string]struct{} // Keys of the values in dataMap of each bid
 }
 
 func NewLocalConfidentialStore() *LocalConfidentialStore {
 	return &LocalConfidentialStore{
-		bids:    make(map[suave.BidId]suave.Bid),
-		dataMap: make(map[string][]byte),
-		index:   make(map[string][]suave.BidId),
+		bids:      make(map[suave.BidId]suave.Bid),
+		dataMap:   make(map[string][]byte),
+		index:     make(map[string][]suave.BidId),
+		valueKeys: make(map[suave.BidId]map[string]struct{}),
 	}
 }
 
@@ -32,33 +34,63 @@ func (l *LocalConfidentialStore) Stop() error {
 }
 
 func (l *LocalConfidentialStore) InitializeBid(bid suave.Bid) error {
+	return l.Commit([]suave.Bid{bid}, nil)
+}
+
+func (l *LocalConfidentialStore) Store(bid suave.Bid, caller common.Address, key string, value []byte) (suave.Bid, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.store(bid, caller, key, value)
+	return bid, nil
+}
+
+func (l *LocalConfidentialStore) Commit(bids []suave.Bid, writes []StoreWrite) error {
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	_, found := l.bids[bid.Id]
-	if found {
-		return suave.ErrBidAlreadyPresent
+	// Validate everything before touching the maps so that a failure leaves the store untouched
+	pendingBids := make(map[suave.BidId]struct{}, len(bids))
+	for _, bid := range bids {
+		if _, found := l.bids[bid.Id]; found {
+			return suave.ErrBidAlreadyPresent
+		}
+		if _, found := pendingBids[bid.Id]; found {
+			return suave.ErrBidAlreadyPresent
+		}
+		pendingBids[bid.Id] = struct{}{}
 	}
 
-	l.bids[bid.Id] = bid
+	for _, bid := range bids {
+		l.bids[bid.Id] = bid
+
+		// index the bid by (protocol, block number)
+		indexKey := localIndexKey(bid)
+		bidIds := l.index[indexKey]
+		bidIds = append(bidIds, bid.Id)
+		l.index[indexKey] = bidIds
+	}
 
-	// index the bid by (protocol, block number)
-	indexKey := fmt.Sprintf("protocol-%s-bn-%d", bid.Version, bid.DecryptionCondition)
-	bidIds := l.index[indexKey]
-	bidIds = append(bidIds, bid.Id)
-	l.index[indexKey] = bidIds
+	for _, sw := range writes {
+		l.store(sw.Bid, sw.Caller, sw.Key, sw.Value)
+	}
 
 	return nil
 }
 
-func (l *LocalConfidentialStore) Store(bid suave.Bid, caller common.Address, key string, value []byte) (suave.Bid, error) {
-	l.lock.Lock()
-	defer l.lock.Unlock()
+func localIndexKey(bid suave.Bid) string {
+	return fmt.Sprintf("protocol-%s-bn-%d", bid.Version, bid.DecryptionCondition)
+}
 
-	l.dataMap[fmt.Sprintf("%x-%s", bid.Id, key)] = append(make([]byte, 0, len(value)), value...)
+func (l *LocalConfidentialStore) store(bid suave.Bid, caller common.Address, key string, value []byte) {
+	dataKey := fmt.Sprintf("%x-%s", bid.Id, key)
+	l.dataMap[dataKey] = append(make([]byte, 0, len(value)), value...)
+	if l.valueKeys[bid.Id] == nil {
+		l.valueKeys[bid.Id] = make(map[string]struct{})
+	}
+	l.valueKeys[bid.Id][dataKey] = struct{}{}
 
-	defer log.Trace("CSSW", "caller", caller, "key", key, "value", value, "stored", l.dataMap[fmt.Sprintf("%x-%s", bid.Id, key)])
-	return bid, nil
+	log.Trace("CSSW", "caller", caller, "key", key, "value", value, "stored", l.dataMap[fmt.Sprintf("%x-%s", bid.Id, key)])
 }
 
 func (l *LocalConfidentialStore) Retrieve(bid suave.Bid, caller common.Address, key string) ([]byte, error) {
@@ -80,7 +112,7 @@ func (l *LocalConfidentialStore) FetchBidById(bidId suave.BidId) (suave.Bid, err
 
 	bid, found := l.bids[bidId]
 	if !found {
-		return suave.Bid{}, errors.New("bid not found")
+		return suave.Bid{}, suave.ErrBidNotFound
 	}
 
 	return bid, nil
@@ -90,9 +122,8 @@ func (l *LocalConfidentialStore) FetchBidsByProtocolAndBlock(blockNumber uint64,
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	indexKey := fmt.Sprintf("protocol-%s-bn-%d", namespace, blockNumber)
-	bidIDs, ok := l.index[indexKey]
-	if !ok {
+	bidIDs := l.fetchBidIdsByProtocolAndBlock(blockNumber, namespace)
+	if bidIDs == nil {
 		return nil
 	}
 
@@ -106,3 +137,103 @@ func (l *LocalConfidentialStore) FetchBidsByProtocolAndBlock(blockNumber uint64,
 
 	return res
 }
+
+func (l *LocalConfidentialStore) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return queryBids(query, l.fetchBidIdsByProtocolAndBlock, func(bidId suave.BidId) (suave.Bid, error) {
+		bid, found := l.bids[bidId]
+		if !found {
+			return suave.Bid{}, suave.ErrBidNotFound
+		}
+		return bid, nil
+	})
+}
+
+func (l *LocalConfidentialStore) fetchBidIdsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.BidId {
+	indexKey := fmt.Sprintf("protocol-%s-bn-%d", namespace, blockNumber)
+	return l.index[indexKey]
+}
+
+func (l *LocalConfidentialStore) IterateValues(fn func(bidId suave.BidId, key string, value []byte) error) error {
+	l.lock.Lock()
+	dataMap := make(map[string][]byte, len(l.dataMap))
+	for dataKey, value := range l.dataMap {
+		dataMap[dataKey] = append(make([]byte, 0, len(value)), value...)
+	}
+	l.lock.Unlock()
+
+	for dataKey, value := range dataMap {
+		bidId, key, err := parseBidValueKey(dataKey)
+		if err != nil {
+			return err
+		}
+		if err := fn(bidId, key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseBidValueKey splits a "<bid id hex>-<key>" value key
+func parseBidValueKey(dataKey string) (suave.BidId, string, error) {
+	var bidId suave.BidId
+	idLen := hex.EncodedLen(len(bidId))
+	if len(dataKey) <= idLen || dataKey[idLen] != '-' {
+		return suave.BidId{}, "", fmt.Errorf("malformed value key %s", dataKey)
+	}
+	if _, err := hex.Decode(bidId[:], []byte(dataKey[:idLen])); err != nil {
+		return suave.BidId{}, "", fmt.Errorf("malformed value key %s: %w", dataKey, err)
+	}
+	return bidId, dataKey[idLen+1:], nil
+}
+
+func (l *LocalConfidentialStore) Prune(decryptionConditionBelow uint64) (PruneResult, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	res := PruneResult{}
+	pruned := make(map[string]map[suave.BidId]struct{}) // Pruned bids by index key
+	for bidId, bid := range l.bids {
+		if bid.DecryptionCondition >= decryptionConditionBelow {
+			continue
+		}
+
+		delete(l.bids, bidId)
+		res.Bids++
+
+		for dataKey := range l.valueKeys[bidId] {
+			delete(l.dataMap, dataKey)
+			res.Values++
+		}
+		delete(l.valueKeys, bidId)
+
+		indexKey := localIndexKey(bid)
+		if pruned[indexKey] == nil {
+			pruned[indexKey] = make(map[suave.BidId]struct{})
+		}
+		pruned[indexKey][bidId] = struct{}{}
+	}
+
+	// Only the pruned bids are removed from the index, entries are removed once empty
+	for indexKey, prunedIds := range pruned {
+		var retained []suave.BidId
+		for _, bidId := range l.index[indexKey] {
+			if _, found := prunedIds[bidId]; !found {
+				retained = append(retained, bidId)
+			}
+		}
+		if len(retained) > 0 {
+			l.index[indexKey] = retained
+			continue
+		}
+		if _, found := l.index[indexKey]; found {
+			delete(l.index, indexKey)
+			res.IndexEntries++
+		}
+	}
+
+	return res, nil
+}
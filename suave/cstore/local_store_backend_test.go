@@ -2,9 +2,71 @@ package cstore
 
 import (
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLocal_StoreSuite(t *testing.T) {
 	store := NewLocalConfidentialStore()
 	testBackendStore(t, store)
 }
+
+func TestLocal_Commit(t *testing.T) {
+	store := NewLocalConfidentialStore()
+	testBackendCommit(t, store)
+}
+
+func TestLocal_Prune(t *testing.T) {
+	store := NewLocalConfidentialStore()
+	testBackendPrune(t, store)
+}
+
+func TestLocal_Query(t *testing.T) {
+	store := NewLocalConfidentialStore()
+	testBackendQuery(t, store)
+}
+
+func TestLocal_IterateValues(t *testing.T) {
+	store := NewLocalConfidentialStore()
+	testBackendIterateValues(t, store)
+}
+
+func TestLocal_Encryption(t *testing.T) {
+	store := NewLocalConfidentialStore()
+	testBackendEncryption(t, store)
+}
+
+func TestLocal_PruneSharedIndexKey(t *testing.T) {
+	store := NewLocalConfidentialStore()
+
+	newBid := func(decryptionCondition uint64) suave.Bid {
+		bid := suave.Bid{Id: suave.RandomBidId(), DecryptionCondition: decryptionCondition, Version: "v0-test"}
+		require.NoError(t, store.Commit([]suave.Bid{bid}, []StoreWrite{{Bid: bid, Key: "xx", Value: []byte{0x1}}}))
+		return bid
+	}
+	expiredBid1 := newBid(20)
+	expiredBid2 := newBid(20)
+	retainedBid := newBid(21)
+
+	// Entries listing bids which are not pruned keep them
+	indexKey := localIndexKey(expiredBid1)
+	store.index[indexKey] = append(store.index[indexKey], retainedBid.Id)
+
+	res, err := store.Prune(21)
+	require.NoError(t, err)
+	require.Equal(t, PruneResult{Bids: 2, Values: 2}, res)
+	require.Equal(t, []suave.BidId{retainedBid.Id}, store.index[indexKey])
+
+	// The values of the pruned bids are gone, and only them
+	require.Len(t, store.dataMap, 1)
+	require.Len(t, store.valueKeys, 1)
+	value, err := store.Retrieve(retainedBid, common.Address{}, "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x1}, value)
+	for _, bid := range []suave.Bid{expiredBid1, expiredBid2} {
+		_, err := store.Retrieve(bid, common.Address{}, "xx")
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,309 @@
+package cstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	outboxPrefix = []byte("suave-outbox-") // outboxPrefix + id (uint64 big endian) -> outboxEntry
+
+	outboxDepthGauge     = metrics.NewRegisteredGauge("suave/cstore/outbox/depth", nil)
+	outboxAgeGauge       = metrics.NewRegisteredGauge("suave/cstore/outbox/age", nil)
+	outboxPublishedMeter = metrics.NewRegisteredMeter("suave/cstore/outbox/published", nil)
+	outboxFailuresMeter  = metrics.NewRegisteredMeter("suave/cstore/outbox/failures", nil)
+
+	outboxMinRetryDelay   = 500 * time.Millisecond
+	outboxMaxRetryDelay   = time.Minute
+	outboxMetricsInterval = 10 * time.Second
+	outboxBatchSize       = 256 // Entries read from the database per publishing round
+)
+
+// Outbox persists the messages the engine publishes in a database until the
+// transport has accepted them, so that writes are not lost when the transport
+// is unavailable or the node restarts. Messages are published in the order they
+// were added, failed publications are retried with an exponential backoff.
+// Messages are sealed for publishing when they are published, the engine
+// persists them without the values of the writes.
+type Outbox struct {
+	db ethdb.KeyValueStore
+
+	lock   sync.Mutex
+	nextId uint64
+	depth  uint64
+
+	wake    chan struct{}
+	running sync.WaitGroup
+}
+
+// OutboxStatus describes the messages waiting to be published.
+type OutboxStatus struct {
+	Depth uint64 `json:"depth"`
+	// OldestAdded is the unix timestamp the oldest waiting message was added at, zero if there is none
+	OldestAdded uint64 `json:"oldestAdded"`
+	// OldestAge is the number of seconds the oldest waiting message has been waiting for
+	OldestAge uint64 `json:"oldestAge"`
+}
+
+type outboxEntry struct {
+	Added   uint64    `json:"added"`
+	Message DAMessage `json:"message"`
+}
+
+type outboxItem struct {
+	key   []byte
+	entry outboxEntry
+}
+
+// NewOutbox opens the outbox kept in the given database, messages left over
+// from a previous run are published once the engine is started.
+func NewOutbox(db ethdb.KeyValueStore) (*Outbox, error) {
+	o := &Outbox{
+		db:   db,
+		wake: make(chan struct{}, 1),
+	}
+
+	it := db.NewIterator(outboxPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		id, err := outboxId(it.Key())
+		if err != nil {
+			return nil, err
+		}
+		o.depth++
+		o.nextId = id + 1
+	}
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("confidential outbox: could not load messages: %w", err)
+	}
+
+	if o.depth > 0 {
+		log.Info("Confidential outbox: loaded unpublished messages", "messages", o.depth)
+	}
+	outboxDepthGauge.Update(int64(o.depth))
+
+	return o, nil
+}
+
+func outboxKey(id uint64) []byte {
+	key := make([]byte, len(outboxPrefix)+8)
+	copy(key, outboxPrefix)
+	binary.BigEndian.PutUint64(key[len(outboxPrefix):], id)
+	return key
+}
+
+func outboxId(key []byte) (uint64, error) {
+	if len(key) != len(outboxPrefix)+8 {
+		return 0, fmt.Errorf("confidential outbox: invalid key %x", key)
+	}
+	return binary.BigEndian.Uint64(key[len(outboxPrefix):]), nil
+}
+
+// Add persists the message and schedules it for publishing.
+func (o *Outbox) Add(message DAMessage) error {
+	data, err := json.Marshal(outboxEntry{Added: uint64(time.Now().Unix()), Message: message})
+	if err != nil {
+		return fmt.Errorf("confidential outbox: could not encode message: %w", err)
+	}
+
+	o.lock.Lock()
+	if err := o.db.Put(outboxKey(o.nextId), data); err != nil {
+		o.lock.Unlock()
+		return fmt.Errorf("confidential outbox: could not persist message: %w", err)
+	}
+	o.nextId++
+	o.depth++
+	outboxDepthGauge.Update(int64(o.depth))
+	o.lock.Unlock()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Status returns the number of waiting messages and the age of the oldest one.
+func (o *Outbox) Status() (OutboxStatus, error) {
+	o.lock.Lock()
+	status := OutboxStatus{Depth: o.depth}
+	o.lock.Unlock()
+
+	items, err := o.pending(1)
+	if err != nil {
+		return OutboxStatus{}, err
+	}
+	if len(items) > 0 {
+		status.OldestAdded = items[0].entry.Added
+		if now := uint64(time.Now().Unix()); now > status.OldestAdded {
+			status.OldestAge = now - status.OldestAdded
+		}
+	}
+	return status, nil
+}
+
+// pending returns up to limit of the oldest waiting messages.
+func (o *Outbox) pending(limit int) ([]outboxItem, error) {
+	it := o.db.NewIterator(outboxPrefix, nil)
+	defer it.Release()
+
+	var items []outboxItem
+	for len(items) < limit && it.Next() {
+		var entry outboxEntry
+		if err := json.Unmarshal(it.Value(), &entry); err != nil {
+			return nil, fmt.Errorf("confidential outbox: corrupted message %x: %w", it.Key(), err)
+		}
+		items = append(items, outboxItem{key: common.CopyBytes(it.Key()), entry: entry})
+	}
+	return items, it.Error()
+}
+
+// sealFunc returns the message to publish for a message of the outbox.
+type sealFunc func(DAMessage) (DAMessage, error)
+
+// publishPending publishes the waiting messages in order, and stops at the
+// first one which cannot be sealed or the transport does not accept.
+func (o *Outbox) publishPending(ctx context.Context, transport StoreTransportTopic, seal sealFunc) error {
+	for ctx.Err() == nil {
+		items, err := o.pending(outboxBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		for _, item := range items {
+			message := item.entry.Message
+			if seal != nil {
+				if message, err = seal(message); err != nil {
+					outboxFailuresMeter.Mark(1)
+					return err
+				}
+			}
+
+			if err := transport.Publish(message); err != nil {
+				outboxFailuresMeter.Mark(1)
+				return err
+			}
+			outboxPublishedMeter.Mark(1)
+
+			o.lock.Lock()
+			err := o.db.Delete(item.key)
+			if err == nil {
+				o.depth--
+				outboxDepthGauge.Update(int64(o.depth))
+			}
+			o.lock.Unlock()
+			if err != nil {
+				// Published, but will be published again on the next round
+				return fmt.Errorf("confidential outbox: could not remove published message: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) updateAgeMetric() {
+	status, err := o.Status()
+	if err != nil {
+		log.Warn("Confidential outbox: could not read status", "err", err)
+		return
+	}
+	outboxAgeGauge.Update(int64(status.OldestAge))
+}
+
+// start publishes the messages, sealed with seal if not nil, until the context is cancelled.
+func (o *Outbox) start(ctx context.Context, transport StoreTransportTopic, seal sealFunc) {
+	o.running.Add(1)
+	go func() {
+		defer o.running.Done()
+		o.run(ctx, transport, seal)
+	}()
+}
+
+// wait blocks until the publishing loop has exited.
+func (o *Outbox) wait() {
+	o.running.Wait()
+}
+
+func (o *Outbox) run(ctx context.Context, transport StoreTransportTopic, seal sealFunc) {
+	metricsTicker := time.NewTicker(outboxMetricsInterval)
+	defer metricsTicker.Stop()
+
+	var retryDelay time.Duration
+	for {
+		if err := o.publishPending(ctx, transport, seal); err != nil {
+			retryDelay *= 2
+			if retryDelay < outboxMinRetryDelay {
+				retryDelay = outboxMinRetryDelay
+			}
+			if retryDelay > outboxMaxRetryDelay {
+				retryDelay = outboxMaxRetryDelay
+			}
+			log.Warn("Confidential outbox: could not publish message, retrying", "in", retryDelay, "err", err)
+		} else {
+			retryDelay = 0
+		}
+		o.updateAgeMetric()
+
+		// New messages do not cut a backoff short
+		wake := o.wake
+		var retry <-chan time.Time
+		if retryDelay > 0 {
+			wake = nil
+			retry = time.After(retryDelay)
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done(): // Stop() called
+				return
+			case <-wake:
+				break wait
+			case <-retry:
+				break wait
+			case <-metricsTicker.C:
+				o.updateAgeMetric()
+			}
+		}
+	}
+}
+
+// SetOutbox makes the engine publish its messages through the outbox. Must be called before Start().
+func (e *ConfidentialStoreEngine) SetOutbox(outbox *Outbox) {
+	e.outbox = outbox
+}
+
+// OutboxStatus returns the status of the engine's outbox, empty if it has none.
+func (e *ConfidentialStoreEngine) OutboxStatus() (OutboxStatus, error) {
+	if e.outbox == nil {
+		return OutboxStatus{}, nil
+	}
+	return e.outbox.Status()
+}
+
+// OutboxAPI exposes the status of the confidential store outbox over RPC.
+type OutboxAPI struct {
+	engine *ConfidentialStoreEngine
+}
+
+func NewOutboxAPI(engine *ConfidentialStoreEngine) *OutboxAPI {
+	return &OutboxAPI{engine}
+}
+
+// OutboxStatus returns the number of messages waiting to be published and the age of the oldest one.
+func (api *OutboxAPI) OutboxStatus(ctx context.Context) (OutboxStatus, error) {
+	return api.engine.OutboxStatus()
+}
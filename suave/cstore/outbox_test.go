@@ -0,0 +1,243 @@
+package cstore
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyTransport rejects messages until it is brought up
+type flakyTransport struct {
+	MockTransport
+
+	lock      sync.Mutex
+	up        bool
+	attempts  int
+	published []DAMessage
+}
+
+func (f *flakyTransport) Publish(message DAMessage) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.attempts++
+	if !f.up {
+		return errors.New("transport down")
+	}
+	f.published = append(f.published, message)
+	return nil
+}
+
+func (f *flakyTransport) setUp(up bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.up = up
+}
+
+func (f *flakyTransport) state() (int, []DAMessage) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.attempts, append([]DAMessage(nil), f.published...)
+}
+
+func TestOutboxRetriesUntilPublished(t *testing.T) {
+	defer func(delay time.Duration) { outboxMinRetryDelay = delay }(outboxMinRetryDelay)
+	outboxMinRetryDelay = 10 * time.Millisecond
+
+	db := memorydb.New()
+	outbox, err := NewOutbox(db)
+	require.NoError(t, err)
+
+	var messages []DAMessage
+	for i := 0; i < 3; i++ {
+		message := DAMessage{StoreUUID: uuid.New(), Sequence: uint64(i + 1), Signature: []byte{byte(i)}}
+		require.NoError(t, outbox.Add(message))
+		messages = append(messages, message)
+	}
+
+	transport := &flakyTransport{}
+	ctx, cancel := context.WithCancel(context.Background())
+	outbox.start(ctx, transport, nil)
+
+	// Failed publications are retried, and the messages kept
+	require.Eventually(t, func() bool {
+		attempts, _ := transport.state()
+		return attempts >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	status, err := outbox.Status()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), status.Depth)
+	require.NotZero(t, status.OldestAdded)
+
+	// The messages survive a restart
+	cancel()
+	outbox.wait()
+
+	outbox, err = NewOutbox(db)
+	require.NoError(t, err)
+	status, err = outbox.Status()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), status.Depth)
+
+	// Once the transport is back the messages are published in order and removed
+	transport.setUp(true)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	outbox.start(ctx, transport, nil)
+
+	require.Eventually(t, func() bool {
+		_, published := transport.state()
+		return len(published) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	_, published := transport.state()
+	require.Equal(t, messages, published)
+
+	status, err = outbox.Status()
+	require.NoError(t, err)
+	require.Equal(t, OutboxStatus{}, status)
+
+	// New messages are published right away
+	require.NoError(t, outbox.Add(DAMessage{StoreUUID: uuid.New(), Sequence: 4}))
+	require.Eventually(t, func() bool {
+		_, published := transport.state()
+		return len(published) == 4
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestEngineFinalizeThroughOutbox(t *testing.T) {
+	transport := &flakyTransport{}
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), transport, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+
+	outbox, err := NewOutbox(memorydb.New())
+	require.NoError(t, err)
+	engine.SetOutbox(outbox)
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	testBid, err := engine.InitializeBid(types.Bid{
+		AllowedPeekers: []common.Address{{0x41}},
+	}, creationTx)
+	require.NoError(t, err)
+
+	// Not published before the engine is started
+	require.NoError(t, engine.Finalize(creationTx, map[suave.BidId]suave.Bid{testBid.Id: testBid}, []StoreWrite{{
+		Bid:    testBid,
+		Caller: common.Address{0x41},
+		Key:    "xx",
+		Value:  []byte{0x43},
+	}}))
+
+	status, err := engine.OutboxStatus()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), status.Depth)
+
+	transport.setUp(true)
+	require.NoError(t, engine.Start())
+	t.Cleanup(func() { engine.Stop() })
+
+	require.Eventually(t, func() bool {
+		_, published := transport.state()
+		for _, message := range published {
+			if message.SourceTx != nil && message.SourceTx.Hash() == creationTx.Hash() {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+
+	status, err = engine.OutboxStatus()
+	require.NoError(t, err)
+	require.Zero(t, status.Depth)
+}
+
+func TestEngineOutboxSealsWhenPublishing(t *testing.T) {
+	transport := &flakyTransport{}
+	db := memorydb.New()
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), transport, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+
+	outbox, err := NewOutbox(db)
+	require.NoError(t, err)
+	engine.SetOutbox(outbox)
+
+	store := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+	announcements, err := store.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, engine.handleKeyAnnouncements(announcements))
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	testBid, err := engine.InitializeBid(types.Bid{
+		AllowedPeekers: []common.Address{{0x41}},
+		AllowedStores:  []common.Address{{0x43}},
+	}, creationTx)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.Finalize(creationTx, map[suave.BidId]suave.Bid{testBid.Id: testBid}, []StoreWrite{{
+		Bid:    testBid,
+		Caller: common.Address{0x41},
+		Key:    "xx",
+		Value:  []byte{0x43},
+	}}))
+
+	// The persisted message holds neither the value nor its encryption
+	items, err := outbox.pending(1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Empty(t, items[0].entry.Message.StoreWrites[0].Value)
+	require.Empty(t, items[0].entry.Message.StoreWrites[0].EncryptedValues)
+
+	// The store restarts with a new key while the message waits
+	restarted := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+	restarted.transportKeyEpoch = store.transportKeyEpoch + 1
+	announcements, err = restarted.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, engine.handleKeyAnnouncements(announcements))
+
+	transport.setUp(true)
+	require.NoError(t, engine.Start())
+	t.Cleanup(func() { engine.Stop() })
+
+	var published DAMessage
+	require.Eventually(t, func() bool {
+		_, messages := transport.state()
+		for _, message := range messages {
+			if message.SourceTx != nil {
+				published = message
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, restarted.NewMessage(published))
+	value, err := restarted.Retrieve(testBid.Id, common.Address{0x41}, "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x43}, value)
+}
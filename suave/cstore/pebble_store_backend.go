@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/ethereum/go-ethereum/common"
@@ -15,6 +16,8 @@ import (
 var (
 	formatPebbleBidKey      = formatRedisBidKey
 	formatPebbleBidValueKey = formatRedisBidValueKey
+
+	pebbleBidValuePrefix = redisBidValuePrefix
 )
 
 type PebbleStoreBackend struct {
@@ -69,9 +72,34 @@ func (b *PebbleStoreBackend) Stop() error {
 }
 
 func (b *PebbleStoreBackend) InitializeBid(bid suave.Bid) error {
+	return b.Commit([]suave.Bid{bid}, nil)
+}
+
+func (b *PebbleStoreBackend) Commit(bids []suave.Bid, writes []StoreWrite) error {
+	// The indexed batch lets us read our own index updates when several bids share a (block, namespace) pair
+	batch := b.db.NewIndexedBatch()
+	defer batch.Close()
+
+	for _, bid := range bids {
+		if err := b.initializeBid(batch, bid); err != nil {
+			return err
+		}
+	}
+
+	for _, sw := range writes {
+		storeKey := []byte(formatPebbleBidValueKey(sw.Bid.Id, sw.Key))
+		if err := batch.Set(storeKey, sw.Value, nil); err != nil {
+			return err
+		}
+	}
+
+	return batch.Commit(pebble.Sync)
+}
+
+func (b *PebbleStoreBackend) initializeBid(batch *pebble.Batch, bid suave.Bid) error {
 	key := []byte(formatPebbleBidKey(bid.Id))
 
-	_, closer, err := b.db.Get(key)
+	_, closer, err := batch.Get(key)
 	if !errors.Is(err, pebble.ErrNotFound) {
 		if err == nil {
 			closer.Close()
@@ -84,7 +112,7 @@ func (b *PebbleStoreBackend) InitializeBid(bid suave.Bid) error {
 		return err
 	}
 
-	err = b.db.Set(key, data, nil)
+	err = batch.Set(key, data, nil)
 	if err != nil {
 		return err
 	}
@@ -93,7 +121,7 @@ func (b *PebbleStoreBackend) InitializeBid(bid suave.Bid) error {
 	var currentValues bidByBlockAndProtocolIndexType
 
 	dbBlockProtoIndexKey := bidByBlockAndProtocolIndexDbKey(bid.DecryptionCondition, bid.Version)
-	rawCurrentValues, closer, err := b.db.Get(dbBlockProtoIndexKey)
+	rawCurrentValues, closer, err := batch.Get(dbBlockProtoIndexKey)
 	if err != nil {
 		if !errors.Is(err, pebble.ErrNotFound) {
 			return err
@@ -112,7 +140,7 @@ func (b *PebbleStoreBackend) InitializeBid(bid suave.Bid) error {
 		return err
 	}
 
-	return b.db.Set(dbBlockProtoIndexKey, rawUpdatedValues, nil)
+	return batch.Set(dbBlockProtoIndexKey, rawUpdatedValues, nil)
 }
 
 func (b *PebbleStoreBackend) FetchBidById(bidId suave.BidId) (suave.Bid, error) {
@@ -151,6 +179,27 @@ func (b *PebbleStoreBackend) Retrieve(bid suave.Bid, caller common.Address, key
 }
 
 func (b *PebbleStoreBackend) FetchBidsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.Bid {
+	currentBidIds := b.fetchBidIdsByProtocolAndBlock(blockNumber, namespace)
+	if currentBidIds == nil {
+		return nil
+	}
+
+	bids := []suave.Bid{}
+	for _, bidId := range currentBidIds {
+		bid, err := b.FetchBidById(bidId)
+		if err == nil {
+			bids = append(bids, bid)
+		}
+	}
+
+	return bids
+}
+
+func (b *PebbleStoreBackend) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	return queryBids(query, b.fetchBidIdsByProtocolAndBlock, b.FetchBidById)
+}
+
+func (b *PebbleStoreBackend) fetchBidIdsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.BidId {
 	dbBlockProtoIndexKey := bidByBlockAndProtocolIndexDbKey(blockNumber, namespace)
 	rawCurrentValues, closer, err := b.db.Get(dbBlockProtoIndexKey)
 	if err != nil {
@@ -164,13 +213,108 @@ func (b *PebbleStoreBackend) FetchBidsByProtocolAndBlock(blockNumber uint64, nam
 		return nil
 	}
 
-	bids := []suave.Bid{}
-	for _, bidId := range currentBidIds {
-		bid, err := b.FetchBidById(bidId)
-		if err == nil {
-			bids = append(bids, bid)
+	return currentBidIds
+}
+
+func (b *PebbleStoreBackend) Prune(decryptionConditionBelow uint64) (PruneResult, error) {
+	res := PruneResult{}
+
+	batch := b.db.NewBatch()
+	defer batch.Close()
+
+	indexPrefix := []byte("bids-block-")
+	iter := b.db.NewIter(&pebble.IterOptions{
+		LowerBound: indexPrefix,
+		UpperBound: pebbleUpperBound(indexPrefix),
+	})
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var blockNumber uint64
+		if _, err := fmt.Sscanf(string(iter.Key()), "bids-block-%d-ns-", &blockNumber); err != nil {
+			return PruneResult{}, fmt.Errorf("could not parse index key %s: %w", string(iter.Key()), err)
 		}
+		if blockNumber >= decryptionConditionBelow {
+			continue
+		}
+
+		var bidIds bidByBlockAndProtocolIndexType
+		if err := json.Unmarshal(iter.Value(), &bidIds); err != nil {
+			return PruneResult{}, err
+		}
+
+		for _, bidId := range bidIds {
+			if err := batch.Delete([]byte(formatPebbleBidKey(bidId)), nil); err != nil {
+				return PruneResult{}, err
+			}
+			res.Bids++
+
+			valuesPrefix := []byte(formatPebbleBidValueKey(bidId, ""))
+			valuesIter := b.db.NewIter(&pebble.IterOptions{
+				LowerBound: valuesPrefix,
+				UpperBound: pebbleUpperBound(valuesPrefix),
+			})
+			for valuesIter.First(); valuesIter.Valid(); valuesIter.Next() {
+				if err := batch.Delete(common.CopyBytes(valuesIter.Key()), nil); err != nil {
+					valuesIter.Close()
+					return PruneResult{}, err
+				}
+				res.Values++
+			}
+			if err := valuesIter.Close(); err != nil {
+				return PruneResult{}, err
+			}
+		}
+
+		if err := batch.Delete(common.CopyBytes(iter.Key()), nil); err != nil {
+			return PruneResult{}, err
+		}
+		res.IndexEntries++
 	}
 
-	return bids
+	if err := iter.Error(); err != nil {
+		return PruneResult{}, err
+	}
+
+	if err := batch.Commit(pebble.Sync); err != nil {
+		return PruneResult{}, err
+	}
+
+	return res, nil
+}
+
+func (b *PebbleStoreBackend) IterateValues(fn func(bidId suave.BidId, key string, value []byte) error) error {
+	valuesPrefix := []byte(pebbleBidValuePrefix)
+	iter := b.db.NewIter(&pebble.IterOptions{
+		LowerBound: valuesPrefix,
+		UpperBound: pebbleUpperBound(valuesPrefix),
+	})
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		bidId, key, err := parseBidValueKey(strings.TrimPrefix(string(iter.Key()), pebbleBidValuePrefix))
+		if err != nil {
+			return err
+		}
+		if err := fn(bidId, key, common.CopyBytes(iter.Value())); err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+// pebbleUpperBound returns the upper bound for iterating over the given prefix
+func pebbleUpperBound(prefix []byte) (limit []byte) {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		c := prefix[i]
+		if c == 0xff {
+			continue
+		}
+		limit = make([]byte, i+1)
+		copy(limit, prefix)
+		limit[i] = c + 1
+		break
+	}
+	return limit
 }
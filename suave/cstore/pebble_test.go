@@ -9,3 +9,33 @@ func TestPebbleStore(t *testing.T) {
 	store, _ := NewPebbleStoreBackend(tmpDir)
 	testBackendStore(t, store)
 }
+
+func TestPebbleStore_Commit(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := NewPebbleStoreBackend(tmpDir)
+	testBackendCommit(t, store)
+}
+
+func TestPebbleStore_Prune(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := NewPebbleStoreBackend(tmpDir)
+	testBackendPrune(t, store)
+}
+
+func TestPebbleStore_Query(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := NewPebbleStoreBackend(tmpDir)
+	testBackendQuery(t, store)
+}
+
+func TestPebbleStore_IterateValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := NewPebbleStoreBackend(tmpDir)
+	testBackendIterateValues(t, store)
+}
+
+func TestPebbleStore_Encryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, _ := NewPebbleStoreBackend(tmpDir)
+	testBackendEncryption(t, store)
+}
@@ -37,7 +37,7 @@ func TestRedisTransport(t *testing.T) {
 		Signature: []byte{},
 	}
 
-	redisPubSub.Publish(daMsg)
+	require.NoError(t, redisPubSub.Publish(daMsg))
 
 	select {
 	case msg := <-msgSub:
@@ -53,7 +53,7 @@ func TestRedisTransport(t *testing.T) {
 	}
 
 	daMsg.StoreWrites[0].Bid.Id[0] = 0x43
-	redisPubSub.Publish(daMsg)
+	require.NoError(t, redisPubSub.Publish(daMsg))
 
 	select {
 	case msg := <-msgSub:
@@ -71,14 +71,14 @@ func TestEngineOnRedis(t *testing.T) {
 	redisPubSub1 := NewRedisPubSubTransport(mrPubSub.Addr())
 	redisStoreBackend1, _ := NewRedisStoreBackend(mrStore1.Addr())
 
-	engine1 := NewConfidentialStoreEngine(redisStoreBackend1, redisPubSub1, MockSigner{}, MockChainSigner{})
+	engine1 := NewConfidentialStoreEngine(redisStoreBackend1, redisPubSub1, FakeDASigner{localAddresses: []common.Address{{}}}, MockChainSigner{})
 	require.NoError(t, engine1.Start())
 	t.Cleanup(func() { engine1.Stop() })
 
 	redisPubSub2 := NewRedisPubSubTransport(mrPubSub.Addr())
 	redisStoreBackend2, _ := NewRedisStoreBackend(mrStore2.Addr())
 
-	engine2 := NewConfidentialStoreEngine(redisStoreBackend2, redisPubSub2, MockSigner{}, MockChainSigner{})
+	engine2 := NewConfidentialStoreEngine(redisStoreBackend2, redisPubSub2, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
 	require.NoError(t, engine2.Start())
 	t.Cleanup(func() { engine2.Stop() })
 
@@ -94,7 +94,7 @@ func TestEngineOnRedis(t *testing.T) {
 	bid, err := engine1.InitializeBid(types.Bid{
 		DecryptionCondition: uint64(13),
 		AllowedPeekers:      []common.Address{{0x41, 0x39}},
-		AllowedStores:       []common.Address{{}},
+		AllowedStores:       []common.Address{{}, {0x43}},
 		Version:             string("vv"),
 	}, dummyCreationTx)
 	require.NoError(t, err)
@@ -107,6 +107,9 @@ func TestEngineOnRedis(t *testing.T) {
 	subch, cancel := redisPubSub3.Subscribe()
 	t.Cleanup(cancel)
 
+	// Writes are only sent once the other store announced its transport key
+	require.Eventually(t, func() bool { return engine1.knowsStoreKey(common.Address{0x43}) }, time.Second, 5*time.Millisecond)
+
 	// Trigger propagation
 	err = engine1.Finalize(dummyCreationTx, nil, []StoreWrite{{
 		Bid:    bid,
@@ -136,19 +139,26 @@ func TestEngineOnRedis(t *testing.T) {
 
 	// require.NoError(t, engine1.Finalize(dummyCreationTx))
 
-	select {
-	case msg := <-subch:
-		rececivedBidJson, err := json.Marshal(msg.StoreWrites[0].Bid)
-		require.NoError(t, err)
-
-		require.Equal(t, submittedBidJson, rececivedBidJson)
-		require.Equal(t, "xx", msg.StoreWrites[0].Key)
-		require.Equal(t, suave.Bytes{0x43, 0x14}, msg.StoreWrites[0].Value)
-		require.Equal(t, bid.AllowedPeekers[0], msg.StoreWrites[0].Caller)
-	case <-time.After(20 * time.Millisecond):
-		t.Error("did not receive expected message")
+	// Skip the key announcements and sync requests of the engines
+	var msg DAMessage
+	timeout := time.After(20 * time.Millisecond)
+	for len(msg.StoreWrites) == 0 {
+		select {
+		case msg = <-subch:
+		case <-timeout:
+			t.Fatal("did not receive expected message")
+		}
 	}
 
+	rececivedBidJson, err := json.Marshal(msg.StoreWrites[0].Bid)
+	require.NoError(t, err)
+
+	require.Equal(t, submittedBidJson, rececivedBidJson)
+	require.Equal(t, "xx", msg.StoreWrites[0].Key)
+	require.Empty(t, msg.StoreWrites[0].Value)
+	require.Contains(t, msg.StoreWrites[0].EncryptedValues, common.Address{0x43})
+	require.Equal(t, bid.AllowedPeekers[0], msg.StoreWrites[0].Caller)
+
 	retrievedData, err := engine2.Retrieve(bid.Id, bid.AllowedPeekers[0], "xx")
 	require.NoError(t, err)
 	require.Equal(t, []byte{0x43, 0x14}, retrievedData)
@@ -5,14 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/log"
 	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/exp/slices"
 )
 
 var _ ConfidentialStorageBackend = &RedisStoreBackend{}
@@ -22,11 +25,19 @@ var (
 		return fmt.Sprintf("bid-%x", bidId)
 	}
 
+	redisBidValuePrefix    = "bid-data-"
 	formatRedisBidValueKey = func(bidId suave.BidId, key string) string {
-		return fmt.Sprintf("bid-data-%x-%s", bidId, key)
+		return fmt.Sprintf("%s%x-%s", redisBidValuePrefix, bidId, key)
+	}
+
+	formatRedisBidIndexKey = func(namespace string, blockNumber uint64) string {
+		return fmt.Sprintf("protocol-%s-bn-%d", namespace, blockNumber)
 	}
 
 	ffStoreTTL = 24 * time.Hour
+
+	redisCommitAttempts = 10 // Of a transaction aborted by concurrent changes to its keys
+	redisCommitBackoff  = time.Millisecond
 )
 
 type RedisStoreBackend struct {
@@ -97,29 +108,77 @@ func (r *RedisStoreBackend) Stop() error {
 }
 
 func (r *RedisStoreBackend) InitializeBid(bid suave.Bid) error {
-	key := formatRedisBidKey(bid.Id)
+	return r.Commit([]suave.Bid{bid}, nil)
+}
 
-	err := r.client.Get(r.ctx, key).Err()
-	if !errors.Is(err, redis.Nil) {
-		return suave.ErrBidAlreadyPresent
+// Commit watches the bid and index keys it is about to touch and applies all
+// of the writes in a single MULTI/EXEC transaction. If any of the watched keys
+// changes concurrently the transaction is aborted and nothing is written, it is
+// then attempted again, after a growing random delay, a bounded number of times.
+func (r *RedisStoreBackend) Commit(bids []suave.Bid, writes []StoreWrite) error {
+	watchedKeys := []string{}
+	for _, bid := range bids {
+		watchedKeys = append(watchedKeys, formatRedisBidKey(bid.Id), formatRedisBidValueKey(mempoolConfStoreId, formatRedisBidIndexKey(bid.Version, bid.DecryptionCondition)))
 	}
 
-	data, err := json.Marshal(bid)
-	if err != nil {
-		return err
-	}
+	txf := func(tx *redis.Tx) error {
+		bidsData := make(map[string][]byte, len(bids))
+		indexes := make(map[string][]suave.BidId)
+		for _, bid := range bids {
+			key := formatRedisBidKey(bid.Id)
+			if _, found := bidsData[key]; found {
+				return suave.ErrBidAlreadyPresent
+			}
+
+			err := tx.Get(r.ctx, key).Err()
+			if !errors.Is(err, redis.Nil) {
+				return suave.ErrBidAlreadyPresent
+			}
+
+			data, err := json.Marshal(bid)
+			if err != nil {
+				return err
+			}
+			bidsData[key] = data
+
+			indexKey := formatRedisBidValueKey(mempoolConfStoreId, formatRedisBidIndexKey(bid.Version, bid.DecryptionCondition))
+			bidIds, found := indexes[indexKey]
+			if !found {
+				rawBidIds, err := tx.Get(r.ctx, indexKey).Bytes()
+				if err == nil {
+					bidIds = suave.MustDecode[[]suave.BidId](rawBidIds)
+				} else if !errors.Is(err, redis.Nil) {
+					return fmt.Errorf("unexpected redis error: %w", err)
+				}
+			}
+			indexes[indexKey] = append(bidIds, bid.Id)
+		}
 
-	err = r.client.Set(r.ctx, key, string(data), ffStoreTTL).Err()
-	if err != nil {
+		_, err := tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			for key, data := range bidsData {
+				pipe.Set(r.ctx, key, string(data), ffStoreTTL)
+			}
+			for indexKey, bidIds := range indexes {
+				pipe.Set(r.ctx, indexKey, string(suave.MustEncode(bidIds)), ffStoreTTL)
+			}
+			for _, sw := range writes {
+				pipe.Set(r.ctx, formatRedisBidValueKey(sw.Bid.Id, sw.Key), string(sw.Value), redisValueTTL(sw.Key))
+			}
+			return nil
+		})
 		return err
 	}
 
-	err = r.indexBid(bid)
-	if err != nil {
-		return err
-	}
+	for i := 0; i < redisCommitAttempts; i++ {
+		err := r.client.Watch(r.ctx, txf, watchedKeys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
 
-	return nil
+		// Randomized so that the same committers do not keep colliding
+		time.Sleep(time.Duration(rand.Int63n(int64(redisCommitBackoff) << i)))
+	}
+	return fmt.Errorf("could not commit after %d attempts: %w", redisCommitAttempts, redis.TxFailedErr)
 }
 
 func (r *RedisStoreBackend) FetchBidById(bidId suave.BidId) (suave.Bid, error) {
@@ -141,7 +200,7 @@ func (r *RedisStoreBackend) FetchBidById(bidId suave.BidId) (suave.Bid, error) {
 
 func (r *RedisStoreBackend) Store(bid suave.Bid, caller common.Address, key string, value []byte) (suave.Bid, error) {
 	storeKey := formatRedisBidValueKey(bid.Id, key)
-	err := r.client.Set(r.ctx, storeKey, string(value), ffStoreTTL).Err()
+	err := r.client.Set(r.ctx, storeKey, string(value), redisValueTTL(key)).Err()
 	if err != nil {
 		return suave.Bid{}, fmt.Errorf("unexpected redis error: %w", err)
 	}
@@ -149,6 +208,16 @@ func (r *RedisStoreBackend) Store(bid suave.Bid, caller common.Address, key stri
 	return bid, nil
 }
 
+// redisValueTTL returns the expiry of a value of a bid. The metadata the engine
+// keeps under reserved keys never expires, replays of writes must be rejected
+// even after the values they wrote expired. It is deleted when the bid is pruned.
+func redisValueTTL(key string) time.Duration {
+	if isReservedKey(key) {
+		return 0
+	}
+	return ffStoreTTL
+}
+
 func (r *RedisStoreBackend) Retrieve(bid suave.Bid, caller common.Address, key string) ([]byte, error) {
 	storeKey := formatRedisBidValueKey(bid.Id, key)
 	data, err := r.client.Get(r.ctx, storeKey).Bytes()
@@ -165,39 +234,129 @@ var (
 	mempoolConfidentialStoreBid = suave.Bid{Id: mempoolConfStoreId, AllowedPeekers: []common.Address{mempoolConfStoreAddr}}
 )
 
-func (r *RedisStoreBackend) indexBid(bid suave.Bid) error {
-	defer log.Info("bid submitted", "bid", bid, "store", r.Store)
+func (r *RedisStoreBackend) FetchBidsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.Bid {
+	bidIDs := r.fetchBidIdsByProtocolAndBlock(blockNumber, namespace)
+	if bidIDs == nil {
+		return nil
+	}
+
+	res := []suave.Bid{}
 
-	var bidsByBlockAndProtocol []suave.BidId
-	bidsByBlockAndProtocolBytes, err := r.Retrieve(mempoolConfidentialStoreBid, mempoolConfStoreAddr, fmt.Sprintf("protocol-%s-bn-%d", bid.Version, bid.DecryptionCondition))
-	if err == nil {
-		bidsByBlockAndProtocol = suave.MustDecode[[]suave.BidId](bidsByBlockAndProtocolBytes)
+	for _, id := range bidIDs {
+		bid, err := r.FetchBidById(id)
+		if err != nil {
+			continue
+		}
+		res = append(res, bid)
 	}
-	// store bid by block number and by protocol + block number
-	bidsByBlockAndProtocol = append(bidsByBlockAndProtocol, bid.Id)
 
-	r.Store(mempoolConfidentialStoreBid, mempoolConfStoreAddr, fmt.Sprintf("protocol-%s-bn-%d", bid.Version, bid.DecryptionCondition), suave.MustEncode(bidsByBlockAndProtocol))
+	return res
+}
 
-	return nil
+func (r *RedisStoreBackend) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	return queryBids(query, r.fetchBidIdsByProtocolAndBlock, r.FetchBidById)
 }
 
-func (r *RedisStoreBackend) FetchBidsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.Bid {
-	bidsByProtocolBytes, err := r.Retrieve(mempoolConfidentialStoreBid, mempoolConfStoreAddr, fmt.Sprintf("protocol-%s-bn-%d", namespace, blockNumber))
+func (r *RedisStoreBackend) fetchBidIdsByProtocolAndBlock(blockNumber uint64, namespace string) []suave.BidId {
+	bidsByProtocolBytes, err := r.Retrieve(mempoolConfidentialStoreBid, mempoolConfStoreAddr, formatRedisBidIndexKey(namespace, blockNumber))
 	if err != nil {
 		return nil
 	}
 
-	res := []suave.Bid{}
+	return suave.MustDecode[[]suave.BidId](bidsByProtocolBytes)
+}
 
-	bidIDs := suave.MustDecode[[]suave.BidId](bidsByProtocolBytes)
-	for _, id := range bidIDs {
-		bid, err := r.FetchBidById(id)
+func (r *RedisStoreBackend) IterateValues(fn func(bidId suave.BidId, key string, value []byte) error) error {
+	iter := r.client.Scan(r.ctx, 0, redisBidValuePrefix+"*", 0).Iterator()
+	for iter.Next(r.ctx) {
+		bidId, key, err := parseBidValueKey(strings.TrimPrefix(iter.Val(), redisBidValuePrefix))
 		if err != nil {
+			return err
+		}
+		if bidId == mempoolConfStoreId {
+			// Index entries are internal to the backend
 			continue
 		}
-		res = append(res, bid)
+
+		value, err := r.client.Get(r.ctx, iter.Val()).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue // Expired in the meantime
+			}
+			return fmt.Errorf("unexpected redis error: %w", err)
+		}
+
+		if err := fn(bidId, key, value); err != nil {
+			return err
+		}
 	}
 
-	// defer log.Info("bids fetched", "bids", string(bidsByProtocolBytes))
-	return res
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("unexpected redis error: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStoreBackend) Prune(decryptionConditionBelow uint64) (PruneResult, error) {
+	res := PruneResult{}
+	keysToDelete := []string{}
+
+	indexIter := r.client.Scan(r.ctx, 0, formatRedisBidValueKey(mempoolConfStoreId, "protocol-*"), 0).Iterator()
+	for indexIter.Next(r.ctx) {
+		indexKey := indexIter.Val()
+
+		bnIdx := strings.LastIndex(indexKey, "-bn-")
+		if bnIdx == -1 {
+			continue
+		}
+		blockNumber, err := strconv.ParseUint(indexKey[bnIdx+len("-bn-"):], 10, 64)
+		if err != nil || blockNumber >= decryptionConditionBelow {
+			continue
+		}
+
+		rawBidIds, err := r.client.Get(r.ctx, indexKey).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue // Expired in the meantime
+			}
+			return PruneResult{}, fmt.Errorf("unexpected redis error: %w", err)
+		}
+
+		bidIds := suave.MustDecode[[]suave.BidId](rawBidIds)
+		if slices.Contains(bidIds, mempoolConfStoreId) {
+			// The mempool bid holds the index itself and must never be pruned
+			continue
+		}
+
+		for _, bidId := range bidIds {
+			keysToDelete = append(keysToDelete, formatRedisBidKey(bidId))
+			res.Bids++
+
+			valuesIter := r.client.Scan(r.ctx, 0, formatRedisBidValueKey(bidId, "*"), 0).Iterator()
+			for valuesIter.Next(r.ctx) {
+				keysToDelete = append(keysToDelete, valuesIter.Val())
+				res.Values++
+			}
+			if err := valuesIter.Err(); err != nil {
+				return PruneResult{}, fmt.Errorf("unexpected redis error: %w", err)
+			}
+		}
+
+		keysToDelete = append(keysToDelete, indexKey)
+		res.IndexEntries++
+	}
+
+	if err := indexIter.Err(); err != nil {
+		return PruneResult{}, fmt.Errorf("unexpected redis error: %w", err)
+	}
+
+	if len(keysToDelete) == 0 {
+		return res, nil
+	}
+
+	if err := r.client.Del(r.ctx, keysToDelete...).Err(); err != nil {
+		return PruneResult{}, fmt.Errorf("unexpected redis error: %w", err)
+	}
+
+	return res, nil
 }
@@ -8,3 +8,33 @@ func TestRedis_StoreSuite(t *testing.T) {
 	store, _ := NewRedisStoreBackend("")
 	testBackendStore(t, store)
 }
+
+func TestRedis_Commit(t *testing.T) {
+	store, _ := NewRedisStoreBackend("")
+	testBackendCommit(t, store)
+}
+
+func TestRedis_ConcurrentCommit(t *testing.T) {
+	store, _ := NewRedisStoreBackend("")
+	testBackendConcurrentCommit(t, store)
+}
+
+func TestRedis_Prune(t *testing.T) {
+	store, _ := NewRedisStoreBackend("")
+	testBackendPrune(t, store)
+}
+
+func TestRedis_Query(t *testing.T) {
+	store, _ := NewRedisStoreBackend("")
+	testBackendQuery(t, store)
+}
+
+func TestRedis_IterateValues(t *testing.T) {
+	store, _ := NewRedisStoreBackend("")
+	testBackendIterateValues(t, store)
+}
+
+func TestRedis_Encryption(t *testing.T) {
+	store, _ := NewRedisStoreBackend("")
+	testBackendEncryption(t, store)
+}
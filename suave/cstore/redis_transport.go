@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -136,15 +137,18 @@ func (r *RedisPubSubTransport) Subscribe() (<-chan DAMessage, context.CancelFunc
 	return ch, cancel
 }
 
-func (r *RedisPubSubTransport) Publish(message DAMessage) {
+func (r *RedisPubSubTransport) Publish(message DAMessage) error {
 	log.Trace("Redis pubsub: publishing", "message", message)
 	data, err := json.Marshal(message)
 	if err != nil {
 		log.Error("Redis pubsub: could not marshal message", "err", err)
-		return
+		return err
 	}
 
-	r.client.Publish(r.ctx, redisUpsertTopic, common.Bytes2Hex(data))
+	if err := r.client.Publish(r.ctx, redisUpsertTopic, common.Bytes2Hex(data)).Err(); err != nil {
+		return fmt.Errorf("redis pubsub: could not publish message: %w", err)
+	}
+	return nil
 }
 
 func connectRedis(redisURI string) (*redis.Client, error) {
@@ -0,0 +1,64 @@
+package cstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+var (
+	errUnregisteredDAKey    = errors.New("DA key of no registered execution node")
+	errUnsupportedNamespace = errors.New("namespace not supported by the execution node")
+)
+
+// ExecutionNodeRegistry looks up the execution nodes registered on chain.
+type ExecutionNodeRegistry interface {
+	// ExecutionNodeByDAKey returns the execution node registered with the DA
+	// key, or nil if there is none.
+	ExecutionNodeByDAKey(daKey common.Address) (*suave.ExecutionNode, error)
+}
+
+// SetExecutionNodeRegistry makes the engine only accept messages signed with
+// the DA key of a registered execution node, and writes of bids in the
+// namespaces the node supports.
+func (e *ConfidentialStoreEngine) SetExecutionNodeRegistry(registry ExecutionNodeRegistry) {
+	e.registry = registry
+}
+
+// validateExecutionNode checks the message signer and the writes of a message
+// against the execution node registry, if the engine has one.
+func (e *ConfidentialStoreEngine) validateExecutionNode(signer common.Address, storeWrites []StoreWrite) error {
+	if e.registry == nil {
+		return nil
+	}
+
+	node, err := e.registry.ExecutionNodeByDAKey(signer)
+	if err != nil {
+		return fmt.Errorf("confidential engine: could not look up execution node of %s: %w", signer.Hex(), err)
+	}
+	if node == nil {
+		return fmt.Errorf("confidential engine: %w: %s", errUnregisteredDAKey, signer.Hex())
+	}
+	for _, sw := range storeWrites {
+		if !node.SupportsNamespace(sw.Bid.Version) {
+			return fmt.Errorf("confidential engine: %w: %s of bid %x by %s", errUnsupportedNamespace, sw.Bid.Version, sw.Bid.Id, node.Address.Hex())
+		}
+	}
+	return nil
+}
+
+// isExecutionNode reports whether the address is the DA key of a registered
+// execution node, and false if the engine has no registry.
+func (e *ConfidentialStoreEngine) isExecutionNode(daKey common.Address) (bool, error) {
+	if e.registry == nil {
+		return false, nil
+	}
+
+	node, err := e.registry.ExecutionNodeByDAKey(daKey)
+	if err != nil {
+		return false, fmt.Errorf("confidential engine: could not look up execution node of %s: %w", daKey.Hex(), err)
+	}
+	return node != nil, nil
+}
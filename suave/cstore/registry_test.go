@@ -0,0 +1,96 @@
+package cstore
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExecutionNodeRegistry map[common.Address]*suave.ExecutionNode
+
+func (r fakeExecutionNodeRegistry) ExecutionNodeByDAKey(daKey common.Address) (*suave.ExecutionNode, error) {
+	return r[daKey], nil
+}
+
+func TestNewMessageExecutionNodeRegistry(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+
+	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	dummyCreationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	tstore := engine.NewTransactionalStore(dummyCreationTx)
+	testBid, err := tstore.InitializeBid(types.Bid{
+		Salt:                RandomBidId(),
+		DecryptionCondition: 46,
+		AllowedPeekers:      []common.Address{{0x43}},
+		AllowedStores:       []common.Address{{0x42}},
+		Version:             "v0-test",
+	})
+	require.NoError(t, err)
+	require.NoError(t, tstore.Finalize())
+
+	bid, err := engine.FetchBidById(testBid.Id)
+	require.NoError(t, err)
+
+	sequence := uint64(100)
+	newSignedMessage := func(signer common.Address) DAMessage {
+		sequence++
+		sw := StoreWrite{Bid: bid, Caller: common.Address{0x43}, Key: "key", Version: WriteVersion{Sequence: sequence, Signer: signer}}
+
+		encryptedValue, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(&engine.transportKey.PublicKey), []byte{0x01}, nil, encryptionAdditionalData(sw.Bid.Id, sw.Key))
+		require.NoError(t, err)
+		sw.EncryptedValues = map[common.Address]suave.Bytes{{0x42}: encryptedValue}
+
+		// The message is signed by the execution node of its source transaction
+		sourceTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				ExecutionNode: signer,
+				Nonce:         sequence,
+			},
+		}), types.NewSuaveSigner(new(big.Int)), testKey)
+		require.NoError(t, err)
+
+		daMessage := DAMessage{
+			SourceTx:    sourceTx,
+			StoreUUID:   uuid.New(),
+			StoreWrites: []StoreWrite{sw},
+			Sequence:    sequence,
+		}
+
+		daMessageBytes, err := SerializeMessageForSigning(&daMessage)
+		require.NoError(t, err)
+
+		daMessage.Signature, err = MockSigner{}.Sign(signer, daMessageBytes)
+		require.NoError(t, err)
+		return daMessage
+	}
+
+	registry := fakeExecutionNodeRegistry{
+		{0x42}: {Address: common.Address{0x1}, DAKeys: []common.Address{{0x42}}, Namespaces: []string{"v1-test"}},
+	}
+	engine.SetExecutionNodeRegistry(registry)
+
+	// Messages signed with the DA key of no execution node are rejected
+	err = engine.NewMessage(newSignedMessage(common.Address{0x44}))
+	require.ErrorIs(t, err, errUnregisteredDAKey)
+
+	// So are writes to bids of namespaces the execution node does not store
+	err = engine.NewMessage(newSignedMessage(common.Address{0x42}))
+	require.ErrorIs(t, err, errUnsupportedNamespace)
+
+	registry[common.Address{0x42}].Namespaces = append(registry[common.Address{0x42}].Namespaces, "v0-test")
+	require.NoError(t, engine.NewMessage(newSignedMessage(common.Address{0x42})))
+}
@@ -0,0 +1,109 @@
+package cstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	prunedBidsMeter    = metrics.NewRegisteredMeter("suave/cstore/retention/bids", nil)
+	prunedValuesMeter  = metrics.NewRegisteredMeter("suave/cstore/retention/values", nil)
+	prunedIndexMeter   = metrics.NewRegisteredMeter("suave/cstore/retention/index", nil)
+	pruneFailuresMeter = metrics.NewRegisteredMeter("suave/cstore/retention/failures", nil)
+
+	defaultRetentionSweepInterval = time.Minute
+
+	errExpiredBid = errors.New("bid is past its retention")
+)
+
+// RetentionPolicy describes for how long bids and their data are kept in the
+// confidential store. A bid is pruned once the current block of the target
+// chain is past its DecryptionCondition by more than GracePeriod blocks.
+type RetentionPolicy struct {
+	// GracePeriod is the number of blocks past the decryption condition a bid is retained for.
+	GracePeriod uint64
+	// SweepInterval is how often the sweeper runs (default: one minute).
+	SweepInterval time.Duration
+	// CurrentBlock returns the block number of the target chain, which decryption
+	// conditions refer to. It is called for every received write.
+	CurrentBlock func() (uint64, error)
+}
+
+// PruneResult holds the number of items removed by a single prune.
+type PruneResult struct {
+	Bids         int
+	Values       int
+	IndexEntries int
+}
+
+// SetRetentionPolicy configures the background sweeper. Must be called before Start().
+func (e *ConfidentialStoreEngine) SetRetentionPolicy(policy RetentionPolicy) {
+	if policy.SweepInterval == 0 {
+		policy.SweepInterval = defaultRetentionSweepInterval
+	}
+	e.retention = &policy
+}
+
+// Prune removes every bid (and the data and index entries associated with it)
+// whose decryption condition is below the given block number.
+func (e *ConfidentialStoreEngine) Prune(decryptionConditionBelow uint64) (PruneResult, error) {
+	res, err := e.storage.Prune(decryptionConditionBelow)
+	e.pruneSyncLog(decryptionConditionBelow)
+
+	prunedBidsMeter.Mark(int64(res.Bids))
+	prunedValuesMeter.Mark(int64(res.Values))
+	prunedIndexMeter.Mark(int64(res.IndexEntries))
+	if err != nil {
+		pruneFailuresMeter.Mark(1)
+	}
+
+	return res, err
+}
+
+// retainedSinceBlock returns the lowest decryption condition of the bids which
+// are still retained, zero if bids are kept forever or the current block of the
+// target chain is unknown.
+func (e *ConfidentialStoreEngine) retainedSinceBlock() uint64 {
+	if e.retention == nil {
+		return 0
+	}
+
+	currentBlock, err := e.retention.CurrentBlock()
+	if err != nil {
+		log.Warn("Confidential engine: could not get the current block of the target chain", "err", err)
+		return 0
+	}
+	if currentBlock <= e.retention.GracePeriod {
+		return 0
+	}
+	return currentBlock - e.retention.GracePeriod
+}
+
+func (e *ConfidentialStoreEngine) sweep() {
+	ticker := time.NewTicker(e.retention.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done(): // Stop() called
+			return
+		case <-ticker.C:
+			retainedSince := e.retainedSinceBlock()
+			if retainedSince == 0 {
+				continue
+			}
+
+			res, err := e.Prune(retainedSince)
+			if err != nil {
+				log.Warn("Confidential engine: could not prune expired bids", "err", err)
+				continue
+			}
+			if res.Bids > 0 {
+				log.Debug("Confidential engine: pruned expired bids", "below", retainedSince, "bids", res.Bids, "values", res.Values, "index", res.IndexEntries)
+			}
+		}
+	}
+}
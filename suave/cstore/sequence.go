@@ -0,0 +1,215 @@
+package cstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+// reservedKeyPrefix marks the keys of a bid the engine keeps its own metadata
+// under, they are not accessible to contracts.
+const reservedKeyPrefix = "\x00suave:"
+
+var (
+	errReplayedMessage = errors.New("message replayed")
+	errReservedKey     = errors.New("key is reserved")
+
+	// engineStateBid holds the sequences of the signers of the engine. It is
+	// never initialized so it is neither returned by queries nor pruned.
+	engineStateBid = suave.Bid{Id: types.BidId{0x3a}}
+)
+
+// WriteVersion orders the writes to the same key of a bid. Every store applies
+// the write with the highest version, regardless of the order writes arrive in.
+// Sequences are Lamport timestamps: a signer always publishes with a sequence
+// higher than any it has seen, ties between signers are broken by address.
+type WriteVersion struct {
+	Sequence uint64         `json:"sequence"`
+	Signer   common.Address `json:"signer"`
+}
+
+func (v WriteVersion) Less(other WriteVersion) bool {
+	if v.Sequence != other.Sequence {
+		return v.Sequence < other.Sequence
+	}
+	return bytes.Compare(v.Signer[:], other.Signer[:]) < 0
+}
+
+// signerSequence is the persisted sequence state of a local signer, so that it
+// never publishes a sequence twice across restarts.
+type signerSequence struct {
+	Highest uint64 `json:"highest"`
+}
+
+func isReservedKey(key string) bool {
+	return strings.HasPrefix(key, reservedKeyPrefix)
+}
+
+func writeVersionKey(key string) string {
+	return reservedKeyPrefix + "version:" + key
+}
+
+// writeSeenKey marks the write of the key with the version as seen. Every write
+// is marked, however late it arrives, so that a replay is rejected for as long
+// as the bid is kept.
+func writeSeenKey(key string, version WriteVersion) string {
+	return fmt.Sprintf("%sseen:%x:%d:%s", reservedKeyPrefix, version.Signer, version.Sequence, key)
+}
+
+func signerSequenceKey(signer common.Address) string {
+	return fmt.Sprintf("%ssequence:%x", reservedKeyPrefix, signer)
+}
+
+func (e *ConfidentialStoreEngine) fetchSignerSequence(signer common.Address) (signerSequence, error) {
+	data, err := e.storage.Retrieve(engineStateBid, common.Address{}, signerSequenceKey(signer))
+	if err != nil {
+		// Nothing published by the signer yet
+		return signerSequence{}, nil
+	}
+
+	var sequence signerSequence
+	if err := json.Unmarshal(data, &sequence); err != nil {
+		return signerSequence{}, fmt.Errorf("confidential engine: corrupted sequence of %x: %w", signer, err)
+	}
+	return sequence, nil
+}
+
+func (e *ConfidentialStoreEngine) storeSignerSequence(signer common.Address, sequence signerSequence) error {
+	data, err := json.Marshal(sequence)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.storage.Store(engineStateBid, common.Address{}, signerSequenceKey(signer), data)
+	return err
+}
+
+// nextSequence returns the sequence the signer publishes its next message with.
+func (e *ConfidentialStoreEngine) nextSequence(signer common.Address) (uint64, error) {
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+
+	state, err := e.fetchSignerSequence(signer)
+	if err != nil {
+		return 0, err
+	}
+
+	sequence := e.clock
+	if state.Highest > sequence {
+		sequence = state.Highest
+	}
+	sequence++
+
+	if err := e.storeSignerSequence(signer, signerSequence{Highest: sequence}); err != nil {
+		return 0, fmt.Errorf("confidential engine: could not persist sequence of %x: %w", signer, err)
+	}
+	e.clock = sequence
+
+	return sequence, nil
+}
+
+// acceptWrites marks the writes of the message of the signer as seen, and
+// rejects the message if any of them was already seen.
+func (e *ConfidentialStoreEngine) acceptWrites(signer common.Address, sequence uint64, writes []StoreWrite) error {
+	if sequence == 0 {
+		return fmt.Errorf("confidential engine: message from %x has no sequence", signer)
+	}
+
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+
+	for _, sw := range writes {
+		if e.isSeenWrite(sw) {
+			return fmt.Errorf("confidential engine: sequence %d of %x: %w", sequence, signer, errReplayedMessage)
+		}
+	}
+
+	if err := e.storage.Commit(nil, seenWrites(writes)); err != nil {
+		return fmt.Errorf("confidential engine: could not mark writes of %x as seen: %w", signer, err)
+	}
+	e.observeSequence(sequence)
+
+	return nil
+}
+
+// acceptSyncedWrites marks the writes synced from the signer as seen, and
+// returns those which were not seen before.
+func (e *ConfidentialStoreEngine) acceptSyncedWrites(signer common.Address, writes []StoreWrite) ([]StoreWrite, error) {
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+
+	unseen := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		if e.isSeenWrite(sw) {
+			continue
+		}
+		unseen = append(unseen, sw)
+	}
+
+	if err := e.storage.Commit(nil, seenWrites(unseen)); err != nil {
+		return nil, fmt.Errorf("confidential engine: could not mark writes synced from %x as seen: %w", signer, err)
+	}
+	for _, sw := range unseen {
+		e.observeSequence(sw.Version.Sequence)
+	}
+
+	return unseen, nil
+}
+
+// isSeenWrite returns whether the write was marked as seen. Must be called with sequenceLock held.
+func (e *ConfidentialStoreEngine) isSeenWrite(sw StoreWrite) bool {
+	_, err := e.storage.Retrieve(sw.Bid, common.Address{}, writeSeenKey(sw.Key, sw.Version))
+	return err == nil
+}
+
+// observeSequence advances the Lamport clock past a sequence seen from another store.
+// Must be called with sequenceLock held.
+func (e *ConfidentialStoreEngine) observeSequence(sequence uint64) {
+	if sequence > e.clock {
+		e.clock = sequence
+	}
+}
+
+// fetchWriteVersion returns the version of the last write applied to the key, false if unknown.
+func (e *ConfidentialStoreEngine) fetchWriteVersion(bid suave.Bid, key string) (WriteVersion, bool) {
+	data, err := e.storage.Retrieve(bid, common.Address{}, writeVersionKey(key))
+	if err != nil {
+		return WriteVersion{}, false
+	}
+
+	var version WriteVersion
+	if err := json.Unmarshal(data, &version); err != nil {
+		log.Warn("Confidential engine: corrupted write version", "bid", bid.Id, "key", key, "err", err)
+		return WriteVersion{}, false
+	}
+	return version, true
+}
+
+// versionWrites returns the writes persisting the version of each of the writes.
+func versionWrites(writes []StoreWrite) ([]StoreWrite, error) {
+	res := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		data, err := json.Marshal(sw.Version)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, StoreWrite{Bid: sw.Bid, Caller: sw.Caller, Key: writeVersionKey(sw.Key), Value: data})
+	}
+	return res, nil
+}
+
+// seenWrites returns the writes marking each of the writes as seen.
+func seenWrites(writes []StoreWrite) []StoreWrite {
+	res := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		res = append(res, StoreWrite{Bid: sw.Bid, Caller: sw.Caller, Key: writeSeenKey(sw.Key, sw.Version), Value: []byte{1}})
+	}
+	return res
+}
@@ -0,0 +1,237 @@
+package cstore
+
+import (
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingTransport struct {
+	MockTransport
+
+	lock     sync.Mutex
+	messages []DAMessage
+}
+
+func (r *recordingTransport) Publish(message DAMessage) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+// sourceMessages returns the published messages carrying writes
+func (r *recordingTransport) sourceMessages() []DAMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var res []DAMessage
+	for _, message := range r.messages {
+		if message.SourceTx != nil {
+			res = append(res, message)
+		}
+	}
+	return res
+}
+
+func TestAcceptWritesLate(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x50}}}, MockChainSigner{})
+
+	signer := common.Address{0x10}
+	newWrites := func(sequence uint64) []StoreWrite {
+		return []StoreWrite{{Bid: suave.Bid{Id: types.BidId{0x1}}, Key: "a", Version: WriteVersion{Sequence: sequence, Signer: signer}}}
+	}
+
+	// Writes are accepted however late they arrive
+	require.NoError(t, engine.acceptWrites(signer, 1000, newWrites(1000)))
+	require.NoError(t, engine.acceptWrites(signer, 1, newWrites(1)))
+	require.NoError(t, engine.acceptWrites(signer, 500, newWrites(500)))
+
+	// And so are replays rejected
+	for _, sequence := range []uint64{1, 500, 1000} {
+		require.ErrorIs(t, engine.acceptWrites(signer, sequence, newWrites(sequence)), errReplayedMessage)
+	}
+}
+
+func TestWriteOrderingProperty(t *testing.T) {
+	senderAddresses := []common.Address{{0x10}, {0x11}, {0x12}}
+	receiverAddresses := []common.Address{{0x50}, {0x51}, {0x52}}
+
+	var receivers []*ConfidentialStoreEngine
+	for _, addr := range receiverAddresses {
+		receivers = append(receivers, NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{addr}}, MockChainSigner{}))
+	}
+
+	var senders []*ConfidentialStoreEngine
+	var transports []*recordingTransport
+	for _, addr := range senderAddresses {
+		transport := &recordingTransport{}
+		sender := NewConfidentialStoreEngine(NewLocalConfidentialStore(), transport, FakeDASigner{localAddresses: []common.Address{addr}}, MockChainSigner{})
+		for _, receiver := range receivers {
+			announcements, err := receiver.keyAnnouncements()
+			require.NoError(t, err)
+			require.NoError(t, sender.handleKeyAnnouncements(announcements))
+		}
+
+		senders = append(senders, sender)
+		transports = append(transports, transport)
+	}
+	// Senders are allowed stores of the bid as well
+	for _, sender := range senders {
+		for _, other := range senders {
+			announcements, err := other.keyAnnouncements()
+			require.NoError(t, err)
+			require.NoError(t, sender.handleKeyAnnouncements(announcements))
+		}
+	}
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	newSourceTx := func(executionNode common.Address, nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				ExecutionNode: executionNode,
+				Nonce:         nonce,
+			},
+		}), types.NewSuaveSigner(new(big.Int)), testKey)
+		require.NoError(t, err)
+		return tx
+	}
+
+	bid, err := senders[0].InitializeBid(types.Bid{
+		AllowedPeekers: []common.Address{{0x01}},
+		AllowedStores:  append(append([]common.Address{}, senderAddresses...), receiverAddresses...),
+	}, newSourceTx(senderAddresses[0], 0))
+	require.NoError(t, err)
+
+	for seed := int64(0); seed < 10; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+
+		// Senders write conflicting values to a few keys
+		numWrites := 20
+		keys := []string{"a", "b", "c"}
+		for i := 0; i < numWrites; i++ {
+			sender := rng.Intn(len(senders))
+			nonce := uint64(seed)*uint64(numWrites) + uint64(i)
+			require.NoError(t, senders[sender].Finalize(newSourceTx(senderAddresses[sender], nonce), nil, []StoreWrite{{
+				Bid:    bid,
+				Caller: common.Address{0x01},
+				Key:    keys[rng.Intn(len(keys))],
+				Value:  big.NewInt(int64(nonce) + 1).Bytes(),
+			}}))
+		}
+
+		var messages []DAMessage
+		require.Eventually(t, func() bool {
+			messages = nil
+			for _, transport := range transports {
+				messages = append(messages, transport.sourceMessages()...)
+			}
+			return len(messages) == int(seed+1)*numWrites
+		}, time.Second, 5*time.Millisecond)
+
+		// The value with the highest version wins
+		expected := make(map[string][]byte)
+		expectedVersions := make(map[string]WriteVersion)
+		for _, message := range messages {
+			sw := message.StoreWrites[0]
+			signer, err := ExecutionNodeFromTransaction(message.SourceTx)
+			require.NoError(t, err)
+			require.Equal(t, WriteVersion{Sequence: message.Sequence, Signer: signer}, sw.Version)
+			if current, found := expectedVersions[sw.Key]; !found || current.Less(sw.Version) {
+				expectedVersions[sw.Key] = sw.Version
+				expected[sw.Key] = big.NewInt(int64(message.SourceTx.Nonce()) + 1).Bytes()
+			}
+		}
+
+		for _, receiver := range receivers {
+			// Deliver in random order, with replays
+			delivery := append([]DAMessage{}, messages...)
+			for i := 0; i < len(messages)/2; i++ {
+				delivery = append(delivery, messages[rng.Intn(len(messages))])
+			}
+			rng.Shuffle(len(delivery), func(i, j int) { delivery[i], delivery[j] = delivery[j], delivery[i] })
+
+			for _, message := range delivery {
+				err := receiver.NewMessage(message)
+				if err != nil {
+					require.ErrorIs(t, err, errReplayedMessage)
+				}
+			}
+
+			for key, value := range expected {
+				stored, err := receiver.Retrieve(bid.Id, common.Address{0x01}, key)
+				require.NoError(t, err)
+				require.Equal(t, value, stored, "seed %d, key %s", seed, key)
+			}
+		}
+	}
+
+	// The replay protection state is persisted
+	restarted := NewConfidentialStoreEngine(receivers[0].storage, MockTransport{}, FakeDASigner{localAddresses: receiverAddresses[:1]}, MockChainSigner{})
+	messages := transports[0].sourceMessages()
+	message := messages[len(messages)-1]
+	require.ErrorIs(t, restarted.acceptWrites(senderAddresses[0], message.Sequence, message.StoreWrites), errReplayedMessage)
+}
+
+func TestPrunedBidReplay(t *testing.T) {
+	transport := &recordingTransport{}
+	sender := NewConfidentialStoreEngine(NewLocalConfidentialStore(), transport, FakeDASigner{localAddresses: []common.Address{{0x10}}}, MockChainSigner{})
+	receiver := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x50}}}, MockChainSigner{})
+
+	currentBlock := uint64(10)
+	receiver.SetRetentionPolicy(RetentionPolicy{
+		GracePeriod:  5,
+		CurrentBlock: func() (uint64, error) { return currentBlock, nil },
+	})
+
+	announcements, err := receiver.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, sender.handleKeyAnnouncements(announcements))
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x10},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	bid, err := sender.InitializeBid(types.Bid{
+		DecryptionCondition: 10,
+		AllowedPeekers:      []common.Address{{0x01}},
+		AllowedStores:       []common.Address{{0x50}},
+	}, creationTx)
+	require.NoError(t, err)
+
+	require.NoError(t, sender.Finalize(creationTx, map[suave.BidId]suave.Bid{bid.Id: bid}, []StoreWrite{{
+		Bid:    bid,
+		Caller: common.Address{0x01},
+		Key:    "xx",
+		Value:  []byte{0x01},
+	}}))
+	require.Eventually(t, func() bool { return len(transport.sourceMessages()) == 1 }, time.Second, 5*time.Millisecond)
+	message := transport.sourceMessages()[0]
+
+	require.NoError(t, receiver.NewMessage(message))
+
+	// Once the bid is pruned, replaying the message does not bring it back
+	currentBlock = 20
+	res, err := receiver.Prune(receiver.retainedSinceBlock())
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Bids)
+
+	require.ErrorIs(t, receiver.NewMessage(message), errExpiredBid)
+
+	_, err = receiver.FetchBidById(bid.Id)
+	require.ErrorIs(t, err, suave.ErrBidNotFound)
+}
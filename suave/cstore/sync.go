@@ -0,0 +1,372 @@
+package cstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+	"golang.org/x/time/rate"
+)
+
+var (
+	syncRequestsMeter       = metrics.NewRegisteredMeter("suave/cstore/sync/requests", nil)
+	syncServedWritesMeter   = metrics.NewRegisteredMeter("suave/cstore/sync/served", nil)
+	syncSyncedWritesMeter   = metrics.NewRegisteredMeter("suave/cstore/sync/synced", nil)
+	syncRejectedWritesMeter = metrics.NewRegisteredMeter("suave/cstore/sync/rejected", nil)
+	syncLimitedMeter        = metrics.NewRegisteredMeter("suave/cstore/sync/limited", nil)
+
+	errSyncRateLimited = errors.New("sync request rate limit exceeded")
+
+	syncLogLimit          = 65536       // Writes kept for answering sync requests
+	syncResponseBatchSize = 256         // Writes per sync response message
+	syncRequestInterval   = time.Minute // Interval at which each store may request a sync
+	syncRequestBurst      = 3           // Sync requests a store may send at once
+	syncLimitersCached    = 1024        // Stores whose rate limiters are kept
+)
+
+// SyncRequest asks the other stores for the writes they have seen to the
+// requesting stores, so that a store which was not subscribed to the transport
+// catches up on the writes it missed. The request is signed by each of the
+// requesting stores.
+type SyncRequest struct {
+	Id     uuid.UUID        `json:"id"`
+	Stores []common.Address `json:"stores"`
+	// SinceBlock limits the writes to bids with a decryption condition of at least SinceBlock
+	SinceBlock uint64 `json:"sinceBlock"`
+	// SinceSequence holds the sequence of the last write already synced from each store
+	SinceSequence map[uuid.UUID]uint64 `json:"sinceSequence,omitempty"`
+	// Signatures holds the signature of the request by each of the stores, in order
+	Signatures []suave.Bytes `json:"signatures"`
+}
+
+// SyncResponse marks a message as an answer to a sync request. The message is
+// signed by Store, which has to be an allowed store of every bid written to.
+type SyncResponse struct {
+	RequestId uuid.UUID      `json:"requestId"`
+	Store     common.Address `json:"store"`
+	// Sequence is the responder's sequence of the last write in the message
+	Sequence uint64 `json:"sequence"`
+}
+
+type syncLogEntry struct {
+	sequence uint64
+	write    StoreWrite
+}
+
+// RequestSync asks the other stores for the writes to the local addresses on
+// bids with a decryption condition of at least sinceBlock. Writes already synced
+// from a store are not requested again.
+func (e *ConfidentialStoreEngine) RequestSync(sinceBlock uint64) error {
+	stores := e.daSigner.LocalAddresses()
+	if len(stores) == 0 {
+		return nil
+	}
+
+	// Responses are encrypted to our keys, make sure they are known
+	announcements, err := e.keyAnnouncements()
+	if err != nil {
+		return err
+	}
+
+	e.syncLock.Lock()
+	sinceSequence := make(map[uuid.UUID]uint64, len(e.syncedSequences))
+	for store, sequence := range e.syncedSequences {
+		sinceSequence[store] = sequence
+	}
+	e.syncLock.Unlock()
+
+	request := &SyncRequest{
+		Id:            uuid.New(),
+		Stores:        stores,
+		SinceBlock:    sinceBlock,
+		SinceSequence: sinceSequence,
+	}
+
+	requestBytes, err := SerializeSyncRequestForSigning(request)
+	if err != nil {
+		return fmt.Errorf("confidential engine: could not hash sync request for signing: %w", err)
+	}
+	for _, store := range stores {
+		signature, err := e.daSigner.Sign(store, requestBytes)
+		if err != nil {
+			return fmt.Errorf("confidential engine: could not sign sync request for %x: %w", store, err)
+		}
+		request.Signatures = append(request.Signatures, signature)
+	}
+
+	go e.publish(DAMessage{
+		StoreUUID:        e.storeUUID,
+		KeyAnnouncements: announcements,
+		SyncRequest:      request,
+	})
+
+	return nil
+}
+
+// recordWrites adds writes this store has signed to the log sync requests are answered from.
+func (e *ConfidentialStoreEngine) recordWrites(writes []StoreWrite) {
+	e.syncLock.Lock()
+	defer e.syncLock.Unlock()
+
+	for _, sw := range writes {
+		e.syncSequence++
+		e.syncLog = append(e.syncLog, syncLogEntry{sequence: e.syncSequence, write: sw})
+	}
+
+	if len(e.syncLog) > syncLogLimit {
+		e.syncLog = append([]syncLogEntry(nil), e.syncLog[len(e.syncLog)-syncLogLimit:]...)
+	}
+}
+
+// pruneSyncLog drops the writes to bids with a decryption condition below the given block.
+func (e *ConfidentialStoreEngine) pruneSyncLog(decryptionConditionBelow uint64) {
+	e.syncLock.Lock()
+	defer e.syncLock.Unlock()
+
+	retained := e.syncLog[:0]
+	for _, entry := range e.syncLog {
+		if entry.write.Bid.DecryptionCondition >= decryptionConditionBelow {
+			retained = append(retained, entry)
+		}
+	}
+	e.syncLog = retained
+}
+
+// handleSyncRequest publishes the writes the requesting stores are allowed to
+// receive. A store only vouches for the versions it signed, so each write is
+// sent by the local address it was signed by, and only the writes signed by
+// this store are sent, the requesting stores get the others from their signers.
+// The request has to be signed by each of the requesting stores, which have to be
+// registered execution nodes, and each store is rate limited.
+func (e *ConfidentialStoreEngine) handleSyncRequest(request *SyncRequest) error {
+	syncRequestsMeter.Mark(1)
+
+	if err := verifySyncRequest(e.daSigner, request); err != nil {
+		return err
+	}
+	for _, store := range request.Stores {
+		if err := e.validateExecutionNode(store, nil); err != nil {
+			return err
+		}
+	}
+	if !e.allowSyncRequest(request.Stores) {
+		syncLimitedMeter.Mark(1)
+		return fmt.Errorf("confidential engine: %w for %v", errSyncRateLimited, request.Stores)
+	}
+
+	localAddresses := e.daSigner.LocalAddresses()
+	recipients := make([]common.Address, 0, len(request.Stores))
+	for _, store := range request.Stores {
+		if !slices.Contains(localAddresses, store) {
+			recipients = append(recipients, store)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	sinceSequence := request.SinceSequence[e.storeUUID]
+
+	e.syncLock.Lock()
+	writesByStore := make(map[common.Address][]syncLogEntry)
+	for _, entry := range e.syncLog {
+		bid := entry.write.Bid
+		if entry.sequence <= sinceSequence || bid.DecryptionCondition < request.SinceBlock {
+			continue
+		}
+
+		if slices.IndexFunc(recipients, func(store common.Address) bool { return slices.Contains(bid.AllowedStores, store) }) == -1 {
+			continue
+		}
+
+		signer := entry.write.Version.Signer
+		if !slices.Contains(localAddresses, signer) || !slices.Contains(bid.AllowedStores, signer) {
+			continue
+		}
+
+		writesByStore[signer] = append(writesByStore[signer], entry)
+	}
+	e.syncLock.Unlock()
+
+	for signer, entries := range writesByStore {
+		for len(entries) > 0 {
+			batch := entries
+			if len(batch) > syncResponseBatchSize {
+				batch = batch[:syncResponseBatchSize]
+			}
+			entries = entries[len(batch):]
+
+			if err := e.publishSyncResponse(request.Id, signer, batch, recipients); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// allowSyncRequest returns whether the rate limit of every requesting store
+// allows the request.
+func (e *ConfidentialStoreEngine) allowSyncRequest(stores []common.Address) bool {
+	e.syncLock.Lock()
+	defer e.syncLock.Unlock()
+
+	allowed := true
+	for _, store := range stores {
+		limiter, found := e.syncLimiters.Get(store)
+		if !found {
+			limiter = rate.NewLimiter(rate.Every(syncRequestInterval), syncRequestBurst)
+			e.syncLimiters.Add(store, limiter)
+		}
+		if !limiter.Allow() {
+			allowed = false
+		}
+	}
+	return allowed
+}
+
+func (e *ConfidentialStoreEngine) publishSyncResponse(requestId uuid.UUID, signer common.Address, entries []syncLogEntry, recipients []common.Address) error {
+	writes := make([]StoreWrite, 0, len(entries))
+	for _, entry := range entries {
+		writes = append(writes, entry.write)
+	}
+
+	// The requesting stores announced their keys along with the request
+	transportWrites, _, err := e.encryptStoreWrites(writes, recipients)
+	if err != nil {
+		return err
+	}
+
+	message := DAMessage{
+		StoreWrites: transportWrites,
+		StoreUUID:   e.storeUUID,
+		SyncResponse: &SyncResponse{
+			RequestId: requestId,
+			Store:     signer,
+			Sequence:  entries[len(entries)-1].sequence,
+		},
+	}
+
+	if err := e.signMessage(signer, &message); err != nil {
+		return err
+	}
+
+	syncServedWritesMeter.Mark(int64(len(writes)))
+	go e.publish(message)
+	return nil
+}
+
+// handleSyncResponse validates and stores the writes of a sync response
+// with the same checks as writes propagated along with their source transaction.
+// Every write has to be versioned by the responding store, which cannot vouch for
+// the versions of other stores. Writes already seen are dropped.
+func (e *ConfidentialStoreEngine) handleSyncResponse(message DAMessage) error {
+	if message.StoreUUID == e.storeUUID {
+		return nil
+	}
+
+	signer, err := verifySyncResponseSigner(e.daSigner, &message)
+	if err != nil {
+		return err
+	}
+
+	if err := e.validateExecutionNode(signer, message.StoreWrites); err != nil {
+		syncRejectedWritesMeter.Mark(int64(len(message.StoreWrites)))
+		return err
+	}
+
+	for _, sw := range message.StoreWrites {
+		if sw.Version.Signer != signer {
+			syncRejectedWritesMeter.Mark(int64(len(message.StoreWrites)))
+			return fmt.Errorf("confidential engine: synced write of %s on bid %x versioned by %x, not by the responding store %x", sw.Key, sw.Bid.Id, sw.Version.Signer, signer)
+		}
+	}
+
+	writes, err := e.validateStoreWrites(signer, message.StoreWrites)
+	if err != nil {
+		syncRejectedWritesMeter.Mark(int64(len(message.StoreWrites)))
+		return err
+	}
+
+	writes, err = e.acceptSyncedWrites(signer, writes)
+	if err != nil {
+		return err
+	}
+
+	e.applyWrites(writes)
+	syncSyncedWritesMeter.Mark(int64(len(writes)))
+
+	e.syncLock.Lock()
+	if message.SyncResponse.Sequence > e.syncedSequences[message.StoreUUID] {
+		e.syncedSequences[message.StoreUUID] = message.SyncResponse.Sequence
+	}
+	e.syncLock.Unlock()
+
+	if len(writes) > 0 {
+		log.Debug("Confidential engine: synced writes", "store", signer, "writes", len(writes))
+	}
+	return nil
+}
+
+// verifySyncResponseSigner checks that the sync response is signed by the store it claims to be sent by.
+func verifySyncResponseSigner(daSigner DASigner, message *DAMessage) (common.Address, error) {
+	msgBytes, err := SerializeMessageForSigning(message)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("confidential engine: could not hash received sync response: %w", err)
+	}
+
+	signer, err := daSigner.Sender(msgBytes, message.Signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("confidential engine: incorrect sync response signature: %w", err)
+	}
+	if signer != message.SyncResponse.Store {
+		return common.Address{}, fmt.Errorf("confidential engine: sync response signer %x, expected %x", signer, message.SyncResponse.Store)
+	}
+
+	return signer, nil
+}
+
+// verifySyncRequest checks that the sync request is signed by each of the stores it is sent for.
+func verifySyncRequest(daSigner DASigner, request *SyncRequest) error {
+	if len(request.Stores) == 0 || len(request.Signatures) != len(request.Stores) {
+		return fmt.Errorf("confidential engine: sync request with %d signatures for %d stores", len(request.Signatures), len(request.Stores))
+	}
+
+	requestBytes, err := SerializeSyncRequestForSigning(request)
+	if err != nil {
+		return fmt.Errorf("confidential engine: could not hash received sync request: %w", err)
+	}
+
+	for i, store := range request.Stores {
+		signer, err := daSigner.Sender(requestBytes, request.Signatures[i])
+		if err != nil {
+			return fmt.Errorf("confidential engine: incorrect sync request signature: %w", err)
+		}
+		if signer != store {
+			return fmt.Errorf("confidential engine: sync request signer %x, expected %x", signer, store)
+		}
+	}
+
+	return nil
+}
+
+func SerializeSyncRequestForSigning(request *SyncRequest) ([]byte, error) {
+	requestBytes, err := json.Marshal(SyncRequest{
+		Id:            request.Id,
+		Stores:        request.Stores,
+		SinceBlock:    request.SinceBlock,
+		SinceSequence: request.SinceSequence,
+	})
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return []byte(fmt.Sprintf("\x19Suave Signed Message:\n%d%s", len(requestBytes), string(requestBytes))), nil
+}
@@ -0,0 +1,251 @@
+package cstore
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTransportHub relays messages between the engines of a test, like a shared redis would
+type memoryTransportHub struct {
+	lock        sync.Mutex
+	subscribers map[chan DAMessage]struct{}
+}
+
+func newMemoryTransportHub() *memoryTransportHub {
+	return &memoryTransportHub{subscribers: make(map[chan DAMessage]struct{})}
+}
+
+func (h *memoryTransportHub) Start() error { return nil }
+func (h *memoryTransportHub) Stop() error  { return nil }
+
+func (h *memoryTransportHub) Subscribe() (<-chan DAMessage, context.CancelFunc) {
+	ch := make(chan DAMessage, 1024)
+
+	h.lock.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.lock.Unlock()
+
+	return ch, func() {
+		h.lock.Lock()
+		delete(h.subscribers, ch)
+		h.lock.Unlock()
+	}
+}
+
+func (h *memoryTransportHub) Publish(message DAMessage) error {
+	// Go through the wire encoding
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for ch := range h.subscribers {
+		var msg DAMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+func (h *memoryTransportHub) numSubscribers() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.subscribers)
+}
+
+func (e *ConfidentialStoreEngine) knowsStoreKey(store common.Address) bool {
+	e.storeKeysLock.RLock()
+	defer e.storeKeysLock.RUnlock()
+	_, found := e.storeKeys[store]
+	return found
+}
+
+func TestEngineCatchUpSync(t *testing.T) {
+	hub := newMemoryTransportHub()
+
+	engineA := NewConfidentialStoreEngine(NewLocalConfidentialStore(), hub, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+	engineB := NewConfidentialStoreEngine(NewLocalConfidentialStore(), hub, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+
+	require.NoError(t, engineA.Start())
+	require.NoError(t, engineB.Start())
+	t.Cleanup(func() {
+		engineA.Stop()
+		engineB.Stop()
+	})
+
+	require.Eventually(t, func() bool {
+		return engineA.knowsStoreKey(common.Address{0x43}) && engineB.knowsStoreKey(common.Address{0x42})
+	}, time.Second, 10*time.Millisecond)
+
+	// Take A offline
+	require.NoError(t, engineA.Stop())
+	require.Eventually(t, func() bool { return hub.numSubscribers() == 1 }, time.Second, 10*time.Millisecond)
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	var bids []suave.Bid
+	for i := 0; i < 3; i++ {
+		creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				ExecutionNode: common.Address{0x43},
+				Nonce:         uint64(i),
+			},
+		}), types.NewSuaveSigner(new(big.Int)), testKey)
+		require.NoError(t, err)
+
+		bid, err := engineB.InitializeBid(types.Bid{
+			DecryptionCondition: uint64(i),
+			AllowedPeekers:      []common.Address{{0x01}},
+			AllowedStores:       []common.Address{{0x42}},
+		}, creationTx)
+		require.NoError(t, err)
+
+		require.NoError(t, engineB.Finalize(creationTx, map[suave.BidId]suave.Bid{bid.Id: bid}, []StoreWrite{{
+			Bid:    bid,
+			Caller: common.Address{0x01},
+			Key:    "xx",
+			Value:  []byte{byte(i)},
+		}}))
+		bids = append(bids, bid)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = engineA.FetchBidById(bids[0].Id)
+	require.ErrorIs(t, err, suave.ErrBidNotFound)
+
+	// A requests the missed writes when restarted
+	require.NoError(t, engineA.Start())
+
+	require.Eventually(t, func() bool {
+		for i, bid := range bids {
+			value, err := engineA.Retrieve(bid.Id, common.Address{0x01}, "xx")
+			if err != nil || value[0] != byte(i) {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	engineA.syncLock.Lock()
+	require.Equal(t, uint64(3), engineA.syncedSequences[engineB.storeUUID])
+	engineA.syncLock.Unlock()
+}
+
+func TestEngineSyncLogPruning(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+
+	engine.recordWrites([]StoreWrite{
+		{Bid: suave.Bid{DecryptionCondition: 1, AllowedStores: []common.Address{{0x42}, {0x43}}}},
+		{Bid: suave.Bid{DecryptionCondition: 2, AllowedStores: []common.Address{{0x43}}}},
+		{Bid: suave.Bid{DecryptionCondition: 3, AllowedStores: []common.Address{{0x42}, {0x43}}}},
+		{Bid: suave.Bid{DecryptionCondition: 4, AllowedStores: []common.Address{{0x42}, {0x43}}}},
+	})
+
+	engine.pruneSyncLog(2)
+	require.Len(t, engine.syncLog, 3)
+	require.Equal(t, uint64(2), engine.syncLog[0].sequence)
+}
+
+func TestEngineSyncRequestValidation(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+
+	// Requests have to be signed by each of the requesting stores
+	request := &SyncRequest{Stores: []common.Address{{0x42}}}
+	require.ErrorContains(t, engine.handleSyncRequest(request), "0 signatures for 1 stores")
+
+	request.Signatures = []suave.Bytes{common.Address{0x44}.Bytes()}
+	require.ErrorContains(t, engine.handleSyncRequest(request), "sync request signer")
+
+	// Each store is rate limited
+	request.Signatures = []suave.Bytes{common.Address{0x42}.Bytes()}
+	for i := 0; i < syncRequestBurst; i++ {
+		require.NoError(t, engine.handleSyncRequest(request))
+	}
+	require.ErrorIs(t, engine.handleSyncRequest(request), errSyncRateLimited)
+
+	other := &SyncRequest{Stores: []common.Address{{0x45}}, Signatures: []suave.Bytes{common.Address{0x45}.Bytes()}}
+	require.NoError(t, engine.handleSyncRequest(other))
+}
+
+func TestEngineSyncResponseForgedVersion(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
+	responder := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x43}}}, MockChainSigner{})
+
+	announcements, err := engine.keyAnnouncements()
+	require.NoError(t, err)
+	require.NoError(t, responder.handleKeyAnnouncements(announcements))
+
+	testKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	creationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x43},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	bid, err := responder.InitializeBid(types.Bid{
+		AllowedPeekers: []common.Address{{0x01}},
+		AllowedStores:  []common.Address{{0x42}},
+	}, creationTx)
+	require.NoError(t, err)
+
+	newSyncResponse := func(value byte, version WriteVersion) DAMessage {
+		writes, _, err := responder.encryptStoreWrites([]StoreWrite{{
+			Bid:     bid,
+			Caller:  common.Address{0x01},
+			Key:     "xx",
+			Value:   []byte{value},
+			Version: version,
+		}}, []common.Address{{0x42}})
+		require.NoError(t, err)
+
+		message := DAMessage{
+			StoreWrites:  writes,
+			StoreUUID:    responder.storeUUID,
+			SyncResponse: &SyncResponse{RequestId: uuid.New(), Store: common.Address{0x43}, Sequence: 1},
+		}
+		require.NoError(t, responder.signMessage(common.Address{0x43}, &message))
+		return message
+	}
+
+	synced := newSyncResponse(0x01, WriteVersion{Sequence: 10, Signer: common.Address{0x43}})
+	require.NoError(t, engine.handleSyncResponse(synced))
+
+	// The responder cannot vouch for a version of another store
+	forged := newSyncResponse(0x02, WriteVersion{Sequence: math.MaxUint64, Signer: common.Address{0x99}})
+	require.ErrorContains(t, engine.handleSyncResponse(forged), "not by the responding store")
+
+	value, err := engine.Retrieve(bid.Id, common.Address{0x01}, "xx")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01}, value)
+
+	version, found := engine.fetchWriteVersion(bid, "xx")
+	require.True(t, found)
+	require.Equal(t, WriteVersion{Sequence: 10, Signer: common.Address{0x43}}, version)
+
+	// Writes already seen are dropped rather than failing the response
+	require.NoError(t, engine.handleSyncResponse(synced))
+}
@@ -1,8 +1,10 @@
 package cstore
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -46,6 +48,79 @@ func (s *TransactionalStore) FetchBidsByProtocolAndBlock(blockNumber uint64, nam
 	return bids
 }
 
+// FetchBids pages through bids pending in this transaction first, followed by
+// the bids already committed to the engine.
+func (s *TransactionalStore) FetchBids(query suave.BidQuery) ([]suave.Bid, uint64, error) {
+	if err := validateBidQuery(query); err != nil {
+		return nil, 0, err
+	}
+
+	namespaces := dedupNamespaces(query.Namespaces)
+	namespaceOrder := make(map[string]int, len(namespaces))
+	for i, namespace := range namespaces {
+		namespaceOrder[namespace] = i
+	}
+
+	s.pendingLock.Lock()
+	pending := []suave.Bid{}
+	for _, bid := range s.pendingBids {
+		if _, found := namespaceOrder[bid.Version]; found && bid.DecryptionCondition >= query.FromBlock && bid.DecryptionCondition <= query.ToBlock {
+			pending = append(pending, bid)
+		}
+	}
+	s.pendingLock.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].DecryptionCondition != pending[j].DecryptionCondition {
+			return pending[i].DecryptionCondition < pending[j].DecryptionCondition
+		}
+		if pending[i].Version != pending[j].Version {
+			return namespaceOrder[pending[i].Version] < namespaceOrder[pending[j].Version]
+		}
+		return bytes.Compare(pending[i].Id[:], pending[j].Id[:]) < 0
+	})
+
+	numPending := uint64(len(pending))
+	if query.Cursor < numPending {
+		bids := pending[query.Cursor:]
+		if uint64(len(bids)) >= query.Limit {
+			bids = bids[:query.Limit]
+			if query.Cursor+query.Limit < numPending {
+				return bids, query.Cursor + query.Limit, nil
+			}
+		}
+
+		remaining := query.Limit - uint64(len(bids))
+		if remaining == 0 {
+			// The page ends exactly at the last pending bid, committed bids (if any) are on the next page
+			return bids, numPending, nil
+		}
+
+		engineQuery := query
+		engineQuery.Cursor = 0
+		engineQuery.Limit = remaining
+		committed, next, err := s.engine.FetchBids(engineQuery)
+		if err != nil {
+			return nil, 0, err
+		}
+		if next != 0 {
+			next += numPending
+		}
+		return append(bids, committed...), next, nil
+	}
+
+	engineQuery := query
+	engineQuery.Cursor = query.Cursor - numPending
+	committed, next, err := s.engine.FetchBids(engineQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	if next != 0 {
+		next += numPending
+	}
+	return committed, next, nil
+}
+
 func (s *TransactionalStore) Store(bidId suave.BidId, caller common.Address, key string, value []byte) (suave.Bid, error) {
 	bid, err := s.FetchBidById(bidId)
 	if err != nil {
@@ -56,8 +131,28 @@ func (s *TransactionalStore) Store(bidId suave.BidId, caller common.Address, key
 		return suave.Bid{}, fmt.Errorf("confidential store transaction: %x not allowed to store %s on %x", caller, key, bidId)
 	}
 
+	if isReservedKey(key) {
+		return suave.Bid{}, fmt.Errorf("confidential store transaction: %s on %x: %w", key, bidId, errReservedKey)
+	}
+
+	if !bid.CanWrite(caller, key) {
+		return suave.Bid{}, fmt.Errorf("confidential store transaction: %x not allowed to write %s on %x: %w", caller, key, bidId, suave.ErrKeyAccessDenied)
+	}
+
 	s.pendingLock.Lock()
 	defer s.pendingLock.Unlock()
+
+	if bid.IsWriteOnce(key) {
+		for _, sw := range s.pendingWrites {
+			if sw.Bid.Id == bid.Id && sw.Key == key {
+				return suave.Bid{}, fmt.Errorf("confidential store transaction: %s on %x: %w", key, bidId, suave.ErrKeyWriteOnce)
+			}
+		}
+		if _, err := s.engine.storage.Retrieve(bid, caller, key); err == nil {
+			return suave.Bid{}, fmt.Errorf("confidential store transaction: %s on %x: %w", key, bidId, suave.ErrKeyWriteOnce)
+		}
+	}
+
 	s.pendingWrites = append(s.pendingWrites, StoreWrite{
 		Bid:    bid,
 		Caller: caller,
@@ -78,6 +173,14 @@ func (s *TransactionalStore) Retrieve(bidId suave.BidId, caller common.Address,
 		return nil, fmt.Errorf("confidential store transaction: %x not allowed to retrieve %s on %x", caller, key, bidId)
 	}
 
+	if isReservedKey(key) {
+		return nil, fmt.Errorf("confidential store transaction: %s on %x: %w", key, bidId, errReservedKey)
+	}
+
+	if !bid.CanRead(caller, key) {
+		return nil, fmt.Errorf("confidential store transaction: %x not allowed to read %s on %x: %w", caller, key, bidId, suave.ErrKeyAccessDenied)
+	}
+
 	s.pendingLock.Lock()
 
 	for _, sw := range s.pendingWrites {
@@ -109,6 +212,53 @@ func (s *TransactionalStore) InitializeBid(rawBid types.Bid) (types.Bid, error)
 	return bid.ToInnerBid(), nil
 }
 
+// SetKeyAccessRule sets the access rule for keys with the given prefix,
+// replacing any rule for the same prefix. Rules are part of the signed bid and
+// can only be set on bids initialized in this transaction.
+func (s *TransactionalStore) SetKeyAccessRule(bidId suave.BidId, caller common.Address, rule suave.KeyAccessRule) (suave.Bid, error) {
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+
+	bid, found := s.pendingBids[bidId]
+	if !found {
+		return suave.Bid{}, fmt.Errorf("confidential store transaction: key rules can only be set on bids initialized in the same transaction, %x is not", bidId)
+	}
+
+	if !slices.Contains(bid.AllowedPeekers, caller) && !slices.Contains(bid.AllowedPeekers, suave.AllowedPeekerAny) {
+		return suave.Bid{}, fmt.Errorf("confidential store transaction: %x not allowed to set key rules on %x", caller, bidId)
+	}
+
+	keyRules := make([]suave.KeyAccessRule, 0, len(bid.KeyRules)+1)
+	for _, existing := range bid.KeyRules {
+		if existing.Prefix != rule.Prefix {
+			keyRules = append(keyRules, existing)
+		}
+	}
+	bid.KeyRules = append(keyRules, rule)
+
+	if err := s.engine.signBid(&bid); err != nil {
+		return suave.Bid{}, err
+	}
+	s.pendingBids[bidId] = bid
+
+	// Writes carry the bid to other stores, make sure they see the new rules
+	for i := range s.pendingWrites {
+		if s.pendingWrites[i].Bid.Id == bidId {
+			s.pendingWrites[i].Bid = bid
+		}
+	}
+
+	return bid, nil
+}
+
+// PendingWrites returns the writes of the transaction, not finalized yet.
+func (s *TransactionalStore) PendingWrites() []StoreWrite {
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+
+	return append([]StoreWrite(nil), s.pendingWrites...)
+}
+
 func (s *TransactionalStore) Finalize() error {
 	return s.engine.Finalize(s.sourceTx, s.pendingBids, s.pendingWrites)
 }
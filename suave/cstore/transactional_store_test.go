@@ -12,7 +12,7 @@ import (
 )
 
 func TestTransactionalStore(t *testing.T) {
-	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, MockSigner{}, MockChainSigner{})
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, FakeDASigner{localAddresses: []common.Address{{0x42}}}, MockChainSigner{})
 
 	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	dummyCreationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
@@ -60,6 +60,11 @@ func TestTransactionalStore(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte{0x44}, tretrieved)
 
+	writes := tstore.PendingWrites()
+	require.Len(t, writes, 1)
+	require.Equal(t, "xx", writes[0].Key)
+	require.Equal(t, testBid.AllowedPeekers[0], writes[0].Caller)
+
 	// Not finalized, engine should return empty
 	_, err = engine.FetchBidById(testBid.Id)
 	require.Error(t, err)
@@ -81,3 +86,121 @@ func TestTransactionalStore(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte{0x44}, eretrieved)
 }
+
+func TestTransactionalStoreFetchBids(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, MockSigner{}, MockChainSigner{})
+
+	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	dummyCreationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	committedBid := suave.Bid{Id: suave.RandomBidId(), DecryptionCondition: 46, Version: "v0-test"}
+	require.NoError(t, engine.Backend().InitializeBid(committedBid))
+
+	tstore := engine.NewTransactionalStore(dummyCreationTx)
+
+	pendingBid, err := tstore.InitializeBid(types.Bid{
+		Salt:                RandomBidId(),
+		DecryptionCondition: 47,
+		AllowedPeekers:      []common.Address{{0x43}},
+		Version:             "v0-test",
+	})
+	require.NoError(t, err)
+
+	query := suave.BidQuery{FromBlock: 46, ToBlock: 47, Namespaces: []string{"v0-test"}, Limit: 1}
+
+	// Pending bids come first
+	bids, cursor, err := tstore.FetchBids(query)
+	require.NoError(t, err)
+	require.Len(t, bids, 1)
+	require.Equal(t, pendingBid, bids[0].ToInnerBid())
+	require.Equal(t, uint64(1), cursor)
+
+	query.Cursor = cursor
+	bids, cursor, err = tstore.FetchBids(query)
+	require.NoError(t, err)
+	require.Equal(t, []suave.Bid{committedBid}, bids)
+	require.Zero(t, cursor)
+
+	query.Cursor = 0
+	query.Limit = 5
+	bids, cursor, err = tstore.FetchBids(query)
+	require.NoError(t, err)
+	require.Len(t, bids, 2)
+	require.Equal(t, pendingBid, bids[0].ToInnerBid())
+	require.Equal(t, committedBid, bids[1])
+	require.Zero(t, cursor)
+}
+
+func TestTransactionalStoreKeyRules(t *testing.T) {
+	engine := NewConfidentialStoreEngine(NewLocalConfidentialStore(), MockTransport{}, MockSigner{}, MockChainSigner{})
+
+	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	dummyCreationTx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: common.Address{0x42},
+		},
+	}), types.NewSuaveSigner(new(big.Int)), testKey)
+	require.NoError(t, err)
+
+	owner, other := common.Address{0x43}, common.Address{0x44}
+
+	tstore := engine.NewTransactionalStore(dummyCreationTx)
+	testBid, err := tstore.InitializeBid(types.Bid{
+		Salt:                RandomBidId(),
+		DecryptionCondition: 46,
+		AllowedPeekers:      []common.Address{owner, other},
+		Version:             "v0-test",
+	})
+	require.NoError(t, err)
+
+	// Writes made before the rules are set are carried with the updated bid
+	_, err = tstore.Store(testBid.Id, other, "public", []byte{0x01})
+	require.NoError(t, err)
+
+	_, err = tstore.SetKeyAccessRule(testBid.Id, common.Address{0x45}, suave.KeyAccessRule{Prefix: "secret"})
+	require.Error(t, err)
+
+	_, err = tstore.SetKeyAccessRule(testBid.Id, owner, suave.KeyAccessRule{Prefix: "secret", Writers: []common.Address{owner}, Readers: []common.Address{owner}})
+	require.NoError(t, err)
+	ruledBid, err := tstore.SetKeyAccessRule(testBid.Id, owner, suave.KeyAccessRule{Prefix: "once", WriteOnce: true})
+	require.NoError(t, err)
+	require.Len(t, ruledBid.KeyRules, 2)
+
+	_, err = tstore.Store(testBid.Id, other, "secret-key", []byte{0x02})
+	require.ErrorIs(t, err, suave.ErrKeyAccessDenied)
+	_, err = tstore.Store(testBid.Id, owner, "secret-key", []byte{0x02})
+	require.NoError(t, err)
+
+	_, err = tstore.Retrieve(testBid.Id, other, "secret-key")
+	require.ErrorIs(t, err, suave.ErrKeyAccessDenied)
+	retrieved, err := tstore.Retrieve(testBid.Id, owner, "secret-key")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x02}, retrieved)
+
+	_, err = tstore.Store(testBid.Id, other, "once", []byte{0x03})
+	require.NoError(t, err)
+	_, err = tstore.Store(testBid.Id, owner, "once", []byte{0x04})
+	require.ErrorIs(t, err, suave.ErrKeyWriteOnce)
+
+	require.NoError(t, tstore.Finalize())
+
+	_, err = engine.Retrieve(testBid.Id, other, "secret-key")
+	require.ErrorIs(t, err, suave.ErrKeyAccessDenied)
+	retrieved, err = engine.Retrieve(testBid.Id, owner, "secret-key")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x02}, retrieved)
+
+	// Rules are enforced against committed data in later transactions
+	tstore = engine.NewTransactionalStore(dummyCreationTx)
+
+	_, err = tstore.Store(testBid.Id, owner, "once", []byte{0x05})
+	require.ErrorIs(t, err, suave.ErrKeyWriteOnce)
+
+	_, err = tstore.SetKeyAccessRule(testBid.Id, owner, suave.KeyAccessRule{Prefix: "public", Writers: []common.Address{owner}})
+	require.Error(t, err)
+}
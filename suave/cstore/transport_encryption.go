@@ -0,0 +1,424 @@
+package cstore
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/log"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"golang.org/x/exp/slices"
+)
+
+var storeKeyAnnounceInterval = time.Minute
+
+var (
+	errNoStoreKey = errors.New("no transport key announced by store")
+
+	deferredMessagesLimit = 4096 // Messages kept for stores which have not announced their key
+)
+
+// deferredMessage is a message of writes waiting for the store to announce its key.
+type deferredMessage struct {
+	store   common.Address
+	message DAMessage
+}
+
+// StoreKeyAnnouncement binds the key store writes are encrypted to over the
+// transport to the address of a store. It is signed by the store address.
+type StoreKeyAnnouncement struct {
+	Store     common.Address `json:"store"`
+	PublicKey suave.Bytes    `json:"publicKey"`
+	// Epoch is the time the key was generated at in milliseconds, a key replaces
+	// the keys of earlier epochs and announcements of those are ignored
+	Epoch     uint64      `json:"epoch"`
+	Signature suave.Bytes `json:"signature"`
+}
+
+// announcedKey is the transport key of another store.
+type announcedKey struct {
+	key   *ecies.PublicKey
+	epoch uint64
+}
+
+// AnnounceStoreKeys publishes the transport encryption key of every local address,
+// other stores need it to send writes to this store.
+func (e *ConfidentialStoreEngine) AnnounceStoreKeys() error {
+	announcements, err := e.keyAnnouncements()
+	if err != nil {
+		return err
+	}
+
+	if len(announcements) == 0 {
+		return nil
+	}
+
+	go e.publish(DAMessage{StoreUUID: e.storeUUID, KeyAnnouncements: announcements})
+	return nil
+}
+
+func (e *ConfidentialStoreEngine) keyAnnouncements() ([]StoreKeyAnnouncement, error) {
+	var announcements []StoreKeyAnnouncement
+
+	publicKey := crypto.FromECDSAPub(&e.transportKey.PublicKey)
+	for _, addr := range e.daSigner.LocalAddresses() {
+		announcement := StoreKeyAnnouncement{Store: addr, PublicKey: publicKey, Epoch: e.transportKeyEpoch}
+
+		announcementBytes, err := SerializeKeyAnnouncementForSigning(&announcement)
+		if err != nil {
+			return nil, fmt.Errorf("confidential engine: could not hash key announcement for signing: %w", err)
+		}
+
+		announcement.Signature, err = e.daSigner.Sign(addr, announcementBytes)
+		if err != nil {
+			// Locked accounts do not receive writes until they can announce their key
+			log.Debug("Confidential engine: could not sign key announcement", "addr", addr, "err", err)
+			continue
+		}
+
+		announcements = append(announcements, announcement)
+	}
+
+	return announcements, nil
+}
+
+func (e *ConfidentialStoreEngine) announceStoreKeys() {
+	ticker := time.NewTicker(storeKeyAnnounceInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.AnnounceStoreKeys(); err != nil {
+			log.Warn("Confidential engine: could not announce store keys", "err", err)
+		}
+
+		select {
+		case <-e.ctx.Done(): // Stop() called
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleKeyAnnouncements records the transport keys of other stores, and sends
+// them the writes deferred until they announced their key. Announcements of a
+// key older than the known one are ignored, so that replaying them does not
+// make writes be encrypted to a key the store no longer has. Seeing a new store
+// triggers an announcement of our own keys so that it learns them without
+// waiting for the next periodic announcement.
+func (e *ConfidentialStoreEngine) handleKeyAnnouncements(announcements []StoreKeyAnnouncement) error {
+	var newStores []common.Address
+	for _, announcement := range announcements {
+		if err := verifyKeyAnnouncement(e.daSigner, &announcement); err != nil {
+			return err
+		}
+
+		publicKey, err := crypto.UnmarshalPubkey(announcement.PublicKey)
+		if err != nil {
+			return fmt.Errorf("confidential engine: invalid key announced for %x: %w", announcement.Store, err)
+		}
+
+		e.storeKeysLock.Lock()
+		known, found := e.storeKeys[announcement.Store]
+		if !found || announcement.Epoch > known.epoch {
+			e.storeKeys[announcement.Store] = announcedKey{key: ecies.ImportECDSAPublic(publicKey), epoch: announcement.Epoch}
+			newStores = append(newStores, announcement.Store)
+		} else if announcement.Epoch < known.epoch {
+			log.Debug("Confidential engine: ignoring announcement of an old key", "store", announcement.Store, "epoch", announcement.Epoch, "current", known.epoch)
+		}
+		e.storeKeysLock.Unlock()
+	}
+
+	for _, store := range newStores {
+		e.sendDeferredMessages(store)
+	}
+
+	if len(newStores) > 0 {
+		return e.AnnounceStoreKeys()
+	}
+	return nil
+}
+
+// storeKey returns the transport key of an allowed store, and nil if the
+// address is not a store. Without a registry to tell stores apart from other
+// addresses every address is taken for a store. It fails with errNoStoreKey if
+// the store has not announced its key yet. Must be called with storeKeysLock held.
+func (e *ConfidentialStoreEngine) storeKey(store common.Address) (*ecies.PublicKey, error) {
+	if known, found := e.storeKeys[store]; found {
+		return known.key, nil
+	}
+
+	if e.registry != nil {
+		isStore, err := e.isExecutionNode(store)
+		if err != nil {
+			return nil, err
+		}
+		if !isStore {
+			return nil, nil
+		}
+	}
+
+	return nil, errNoStoreKey
+}
+
+// writeRecipients returns the keys of the allowed stores of the bid to send
+// its writes to, and the stores which have not announced their key yet. If
+// recipients is not nil, only the allowed stores among recipients are returned.
+// Allowed stores which are not registered execution nodes, such as the contracts
+// some bids list, are not stores and are skipped. Must be called with storeKeysLock held.
+func (e *ConfidentialStoreEngine) writeRecipients(bid suave.Bid, recipients []common.Address, localAddresses []common.Address) (map[common.Address]*ecies.PublicKey, []common.Address, error) {
+	storeKeys := make(map[common.Address]*ecies.PublicKey, len(bid.AllowedStores))
+	var unkeyedStores []common.Address
+	for _, store := range bid.AllowedStores {
+		if slices.Contains(localAddresses, store) {
+			// Already stored locally
+			continue
+		}
+
+		if recipients != nil && !slices.Contains(recipients, store) {
+			continue
+		}
+
+		storeKey, err := e.storeKey(store)
+		if errors.Is(err, errNoStoreKey) {
+			if !slices.Contains(unkeyedStores, store) {
+				unkeyedStores = append(unkeyedStores, store)
+			}
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if storeKey == nil {
+			log.Debug("Confidential engine: not sending write to an address which is not a store", "bid", bid.Id, "addr", store)
+			continue
+		}
+
+		storeKeys[store] = storeKey
+	}
+
+	return storeKeys, unkeyedStores, nil
+}
+
+// unkeyedStores returns the allowed stores of the writes which have not announced their key yet.
+func (e *ConfidentialStoreEngine) unkeyedStores(writes []StoreWrite) ([]common.Address, error) {
+	localAddresses := e.daSigner.LocalAddresses()
+
+	e.storeKeysLock.RLock()
+	defer e.storeKeysLock.RUnlock()
+
+	var res []common.Address
+	for _, sw := range writes {
+		_, unkeyedStores, err := e.writeRecipients(sw.Bid, nil, localAddresses)
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range unkeyedStores {
+			if !slices.Contains(res, store) {
+				res = append(res, store)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// encryptStoreWrites returns the writes to send over the transport, with the
+// plaintext value replaced by its encryption to each of the bid's allowed stores.
+// If recipients is not nil, only the allowed stores among recipients are encrypted to.
+// Allowed stores which have not announced their transport key yet are not
+// encrypted to and are returned, for the writes to be sent to them once they do.
+func (e *ConfidentialStoreEngine) encryptStoreWrites(writes []StoreWrite, recipients []common.Address) ([]StoreWrite, []common.Address, error) {
+	localAddresses := e.daSigner.LocalAddresses()
+
+	e.storeKeysLock.RLock()
+	defer e.storeKeysLock.RUnlock()
+
+	var unkeyedStores []common.Address
+	res := make([]StoreWrite, 0, len(writes))
+	for _, sw := range writes {
+		storeKeys, unkeyed, err := e.writeRecipients(sw.Bid, recipients, localAddresses)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, store := range unkeyed {
+			if !slices.Contains(unkeyedStores, store) {
+				unkeyedStores = append(unkeyedStores, store)
+			}
+		}
+
+		encryptedValues := make(map[common.Address]suave.Bytes, len(storeKeys))
+		for store, storeKey := range storeKeys {
+			if len(sw.Value) == 0 {
+				// ECIES does not encrypt empty messages, there is nothing to hide
+				encryptedValues[store] = suave.Bytes{}
+				continue
+			}
+
+			encrypted, err := ecies.Encrypt(rand.Reader, storeKey, sw.Value, nil, encryptionAdditionalData(sw.Bid.Id, sw.Key))
+			if err != nil {
+				return nil, nil, fmt.Errorf("confidential engine: could not encrypt write for store %x: %w", store, err)
+			}
+			encryptedValues[store] = encrypted
+		}
+
+		res = append(res, StoreWrite{
+			Bid:             sw.Bid,
+			Caller:          sw.Caller,
+			Key:             sw.Key,
+			Version:         sw.Version,
+			EncryptedValues: encryptedValues,
+		})
+	}
+
+	return res, unkeyedStores, nil
+}
+
+// sealMessage returns the message of writes to publish: the values encrypted
+// to the allowed stores among recipients, all of them if nil, and the message
+// signed by the execution node of its source transaction. The writes to stores
+// which have not announced their key yet are deferred until they do.
+func (e *ConfidentialStoreEngine) sealMessage(message DAMessage, recipients []common.Address) (DAMessage, error) {
+	signer, err := ExecutionNodeFromTransaction(message.SourceTx)
+	if err != nil {
+		return DAMessage{}, fmt.Errorf("confidential engine: could not recover execution node from source transaction: %w", err)
+	}
+
+	transportWrites, unkeyedStores, err := e.encryptStoreWrites(message.StoreWrites, recipients)
+	if err != nil {
+		return DAMessage{}, err
+	}
+
+	sealed := DAMessage{
+		SourceTx:    message.SourceTx,
+		StoreWrites: transportWrites,
+		StoreUUID:   message.StoreUUID,
+		Sequence:    message.Sequence,
+	}
+	if err := e.signMessage(signer, &sealed); err != nil {
+		return DAMessage{}, err
+	}
+
+	e.deferMessage(unkeyedStores, message)
+	return sealed, nil
+}
+
+// deferMessage keeps the message of writes to send to the stores once they
+// announce their transport key. The message is kept without the values, which
+// are read from the storage when it is sent.
+func (e *ConfidentialStoreEngine) deferMessage(stores []common.Address, message DAMessage) {
+	if len(stores) == 0 {
+		return
+	}
+
+	deferred := DAMessage{
+		SourceTx:    message.SourceTx,
+		StoreWrites: withoutValues(message.StoreWrites),
+		StoreUUID:   message.StoreUUID,
+		Sequence:    message.Sequence,
+	}
+
+	e.deferredLock.Lock()
+	defer e.deferredLock.Unlock()
+
+	for _, store := range stores {
+		// A message is sealed again when its publication is retried
+		if slices.ContainsFunc(e.deferredMessages, func(d deferredMessage) bool {
+			return d.store == store && d.message.Sequence == deferred.Sequence && d.message.SourceTx.Hash() == deferred.SourceTx.Hash()
+		}) {
+			continue
+		}
+
+		log.Debug("Confidential engine: deferring writes to a store which has not announced its key", "store", store, "sequence", message.Sequence)
+		e.deferredMessages = append(e.deferredMessages, deferredMessage{store: store, message: deferred})
+	}
+	if dropped := len(e.deferredMessages) - deferredMessagesLimit; dropped > 0 {
+		log.Warn("Confidential engine: dropping writes deferred for too long", "messages", dropped)
+		e.deferredMessages = append([]deferredMessage(nil), e.deferredMessages[dropped:]...)
+	}
+}
+
+// sendDeferredMessages sends the messages deferred until the store announced its key.
+func (e *ConfidentialStoreEngine) sendDeferredMessages(store common.Address) {
+	e.deferredLock.Lock()
+	var messages []DAMessage
+	retained := e.deferredMessages[:0]
+	for _, deferred := range e.deferredMessages {
+		if deferred.store == store {
+			messages = append(messages, deferred.message)
+		} else {
+			retained = append(retained, deferred)
+		}
+	}
+	e.deferredMessages = retained
+	e.deferredLock.Unlock()
+
+	for _, message := range messages {
+		message.StoreWrites = e.loadStoreWrites(message.StoreWrites)
+
+		sealed, err := e.sealMessage(message, []common.Address{store})
+		if err != nil {
+			log.Warn("Confidential engine: could not send deferred writes", "store", store, "err", err)
+			continue
+		}
+
+		go e.publish(sealed)
+	}
+}
+
+// decryptStoreWrite returns the value of a write received over the transport,
+// and false if the write was not sent to any of the local addresses.
+func (e *ConfidentialStoreEngine) decryptStoreWrite(sw StoreWrite) ([]byte, bool, error) {
+	for _, addr := range e.daSigner.LocalAddresses() {
+		encrypted, found := sw.EncryptedValues[addr]
+		if !found {
+			continue
+		}
+
+		if len(encrypted) == 0 {
+			return []byte{}, true, nil
+		}
+
+		value, err := ecies.ImportECDSA(e.transportKey).Decrypt(encrypted, nil, encryptionAdditionalData(sw.Bid.Id, sw.Key))
+		if err != nil {
+			return nil, false, fmt.Errorf("confidential engine: could not decrypt write to %x on bid %x: %w", addr, sw.Bid.Id, err)
+		}
+		return value, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// verifyKeyAnnouncement checks that the announcement is signed by the store it announces a key for.
+func verifyKeyAnnouncement(daSigner DASigner, announcement *StoreKeyAnnouncement) error {
+	announcementBytes, err := SerializeKeyAnnouncementForSigning(announcement)
+	if err != nil {
+		return fmt.Errorf("confidential engine: could not hash received key announcement: %w", err)
+	}
+
+	signer, err := daSigner.Sender(announcementBytes, announcement.Signature)
+	if err != nil {
+		return fmt.Errorf("confidential engine: incorrect key announcement signature: %w", err)
+	}
+	if signer != announcement.Store {
+		return fmt.Errorf("confidential engine: key announcement signer %x, expected %x", signer, announcement.Store)
+	}
+
+	return nil
+}
+
+func SerializeKeyAnnouncementForSigning(announcement *StoreKeyAnnouncement) ([]byte, error) {
+	announcementBytes, err := json.Marshal(StoreKeyAnnouncement{
+		Store:     announcement.Store,
+		PublicKey: announcement.PublicKey,
+		Epoch:     announcement.Epoch,
+	})
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return []byte(fmt.Sprintf("\x19Suave Signed Message:\n%d%s", len(announcementBytes), string(announcementBytes))), nil
+}
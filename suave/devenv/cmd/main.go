@@ -22,6 +22,18 @@ var (
 	exNodeEthAddr = common.HexToAddress("b5feafbdd752ad52afb7e1bd2e40432a485bbb7f")
 	exNodeNetAddr = "http://localhost:8545"
 
+	// Key of the execution node, registers the node in the execution node registry
+	exNodeKey = newPrivKeyFromHex("6c45335a22461ccdb978b78ab61b238bad2fae4544fb55c14eb096c875ccfc52")
+
+	// Namespaces of the bids stored by the execution node
+	exNodeNamespaces = []string{
+		"mevshare:v0:unmatchedBundles",
+		"mevshare:v0:ethBundles",
+		"mevshare:v0:ethBundleSimResults",
+		"mevshare:v0:matchBids",
+		"mevshare:v0:mergedBids",
+	}
+
 	// This account is funded in both devnev networks
 	// address: 0xBE69d72ca5f88aCba033a063dF5DBe43a4148De0
 	fundedAccount = newPrivKeyFromHex("91ab9a7e53c220e6210460b65a7a3bb2ca181412a8a7b43ff336b3df1737ce12")
@@ -53,6 +65,26 @@ func main() {
 	var bidId [16]byte
 
 	steps := []step{
+		{
+			name: "Register execution node",
+			action: func() error {
+				exNodeClt := sdk.NewClient(rpc, exNodeKey.priv, exNodeEthAddr)
+				txnResult, err := exNodeClt.RegisterExecutionNode([]common.Address{exNodeEthAddr}, exNodeNamespaces)
+				if err != nil {
+					return err
+				}
+				receipt, err := txnResult.Wait()
+				if err != nil {
+					return err
+				}
+				if receipt.Status == 0 {
+					return fmt.Errorf("failed to register execution node")
+				}
+
+				fmt.Printf("- Execution node registered: %s\n", exNodeEthAddr.Hex())
+				return nil
+			},
+		},
 		{
 			name: "Create and fund test accounts",
 			action: func() error {
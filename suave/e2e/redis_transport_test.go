@@ -6,9 +6,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+	"github.com/ethereum/go-ethereum/suave/cstore"
 	"github.com/ethereum/go-ethereum/suave/sdk"
 	"github.com/stretchr/testify/require"
 )
@@ -84,3 +87,108 @@ func TestRedisBackends(t *testing.T) {
 		require.Equal(t, 1, len(block.Transactions()))
 	}
 }
+
+func TestRedisTransportEncryption(t *testing.T) {
+	mr := miniredis.RunT(t)
+	withMiniredisTransportOpt := func(c *frameworkConfig) {
+		c.suaveConfig.RedisStorePubsubUri = mr.Addr()
+	}
+
+	fr1 := newFramework(t, WithExecutionNode(), withMiniredisTransportOpt)
+	t.Cleanup(fr1.Close)
+
+	var keystoreBackend *keystore.KeyStore = fr1.suethSrv.service.APIBackend.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	keystoreBackend.ImportECDSA(testKey, "")
+
+	fr2 := newFramework(t, WithExecutionNode(), withMiniredisTransportOpt)
+	t.Cleanup(fr2.Close)
+
+	fr3 := newFramework(t, WithExecutionNode(), withMiniredisTransportOpt)
+	t.Cleanup(fr3.Close)
+
+	// Accounts are only available once the nodes are running, announce them explicitly
+	for _, fr := range []*framework{fr1, fr2, fr3} {
+		require.NoError(t, fr.ConfidentialEngine().AnnounceStoreKeys())
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	// Observe the transport as any outside party could
+	observer := cstore.NewRedisPubSubTransport(mr.Addr())
+	require.NoError(t, observer.Start())
+	t.Cleanup(func() { observer.Stop() })
+
+	observedMessages, cancel := observer.Subscribe()
+	t.Cleanup(cancel)
+
+	clt1 := fr1.NewSDKClient()
+
+	ethTx, err := clt1.SignTxn(&types.LegacyTx{
+		Nonce:    0,
+		To:       &testAddr,
+		Value:    big.NewInt(1000),
+		Gas:      21000,
+		GasPrice: big.NewInt(13),
+		Data:     []byte{},
+	})
+	require.NoError(t, err)
+
+	targetBlock := uint64(1)
+	bundle := &types.SBundle{
+		BlockNumber:     big.NewInt(int64(targetBlock)),
+		Txs:             types.Transactions{ethTx},
+		RevertingHashes: []common.Hash{},
+	}
+	bundleBytes, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	allowedPeekers := []common.Address{newBlockBidAddress, newBundleBidAddress, buildEthBlockAddress}
+	allowedStores := []common.Address{fr1.ExecutionNode(), fr2.ExecutionNode()}
+
+	confidentialDataBytes, err := BundleBidContract.Abi.Methods["fetchBidConfidentialBundleData"].Outputs.Pack(bundleBytes)
+	require.NoError(t, err)
+
+	bundleBidContractI := sdk.GetContract(newBundleBidAddress, BundleBidContract.Abi, clt1)
+
+	_, err = bundleBidContractI.SendTransaction("newBid", []interface{}{targetBlock + 1, allowedPeekers, allowedStores}, confidentialDataBytes)
+	requireNoRpcError(t, err)
+
+	block := fr1.suethSrv.ProgressChain()
+	require.Equal(t, 1, len(block.Transactions()))
+	require.Equal(t, uint64(1), block.Receipts[0].Status)
+
+	unpacked, err := BundleBidContract.Abi.Events["BidEvent"].Inputs.Unpack(block.Receipts[0].Logs[0].Data)
+	require.NoError(t, err)
+	bidId := suave.BidId(unpacked[0].([16]byte))
+
+	time.Sleep(1000 * time.Millisecond)
+
+	{ // The allowed store received and decrypted the bundle
+		_, err := fr2.ConfidentialEngine().FetchBidById(bidId)
+		require.NoError(t, err)
+
+		storedBundle, err := fr2.ConfidentialEngine().Retrieve(bidId, newBundleBidAddress, "default:v0:ethBundles")
+		require.NoError(t, err)
+		require.Equal(t, bundleBytes, storedBundle)
+	}
+
+	{ // The store not allowed did not receive anything
+		_, err := fr3.ConfidentialEngine().FetchBidById(bidId)
+		require.Error(t, err)
+	}
+
+	{ // Nothing sent over the transport is readable, and only the allowed store can decrypt it
+		var storeWrites []cstore.StoreWrite
+		for len(observedMessages) > 0 {
+			msg := <-observedMessages
+			storeWrites = append(storeWrites, msg.StoreWrites...)
+		}
+		require.NotEmpty(t, storeWrites)
+
+		for _, sw := range storeWrites {
+			require.Equal(t, bidId, sw.Bid.Id)
+			require.Empty(t, sw.Value)
+			require.Contains(t, sw.EncryptedValues, fr2.ExecutionNode())
+			require.NotContains(t, sw.EncryptedValues, fr3.ExecutionNode())
+		}
+	}
+}
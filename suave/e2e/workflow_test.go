@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
@@ -603,7 +604,7 @@ func TestMevShare(t *testing.T) {
 		require.NoError(t, json.Unmarshal(payloadData, &payloadEnvelope))
 		require.Equal(t, 4, len(payloadEnvelope.ExecutionPayload.Transactions)) // users tx, backrun, user refund, proposer payment
 
-		ethBlock, err := engine.ExecutableDataToBlock(*payloadEnvelope.ExecutionPayload)
+		ethBlock, err := engine.ExecutableDataToBlock(*payloadEnvelope.ExecutionPayload, payloadEnvelope.ParentBeaconBlockRoot)
 		require.NoError(t, err)
 
 		require.Equal(t, ethTx.Hash(), ethBlock.Transactions()[0].Hash())
@@ -693,9 +694,7 @@ func TestMevShareBundleSenderContract(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, uint64(1), receipt.Status)
 
-		retrievedBlockNumber, err := hexutil.DecodeUint64(bundleSentToBuilder.Params[0].Inclusion.Block)
-		require.NoError(t, err)
-		require.Equal(t, targetBlock, retrievedBlockNumber)
+		require.Equal(t, targetBlock, uint64(bundleSentToBuilder.Params[0].Inclusion.Block))
 
 		encodedUserTxBytes, err := userTx.MarshalBinary()
 		require.NoError(t, err)
@@ -705,7 +704,7 @@ func TestMevShareBundleSenderContract(t *testing.T) {
 
 		retrievedTxs := []string{}
 		for _, be := range bundleSentToBuilder.Params[0].Body {
-			retrievedTxs = append(retrievedTxs, be.Tx)
+			retrievedTxs = append(retrievedTxs, be.Tx.String())
 		}
 
 		expectedTxs := []string{hexutil.Encode(encodedUserTxBytes), hexutil.Encode(encodedmatchTxBytes)}
@@ -1096,6 +1095,229 @@ func TestE2EPrecompile_Call(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestSuaveNamespace(t *testing.T) {
+	fr := newFramework(t)
+	defer fr.Close()
+
+	clt := ethclient.NewClient(fr.suethSrv.RPCNode())
+	ctx := context.Background()
+
+	newRequest := func(nonce uint64, to common.Address, data []byte) *types.Transaction {
+		tx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				ExecutionNode: fr.ExecutionNode(),
+				Nonce:         nonce,
+				To:            &to,
+				Gas:           1000000,
+				GasPrice:      big.NewInt(10),
+				Data:          data,
+			},
+		}), signer, testKey)
+		require.NoError(t, err)
+		return tx
+	}
+
+	// Simulations report the result without submitting it
+	request := newRequest(0, isConfidentialAddress, []byte{})
+	simulation, err := clt.SimulateConfidentialRequest(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Bytes{1}, simulation.Result)
+	require.Empty(t, simulation.Error)
+	require.NotZero(t, simulation.GasUsed)
+	require.Empty(t, simulation.StoreWrites)
+
+	status, err := clt.ConfidentialRequestStatus(ctx, request.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "unknown", status.Status)
+
+	// Sent requests are tracked until their result is included
+	resultHash, err := clt.SendConfidentialRequest(ctx, request)
+	require.NoError(t, err)
+
+	status, err = clt.ConfidentialRequestStatus(ctx, request.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "pending", status.Status)
+	require.Equal(t, resultHash, *status.ResultHash)
+
+	block := fr.suethSrv.ProgressChain()
+	require.Len(t, block.Transactions(), 1)
+	require.Equal(t, resultHash, block.Transactions()[0].Hash())
+
+	status, err = clt.ConfidentialRequestStatus(ctx, request.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "included", status.Status)
+	require.Equal(t, block.NumberU64(), uint64(*status.BlockNumber))
+
+	// Reverts are reported with their data
+	reverting := newRequest(1, newBundleBidAddress, []byte{0x1, 0x2, 0x3, 0x4})
+	simulation, err = clt.SimulateConfidentialRequest(ctx, reverting)
+	require.NoError(t, err)
+	require.Equal(t, "execution reverted", simulation.Error)
+
+	_, err = clt.SendConfidentialRequest(ctx, reverting)
+	var rpcErr rpc.DataError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, hexutil.Encode(simulation.Revert), rpcErr.ErrorData())
+
+	status, err = clt.ConfidentialRequestStatus(ctx, reverting.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "failed", status.Status)
+	require.Contains(t, status.Error, "execution reverted")
+
+	// Only confidential compute requests are accepted
+	_, err = clt.SimulateConfidentialRequest(ctx, types.MustSignNewTx(testKey, signer, &types.LegacyTx{To: &testAddr, Gas: 21000, GasPrice: big.NewInt(10)}))
+	require.ErrorContains(t, err, "not a confidential compute request")
+}
+
+func TestSuaveNamespaceAsync(t *testing.T) {
+	fr := newFramework(t)
+	defer fr.Close()
+
+	clt := ethclient.NewClient(fr.suethSrv.RPCNode())
+	ctx := context.Background()
+
+	request, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+		ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+			ExecutionNode: fr.ExecutionNode(),
+			Nonce:         0,
+			To:            &isConfidentialAddress,
+			Gas:           1000000,
+			GasPrice:      big.NewInt(10),
+		},
+	}), signer, testKey)
+	require.NoError(t, err)
+
+	statuses := make(chan *ethclient.ConfidentialRequestStatus, 16)
+	sub, err := clt.SubscribeConfidentialRequestStatus(ctx, request.Hash(), statuses)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	waitStatus := func(want string) *ethclient.ConfidentialRequestStatus {
+		for {
+			select {
+			case status := <-statuses:
+				if status.Status == want {
+					return status
+				}
+			case err := <-sub.Err():
+				t.Fatalf("subscription failed: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("status %s not notified", want)
+			}
+		}
+	}
+	waitStatus("unknown")
+
+	// Submitted requests are executed in the background
+	hash, err := clt.SubmitConfidentialRequest(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, request.Hash(), hash)
+
+	pending := waitStatus("pending")
+
+	status, err := clt.ConfidentialRequestStatus(ctx, request.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "pending", status.Status)
+	require.Equal(t, pending.ResultHash, status.ResultHash)
+
+	block := fr.suethSrv.ProgressChain()
+	require.Len(t, block.Transactions(), 1)
+	require.Equal(t, *pending.ResultHash, block.Transactions()[0].Hash())
+
+	included := waitStatus("included")
+	require.Equal(t, block.NumberU64(), uint64(*included.BlockNumber))
+
+	// Done requests can not be cancelled
+	signature, err := crypto.Sign(accounts.TextHash(request.Hash().Bytes()), testKey)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+	cancelled, err := clt.CancelConfidentialRequest(ctx, request.Hash(), signature)
+	require.NoError(t, err)
+	require.False(t, cancelled)
+}
+
+func TestConfidentialRequestNonces(t *testing.T) {
+	fr := newFramework(t)
+	defer fr.Close()
+
+	clt := ethclient.NewClient(fr.suethSrv.RPCNode())
+	ctx := context.Background()
+
+	newRequest := func(nonce uint64, data []byte) *types.Transaction {
+		tx, err := types.SignTx(types.NewTx(&types.ConfidentialComputeRequest{
+			ConfidentialComputeRecord: types.ConfidentialComputeRecord{
+				ExecutionNode: fr.ExecutionNode(),
+				Nonce:         nonce,
+				To:            &isConfidentialAddress,
+				Gas:           1000000,
+				GasPrice:      big.NewInt(10),
+				Data:          data,
+			},
+		}), signer, testKey)
+		require.NoError(t, err)
+		return tx
+	}
+
+	request := newRequest(0, []byte{})
+	_, err := clt.SendConfidentialRequest(ctx, request)
+	require.NoError(t, err)
+
+	// Requests are executed once, and their nonce is used by their result
+	_, err = clt.SendConfidentialRequest(ctx, request)
+	require.ErrorContains(t, err, txpool.ErrConfidentialRequestKnown.Error())
+
+	_, err = clt.SendConfidentialRequest(ctx, newRequest(0, []byte{0x1}))
+	require.ErrorContains(t, err, txpool.ErrNonceInUse.Error())
+
+	nonce, err := clt.PendingNonceAt(ctx, testAddr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), nonce)
+
+	block := fr.suethSrv.ProgressChain()
+	require.Len(t, block.Transactions(), 1)
+
+	// The txpool picks the new head up asynchronously
+	require.Eventually(t, func() bool {
+		_, err = clt.SendConfidentialRequest(ctx, request)
+		return err != nil && strings.Contains(err.Error(), core.ErrNonceTooLow.Error())
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSDKNonceManager(t *testing.T) {
+	fr := newFramework(t, WithExecutionNode())
+	defer fr.Close()
+
+	clt := fr.NewSDKClient()
+	clt.SetNonceManager(sdk.NewNonceManager(clt.RPC(), testAddr))
+
+	contractAddr := common.Address{0x3}
+	sourceContract := sdk.GetContract(contractAddr, exampleCallSourceContract.Abi, clt)
+
+	// Requests sent concurrently take consecutive nonces
+	errs := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := sourceContract.SendTransaction("callTarget", []interface{}{contractAddr, big.NewInt(101)}, nil)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// The nonce of a failed request is used by the next one
+	_, err := sourceContract.SendTransaction("callTarget", []interface{}{contractAddr, big.NewInt(102)}, nil)
+	require.Error(t, err)
+	_, err = sourceContract.SendTransaction("callTarget", []interface{}{contractAddr, big.NewInt(101)}, nil)
+	require.NoError(t, err)
+
+	block := fr.suethSrv.ProgressChain()
+	require.Len(t, block.Transactions(), 5)
+	for i, tx := range block.Transactions() {
+		require.Equal(t, uint64(i), tx.Nonce())
+	}
+}
+
 type clientWrapper struct {
 	t *testing.T
 
@@ -1151,7 +1373,8 @@ type frameworkConfig struct {
 var defaultFrameworkConfig = frameworkConfig{
 	executionNode:     false,
 	redisStoreBackend: false,
-	suaveConfig:       suave.Config{},
+	// The fake relays listen on the loopback address
+	suaveConfig: suave.Config{HTTP: suave.HTTPConfig{AllowPrivateIPs: true}},
 }
 
 type frameworkOpt func(*frameworkConfig)
@@ -1398,7 +1621,7 @@ func progressChain(t *testing.T, ethservice *eth.Ethereum, parent *types.Header)
 	})
 	require.NoError(t, err)
 	envelope := payload.ResolveFull()
-	block, err := engine.ExecutableDataToBlock(*envelope.ExecutionPayload)
+	block, err := engine.ExecutableDataToBlock(*envelope.ExecutionPayload, envelope.ParentBeaconBlockRoot)
 	require.NoError(t, err)
 
 	n, err := ethservice.BlockChain().InsertChain(types.Blocks{block})
@@ -204,7 +204,11 @@ var structsTemplate = `// Code generated by suave/gen. DO NOT EDIT.
 // Hash: {{hash}}
 package types
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	{{if .UsesBigInt}}"math/big"
+	{{end}}
+	"github.com/ethereum/go-ethereum/common"
+)
 
 {{range .Types}}
 type {{.Name}} {{typ3 .Typ}}
@@ -219,6 +223,9 @@ type {{.Name}} struct {
 {{end}}
 `
 
+// adapterTemplate prefixes the locals holding the inputs and outputs of the
+// precompiles with in and out, so that the names in the spec never collide with
+// the other locals of the adapters.
 var adapterTemplate = `// Code generated by suave/gen. DO NOT EDIT.
 // Hash: {{hash}}
 package vm
@@ -256,39 +263,39 @@ func (b *SuaveRuntimeAdapter) {{.Name}}(input []byte) (res []byte, err error) {
 	_ = unpacked
 	_ = result
 
-	unpacked, err = artifacts.SuaveAbi.Methods["{{.Name}}"].Inputs.Unpack(input)
+	unpacked, err = unpackSuaveInputs("{{.Name}}", input)
 	if err != nil {
 		err = errFailedToUnpackInput
 		return
 	}
 
 	var (
-		{{range .Input}}{{.Name}} {{typ2 .Typ}}
+		{{range .Input}}in{{title .Name}} {{typ2 .Typ}}
 		{{end}})
 	
 	{{range $index, $item := .Input}}{{ if isComplex .Typ }}
-	if err = mapstructure.Decode(unpacked[{{$index}}], &{{.Name}}); err != nil {
+	if err = mapstructure.Decode(unpacked[{{$index}}], &in{{title .Name}}); err != nil {
 		err = errFailedToDecodeField
 		return
 	}
-	{{else}}{{.Name}} = unpacked[{{$index}}].({{typ2 .Typ}}){{end}}
+	{{else}}in{{title .Name}} = unpacked[{{$index}}].({{typ2 .Typ}}){{end}}
 	{{end}}
 
 	var (
-		{{range .Output.Fields}}{{.Name}} {{typ2 .Typ}}
+		{{range .Output.Fields}}out{{title .Name}} {{typ2 .Typ}}
 		{{end}})
 	
-	if {{range .Output.Fields}}{{.Name}},{{end}} err = b.impl.{{.Name}}({{range .Input}}{{.Name}}, {{end}}); err != nil {
+	if {{range .Output.Fields}}out{{title .Name}},{{end}} err = b.impl.{{.Name}}({{range .Input}}in{{title .Name}}, {{end}}); err != nil {
 		return
 	}
 
 	{{ if eq (len .Output.Fields) 0 }}
 	return nil, nil
 	{{else if .Output.Packed}}
-	result = {{range .Output.Fields}}{{.Name}} {{end}}
+	result = {{range .Output.Fields}}out{{title .Name}} {{end}}
 	return result, nil
 	{{else}}
-	result, err = artifacts.SuaveAbi.Methods["{{.Name}}"].Outputs.Pack({{range .Output.Fields}}{{.Name}}, {{end}})
+	result, err = artifacts.SuaveAbi.Methods["{{.Name}}"].Outputs.Pack({{range .Output.Fields}}out{{title .Name}}, {{end}})
 	if err != nil {
 		err = errFailedToPackOutput
 		return
@@ -468,6 +475,18 @@ type desc struct {
 	Functions []functionDef
 }
 
+// UsesBigInt returns whether any of the structs has a field encoded as *big.Int.
+func (d desc) UsesBigInt() bool {
+	for _, s := range d.Structs {
+		for _, f := range s.Fields {
+			if strings.HasPrefix(f.Typ, "uint256") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func toAddressName(input string) string {
 	var result strings.Builder
 	upperPrev := true
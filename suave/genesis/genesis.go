@@ -6,8 +6,7 @@ import (
 	"strings"
 )
 
-// Right now there is only a test.json file because
-// the binary does not compile if the embed.FS is empty.
+// The embedded genesis files can be loaded by name, e.g. 'suave'.
 //
 //go:embed *.json
 var genesisFiles embed.FS
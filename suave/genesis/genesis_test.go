@@ -1,8 +1,12 @@
 package genesis
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	suave "github.com/ethereum/go-ethereum/suave/core"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,3 +23,16 @@ func TestGenesis(t *testing.T) {
 	_, err = Load("not-exists")
 	require.Error(t, err)
 }
+
+func TestSuaveGenesis(t *testing.T) {
+	data, err := Load("suave")
+	require.NoError(t, err)
+
+	genesis := new(core.Genesis)
+	require.NoError(t, json.Unmarshal(data, genesis))
+
+	// The execution node registry is deployed in the genesis
+	registry, ok := genesis.Alloc[suave.ExecutionNodeRegistryAddress]
+	require.True(t, ok)
+	require.Equal(t, vm.ExecutionNodeRegistryCode, registry.Code)
+}
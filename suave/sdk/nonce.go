@@ -0,0 +1,115 @@
+package sdk
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"golang.org/x/exp/slices"
+)
+
+// nonceErrors are the messages of the errors of the node rejecting a
+// transaction or confidential compute request because its nonce is already used,
+// as returned over RPC by the txpool.
+var nonceErrors = []string{
+	"nonce too low",
+	"already known",
+	"replacement transaction underpriced",
+	"confidential compute request already known",
+	"nonce reserved by another confidential compute request",
+	"nonce used by a pooled transaction",
+}
+
+// NonceManager hands out the nonces of an account to the transactions and
+// confidential compute requests sent concurrently on its behalf. The pending
+// nonce of the node only accounts for the transactions it pooled and the
+// requests it is executing, so concurrent senders reading it race for the same
+// nonce. The manager reads it once, and hands out the following nonces itself.
+// Nonces of transactions the node did not accept are handed out again, and the
+// nonce is read again once the node reports the nonces handed out as used.
+type NonceManager struct {
+	client  *ethclient.Client
+	account common.Address
+
+	lock     sync.Mutex
+	synced   bool
+	next     uint64   // Next nonce never handed out
+	released []uint64 // Nonces handed out but not used, in increasing order
+}
+
+// NewNonceManager creates a nonce manager for the account.
+func NewNonceManager(client *ethclient.Client, account common.Address) *NonceManager {
+	return &NonceManager{
+		client:  client,
+		account: account,
+	}
+}
+
+// Next returns the nonce of the next transaction of the account.
+func (m *NonceManager) Next(ctx context.Context) (uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.synced {
+		nonce, err := m.client.PendingNonceAt(ctx, m.account)
+		if err != nil {
+			return 0, err
+		}
+		m.next, m.released, m.synced = nonce, nil, true
+	}
+	if len(m.released) > 0 {
+		nonce := m.released[0]
+		m.released = m.released[1:]
+		return nonce, nil
+	}
+	nonce := m.next
+	m.next++
+	return nonce, nil
+}
+
+// Release hands out the nonce again, as the transaction it was handed out for
+// was not accepted by the node.
+func (m *NonceManager) Release(nonce uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.synced || nonce >= m.next {
+		return
+	}
+	pos, found := slices.BinarySearch(m.released, nonce)
+	if found {
+		return
+	}
+	m.released = slices.Insert(m.released, pos, nonce)
+
+	// Nonces released at the end are never handed out again
+	for len(m.released) > 0 && m.released[len(m.released)-1] == m.next-1 {
+		m.released = m.released[:len(m.released)-1]
+		m.next--
+	}
+}
+
+// Reset makes the manager read the nonce of the account from the node again.
+func (m *NonceManager) Reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.synced, m.released = false, nil
+}
+
+// done records whether the transaction the nonce was handed out for was
+// accepted by the node.
+func (m *NonceManager) done(nonce uint64, err error) {
+	if err == nil {
+		return
+	}
+	for _, nonceErr := range nonceErrors {
+		if strings.Contains(err.Error(), nonceErr) {
+			m.Reset()
+			return
+		}
+	}
+	m.Release(nonce)
+}
@@ -3,7 +3,9 @@ package sdk
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -14,6 +16,17 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
+	suave "github.com/ethereum/go-ethereum/suave/core"
+)
+
+var (
+	// ErrNoExecutionNodeRegistry is returned when looking up execution nodes on a
+	// chain which did not deploy the execution node registry.
+	ErrNoExecutionNodeRegistry = errors.New("execution node registry not deployed")
+
+	// ErrUnregisteredExecutionNode is returned when sending confidential compute
+	// requests to an execution node missing from the execution node registry.
+	ErrUnregisteredExecutionNode = errors.New("execution node not registered")
 )
 
 func DeployContract(bytecode []byte, client *Client) (*TransactionResult, error) {
@@ -42,7 +55,11 @@ func (c *Contract) Address() common.Address {
 	return c.addr
 }
 
-func (c *Contract) SendTransaction(method string, args []interface{}, confidentialDataBytes []byte) (*TransactionResult, error) {
+func (c *Contract) SendTransaction(method string, args []interface{}, confidentialDataBytes []byte) (_ *TransactionResult, err error) {
+	if err := c.client.checkExecutionNode(); err != nil {
+		return nil, err
+	}
+
 	signer, err := c.client.getSigner()
 	if err != nil {
 		return nil, err
@@ -53,11 +70,11 @@ func (c *Contract) SendTransaction(method string, args []interface{}, confidenti
 		return nil, err
 	}
 
-	senderAddr := crypto.PubkeyToAddress(c.client.key.PublicKey)
-	nonce, err := c.client.rpc.PendingNonceAt(context.Background(), senderAddr)
+	nonce, done, err := c.client.nonce()
 	if err != nil {
 		return nil, err
 	}
+	defer func() { done(err) }()
 
 	gasPrice, err := c.client.rpc.SuggestGasPrice(context.Background())
 	if err != nil {
@@ -138,6 +155,9 @@ type Client struct {
 	rpc      *ethclient.Client
 	key      *ecdsa.PrivateKey
 	execNode common.Address
+	nonces   *NonceManager
+
+	execNodeChecked atomic.Bool // Whether the execution node was found registered, or no registry
 }
 
 func NewClient(rpc *rpc.Client, key *ecdsa.PrivateKey, execNode common.Address) *Client {
@@ -153,6 +173,111 @@ func (c *Client) RPC() *ethclient.Client {
 	return c.rpc
 }
 
+// SetNonceManager makes the client take the nonces of its transactions and
+// confidential compute requests from the nonce manager of its account, instead
+// of reading the pending nonce from the node for each of them, so that they can
+// be sent concurrently. Clients of the same account should share the manager.
+func (c *Client) SetNonceManager(nonces *NonceManager) {
+	c.nonces = nonces
+}
+
+// nonce returns the nonce of the next transaction of the client, along with the
+// function to call with the error of sending the transaction.
+func (c *Client) nonce() (uint64, func(error), error) {
+	if c.nonces == nil {
+		nonce, err := c.rpc.PendingNonceAt(context.Background(), crypto.PubkeyToAddress(c.key.PublicKey))
+		return nonce, func(error) {}, err
+	}
+	nonce, err := c.nonces.Next(context.Background())
+	if err != nil {
+		return 0, nil, err
+	}
+	return nonce, func(err error) { c.nonces.done(nonce, err) }, nil
+}
+
+// LookupExecutionNode returns the execution node registered with the address in
+// the execution node registry, or nil if there is none. It returns
+// ErrNoExecutionNodeRegistry if the chain did not deploy the registry.
+func (c *Client) LookupExecutionNode(addr common.Address) (*suave.ExecutionNode, error) {
+	code, err := c.rpc.CodeAt(context.Background(), suave.ExecutionNodeRegistryAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, ErrNoExecutionNodeRegistry
+	}
+
+	registered, err := c.callRegistry("isExecutionNode", addr)
+	if err != nil {
+		return nil, err
+	}
+	if !registered[0].(bool) {
+		return nil, nil
+	}
+
+	node, err := c.callRegistry("getExecutionNode", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &suave.ExecutionNode{
+		Address:    addr,
+		DAKeys:     node[0].([]common.Address),
+		Namespaces: node[1].([]string),
+	}, nil
+}
+
+// RegisterExecutionNode registers the account of the client as an execution
+// node signing its confidential store messages with the DA keys, and storing
+// bids of the namespaces.
+func (c *Client) RegisterExecutionNode(daKeys []common.Address, namespaces []string) (*TransactionResult, error) {
+	calldata, err := suave.ExecutionNodeRegistryABI.Pack("register", daKeys, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendTransaction(&types.LegacyTx{
+		To:   &suave.ExecutionNodeRegistryAddress,
+		Data: calldata,
+	})
+}
+
+func (c *Client) callRegistry(method string, args ...interface{}) ([]interface{}, error) {
+	calldata, err := suave.ExecutionNodeRegistryABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.rpc.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &suave.ExecutionNodeRegistryAddress,
+		Data: calldata,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return suave.ExecutionNodeRegistryABI.Unpack(method, output)
+}
+
+// checkExecutionNode checks that the execution node of the client is
+// registered, if the chain deployed the execution node registry. The registry
+// is only looked up until the node is found registered, the chain rejects the
+// results of the node if it deregisters afterwards.
+func (c *Client) checkExecutionNode() error {
+	if c.execNodeChecked.Load() {
+		return nil
+	}
+	node, err := c.LookupExecutionNode(c.execNode)
+	if errors.Is(err, ErrNoExecutionNodeRegistry) {
+		c.execNodeChecked.Store(true)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return fmt.Errorf("%w: %s", ErrUnregisteredExecutionNode, c.execNode.Hex())
+	}
+	c.execNodeChecked.Store(true)
+	return nil
+}
+
 func (c *Client) getSigner() (types.Signer, error) {
 	chainID, err := c.rpc.ChainID(context.TODO())
 	if err != nil {
@@ -175,15 +300,16 @@ func (c *Client) SignTxn(txn *types.LegacyTx) (*types.Transaction, error) {
 	return ethTx, nil
 }
 
-func (c *Client) SendTransaction(wrappedTxData *types.LegacyTx) (*TransactionResult, error) {
+func (c *Client) SendTransaction(wrappedTxData *types.LegacyTx) (_ *TransactionResult, err error) {
 	senderAddr := crypto.PubkeyToAddress(c.key.PublicKey)
 
 	if wrappedTxData.Nonce == 0 {
-		nonce, err := c.rpc.PendingNonceAt(context.Background(), senderAddr)
+		var done func(error)
+		wrappedTxData.Nonce, done, err = c.nonce()
 		if err != nil {
 			return nil, err
 		}
-		wrappedTxData.Nonce = nonce
+		defer func() { done(err) }()
 	}
 
 	if wrappedTxData.GasPrice == nil {
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.8;
+
+// The execution node registry is deployed in the genesis of SUAVE chains at
+// 0x0000000000000000000000000000000042000000, and is executed natively by the
+// node. Execution nodes register with the keys they sign their confidential
+// store messages with and the bid namespaces they store, any if empty.
+// Registering again replaces the previous registration.
+interface ExecutionNodeRegistry {
+    event ExecutionNodeRegistered(address indexed node);
+    event ExecutionNodeDeregistered(address indexed node);
+
+    function register(address[] calldata daKeys, string[] calldata namespaces) external;
+
+    function deregister() external;
+
+    function isExecutionNode(address node) external view returns (bool);
+
+    function getExecutionNode(address node)
+        external
+        view
+        returns (address[] memory daKeys, string[] memory namespaces);
+
+    function executionNodeOf(address daKey) external view returns (address);
+}
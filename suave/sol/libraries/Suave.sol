@@ -24,6 +24,47 @@ library Suave {
         uint64 gasLimit;
         bytes32 random;
         Withdrawal[] withdrawals;
+        string algorithm;
+        bool fillPending;
+        bytes32 parentBeaconBlockRoot;
+        uint64 excessBlobGas;
+    }
+
+    struct HttpRequest {
+        string url;
+        string method;
+        string[] headers;
+        bytes body;
+        uint64 timeout;
+    }
+
+    struct HttpResponse {
+        uint64 status;
+        string[] headers;
+        bytes body;
+    }
+
+    struct SimulatedBundle {
+        bool success;
+        string error;
+        uint64 gasUsed;
+        uint256 coinbaseDelta;
+        SimulatedTransaction[] txs;
+    }
+
+    struct SimulatedLog {
+        address addr;
+        bytes32[] topics;
+        bytes data;
+    }
+
+    struct SimulatedTransaction {
+        bytes32 txHash;
+        bool success;
+        uint64 gasUsed;
+        string error;
+        uint256 coinbaseDelta;
+        SimulatedLog[] logs;
     }
 
     struct Withdrawal {
@@ -43,6 +84,8 @@ library Suave {
 
     address public constant CONFIDENTIAL_STORE_STORE = 0x0000000000000000000000000000000042020000;
 
+    address public constant DO_HTTPREQUEST = 0x0000000000000000000000000000000043200002;
+
     address public constant ETHCALL = 0x0000000000000000000000000000000042100003;
 
     address public constant EXTRACT_HINT = 0x0000000000000000000000000000000042100037;
@@ -53,10 +96,16 @@ library Suave {
 
     address public constant NEW_BID = 0x0000000000000000000000000000000042030000;
 
+    address public constant QUERY_BIDS = 0x0000000000000000000000000000000042030002;
+
+    address public constant SET_KEY_ACCESS_RULE = 0x0000000000000000000000000000000042030003;
+
     address public constant SIGN_ETH_TRANSACTION = 0x0000000000000000000000000000000040100001;
 
     address public constant SIMULATE_BUNDLE = 0x0000000000000000000000000000000042100000;
 
+    address public constant SIMULATE_ETH_BUNDLE = 0x0000000000000000000000000000000042100004;
+
     address public constant SUBMIT_BUNDLE_JSON_RPC = 0x0000000000000000000000000000000043000001;
 
     address public constant SUBMIT_ETH_BLOCK_BID_TO_RELAY = 0x0000000000000000000000000000000042100002;
@@ -113,6 +162,16 @@ library Suave {
         }
     }
 
+    function doHTTPRequest(HttpRequest memory request) internal view returns (HttpResponse memory) {
+        require(isConfidential());
+        (bool success, bytes memory data) = DO_HTTPREQUEST.staticcall(abi.encode(request));
+        if (!success) {
+            revert PeekerReverted(DO_HTTPREQUEST, data);
+        }
+
+        return abi.decode(data, (HttpResponse));
+    }
+
     function ethcall(address contractAddr, bytes memory input1) internal view returns (bytes memory) {
         (bool success, bytes memory data) = ETHCALL.staticcall(abi.encode(contractAddr, input1));
         if (!success) {
@@ -166,6 +225,36 @@ library Suave {
         return abi.decode(data, (Bid));
     }
 
+    function queryBids(
+        uint64 fromBlock,
+        uint64 toBlock,
+        string[] memory namespaces,
+        uint64 cursor,
+        uint64 limit
+    ) internal view returns (Bid[] memory, uint64) {
+        (bool success, bytes memory data) =
+            QUERY_BIDS.staticcall(abi.encode(fromBlock, toBlock, namespaces, cursor, limit));
+        if (!success) {
+            revert PeekerReverted(QUERY_BIDS, data);
+        }
+
+        return abi.decode(data, (Bid[], uint64));
+    }
+
+    function setKeyAccessRule(
+        BidId bidId,
+        string memory prefix,
+        address[] memory writers,
+        address[] memory readers,
+        bool writeOnce
+    ) internal view {
+        (bool success, bytes memory data) =
+            SET_KEY_ACCESS_RULE.staticcall(abi.encode(bidId, prefix, writers, readers, writeOnce));
+        if (!success) {
+            revert PeekerReverted(SET_KEY_ACCESS_RULE, data);
+        }
+    }
+
     function signEthTransaction(bytes memory txn, string memory chainId, string memory signingKey)
         internal
         view
@@ -188,6 +277,20 @@ library Suave {
         return abi.decode(data, (uint64));
     }
 
+    function simulateEthBundle(BuildBlockArgs memory blockArgs, bytes memory bundleData)
+        internal
+        view
+        returns (SimulatedBundle memory)
+    {
+        require(isConfidential());
+        (bool success, bytes memory data) = SIMULATE_ETH_BUNDLE.staticcall(abi.encode(blockArgs, bundleData));
+        if (!success) {
+            revert PeekerReverted(SIMULATE_ETH_BUNDLE, data);
+        }
+
+        return abi.decode(data, (SimulatedBundle));
+    }
+
     function submitBundleJsonRPC(string memory url, string memory method, bytes memory params)
         internal
         view
@@ -7,13 +7,17 @@ contract SuaveAbi {
 
     function newBid(uint64 decryptionCondition, address[] memory allowedPeekers, address[] memory allowedStores, string memory BidType) external view returns (Suave.Bid memory) {}
 	function fetchBids(uint64 cond, string memory namespace) external view returns (Suave.Bid[] memory) {}
+    function queryBids(uint64 fromBlock, uint64 toBlock, string[] memory namespaces, uint64 cursor, uint64 limit) external view returns (Suave.Bid[] memory, uint64) {}
+    function setKeyAccessRule(Suave.BidId bidId, string memory prefix, address[] memory writers, address[] memory readers, bool writeOnce) external view {}
     function confidentialStoreStore(Suave.BidId bidId, string memory key, bytes memory data) external view {}
     function confidentialStoreRetrieve(Suave.BidId bidId, string memory key) external view returns (bytes memory) {}
     function signEthTransaction(bytes memory txn, string memory chainId, string memory signingKey) external view returns (bytes memory) {}
     function simulateBundle(bytes memory bundleData) external view returns (uint64) {}
+    function simulateEthBundle(Suave.BuildBlockArgs memory blockArgs, bytes memory bundleData) external view returns (Suave.SimulatedBundle memory) {}
     function extractHint(bytes memory bundleData) external view returns (bytes memory) {}
 	function buildEthBlock(Suave.BuildBlockArgs memory blockArgs, Suave.BidId bid, string memory namespace) external view returns (bytes memory, bytes memory) {}
     function submitEthBlockBidToRelay(string memory relayUrl, bytes memory builderBid) external view returns (bytes memory) {}
     function fillMevShareBundle(Suave.BidId bidId) external view returns (bytes memory) {}
     function submitBundleJsonRPC(string memory url, string memory method, bytes memory params) external view returns (bytes memory) {}
+    function doHTTPRequest(Suave.HttpRequest memory request) external view returns (Suave.HttpResponse memory) {}
 }
@@ -63,6 +63,12 @@ library SuaveForge {
         bytes memory data = forgeIt("0x0000000000000000000000000000000042020000", abi.encode(bidId, key, data1));
     }
 
+    function doHTTPRequest(Suave.HttpRequest memory request) internal view returns (Suave.HttpResponse memory) {
+        bytes memory data = forgeIt("0x0000000000000000000000000000000043200002", abi.encode(request));
+
+        return abi.decode(data, (Suave.HttpResponse));
+    }
+
     function ethcall(address contractAddr, bytes memory input1) internal view returns (bytes memory) {
         bytes memory data = forgeIt("0x0000000000000000000000000000000042100003", abi.encode(contractAddr, input1));
 
@@ -101,6 +107,32 @@ library SuaveForge {
         return abi.decode(data, (Suave.Bid));
     }
 
+    function queryBids(
+        uint64 fromBlock,
+        uint64 toBlock,
+        string[] memory namespaces,
+        uint64 cursor,
+        uint64 limit
+    ) internal view returns (Suave.Bid[] memory, uint64) {
+        bytes memory data = forgeIt(
+            "0x0000000000000000000000000000000042030002", abi.encode(fromBlock, toBlock, namespaces, cursor, limit)
+        );
+
+        return abi.decode(data, (Suave.Bid[], uint64));
+    }
+
+    function setKeyAccessRule(
+        Suave.BidId bidId,
+        string memory prefix,
+        address[] memory writers,
+        address[] memory readers,
+        bool writeOnce
+    ) internal view {
+        bytes memory data = forgeIt(
+            "0x0000000000000000000000000000000042030003", abi.encode(bidId, prefix, writers, readers, writeOnce)
+        );
+    }
+
     function signEthTransaction(bytes memory txn, string memory chainId, string memory signingKey)
         internal
         view
@@ -117,6 +149,16 @@ library SuaveForge {
         return abi.decode(data, (uint64));
     }
 
+    function simulateEthBundle(Suave.BuildBlockArgs memory blockArgs, bytes memory bundleData)
+        internal
+        view
+        returns (Suave.SimulatedBundle memory)
+    {
+        bytes memory data = forgeIt("0x0000000000000000000000000000000042100004", abi.encode(blockArgs, bundleData));
+
+        return abi.decode(data, (Suave.SimulatedBundle));
+    }
+
     function submitBundleJsonRPC(string memory url, string memory method, bytes memory params)
         internal
         view